@@ -0,0 +1,467 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/sink"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLayout = "{imei}"
+	dayLayout     = "2006-01-02"
+	gzipExtension = ".gz"
+
+	// Records of a day may arrive late, e.g. from devices buffering them without network
+	completionDelay = time.Hour
+	// Time between two compressions and retention checks
+	maintenanceInterval = 10 * time.Minute
+	// Files not written for this long are closed
+	idleTimeout = 10 * time.Minute
+)
+
+// now can be replaced by tests
+var now = time.Now
+
+// archiveFilename matches the files of the archive, e.g. 2024-01-02.jsonl, 2024-01-02.1.csv.gz
+var archiveFilename = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(\.\d+)?\.(jsonl|csv|gpx)(\.gz)?$`)
+
+// Options of a file archive sink
+type Options struct {
+	Directory     string   // root of the archive
+	Layout        string   // directories of the files under Directory with {imei}, {year}, {month} and {day} placeholders
+	Formats       []string // FormatJSONL, FormatCSV and FormatGPX
+	Compress      bool     // compress the files of completed days with gzip
+	RetentionDays int      // remove the files older than this many days. Zero keeps all files.
+}
+
+// ParseOptions decodes the options of a file archive sink
+func ParseOptions(options map[string]interface{}) (Options, error) {
+	parsed := Options{
+		Layout:   DefaultLayout,
+		Formats:  []string{FormatJSONL, FormatCSV},
+		Compress: true,
+	}
+
+	err := sink.DecodeOptions(options, &parsed)
+	if err != nil {
+		return Options{}, err
+	}
+
+	if parsed.Directory == "" {
+		return Options{}, fmt.Errorf("directory must be specified")
+	}
+	if !strings.Contains(parsed.Layout, "{imei}") {
+		return Options{}, fmt.Errorf("layout must contain {imei}, got %q", parsed.Layout)
+	}
+	if filepath.IsAbs(parsed.Layout) || slices.Contains(strings.Split(filepath.ToSlash(parsed.Layout), "/"), "..") {
+		return Options{}, fmt.Errorf("layout must be relative to the directory, got %q", parsed.Layout)
+	}
+	if len(parsed.Formats) == 0 {
+		return Options{}, fmt.Errorf("at least one format must be specified")
+	}
+	for _, format := range parsed.Formats {
+		if format != FormatJSONL && format != FormatCSV && format != FormatGPX {
+			return Options{}, fmt.Errorf("unknown format: %q", format)
+		}
+	}
+	if parsed.RetentionDays < 0 {
+		return Options{}, fmt.Errorf("retentiondays must not be negative")
+	}
+
+	return parsed, nil
+}
+
+// dayFile is an open file of a device for a day
+type dayFile struct {
+	file      *os.File
+	writer    *bufio.Writer
+	lastWrite time.Time
+}
+
+/*
+Archive writes the records of each device into daily files. It implements sink.Sink.
+Days are in UTC by the timestamp of the records. Files of completed days are compressed and old files are removed.
+*/
+type Archive struct {
+	ctx     context.Context
+	options Options
+
+	files           map[string]*dayFile // by path
+	lastMaintenance time.Time
+
+	sink.HealthState
+}
+
+func NewArchive(ctx context.Context, options Options) *Archive {
+	return &Archive{
+		ctx:     ctx,
+		options: options,
+		files:   make(map[string]*dayFile),
+	}
+}
+
+// Open creates the directory of the archive and completes the days finished while the sink was not running
+func (a *Archive) Open() error {
+	err := os.MkdirAll(a.options.Directory, 0750)
+	if err != nil {
+		return fmt.Errorf("failed to create archive directory. %v", err)
+	}
+
+	return a.maintain()
+}
+
+// Filename returns the path of the file of a device for a day
+func (a *Archive) Filename(imei string, day time.Time, format string) string {
+	day = day.UTC()
+	directory := strings.NewReplacer(
+		"{imei}", imei,
+		"{year}", day.Format("2006"),
+		"{month}", day.Format("01"),
+		"{day}", day.Format("02"),
+	).Replace(a.options.Layout)
+
+	return filepath.Join(a.options.Directory, directory, day.Format(dayLayout)+"."+format)
+}
+
+// Write appends the records of the message to the files of their days. Records are buffered until Flush.
+func (a *Archive) Write(message sink.Message) error {
+	err := a.write(message)
+	a.SetLastError(err)
+
+	return err
+}
+
+func (a *Archive) write(message sink.Message) error {
+	imei := message.Decoded.IMEI
+	if imei == "" || strings.ContainsAny(imei, `/\.`) {
+		return fmt.Errorf("invalid IMEI: %q", imei)
+	}
+
+	for _, avlData := range message.Decoded.Data {
+		record := newRecord(a.ctx, imei, message.SourceAddress, avlData)
+
+		for _, format := range a.options.Formats {
+			file, err := a.open(a.Filename(imei, record.Time, format), format, imei, record.Time.Format(dayLayout))
+			if err != nil {
+				return err
+			}
+
+			err = writeRecord(file.writer, format, record)
+			if err != nil {
+				return fmt.Errorf("failed to write archive file %s. %v", file.file.Name(), err)
+			}
+			file.lastWrite = now()
+		}
+	}
+
+	return nil
+}
+
+// open returns the open file or opens it for appending. New files get the header of their format.
+func (a *Archive) open(path string, format string, imei string, day string) (*dayFile, error) {
+	file, ok := a.files[path]
+	if ok {
+		return file, nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0750)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive directory. %v", err)
+	}
+
+	// #nosec G304 path is built from the configured directory and the IMEI
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive file. %v", err)
+	}
+
+	// GPX footer is written when the day is completed. It is removed if records of the day arrive later.
+	size, err := removeFooter(f, format)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to open archive file %s. %v", path, err)
+	}
+
+	_, err = f.Seek(0, io.SeekEnd)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	file = &dayFile{
+		file:      f,
+		writer:    bufio.NewWriter(f),
+		lastWrite: now(),
+	}
+
+	if size == 0 {
+		err = writeHeader(file.writer, format, imei, day)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to write header of archive file %s. %v", path, err)
+		}
+	}
+
+	a.files[path] = file
+
+	return file, nil
+}
+
+// removeFooter truncates the GPX footer if the file has it and returns the size of the file
+func removeFooter(f *os.File, format string) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+
+	if format != FormatGPX || size < int64(len(gpxFooter)) {
+		return size, nil
+	}
+
+	tail := make([]byte, len(gpxFooter))
+	_, err = f.ReadAt(tail, size-int64(len(tail)))
+	if err != nil {
+		return 0, err
+	}
+	if string(tail) != gpxFooter {
+		return size, nil
+	}
+
+	size -= int64(len(gpxFooter))
+	return size, f.Truncate(size)
+}
+
+// Flush writes the buffered records to disk, closes idle files and completes the finished days from time to time
+func (a *Archive) Flush() error {
+	err := a.flush()
+	if err == nil && now().Sub(a.lastMaintenance) >= maintenanceInterval {
+		err = a.maintain()
+	}
+	a.SetLastError(err)
+
+	return err
+}
+
+func (a *Archive) flush() error {
+	for path, file := range a.files {
+		err := file.writer.Flush()
+		if err != nil {
+			return fmt.Errorf("failed to write archive file %s. %v", path, err)
+		}
+		err = file.file.Sync()
+		if err != nil {
+			return fmt.Errorf("failed to sync archive file %s. %v", path, err)
+		}
+
+		if now().Sub(file.lastWrite) >= idleTimeout {
+			err = a.close(path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (a *Archive) close(path string) error {
+	file, ok := a.files[path]
+	if !ok {
+		return nil
+	}
+	delete(a.files, path)
+
+	err := file.writer.Flush()
+	if err != nil {
+		_ = file.file.Close()
+		return fmt.Errorf("failed to write archive file %s. %v", path, err)
+	}
+
+	return file.file.Close()
+}
+
+func (a *Archive) Close() error {
+	var result error
+	for path := range a.files {
+		err := a.close(path)
+		if err != nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// maintain completes the files of finished days and removes the files older than the retention
+func (a *Archive) maintain() error {
+	a.lastMaintenance = now()
+	today := now().UTC().Truncate(24 * time.Hour)
+
+	return filepath.WalkDir(a.options.Directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		match := archiveFilename.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil
+		}
+		day, err := time.Parse(dayLayout, match[1])
+		if err != nil {
+			return nil
+		}
+
+		if a.options.RetentionDays > 0 && day.Before(today.AddDate(0, 0, -a.options.RetentionDays)) {
+			return a.remove(path)
+		}
+
+		completed := now().Sub(day.AddDate(0, 0, 1)) >= completionDelay
+		if completed && match[4] == "" {
+			return a.complete(path, match[3])
+		}
+
+		return nil
+	})
+}
+
+func (a *Archive) remove(path string) error {
+	err := a.close(path)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return fmt.Errorf("failed to remove old archive file. %v", err)
+	}
+
+	config.GetLogger(a.ctx).Debugf("Archive file %s is removed by the retention", path)
+
+	return nil
+}
+
+// complete closes the file of a finished day, writes the GPX footer and compresses it if enabled
+func (a *Archive) complete(path string, format string) error {
+	err := a.close(path)
+	if err != nil {
+		return err
+	}
+
+	if format == FormatGPX {
+		err = appendFooter(path)
+		if err != nil {
+			return fmt.Errorf("failed to complete archive file %s. %v", path, err)
+		}
+	}
+
+	if !a.options.Compress {
+		return nil
+	}
+
+	err = compress(path, compressedFilename(path))
+	if err != nil {
+		return fmt.Errorf("failed to compress archive file %s. %v", path, err)
+	}
+
+	return nil
+}
+
+// appendFooter writes the GPX footer if the file does not have it yet
+func appendFooter(path string) error {
+	// #nosec G304 path is in the archive directory
+	f, err := os.OpenFile(path, os.O_RDWR, 0640)
+	if err != nil {
+		return err
+	}
+
+	size, err := removeFooter(f, FormatGPX)
+	if err == nil {
+		_, err = f.WriteAt([]byte(gpxFooter), size)
+	}
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+/*
+compressedFilename returns the first free name for the compressed file. Records arriving after their day was compressed
+are archived into additional files, e.g. 2024-01-02.1.jsonl.gz besides 2024-01-02.jsonl.gz.
+*/
+func compressedFilename(path string) string {
+	directory, name := filepath.Split(path)
+	day, extension, _ := strings.Cut(name, ".")
+
+	target := path + gzipExtension
+	for i := 1; fileExists(target); i++ {
+		target = filepath.Join(directory, day+"."+strconv.Itoa(i)+"."+extension+gzipExtension)
+	}
+
+	return target
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compress writes the file into target with gzip and removes it. Target is renamed in place only if it is complete.
+func compress(path string, target string) error {
+	// #nosec G304 path is in the archive directory
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	temporary := target + ".tmp"
+	// #nosec G304 target is in the archive directory
+	destination, err := os.OpenFile(temporary, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(destination)
+	_, err = io.Copy(writer, source)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		err = destination.Sync()
+	}
+	closeErr := destination.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(temporary)
+		return err
+	}
+
+	err = os.Rename(temporary, target)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,190 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"github.com/halacs/haltonika/sink/sinktest"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readLines reads a plain or a gzip compressed file
+func readLines(t *testing.T, path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s. %v", path, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, gzipExtension) {
+		reader, err = gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("Failed to decompress %s. %v", path, err)
+		}
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines
+}
+
+func TestParseOptions(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Options       map[string]interface{}
+		ExpectedError bool
+	}{
+		{
+			Name:    "Defaults",
+			Options: map[string]interface{}{"directory": "/var/lib/haltonika/archive"},
+		},
+		{
+			Name:    "All options",
+			Options: map[string]interface{}{"directory": "archive", "layout": "{year}/{month}/{imei}", "formats": []interface{}{"jsonl", "gpx"}, "compress": false, "retentiondays": 365},
+		},
+		{
+			Name:          "Missing directory",
+			Options:       map[string]interface{}{},
+			ExpectedError: true,
+		},
+		{
+			Name:          "Layout without IMEI",
+			Options:       map[string]interface{}{"directory": "archive", "layout": "{year}"},
+			ExpectedError: true,
+		},
+		{
+			Name:          "Layout outside of the directory",
+			Options:       map[string]interface{}{"directory": "archive", "layout": "../{imei}"},
+			ExpectedError: true,
+		},
+		{
+			Name:          "Unknown format",
+			Options:       map[string]interface{}{"directory": "archive", "formats": []interface{}{"kml"}},
+			ExpectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			_, err := ParseOptions(testCase.Options)
+			if (err != nil) != testCase.ExpectedError {
+				test.Errorf("Wrong result! Expected error: %v Actual: %v", testCase.ExpectedError, err)
+			}
+		})
+	}
+}
+
+func TestArchive(t *testing.T) {
+	defer func() {
+		now = time.Now
+	}()
+	current := time.Date(2018, 6, 3, 23, 40, 0, 0, time.UTC)
+	now = func() time.Time {
+		return current
+	}
+
+	directory := t.TempDir()
+	archive := NewArchive(sinktest.NewContext(), Options{
+		Directory:     directory,
+		Layout:        "{year}/{imei}",
+		Formats:       []string{FormatJSONL, FormatCSV, FormatGPX},
+		Compress:      true,
+		RetentionDays: 30,
+	})
+	err := archive.Open()
+	if err != nil {
+		t.Fatalf("Failed to open archive. %v", err)
+	}
+	defer func() {
+		_ = archive.Close()
+	}()
+
+	message := sinktest.NewMessage(t)
+	err = archive.Write(message)
+	if err != nil {
+		t.Fatalf("Failed to write message. %v", err)
+	}
+	err = archive.Flush()
+	if err != nil {
+		t.Fatalf("Failed to flush archive. %v", err)
+	}
+
+	day := time.Date(2018, 6, 3, 0, 0, 0, 0, time.UTC)
+	jsonl := archive.Filename("352094089397464", day, FormatJSONL)
+	if jsonl != filepath.Join(directory, "2018", "352094089397464", "2018-06-03.jsonl") {
+		t.Errorf("Wrong file name: %v", jsonl)
+	}
+
+	// Header and one line per record
+	testCases := []struct {
+		Format        string
+		ExpectedLines int
+		ExpectedFirst string
+	}{
+		{FormatJSONL, 4, `{"time":"2018-06-03T23:38:10.05Z","imei":"352094089397464"`},
+		{FormatCSV, 5, "time,imei,latitude,longitude,altitude,angle,satellites,speed,priority,eventID,source,io"},
+		{FormatGPX, 7, `<?xml version="1.0" encoding="UTF-8"?>`},
+	}
+	for _, testCase := range testCases {
+		lines := readLines(t, archive.Filename("352094089397464", day, testCase.Format))
+		if len(lines) != testCase.ExpectedLines || !strings.HasPrefix(lines[0], testCase.ExpectedFirst) {
+			t.Errorf("Wrong %s file! Expected: %v lines starting with %v Actual: %v", testCase.Format, testCase.ExpectedLines, testCase.ExpectedFirst, lines)
+		}
+	}
+
+	// Completed day is compressed with GPX footer
+	current = current.Add(2 * time.Hour)
+	err = archive.Flush()
+	if err != nil {
+		t.Fatalf("Failed to flush archive. %v", err)
+	}
+	if fileExists(jsonl) {
+		t.Errorf("File of a completed day must be removed after compression")
+	}
+	lines := readLines(t, jsonl+gzipExtension)
+	if len(lines) != 4 {
+		t.Errorf("Wrong compressed file! Expected: 4 lines Actual: %v", lines)
+	}
+	lines = readLines(t, archive.Filename("352094089397464", day, FormatGPX)+gzipExtension)
+	if len(lines) != 8 || lines[7] != strings.TrimSpace(gpxFooter) {
+		t.Errorf("Wrong compressed GPX file: %v", lines)
+	}
+
+	// Late records of a compressed day get a new file
+	err = archive.Write(message)
+	if err != nil {
+		t.Fatalf("Failed to write message. %v", err)
+	}
+	current = current.Add(maintenanceInterval)
+	err = archive.Flush()
+	if err != nil {
+		t.Fatalf("Failed to flush archive. %v", err)
+	}
+	late := filepath.Join(directory, "2018", "352094089397464", "2018-06-03.1.csv.gz")
+	lines = readLines(t, late)
+	if len(lines) != 5 || lines[0] != strings.Join(csvHeader, ",") {
+		t.Errorf("Wrong late file: %v", lines)
+	}
+
+	// Old files are removed
+	current = current.AddDate(0, 0, 31)
+	err = archive.Flush()
+	if err != nil {
+		t.Fatalf("Failed to flush archive. %v", err)
+	}
+	files, err := filepath.Glob(filepath.Join(directory, "2018", "352094089397464", "*"))
+	if err != nil || len(files) != 0 {
+		t.Errorf("Files must be removed after the retention: %v", files)
+	}
+}
@@ -0,0 +1,133 @@
+package archive
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/sink"
+	"html"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Formats of the archive files
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+	FormatGPX   = "gpx"
+)
+
+var csvHeader = []string{"time", "imei", "latitude", "longitude", "altitude", "angle", "satellites", "speed", "priority", "eventID", "source", "io"}
+
+const (
+	gpxHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<gpx version="1.1" creator="` + config.AppName + `" xmlns="http://www.topografix.com/GPX/1/1">` + "\n" +
+		"<trk><name>%s %s</name><trkseg>\n"
+	gpxFooter = "</trkseg></trk></gpx>\n"
+)
+
+// Record is a line of the JSONL files
+type Record struct {
+	Time       time.Time              `json:"time"`
+	IMEI       string                 `json:"imei"`
+	Latitude   float64                `json:"latitude"`
+	Longitude  float64                `json:"longitude"`
+	Altitude   int16                  `json:"altitude"`
+	Angle      uint16                 `json:"angle"`
+	Satellites uint8                  `json:"satellites"`
+	Speed      uint16                 `json:"speed"`
+	Priority   uint8                  `json:"priority"`
+	EventID    uint16                 `json:"eventID"`
+	Source     string                 `json:"source"`
+	IO         map[string]interface{} `json:"io"` // by IOID<n> keys
+}
+
+func newRecord(ctx context.Context, imei string, source string, record codec.AvlData) Record {
+	return Record{
+		Time:       sink.Timestamp(record).UTC(),
+		IMEI:       imei,
+		Latitude:   sink.Latitude(record),
+		Longitude:  sink.Longitude(record),
+		Altitude:   record.Altitude,
+		Angle:      record.Angle,
+		Satellites: record.VisSat,
+		Speed:      record.Speed,
+		Priority:   record.Priority,
+		EventID:    record.EventID,
+		Source:     source,
+		IO:         sink.IOValues(ctx, record),
+	}
+}
+
+// writeHeader writes the beginning of a new file
+func writeHeader(w io.Writer, format string, imei string, day string) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, csvHeader)
+	case FormatGPX:
+		_, err := fmt.Fprintf(w, gpxHeader, html.EscapeString(imei), day)
+		return err
+	default:
+		return nil
+	}
+}
+
+// writeRecord writes a record in the given format
+func writeRecord(w io.Writer, format string, record Record) error {
+	switch format {
+	case FormatJSONL:
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(line, '\n'))
+		return err
+	case FormatCSV:
+		elements, err := json.Marshal(record.IO)
+		if err != nil {
+			return err
+		}
+
+		return writeCSV(w, []string{
+			record.Time.Format(time.RFC3339Nano),
+			record.IMEI,
+			strconv.FormatFloat(record.Latitude, 'f', -1, 64),
+			strconv.FormatFloat(record.Longitude, 'f', -1, 64),
+			strconv.Itoa(int(record.Altitude)),
+			strconv.Itoa(int(record.Angle)),
+			strconv.Itoa(int(record.Satellites)),
+			strconv.Itoa(int(record.Speed)),
+			strconv.Itoa(int(record.Priority)),
+			strconv.Itoa(int(record.EventID)),
+			record.Source,
+			string(elements),
+		})
+	case FormatGPX:
+		// Records without GPS fix would be jumps to 0,0 on the track
+		if record.Latitude == 0 && record.Longitude == 0 {
+			return nil
+		}
+
+		_, err := fmt.Fprintf(w, "<trkpt lat=\"%s\" lon=\"%s\"><ele>%d</ele><time>%s</time><sat>%d</sat></trkpt>\n",
+			strconv.FormatFloat(record.Latitude, 'f', -1, 64), strconv.FormatFloat(record.Longitude, 'f', -1, 64),
+			record.Altitude, record.Time.Format(time.RFC3339Nano), record.Satellites)
+		return err
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+func writeCSV(w io.Writer, fields []string) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(fields)
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
@@ -0,0 +1,46 @@
+package capture
+
+import (
+	"encoding/hex"
+	"time"
+)
+
+// Direction tells if a packet was received from or sent to a device
+type Direction string
+
+const (
+	Received Direction = "in"
+	Sent     Direction = "out"
+)
+
+const (
+	TransportUdp = "udp"
+	TransportTcp = "tcp"
+)
+
+// Packet is one captured datagram or TCP message. Captures are stored as JSON lines of packets.
+type Packet struct {
+	Time      time.Time `json:"time"`
+	Direction Direction `json:"direction"`
+	Transport string    `json:"transport"`
+	Remote    string    `json:"remote"`
+	IMEI      string    `json:"imei,omitempty"` // empty if the sender is not known yet
+	Data      HexBytes  `json:"data"`
+}
+
+// HexBytes is stored as hex string so captures can be read and grepped easily
+type HexBytes []byte
+
+func (h HexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+func (h *HexBytes) UnmarshalText(text []byte) error {
+	data, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+
+	*h = data
+	return nil
+}
@@ -0,0 +1,230 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, filename string) []Packet {
+	file, err := os.Open(filename) // #nosec G304
+	if err != nil {
+		t.Fatalf("Failed to open capture file. %v", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var packets []Packet
+	reader := NewReader(file)
+	for {
+		packet, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return packets
+		}
+		if err != nil {
+			t.Fatalf("Failed to read capture file. %v", err)
+		}
+		packets = append(packets, packet)
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "capture.jsonl")
+
+	writer, err := NewWriter(Config{
+		Filename: filename,
+		IMEIs:    []string{"350424063817363"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer. %v", err)
+	}
+
+	expected := Packet{
+		Time:      time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC),
+		Direction: Received,
+		Transport: TransportUdp,
+		Remote:    "127.0.0.1:1234",
+		IMEI:      "350424063817363",
+		Data:      []byte{0x00, 0x05, 0xCA, 0xFE},
+	}
+
+	for _, packet := range []Packet{
+		expected,
+		{Time: time.Now(), Direction: Sent, IMEI: "352094089397464", Data: []byte{0x01}}, // filtered out
+		{Time: time.Now(), Direction: Received, Data: []byte{0xFF}},                      // unknown device is filtered out too
+	} {
+		err = writer.Write(packet)
+		if err != nil {
+			t.Fatalf("Failed to write packet. %v", err)
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("Failed to close writer. %v", err)
+	}
+
+	packets := readAll(t, filename)
+	if len(packets) != 1 {
+		t.Fatalf("Wrong number of packets! Expected: 1 Actual: %d", len(packets))
+	}
+
+	actual := packets[0]
+	if !actual.Time.Equal(expected.Time) || actual.Direction != expected.Direction || actual.Transport != expected.Transport ||
+		actual.Remote != expected.Remote || actual.IMEI != expected.IMEI || !bytes.Equal(actual.Data, expected.Data) {
+		t.Errorf("Wrong packet! Expected: %+v Actual: %+v", expected, actual)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "capture.jsonl")
+	current := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time {
+		return current
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	writer, err := NewWriter(Config{
+		Filename: filename,
+		MaxSize:  300,
+		MaxAge:   time.Hour,
+		MaxFiles: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer. %v", err)
+	}
+
+	packet := Packet{Time: current, Direction: Received, Transport: TransportTcp, Remote: "127.0.0.1:1234", Data: make([]byte, 40)}
+
+	// Every packet is about 190 bytes long so each one goes into a new file
+	for i := 0; i < 4; i++ {
+		current = current.Add(time.Second)
+		err = writer.Write(packet)
+		if err != nil {
+			t.Fatalf("Failed to write packet. %v", err)
+		}
+	}
+
+	files, err := writer.RotatedFiles()
+	if err != nil {
+		t.Fatalf("Failed to list rotated files. %v", err)
+	}
+	if len(files) != 2 || filepath.Base(files[1]) != "capture-20240102T030409.000.jsonl" {
+		t.Errorf("Wrong rotated files: %v", files)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("Failed to close writer. %v", err)
+	}
+
+	// Without size limit files are rotated by age only
+	filename = filepath.Join(t.TempDir(), "capture.jsonl")
+	writer, err = NewWriter(Config{
+		Filename: filename,
+		MaxAge:   time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer. %v", err)
+	}
+
+	for _, elapsed := range []time.Duration{0, 30 * time.Minute, time.Hour} {
+		current = current.Add(elapsed)
+		err = writer.Write(packet)
+		if err != nil {
+			t.Fatalf("Failed to write packet. %v", err)
+		}
+	}
+	if len(readAll(t, filename)) != 1 {
+		t.Errorf("File must be rotated after an hour")
+	}
+
+	files, err = writer.RotatedFiles()
+	if err != nil {
+		t.Fatalf("Failed to list rotated files. %v", err)
+	}
+	if len(files) != 1 || len(readAll(t, files[0])) != 2 {
+		t.Errorf("Wrong rotated files: %v", files)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("Failed to close writer. %v", err)
+	}
+}
+
+func TestRotationFailure(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "capture.jsonl")
+	current := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time {
+		return current
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	writer, err := NewWriter(Config{
+		Filename: filename,
+		MaxSize:  300,
+		MaxFiles: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer. %v", err)
+	}
+	defer func() {
+		_ = writer.Close()
+	}()
+
+	packet := Packet{Time: current, Direction: Received, Transport: TransportTcp, Remote: "127.0.0.1:1234", Data: make([]byte, 40)}
+	err = writer.Write(packet)
+	if err != nil {
+		t.Fatalf("Failed to write packet. %v", err)
+	}
+
+	// Non-empty directories can be neither the target of the rename nor removed
+	for _, name := range []string{"capture-20240102T030406.000.jsonl", "capture-20000101T000000.000.jsonl"} {
+		err = os.MkdirAll(filepath.Join(dir, name, "keep"), 0700)
+		if err != nil {
+			t.Fatalf("Failed to create directory. %v", err)
+		}
+	}
+
+	current = current.Add(time.Second)
+	err = writer.Write(packet)
+	if err == nil {
+		t.Errorf("Rename must fail")
+	}
+	if len(readAll(t, filename)) != 2 {
+		t.Errorf("Packet must be written into the reopened file")
+	}
+
+	current = current.Add(time.Second)
+	err = writer.Write(packet)
+	if err == nil {
+		t.Errorf("Removal of old files must fail")
+	}
+	if len(readAll(t, filename)) != 1 {
+		t.Errorf("Packet must be written into the new file")
+	}
+
+	for _, name := range []string{"capture-20240102T030406.000.jsonl", "capture-20000101T000000.000.jsonl"} {
+		err = os.RemoveAll(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Failed to remove directory. %v", err)
+		}
+	}
+
+	current = current.Add(time.Second)
+	err = writer.Write(packet)
+	if err != nil {
+		t.Errorf("Failed to write packet after failed rotations. %v", err)
+	}
+}
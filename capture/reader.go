@@ -0,0 +1,54 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	maxLineLength = 1024 * 1024
+)
+
+// Reader reads packets of a capture file one by one
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+
+	return &Reader{
+		scanner: scanner,
+	}
+}
+
+// Next returns the next packet or io.EOF at the end of the capture
+func (r *Reader) Next() (Packet, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var packet Packet
+		err := json.Unmarshal(line, &packet)
+		if err != nil {
+			return Packet{}, fmt.Errorf("invalid packet in line %d. %v", r.line, err)
+		}
+
+		return packet, nil
+	}
+
+	err := r.scanner.Err()
+	if err != nil {
+		return Packet{}, err
+	}
+
+	return Packet{}, io.EOF
+}
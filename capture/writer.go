@@ -0,0 +1,216 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	rotatedTimeLayout = "20060102T150405.000"
+)
+
+// now can be replaced by tests
+var now = time.Now
+
+// Config tells where and how long packets are captured
+type Config struct {
+	Filename string        // Path of the active capture file. Rotated files get a timestamp before their extension.
+	MaxSize  int64         // Rotate the file after it reached this many bytes. Zero disables rotation by size.
+	MaxAge   time.Duration // Rotate the file after it is this old. Zero disables rotation by age.
+	MaxFiles int           // Number of rotated files to keep. Zero keeps all of them.
+	IMEIs    []string      // Capture packets only of these devices. Empty captures all packets.
+}
+
+// Writer writes packets into a rotating capture file. It is safe for concurrent use.
+type Writer struct {
+	config Config
+	lock   sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func NewWriter(cfg Config) (*Writer, error) {
+	if cfg.Filename == "" {
+		return nil, fmt.Errorf("capture file name must be specified")
+	}
+
+	w := &Writer{
+		config: cfg,
+	}
+
+	err := w.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write appends the packet to the capture file if its device is captured. Rotation happens before writing.
+func (w *Writer) Write(packet Packet) error {
+	if len(w.config.IMEIs) > 0 && !slices.Contains(w.config.IMEIs, packet.IMEI) {
+		return nil
+	}
+
+	line, err := json.Marshal(packet)
+	if err != nil {
+		return fmt.Errorf("failed to encode packet. %v", err)
+	}
+	line = append(line, '\n')
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return fmt.Errorf("capture file is closed")
+	}
+
+	// The packet is written even if the rotation failed as long as a file could be opened
+	var rotateErr error
+	if w.shouldRotate(int64(len(line))) {
+		rotateErr = w.rotate()
+		if w.file == nil {
+			return rotateErr
+		}
+	}
+
+	n, err := w.file.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write capture file. %v", err)
+	}
+
+	return rotateErr
+}
+
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *Writer) open() error {
+	file, err := os.OpenFile(w.config.Filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open capture file. %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to get size of capture file. %v", err)
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.opened = now()
+
+	return nil
+}
+
+func (w *Writer) shouldRotate(next int64) bool {
+	// Never rotate an empty file, a packet bigger than the limit must go somewhere
+	if w.size == 0 {
+		return false
+	}
+	if w.config.MaxSize > 0 && w.size+next > w.config.MaxSize {
+		return true
+	}
+	if w.config.MaxAge > 0 && now().Sub(w.opened) >= w.config.MaxAge {
+		return true
+	}
+
+	return false
+}
+
+// rotate renames the current file and opens a new one. The current file is reopened if it can not be renamed, so
+// capturing goes on.
+func (w *Writer) rotate() error {
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return w.reopen(fmt.Errorf("failed to close capture file. %v", err))
+	}
+
+	err = os.Rename(w.config.Filename, w.rotatedFilename(now()))
+	if err != nil {
+		return w.reopen(fmt.Errorf("failed to rotate capture file. %v", err))
+	}
+
+	err = w.open()
+	if err != nil {
+		return err
+	}
+
+	return w.removeOldFiles()
+}
+
+// reopen opens the capture file after a failed rotation and returns the reason of the failure
+func (w *Writer) reopen(reason error) error {
+	err := w.open()
+	if err != nil {
+		return fmt.Errorf("%v. %v", reason, err)
+	}
+
+	return reason
+}
+
+// rotatedFilename puts the time of rotation before the extension. E.g. capture.jsonl -> capture-20240102T150405.000.jsonl
+func (w *Writer) rotatedFilename(t time.Time) string {
+	ext := filepath.Ext(w.config.Filename)
+	base := strings.TrimSuffix(w.config.Filename, ext)
+
+	return fmt.Sprintf("%s-%s%s", base, t.UTC().Format(rotatedTimeLayout), ext)
+}
+
+// RotatedFiles lists the rotated capture files from the oldest to the newest
+func (w *Writer) RotatedFiles() ([]string, error) {
+	ext := filepath.Ext(w.config.Filename)
+	base := strings.TrimSuffix(w.config.Filename, ext)
+
+	files, err := filepath.Glob(base + "-*" + ext)
+	if err != nil {
+		return nil, err
+	}
+
+	// Timestamps sort lexicographically
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func (w *Writer) removeOldFiles() error {
+	if w.config.MaxFiles <= 0 {
+		return nil
+	}
+
+	files, err := w.RotatedFiles()
+	if err != nil {
+		return fmt.Errorf("failed to list rotated capture files. %v", err)
+	}
+
+	for len(files) > w.config.MaxFiles {
+		err = os.Remove(files[0])
+		if err != nil {
+			return fmt.Errorf("failed to remove old capture file. %v", err)
+		}
+		files = files[1:]
+	}
+
+	return nil
+}
@@ -0,0 +1,369 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/basvdlei/gotsmart/crc16"
+)
+
+// Decoded represents a decoded AVL data packet
+type Decoded struct {
+	IMEI     string    // IMEI number, if 15 digits long it is also validated by its checksum
+	CodecID  byte      // Codec8, Codec8Extended or Codec16
+	NoOfData uint8     // Number of Data
+	Data     []AvlData // Slice with avl data
+	Response []byte    // Response to be sent back to the device to acknowledge the records
+}
+
+// AvlData represents one record of an AVL data packet
+type AvlData struct {
+	UtimeMs        uint64    // Utime in milliseconds
+	Utime          uint64    // Utime in seconds
+	Priority       uint8     // Priority, [0 Low, 1 High, 2 Panic]
+	Lat            int32     // Latitude (between 850000000 and -850000000)
+	Lng            int32     // Longitude (between 1800000000 and -1800000000)
+	Altitude       int16     // Altitude in meters above sea level
+	Angle          uint16    // Angle in degrees, 0 is north, increasing clock-wise
+	VisSat         uint8     // Number of visible satellites
+	Speed          uint16    // Speed in km/h
+	EventID        uint16    // IO element ID of the event which generated the record (0 – data generated not on event)
+	GenerationType uint8     // Why the record was generated. Only Codec 16 has it, GenerationUnsupported otherwise.
+	Elements       []Element // IO elements
+}
+
+// Element represents one IO element. Value is kept as raw bytes, it has to be converted depending on the IO ID.
+type Element struct {
+	Length uint16 // Length of the value in bytes
+	IOID   uint16 // IO element ID
+	Value  []byte // Raw value
+}
+
+// layout describes the differences between the AVL data codecs
+type layout struct {
+	eventIDSize    int  // size of the event IO ID
+	countSize      int  // size of the IO element counters
+	idSize         int  // size of IO element IDs
+	generationType bool // record has generation type
+	variableSized  bool // record has variable sized IO elements
+}
+
+func getLayout(codecID byte) (layout, error) {
+	switch codecID {
+	case Codec8:
+		return layout{eventIDSize: 1, countSize: 1, idSize: 1}, nil
+	case Codec8Extended:
+		return layout{eventIDSize: 2, countSize: 2, idSize: 2, variableSized: true}, nil
+	case Codec16:
+		return layout{eventIDSize: 2, countSize: 1, idSize: 2, generationType: true}, nil
+	default:
+		return layout{}, newDecodeError(ErrInvalidCodec, 0, "want 0x08, 0x8E or 0x10, got 0x%02X", codecID)
+	}
+}
+
+// Decode decodes an AVL data packet received over UDP
+func Decode(packet []byte) (Decoded, error) {
+	r := newReader(packet)
+
+	header, err := r.bytes(udpHeaderLength)
+	if err != nil {
+		return Decoded{}, err
+	}
+
+	packetID := binary.BigEndian.Uint16(header[2:4])
+	if packetID != udpPacketID {
+		return Decoded{}, newDecodeError(ErrNotTeltonika, 2, "packet ID is 0x%04X", packetID)
+	}
+
+	// Length does not include the length field itself
+	length := binary.BigEndian.Uint16(header[0:2])
+	if int(length) != len(packet)-2 {
+		return Decoded{}, newDecodeError(ErrInvalidLength, 0, "packet length is %d but %d bytes received", length, len(packet)-2)
+	}
+
+	imeiLength, err := r.uint16()
+	if err != nil {
+		return Decoded{}, err
+	}
+	if imeiLength != 15 && imeiLength != 16 {
+		return Decoded{}, newDecodeError(ErrInvalidImei, r.pos-2, "IMEI length must be 15 or 16, got %d", imeiLength)
+	}
+
+	imei, err := r.bytes(int(imeiLength))
+	if err != nil {
+		return Decoded{}, err
+	}
+
+	decoded, err := DecodeAvlData(string(imei), packet[r.pos:])
+	if err != nil {
+		return Decoded{}, shiftOffset(err, r.pos)
+	}
+
+	// AVL packet ID is echoed back together with the number of accepted records
+	decoded.Response = []byte{0x00, 0x05, header[2], header[3], 0x01, header[5], decoded.NoOfData}
+
+	return decoded, nil
+}
+
+// DecodeAvlData decodes the AVL data array, that is, the codec ID, the records and the trailing number of data.
+// It is the common part of UDP and TCP packets.
+func DecodeAvlData(imei string, data []byte) (Decoded, error) {
+	decoded := Decoded{
+		IMEI: imei,
+	}
+
+	r := newReader(data)
+
+	var err error
+	decoded.CodecID, err = r.uint8()
+	if err != nil {
+		return Decoded{}, err
+	}
+
+	l, err := getLayout(decoded.CodecID)
+	if err != nil {
+		return Decoded{}, err
+	}
+
+	decoded.NoOfData, err = r.uint8()
+	if err != nil {
+		return Decoded{}, err
+	}
+
+	decoded.Data = make([]AvlData, 0, decoded.NoOfData)
+	for i := 0; i < int(decoded.NoOfData); i++ {
+		record, err := decodeRecord(r, l)
+		if err != nil {
+			return Decoded{}, fmt.Errorf("failed to decode %d. record. %w", i+1, err)
+		}
+
+		decoded.Data = append(decoded.Data, record)
+	}
+
+	// check if packet was correctly parsed
+	endNoOfData, err := r.uint8()
+	if err != nil {
+		return Decoded{}, err
+	}
+	if endNoOfData != decoded.NoOfData {
+		return Decoded{}, newDecodeError(ErrCountMismatch, r.pos-1, "number of data at the end of the packet differs, want %d, got %d", decoded.NoOfData, endNoOfData)
+	}
+	if r.remaining() != 0 {
+		return Decoded{}, newDecodeError(ErrInvalidLength, r.pos, "%d unexpected bytes after the last record", r.remaining())
+	}
+
+	return decoded, nil
+}
+
+// DecodeTcp decodes an AVL data packet received over TCP. The IMEI is known from the handshake of the connection.
+func DecodeTcp(imei string, packet []byte) (Decoded, error) {
+	r := newReader(packet)
+
+	preamble, err := r.uint32()
+	if err != nil {
+		return Decoded{}, err
+	}
+	if preamble != 0 {
+		return Decoded{}, newDecodeError(ErrInvalidPreamble, 0, "want 0x00000000, got 0x%08X", preamble)
+	}
+
+	dataLength, err := r.uint32()
+	if err != nil {
+		return Decoded{}, err
+	}
+	if int64(dataLength) != int64(r.remaining()-tcpCrcLength) {
+		return Decoded{}, newDecodeError(ErrInvalidLength, 4, "data field length is %d but %d bytes received", dataLength, r.remaining()-tcpCrcLength)
+	}
+
+	data, err := r.bytes(int(dataLength))
+	if err != nil {
+		return Decoded{}, err
+	}
+
+	expectedCrc, err := r.uint32()
+	if err != nil {
+		return Decoded{}, err
+	}
+
+	calculatedCrc := uint32(crc16.Checksum(data))
+	if calculatedCrc != expectedCrc {
+		return Decoded{}, newDecodeError(ErrCrcMismatch, r.pos-tcpCrcLength, "calculated: %x received: %x", calculatedCrc, expectedCrc)
+	}
+
+	decoded, err := DecodeAvlData(imei, data)
+	if err != nil {
+		return Decoded{}, shiftOffset(err, tcpHeaderLength)
+	}
+
+	// Number of accepted records is sent back on 4 bytes
+	decoded.Response = binary.BigEndian.AppendUint32(nil, uint32(decoded.NoOfData))
+
+	return decoded, nil
+}
+
+// shiftOffset makes the offset of a decoding error relative to the beginning of the whole packet
+func shiftOffset(err error, offset int) error {
+	var decodeError *DecodeError
+	if errors.As(err, &decodeError) {
+		decodeError.Offset += offset
+	}
+
+	return err
+}
+
+func decodeRecord(r *reader, l layout) (AvlData, error) {
+	record := AvlData{
+		GenerationType: GenerationUnsupported,
+	}
+
+	var err error
+	record.UtimeMs, err = r.uint64()
+	if err != nil {
+		return AvlData{}, err
+	}
+	record.Utime = record.UtimeMs / 1000
+
+	record.Priority, err = r.uint8()
+	if err != nil {
+		return AvlData{}, err
+	}
+	if record.Priority > maxPriority {
+		return AvlData{}, newDecodeError(ErrInvalidPriority, r.pos-1, "want priority <= %d, got %d", maxPriority, record.Priority)
+	}
+
+	lng, err := r.uint32()
+	if err != nil {
+		return AvlData{}, err
+	}
+	record.Lng = int32(lng) // #nosec G115 two's complement
+	if record.Lng <= -maxLongitude || record.Lng >= maxLongitude {
+		return AvlData{}, newDecodeError(ErrInvalidCoordinate, r.pos-4, "longitude is out of range, got %d", record.Lng)
+	}
+
+	lat, err := r.uint32()
+	if err != nil {
+		return AvlData{}, err
+	}
+	record.Lat = int32(lat) // #nosec G115 two's complement
+	if record.Lat <= -maxLatitude || record.Lat >= maxLatitude {
+		return AvlData{}, newDecodeError(ErrInvalidCoordinate, r.pos-4, "latitude is out of range, got %d", record.Lat)
+	}
+
+	altitude, err := r.uint16()
+	if err != nil {
+		return AvlData{}, err
+	}
+	record.Altitude = int16(altitude) // #nosec G115 two's complement
+	if record.Altitude <= minAltitude || record.Altitude >= maxAltitude {
+		return AvlData{}, newDecodeError(ErrInvalidAltitude, r.pos-2, "want %d < altitude < %d, got %d", minAltitude, maxAltitude, record.Altitude)
+	}
+
+	record.Angle, err = r.uint16()
+	if err != nil {
+		return AvlData{}, err
+	}
+	if record.Angle > maxAngle {
+		return AvlData{}, newDecodeError(ErrInvalidAngle, r.pos-2, "want angle <= %d, got %d", maxAngle, record.Angle)
+	}
+
+	record.VisSat, err = r.uint8()
+	if err != nil {
+		return AvlData{}, err
+	}
+
+	record.Speed, err = r.uint16()
+	if err != nil {
+		return AvlData{}, err
+	}
+
+	record.EventID, err = r.uintN(l.eventIDSize)
+	if err != nil {
+		return AvlData{}, err
+	}
+
+	if l.generationType {
+		record.GenerationType, err = r.uint8()
+		if err != nil {
+			return AvlData{}, err
+		}
+	}
+
+	record.Elements, err = decodeElements(r, l)
+	if err != nil {
+		return AvlData{}, err
+	}
+
+	return record, nil
+}
+
+func decodeElements(r *reader, l layout) ([]Element, error) {
+	totalElements, err := r.uintN(l.countSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Do not trust the counter for the allocation, every element needs at least 2 bytes
+	elements := make([]Element, 0, min(int(totalElements), r.remaining()/2))
+
+	// Fixed sized elements are grouped by the size of their values
+	for _, size := range []int{1, 2, 4, 8} {
+		count, err := r.uintN(l.countSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 0; i < int(count); i++ {
+			id, err := r.uintN(l.idSize)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := r.bytes(size)
+			if err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, Element{
+				Length: uint16(size), // #nosec G115
+				IOID:   id,
+				Value:  value,
+			})
+		}
+	}
+
+	if l.variableSized {
+		count, err := r.uint16()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 0; i < int(count); i++ {
+			id, err := r.uint16()
+			if err != nil {
+				return nil, err
+			}
+
+			length, err := r.uint16()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := r.bytes(int(length))
+			if err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, Element{
+				Length: length,
+				IOID:   id,
+				Value:  value,
+			})
+		}
+	}
+
+	if len(elements) != int(totalElements) {
+		return nil, newDecodeError(ErrCountMismatch, r.pos, "number of IO elements differs, want %d, got %d", totalElements, len(elements))
+	}
+
+	return elements, nil
+}
@@ -0,0 +1,272 @@
+package codec
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		Name             string
+		Request          string
+		ExpectedIMEI     string
+		ExpectedCodecID  byte
+		ExpectedRecords  int
+		ExpectedElements int
+		ExpectedResponse string
+	}{
+		{
+			Name:             "Codec8",
+			Request:          "01e4cafe0128000f333532303934303839333937343634080400000163c803eb02010a2524c01d4a377d00d3012f130032421b0a4503f00150051503ef01510052005900be00c1000ab50008b60006426fd8cd3d1ece605a5400005500007300005a0000c0000007c70000000df1000059d910002d33c65300000000570000000064000000f7bf000000000000000163c803e6e8010a2530781d4a316f00d40131130031421b0a4503f00150051503ef01510052005900be00c1000ab50008b60005426fcbcd3d1ece605a5400005500007300005a0000c0000007c70000000ef1000059d910002d33b95300000000570000000064000000f7bf000000000000000163c803df18010a2536961d4a2e4f00d50134130033421b0a4503f00150051503ef01510052005900be00c1000ab50008b6000542702bcd3d1ece605a5400005500007300005a0000c0000007c70000001ef1000059d910002d33aa5300000000570000000064000000f7bf000000000000000163c8039ce2010a25d8d41d49f42c00dc0123120058421b0a4503f00150051503ef01510052005900be00c1000ab50009b60005427031cd79d8ce605a5400005500007300005a0000c0000007c700000019f1000059d910002d32505300000000570000000064000000f7bf000000000004",
+			ExpectedIMEI:     "352094089397464",
+			ExpectedCodecID:  Codec8,
+			ExpectedRecords:  4,
+			ExpectedElements: 27,
+			ExpectedResponse: "0005cafe012804",
+		},
+		{
+			// Checksum of IMEIs is not validated like at the handshake of TCP connections
+			Name:             "Codec8 with wrong IMEI checksum",
+			Request:          "01e4cafe0128000f333532303934303839333937343635080400000163c803eb02010a2524c01d4a377d00d3012f130032421b0a4503f00150051503ef01510052005900be00c1000ab50008b60006426fd8cd3d1ece605a5400005500007300005a0000c0000007c70000000df1000059d910002d33c65300000000570000000064000000f7bf000000000000000163c803e6e8010a2530781d4a316f00d40131130031421b0a4503f00150051503ef01510052005900be00c1000ab50008b60005426fcbcd3d1ece605a5400005500007300005a0000c0000007c70000000ef1000059d910002d33b95300000000570000000064000000f7bf000000000000000163c803df18010a2536961d4a2e4f00d50134130033421b0a4503f00150051503ef01510052005900be00c1000ab50008b6000542702bcd3d1ece605a5400005500007300005a0000c0000007c70000001ef1000059d910002d33aa5300000000570000000064000000f7bf000000000000000163c8039ce2010a25d8d41d49f42c00dc0123120058421b0a4503f00150051503ef01510052005900be00c1000ab50009b60005427031cd79d8ce605a5400005500007300005a0000c0000007c700000019f1000059d910002d32505300000000570000000064000000f7bf000000000004",
+			ExpectedIMEI:     "352094089397465",
+			ExpectedCodecID:  Codec8,
+			ExpectedRecords:  4,
+			ExpectedElements: 27,
+			ExpectedResponse: "0005cafe012804",
+		},
+		{
+			Name:             "Codec8 Extended from traccar",
+			Request:          "0067cafe016b000f3335303432343036333831373336338e01000001839ecd8a70000b5629e81c5451d0000000000000000000000b000500500000150400c800004502001d00000500422e970018000000cd13f000ce005d00430fd3000100f10000547e0000000001",
+			ExpectedIMEI:     "350424063817363",
+			ExpectedCodecID:  Codec8Extended,
+			ExpectedRecords:  1,
+			ExpectedElements: 11,
+			ExpectedResponse: "0005cafe016b01",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			packet, err := hex.DecodeString(testCase.Request)
+			if err != nil {
+				test.Fatalf("Incorrect request data. %v", err)
+			}
+
+			decoded, err := Decode(packet)
+			if err != nil {
+				test.Fatalf("Failed to decode packet. %v", err)
+			}
+
+			if decoded.IMEI != testCase.ExpectedIMEI {
+				test.Errorf("Wrong IMEI! Expected: %v Actual: %v", testCase.ExpectedIMEI, decoded.IMEI)
+			}
+			if decoded.CodecID != testCase.ExpectedCodecID {
+				test.Errorf("Wrong codec ID! Expected: %x Actual: %x", testCase.ExpectedCodecID, decoded.CodecID)
+			}
+			if len(decoded.Data) != testCase.ExpectedRecords {
+				test.Fatalf("Wrong number of records! Expected: %v Actual: %v", testCase.ExpectedRecords, len(decoded.Data))
+			}
+			if len(decoded.Data[0].Elements) != testCase.ExpectedElements {
+				test.Errorf("Wrong number of IO elements! Expected: %v Actual: %v", testCase.ExpectedElements, len(decoded.Data[0].Elements))
+			}
+			if decoded.Data[0].GenerationType != GenerationUnsupported {
+				test.Errorf("Generation type must not be set. Actual: %v", decoded.Data[0].GenerationType)
+			}
+			if hex.EncodeToString(decoded.Response) != testCase.ExpectedResponse {
+				test.Errorf("Wrong reponse! Expected: %v Actual: %v", testCase.ExpectedResponse, hex.EncodeToString(decoded.Response))
+			}
+		})
+	}
+}
+
+func TestDecodeAvlData(t *testing.T) {
+	// Data fields of the TCP examples at https://wiki.teltonika-gps.com/view/Teltonika_Data_Sending_Protocols
+	testCases := []struct {
+		Name                   string
+		Data                   string
+		ExpectedCodecID        byte
+		ExpectedRecords        int
+		ExpectedEventID        uint16
+		ExpectedGenerationType uint8
+		ExpectedElements       []Element
+	}{
+		{
+			Name:                   "Codec8",
+			Data:                   "08010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E000000000000000001",
+			ExpectedCodecID:        Codec8,
+			ExpectedRecords:        1,
+			ExpectedEventID:        1,
+			ExpectedGenerationType: GenerationUnsupported,
+			ExpectedElements: []Element{
+				{Length: 1, IOID: 0x15, Value: []byte{0x03}},
+				{Length: 1, IOID: 0x01, Value: []byte{0x01}},
+				{Length: 2, IOID: 0x42, Value: []byte{0x5E, 0x0F}},
+				{Length: 4, IOID: 0xF1, Value: []byte{0x00, 0x00, 0x60, 0x1A}},
+				{Length: 8, IOID: 0x4E, Value: []byte{0, 0, 0, 0, 0, 0, 0, 0}},
+			},
+		},
+		{
+			Name:                   "Codec16",
+			Data:                   "10020000016BDBC7833000000000000000000000000000000000000B05040200010000030002000B00270042563A00000000016BDBC7871800000000000000000000000000000000000B05040200010000030002000B00260042563A000002",
+			ExpectedCodecID:        Codec16,
+			ExpectedRecords:        2,
+			ExpectedEventID:        0x0B,
+			ExpectedGenerationType: GenerationOnChange,
+			ExpectedElements: []Element{
+				{Length: 1, IOID: 0x01, Value: []byte{0x00}},
+				{Length: 1, IOID: 0x03, Value: []byte{0x00}},
+				{Length: 2, IOID: 0x0B, Value: []byte{0x00, 0x27}},
+				{Length: 2, IOID: 0x42, Value: []byte{0x56, 0x3A}},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			data, err := hex.DecodeString(testCase.Data)
+			if err != nil {
+				test.Fatalf("Incorrect data. %v", err)
+			}
+
+			decoded, err := DecodeAvlData("356307042441013", data)
+			if err != nil {
+				test.Fatalf("Failed to decode data. %v", err)
+			}
+
+			if decoded.CodecID != testCase.ExpectedCodecID {
+				test.Errorf("Wrong codec ID! Expected: %x Actual: %x", testCase.ExpectedCodecID, decoded.CodecID)
+			}
+			if len(decoded.Data) != testCase.ExpectedRecords {
+				test.Fatalf("Wrong number of records! Expected: %v Actual: %v", testCase.ExpectedRecords, len(decoded.Data))
+			}
+
+			record := decoded.Data[0]
+			if record.EventID != testCase.ExpectedEventID {
+				test.Errorf("Wrong event ID! Expected: %v Actual: %v", testCase.ExpectedEventID, record.EventID)
+			}
+			if record.GenerationType != testCase.ExpectedGenerationType {
+				test.Errorf("Wrong generation type! Expected: %v Actual: %v", testCase.ExpectedGenerationType, record.GenerationType)
+			}
+			if len(record.Elements) != len(testCase.ExpectedElements) {
+				test.Fatalf("Wrong number of IO elements! Expected: %v Actual: %v", len(testCase.ExpectedElements), len(record.Elements))
+			}
+			for i, expected := range testCase.ExpectedElements {
+				actual := record.Elements[i]
+				if actual.IOID != expected.IOID || actual.Length != expected.Length || hex.EncodeToString(actual.Value) != hex.EncodeToString(expected.Value) {
+					test.Errorf("Wrong IO element! Expected: %+v Actual: %+v", expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	data, err := hex.DecodeString("10020000016BDBC7833000000000000000000000000000000000000B05040200010000030002000B00270042563A00000000016BDBC7871800000000000000000000000000000000000B05040200010000030002000B00260042563A000002")
+	if err != nil {
+		t.Fatalf("Incorrect data. %v", err)
+	}
+
+	for i := 0; i < len(data); i++ {
+		_, err := DecodeAvlData("356307042441013", data[:i])
+		if err == nil {
+			t.Errorf("Decoding of %d bytes long truncated data must fail", i)
+		}
+	}
+}
+
+func TestValidateIMEI(t *testing.T) {
+	testCases := map[string]bool{
+		"352094089397464":  true,
+		"350424063817363":  true,
+		"356307042441013":  true,
+		"356307042441014":  false,
+		"35630704244101":   false,
+		"35630704244101a":  false,
+		"3563070424410130": false,
+	}
+
+	for imei, expected := range testCases {
+		if ValidateIMEI(imei) != expected {
+			t.Errorf("Wrong validation result of %s IMEI. Expected: %v", imei, expected)
+		}
+	}
+}
+
+func TestDecodeTcp(t *testing.T) {
+	// Codec 8 example from https://wiki.teltonika-gps.com/view/Teltonika_Data_Sending_Protocols
+	packet, err := hex.DecodeString("000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF")
+	if err != nil {
+		t.Fatalf("Incorrect packet. %v", err)
+	}
+
+	decoded, err := DecodeTcp("356307042441013", packet)
+	if err != nil {
+		t.Fatalf("Failed to decode packet. %v", err)
+	}
+	if hex.EncodeToString(decoded.Response) != "00000001" {
+		t.Errorf("Wrong reponse! Expected: 00000001 Actual: %v", hex.EncodeToString(decoded.Response))
+	}
+
+	packet[len(packet)-1]++
+	_, err = DecodeTcp("356307042441013", packet)
+	if !errors.Is(err, ErrCrcMismatch) {
+		t.Errorf("Wrong error! Expected: %v Actual: %v", ErrCrcMismatch, err)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		Packet         string
+		ExpectedReason error
+		ExpectedOffset int
+	}{
+		{
+			Name:           "Not Teltonika",
+			Packet:         "0005beef012804",
+			ExpectedReason: ErrNotTeltonika,
+			ExpectedOffset: 2,
+		},
+		{
+			Name:           "Wrong length",
+			Packet:         "0006cafe012804",
+			ExpectedReason: ErrInvalidLength,
+			ExpectedOffset: 0,
+		},
+		{
+			Name:           "Truncated IMEI",
+			Packet:         "0008cafe0128000f3335",
+			ExpectedReason: ErrTruncated,
+			ExpectedOffset: 8,
+		},
+		{
+			Name:           "Wrong codec",
+			Packet:         "0017cafe016b000f3335303432343036333831373336330901",
+			ExpectedReason: ErrInvalidCodec,
+			ExpectedOffset: 23,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			packet, err := hex.DecodeString(testCase.Packet)
+			if err != nil {
+				test.Fatalf("Incorrect packet. %v", err)
+			}
+
+			_, err = Decode(packet)
+			if !errors.Is(err, testCase.ExpectedReason) {
+				test.Fatalf("Wrong error! Expected: %v Actual: %v", testCase.ExpectedReason, err)
+			}
+
+			var decodeError *DecodeError
+			if !errors.As(err, &decodeError) {
+				test.Fatalf("Error is not a DecodeError: %v", err)
+			}
+			if decodeError.Offset != testCase.ExpectedOffset {
+				test.Errorf("Wrong offset! Expected: %v Actual: %v", testCase.ExpectedOffset, decodeError.Offset)
+			}
+		})
+	}
+}
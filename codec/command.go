@@ -0,0 +1,238 @@
+package codec
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/basvdlei/gotsmart/crc16"
+	"strings"
+	"time"
+)
+
+// https://wiki.teltonika-gps.com/view/Teltonika_Data_Sending_Protocols#Codec_12
+const (
+	Codec12 byte = 0x0C // command and response
+	Codec13 byte = 0x0D // response with device timestamp
+	Codec14 byte = 0x0E // command and response addressed to an IMEI
+)
+
+const (
+	CommandTypeRequest  byte = 0x05
+	CommandTypeResponse byte = 0x06
+	CommandTypeNack     byte = 0x11 // Codec 14 only: command was addressed to another IMEI
+)
+
+const (
+	commandHeaderLength = 8 // 4 bytes preamble + 4 bytes data size
+	commandCrcLength    = 4
+	commandImeiLength   = 8 // IMEI is sent as BCD
+	commandTimeLength   = 4
+)
+
+// Command is a Codec 12, 13 or 14 packet. Commands and their responses share the same format.
+type Command struct {
+	CodecID   byte      // Codec12, Codec13 or Codec14
+	Type      byte      // CommandTypeRequest, CommandTypeResponse or CommandTypeNack
+	IMEI      string    // Codec 14 only
+	Timestamp time.Time // Codec 13 only
+	Payload   []byte    // Text of the command or the response
+}
+
+// IsNack tells if the device refused a Codec 14 command because it was addressed to another IMEI
+func (c Command) IsNack() bool {
+	return c.CodecID == Codec14 && c.Type == CommandTypeNack
+}
+
+// EncodeCommandRequest encodes a command to be sent to the device with the given codec
+func EncodeCommandRequest(codecID byte, imei string, command string) ([]byte, error) {
+	return EncodeCommand(Command{
+		CodecID:   codecID,
+		Type:      CommandTypeRequest,
+		IMEI:      imei,
+		Timestamp: time.Now(),
+		Payload:   []byte(command),
+	})
+}
+
+// EncodeCommand encodes a command or response packet including its preamble and CRC
+func EncodeCommand(command Command) ([]byte, error) {
+	prefix := make([]byte, 0, commandImeiLength)
+
+	switch command.CodecID {
+	case Codec12:
+	case Codec13:
+		prefix = binary.BigEndian.AppendUint32(prefix, uint32(command.Timestamp.Unix())) // #nosec G115
+	case Codec14:
+		imei, err := encodeImei(command.IMEI)
+		if err != nil {
+			return nil, err
+		}
+		prefix = append(prefix, imei...)
+	default:
+		return nil, fmt.Errorf("%w, want 0x0C, 0x0D or 0x0E, got 0x%02X", ErrInvalidCodec, command.CodecID)
+	}
+
+	size := len(prefix) + len(command.Payload)
+
+	// Data field: from the codec ID to the second quantity
+	data := make([]byte, 0, 8+size)
+	data = append(data, command.CodecID, 0x01, command.Type)
+	data = binary.BigEndian.AppendUint32(data, uint32(size)) // #nosec G115
+	data = append(data, prefix...)
+	data = append(data, command.Payload...)
+	data = append(data, 0x01)
+
+	packet := make([]byte, commandHeaderLength, commandHeaderLength+len(data)+commandCrcLength)
+	binary.BigEndian.PutUint32(packet[4:], uint32(len(data))) // #nosec G115
+	packet = append(packet, data...)
+	packet = binary.BigEndian.AppendUint32(packet, uint32(crc16.Checksum(data)))
+
+	return packet, nil
+}
+
+// DecodeCommand decodes a command or response packet and checks its CRC
+func DecodeCommand(packet []byte) (Command, error) {
+	r := newReader(packet)
+
+	preamble, err := r.uint32()
+	if err != nil {
+		return Command{}, err
+	}
+	if preamble != 0 {
+		return Command{}, newDecodeError(ErrInvalidPreamble, 0, "want 0x00000000, got 0x%08X", preamble)
+	}
+
+	dataSize, err := r.uint32()
+	if err != nil {
+		return Command{}, err
+	}
+
+	data, err := r.bytes(int(dataSize))
+	if err != nil {
+		return Command{}, err
+	}
+
+	expectedCrc, err := r.uint32()
+	if err != nil {
+		return Command{}, err
+	}
+
+	calculatedCrc := uint32(crc16.Checksum(data))
+	if calculatedCrc != expectedCrc {
+		return Command{}, newDecodeError(ErrCrcMismatch, r.pos-commandCrcLength, "calculated: %x received: %x", calculatedCrc, expectedCrc)
+	}
+
+	command, err := decodeCommandData(data)
+	if err != nil {
+		return Command{}, shiftOffset(err, commandHeaderLength)
+	}
+
+	return command, nil
+}
+
+// decodeCommandData decodes the data field of a command packet, from the codec ID to the second quantity
+func decodeCommandData(data []byte) (Command, error) {
+	r := newReader(data)
+	command := Command{}
+
+	var err error
+
+	command.CodecID, err = r.uint8()
+	if err != nil {
+		return Command{}, err
+	}
+
+	quantity, err := r.uint8()
+	if err != nil {
+		return Command{}, err
+	}
+
+	command.Type, err = r.uint8()
+	if err != nil {
+		return Command{}, err
+	}
+	if command.Type != CommandTypeRequest && command.Type != CommandTypeResponse && command.Type != CommandTypeNack {
+		return Command{}, newDecodeError(ErrInvalidType, r.pos-1, "want 0x05, 0x06 or 0x11, got 0x%02X", command.Type)
+	}
+
+	size, err := r.uint32()
+	if err != nil {
+		return Command{}, err
+	}
+
+	payload, err := r.bytes(int(size))
+	if err != nil {
+		return Command{}, err
+	}
+
+	switch command.CodecID {
+	case Codec12:
+		command.Payload = payload
+	case Codec13:
+		if len(payload) < commandTimeLength {
+			return Command{}, newDecodeError(ErrInvalidLength, r.pos-len(payload), "codec 13 payload is too short to have timestamp: %d bytes", len(payload))
+		}
+		command.Timestamp = time.Unix(int64(binary.BigEndian.Uint32(payload)), 0)
+		command.Payload = payload[commandTimeLength:]
+	case Codec14:
+		if len(payload) < commandImeiLength {
+			return Command{}, newDecodeError(ErrInvalidLength, r.pos-len(payload), "codec 14 payload is too short to have IMEI: %d bytes", len(payload))
+		}
+		command.IMEI = decodeImei(payload[:commandImeiLength])
+		command.Payload = payload[commandImeiLength:]
+	default:
+		return Command{}, newDecodeError(ErrInvalidCodec, 0, "want 0x0C, 0x0D or 0x0E, got 0x%02X", command.CodecID)
+	}
+
+	endQuantity, err := r.uint8()
+	if err != nil {
+		return Command{}, err
+	}
+	if endQuantity != quantity {
+		return Command{}, newDecodeError(ErrCountMismatch, r.pos-1, "quantity at the end of the packet differs, want %d, got %d", quantity, endQuantity)
+	}
+	if r.remaining() != 0 {
+		return Command{}, newDecodeError(ErrInvalidLength, r.pos, "%d unexpected bytes after the second quantity", r.remaining())
+	}
+
+	return command, nil
+}
+
+// IsCommandCodec tells if the codec ID belongs to a command codec
+func IsCommandCodec(codecID byte) bool {
+	return codecID == Codec12 || codecID == Codec13 || codecID == Codec14
+}
+
+// encodeImei converts the 15 or 16 digits long IMEI to 8 bytes long BCD. E.g. 352093081452251 -> 0x0352093081452251
+func encodeImei(imei string) ([]byte, error) {
+	if len(imei) != 2*commandImeiLength-1 && len(imei) != 2*commandImeiLength {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidImei, imei)
+	}
+
+	for _, c := range imei {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidImei, imei)
+		}
+	}
+
+	padded := imei
+	if len(padded)%2 == 1 {
+		padded = "0" + padded
+	}
+	encoded, err := hex.DecodeString(padded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s. %v", ErrInvalidImei, imei, err)
+	}
+
+	return encoded, nil
+}
+
+// decodeImei decodes a BCD encoded IMEI. Only the padding nibble is removed, so IMEIs starting with zero are kept intact.
+func decodeImei(raw []byte) string {
+	imei := hex.EncodeToString(raw)
+	if strings.HasPrefix(imei, "0") {
+		imei = imei[1:]
+	}
+
+	return imei
+}
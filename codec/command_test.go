@@ -0,0 +1,132 @@
+package codec
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeCommandRequest(t *testing.T) {
+	// Examples from https://wiki.teltonika-gps.com/view/Teltonika_Data_Sending_Protocols
+	testCases := []struct {
+		Name     string
+		CodecID  byte
+		IMEI     string
+		Command  string
+		Expected string
+	}{
+		{
+			Name:     "Codec12 getinfo",
+			CodecID:  Codec12,
+			IMEI:     "352093081452251",
+			Command:  "getinfo",
+			Expected: "000000000000000F0C010500000007676574696E666F0100004312",
+		},
+		{
+			Name:     "Codec14 getver",
+			CodecID:  Codec14,
+			IMEI:     "352093081452251",
+			Command:  "getver",
+			Expected: "00000000000000160E01050000000E0352093081452251676574766572010000D2C1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			encoded, err := EncodeCommandRequest(testCase.CodecID, testCase.IMEI, testCase.Command)
+			if err != nil {
+				test.Fatalf("Failed to encode command. %v", err)
+			}
+
+			if hex.EncodeToString(encoded) != strings.ToLower(testCase.Expected) {
+				test.Errorf("Wrong command! Expected: %v Actual: %v", strings.ToLower(testCase.Expected), hex.EncodeToString(encoded))
+			}
+		})
+	}
+}
+
+func TestDecodeCommand(t *testing.T) {
+	testCases := []struct {
+		Name            string
+		Packet          string
+		ExpectedCodecID byte
+		ExpectedIMEI    string
+		ExpectedNack    bool
+		ExpectedPayload string
+	}{
+		{
+			Name:            "Codec12 response",
+			Packet:          "00000000000000370C01060000002F4449313A31204449323A30204449333A302041494E313A302041494E323A313639323420444F313A3020444F323A3101000066E3",
+			ExpectedCodecID: Codec12,
+			ExpectedPayload: "DI1:1 DI2:0 DI3:0 AIN1:0 AIN2:16924 DO1:0 DO2:1",
+		},
+		{
+			Name:            "Codec14 nACK",
+			Packet:          "00000000000000100E011100000008035209308145225101000032AC",
+			ExpectedCodecID: Codec14,
+			ExpectedIMEI:    "352093081452251",
+			ExpectedNack:    true,
+			ExpectedPayload: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			packet, err := hex.DecodeString(testCase.Packet)
+			if err != nil {
+				test.Fatalf("Incorrect packet. %v", err)
+			}
+
+			decoded, err := DecodeCommand(packet)
+			if err != nil {
+				test.Fatalf("Failed to decode command. %v", err)
+			}
+
+			if decoded.CodecID != testCase.ExpectedCodecID {
+				test.Errorf("Wrong codec ID! Expected: %x Actual: %x", testCase.ExpectedCodecID, decoded.CodecID)
+			}
+			if decoded.IMEI != testCase.ExpectedIMEI {
+				test.Errorf("Wrong IMEI! Expected: %v Actual: %v", testCase.ExpectedIMEI, decoded.IMEI)
+			}
+			if decoded.IsNack() != testCase.ExpectedNack {
+				test.Errorf("Wrong nACK flag! Expected: %v Actual: %v", testCase.ExpectedNack, decoded.IsNack())
+			}
+			if string(decoded.Payload) != testCase.ExpectedPayload {
+				test.Errorf("Wrong payload! Expected: %v Actual: %v", testCase.ExpectedPayload, string(decoded.Payload))
+			}
+		})
+	}
+}
+
+func TestCommandRoundTrip(t *testing.T) {
+	testCases := []Command{
+		{CodecID: Codec12, Type: CommandTypeResponse, Payload: []byte("Ver:03.27.07_00")},
+		{CodecID: Codec13, Type: CommandTypeResponse, Timestamp: time.Unix(1700000000, 0), Payload: []byte("Data Link: 1 GPRS: 1")},
+		{CodecID: Codec14, Type: CommandTypeResponse, IMEI: "350424063817363", Payload: []byte("getstatus")},
+		{CodecID: Codec14, Type: CommandTypeResponse, IMEI: "012345678901234", Payload: []byte("getstatus")}, // only the padding nibble is removed
+	}
+
+	for _, expected := range testCases {
+		encoded, err := EncodeCommand(expected)
+		if err != nil {
+			t.Fatalf("Failed to encode command. %v", err)
+		}
+
+		actual, err := DecodeCommand(encoded)
+		if err != nil {
+			t.Fatalf("Failed to decode command. %v", err)
+		}
+
+		if actual.CodecID != expected.CodecID || actual.Type != expected.Type || actual.IMEI != expected.IMEI || !actual.Timestamp.Equal(expected.Timestamp) || string(actual.Payload) != string(expected.Payload) {
+			t.Errorf("Round trip failed! Expected: %+v Actual: %+v", expected, actual)
+		}
+	}
+
+	for _, imei := range []string{"35042406381736a", "3504240638", "35042406381736312"} {
+		_, err := EncodeCommandRequest(Codec14, imei, "getver")
+		if err == nil {
+			t.Errorf("Encoding must fail with %s invalid IMEI", imei)
+		}
+	}
+}
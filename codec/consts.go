@@ -0,0 +1,34 @@
+package codec
+
+// https://wiki.teltonika-gps.com/view/Teltonika_Data_Sending_Protocols
+const (
+	Codec8         byte = 0x08
+	Codec8Extended byte = 0x8E
+	Codec16        byte = 0x10
+)
+
+// Generation types of Codec 16 records. They tell why the record was generated.
+const (
+	GenerationOnExit      uint8 = 0
+	GenerationOnEntrance  uint8 = 1
+	GenerationOnBoth      uint8 = 2
+	GenerationReserved    uint8 = 3
+	GenerationHysteresis  uint8 = 4
+	GenerationOnChange    uint8 = 5
+	GenerationEventual    uint8 = 6
+	GenerationPeriodical  uint8 = 7
+	GenerationUnsupported uint8 = 0xFF // codec of the record has no generation type
+)
+
+const (
+	udpPacketID     = 0xCAFE
+	udpHeaderLength = 6 // length, packet ID, not usable byte, AVL packet ID
+	tcpHeaderLength = 8 // preamble, data field length
+	tcpCrcLength    = 4
+	maxPriority     = 2
+	maxLongitude    = 1800000000
+	maxLatitude     = 850000000
+	minAltitude     = -5000
+	maxAltitude     = 12000
+	maxAngle        = 360
+)
@@ -0,0 +1,198 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/basvdlei/gotsmart/crc16"
+	"math"
+)
+
+// NewElement creates an IO element with the given value encoded on size bytes (1, 2, 4 or 8)
+func NewElement(ioID uint16, size int, value uint64) (Element, error) {
+	switch size {
+	case 1, 2, 4, 8:
+	default:
+		return Element{}, fmt.Errorf("%w: IO element %d can not be %d bytes long", ErrInvalidLength, ioID, size)
+	}
+
+	raw := binary.BigEndian.AppendUint64(nil, value)
+
+	return Element{
+		Length: uint16(size), // #nosec G115
+		IOID:   ioID,
+		Value:  raw[len(raw)-size:],
+	}, nil
+}
+
+// Encode encodes records into an AVL data packet to be sent over UDP
+func Encode(imei string, avlPacketID byte, codecID byte, records []AvlData) ([]byte, error) {
+	if len(imei) != 15 && len(imei) != 16 {
+		return nil, fmt.Errorf("%w: IMEI length must be 15 or 16, got %d", ErrInvalidImei, len(imei))
+	}
+
+	data, err := EncodeAvlData(codecID, records)
+	if err != nil {
+		return nil, err
+	}
+
+	// Length does not include the length field itself
+	length := udpHeaderLength - 2 + 2 + len(imei) + len(data)
+	if length > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: UDP packet would be %d bytes long", ErrInvalidLength, length)
+	}
+
+	packet := make([]byte, 0, 2+length)
+	packet = binary.BigEndian.AppendUint16(packet, uint16(length)) // #nosec G115
+	packet = binary.BigEndian.AppendUint16(packet, udpPacketID)
+	packet = append(packet, 0x01, avlPacketID)
+	packet = binary.BigEndian.AppendUint16(packet, uint16(len(imei))) // #nosec G115
+	packet = append(packet, imei...)
+	packet = append(packet, data...)
+
+	return packet, nil
+}
+
+// EncodeTcp encodes records into an AVL data packet to be sent over TCP, including its preamble and CRC
+func EncodeTcp(codecID byte, records []AvlData) ([]byte, error) {
+	data, err := EncodeAvlData(codecID, records)
+	if err != nil {
+		return nil, err
+	}
+
+	packet := make([]byte, tcpHeaderLength, tcpHeaderLength+len(data)+tcpCrcLength)
+	binary.BigEndian.PutUint32(packet[4:], uint32(len(data))) // #nosec G115
+	packet = append(packet, data...)
+	packet = binary.BigEndian.AppendUint32(packet, uint32(crc16.Checksum(data)))
+
+	return packet, nil
+}
+
+// EncodeAvlData encodes the AVL data array, that is, the codec ID, the records and the trailing number of data.
+// IO elements are written in the order they are given but grouped by their sizes as the protocol requires.
+func EncodeAvlData(codecID byte, records []AvlData) ([]byte, error) {
+	l, err := getLayout(codecID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 || len(records) > math.MaxUint8 {
+		return nil, fmt.Errorf("%w: number of records must be between 1 and %d, got %d", ErrInvalidLength, math.MaxUint8, len(records))
+	}
+
+	data := []byte{codecID, uint8(len(records))} // #nosec G115
+	for i, record := range records {
+		data, err = encodeRecord(data, l, record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %d. record. %w", i+1, err)
+		}
+	}
+	data = append(data, uint8(len(records))) // #nosec G115
+
+	return data, nil
+}
+
+func encodeRecord(data []byte, l layout, record AvlData) ([]byte, error) {
+	switch {
+	case record.Priority > maxPriority:
+		return nil, fmt.Errorf("%w: want priority <= %d, got %d", ErrInvalidPriority, maxPriority, record.Priority)
+	case record.Lng <= -maxLongitude || record.Lng >= maxLongitude:
+		return nil, fmt.Errorf("%w: longitude is out of range, got %d", ErrInvalidCoordinate, record.Lng)
+	case record.Lat <= -maxLatitude || record.Lat >= maxLatitude:
+		return nil, fmt.Errorf("%w: latitude is out of range, got %d", ErrInvalidCoordinate, record.Lat)
+	case record.Altitude <= minAltitude || record.Altitude >= maxAltitude:
+		return nil, fmt.Errorf("%w: want %d < altitude < %d, got %d", ErrInvalidAltitude, minAltitude, maxAltitude, record.Altitude)
+	case record.Angle > maxAngle:
+		return nil, fmt.Errorf("%w: want angle <= %d, got %d", ErrInvalidAngle, maxAngle, record.Angle)
+	}
+
+	data = binary.BigEndian.AppendUint64(data, record.UtimeMs)
+	data = append(data, record.Priority)
+	data = binary.BigEndian.AppendUint32(data, uint32(record.Lng))      // #nosec G115 two's complement
+	data = binary.BigEndian.AppendUint32(data, uint32(record.Lat))      // #nosec G115 two's complement
+	data = binary.BigEndian.AppendUint16(data, uint16(record.Altitude)) // #nosec G115 two's complement
+	data = binary.BigEndian.AppendUint16(data, record.Angle)
+	data = append(data, record.VisSat)
+	data = binary.BigEndian.AppendUint16(data, record.Speed)
+
+	var err error
+	data, err = appendUintN(data, l.eventIDSize, record.EventID)
+	if err != nil {
+		return nil, fmt.Errorf("event ID: %w", err)
+	}
+
+	if l.generationType {
+		data = append(data, record.GenerationType)
+	}
+
+	return encodeElements(data, l, record.Elements)
+}
+
+func encodeElements(data []byte, l layout, elements []Element) ([]byte, error) {
+	groups := map[int][]Element{}
+	var variableSized []Element
+
+	for _, element := range elements {
+		size := len(element.Value)
+		if element.Length != 0 && int(element.Length) != size {
+			return nil, fmt.Errorf("%w: IO element %d is %d bytes long but its length is %d", ErrInvalidLength, element.IOID, size, element.Length)
+		}
+
+		switch size {
+		case 1, 2, 4, 8:
+			groups[size] = append(groups[size], element)
+		default:
+			if !l.variableSized || size > math.MaxUint16 {
+				return nil, fmt.Errorf("%w: IO element %d can not be %d bytes long with this codec", ErrInvalidLength, element.IOID, size)
+			}
+			variableSized = append(variableSized, element)
+		}
+	}
+
+	if len(elements) > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: too many IO elements: %d", ErrInvalidLength, len(elements))
+	}
+
+	var err error
+	data, err = appendUintN(data, l.countSize, uint16(len(elements))) // #nosec G115
+	if err != nil {
+		return nil, fmt.Errorf("number of IO elements: %w", err)
+	}
+
+	for _, size := range []int{1, 2, 4, 8} {
+		data, err = appendUintN(data, l.countSize, uint16(len(groups[size]))) // #nosec G115
+		if err != nil {
+			return nil, fmt.Errorf("number of %d bytes long IO elements: %w", size, err)
+		}
+
+		for _, element := range groups[size] {
+			data, err = appendUintN(data, l.idSize, element.IOID)
+			if err != nil {
+				return nil, fmt.Errorf("IO element ID: %w", err)
+			}
+			data = append(data, element.Value...)
+		}
+	}
+
+	if l.variableSized {
+		data = binary.BigEndian.AppendUint16(data, uint16(len(variableSized))) // #nosec G115
+		for _, element := range variableSized {
+			data = binary.BigEndian.AppendUint16(data, element.IOID)
+			data = binary.BigEndian.AppendUint16(data, uint16(len(element.Value))) // #nosec G115
+			data = append(data, element.Value...)
+		}
+	}
+
+	return data, nil
+}
+
+// appendUintN is the counterpart of reader.uintN, it fails if the value does not fit into size bytes
+func appendUintN(data []byte, size int, value uint16) ([]byte, error) {
+	if size == 1 {
+		if value > math.MaxUint8 {
+			return nil, fmt.Errorf("%w: %d does not fit into 1 byte", ErrInvalidLength, value)
+		}
+		return append(data, uint8(value)), nil // #nosec G115
+	}
+
+	return binary.BigEndian.AppendUint16(data, value), nil
+}
@@ -0,0 +1,205 @@
+package codec
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeTcp(t *testing.T) {
+	// Codec 8 example from https://wiki.teltonika-gps.com/view/Teltonika_Data_Sending_Protocols
+	records := []AvlData{
+		{
+			UtimeMs:  0x16B40D8EA30,
+			Priority: 1,
+			EventID:  1,
+			Elements: []Element{
+				newElement(t, 0x15, 1, 3),
+				newElement(t, 0x01, 1, 1),
+				newElement(t, 0x42, 2, 0x5E0F),
+				newElement(t, 0xF1, 4, 0x601A),
+				newElement(t, 0x4E, 8, 0),
+			},
+		},
+	}
+	expected := "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF"
+
+	packet, err := EncodeTcp(Codec8, records)
+	if err != nil {
+		t.Fatalf("Failed to encode packet. %v", err)
+	}
+
+	if hex.EncodeToString(packet) != strings.ToLower(expected) {
+		t.Errorf("Wrong packet! Expected: %v Actual: %v", strings.ToLower(expected), hex.EncodeToString(packet))
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	records := []AvlData{
+		{
+			UtimeMs:        1700000000123,
+			Utime:          1700000000,
+			Priority:       2,
+			Lat:            474979870,
+			Lng:            190402360,
+			Altitude:       -12,
+			Angle:          359,
+			VisSat:         11,
+			Speed:          87,
+			EventID:        0xEF,
+			GenerationType: GenerationOnChange,
+			Elements: []Element{
+				newElement(t, 0xEF, 1, 1),
+				newElement(t, 0x42, 2, 12874),
+				newElement(t, 0x10, 4, 123456),
+				newElement(t, 0x4E, 8, 0x0102030405060708),
+			},
+		},
+		{
+			UtimeMs:        1700000060000,
+			Utime:          1700000060,
+			Lat:            -338688197,
+			Lng:            -1512092955,
+			Altitude:       120,
+			GenerationType: GenerationPeriodical,
+			Elements:       []Element{},
+		},
+	}
+
+	for _, codecID := range []byte{Codec8, Codec8Extended, Codec16} {
+		expected := make([]AvlData, len(records))
+		copy(expected, records)
+		if codecID != Codec16 {
+			for i := range expected {
+				expected[i].GenerationType = GenerationUnsupported
+			}
+		}
+
+		udpPacket, err := Encode("356307042441013", 0x2A, codecID, records)
+		if err != nil {
+			t.Fatalf("Failed to encode UDP packet with codec 0x%02X. %v", codecID, err)
+		}
+
+		decoded, err := Decode(udpPacket)
+		if err != nil {
+			t.Fatalf("Failed to decode UDP packet with codec 0x%02X. %v", codecID, err)
+		}
+		if decoded.IMEI != "356307042441013" || decoded.CodecID != codecID || !reflect.DeepEqual(decoded.Data, expected) {
+			t.Errorf("UDP round trip failed with codec 0x%02X! Expected: %+v Actual: %+v", codecID, expected, decoded.Data)
+		}
+		if hex.EncodeToString(decoded.Response) != "0005cafe012a02" {
+			t.Errorf("Wrong reponse! Expected: 0005cafe012a02 Actual: %v", hex.EncodeToString(decoded.Response))
+		}
+
+		tcpPacket, err := EncodeTcp(codecID, records)
+		if err != nil {
+			t.Fatalf("Failed to encode TCP packet with codec 0x%02X. %v", codecID, err)
+		}
+
+		decoded, err = DecodeTcp("356307042441013", tcpPacket)
+		if err != nil {
+			t.Fatalf("Failed to decode TCP packet with codec 0x%02X. %v", codecID, err)
+		}
+		if !reflect.DeepEqual(decoded.Data, expected) {
+			t.Errorf("TCP round trip failed with codec 0x%02X! Expected: %+v Actual: %+v", codecID, expected, decoded.Data)
+		}
+	}
+}
+
+func TestEncodeVariableSized(t *testing.T) {
+	records := []AvlData{
+		{
+			UtimeMs:  1700000000000,
+			Utime:    1700000000,
+			EventID:  385,
+			Elements: []Element{{Length: 3, IOID: 385, Value: []byte{0x01, 0x02, 0x03}}},
+		},
+	}
+
+	packet, err := EncodeTcp(Codec8Extended, records)
+	if err != nil {
+		t.Fatalf("Failed to encode packet. %v", err)
+	}
+
+	decoded, err := DecodeTcp("356307042441013", packet)
+	if err != nil {
+		t.Fatalf("Failed to decode packet. %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Data[0].Elements, records[0].Elements) {
+		t.Errorf("Wrong IO elements! Expected: %+v Actual: %+v", records[0].Elements, decoded.Data[0].Elements)
+	}
+
+	// Neither 2 bytes long IO IDs nor variable sized elements fit into Codec 8
+	_, err = EncodeTcp(Codec8, records)
+	if !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("Wrong error! Expected: %v Actual: %v", ErrInvalidLength, err)
+	}
+}
+
+func TestEncodeInvalid(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		CodecID        byte
+		Records        []AvlData
+		ExpectedReason error
+	}{
+		{
+			Name:           "Unknown codec",
+			CodecID:        Codec12,
+			Records:        []AvlData{{}},
+			ExpectedReason: ErrInvalidCodec,
+		},
+		{
+			Name:           "No records",
+			CodecID:        Codec8,
+			ExpectedReason: ErrInvalidLength,
+		},
+		{
+			Name:           "Invalid priority",
+			CodecID:        Codec8,
+			Records:        []AvlData{{Priority: 3}},
+			ExpectedReason: ErrInvalidPriority,
+		},
+		{
+			Name:           "Invalid latitude",
+			CodecID:        Codec16,
+			Records:        []AvlData{{Lat: 900000000}},
+			ExpectedReason: ErrInvalidCoordinate,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			_, err := EncodeTcp(testCase.CodecID, testCase.Records)
+			if !errors.Is(err, testCase.ExpectedReason) {
+				test.Errorf("Wrong error! Expected: %v Actual: %v", testCase.ExpectedReason, err)
+			}
+		})
+	}
+}
+
+func TestNewElementInvalid(t *testing.T) {
+	for _, size := range []int{-1, 0, 3, 9} {
+		t.Run(fmt.Sprintf("Size %d", size), func(test *testing.T) {
+			_, err := NewElement(0xEF, size, 1)
+			if !errors.Is(err, ErrInvalidLength) {
+				test.Errorf("Wrong error! Expected: %v Actual: %v", ErrInvalidLength, err)
+			}
+		})
+	}
+}
+
+// newElement creates an IO element and fails the test if it is invalid
+func newElement(t *testing.T, ioID uint16, size int, value uint64) Element {
+	t.Helper()
+
+	element, err := NewElement(ioID, size, value)
+	if err != nil {
+		t.Fatalf("Failed to create IO element. %v", err)
+	}
+
+	return element
+}
@@ -0,0 +1,46 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reasons of decoding failures. Use errors.Is to check them and errors.As with *DecodeError to get the offset.
+var (
+	ErrTruncated         = errors.New("unexpected end of packet")
+	ErrNotTeltonika      = errors.New("probably not a Teltonika packet")
+	ErrInvalidLength     = errors.New("invalid length")
+	ErrInvalidImei       = errors.New("invalid IMEI")
+	ErrInvalidCodec      = errors.New("invalid codec ID")
+	ErrInvalidPreamble   = errors.New("invalid preamble")
+	ErrCrcMismatch       = errors.New("CRC mismatch")
+	ErrInvalidPriority   = errors.New("invalid priority")
+	ErrInvalidCoordinate = errors.New("invalid coordinate")
+	ErrInvalidAltitude   = errors.New("invalid altitude")
+	ErrInvalidAngle      = errors.New("invalid angle")
+	ErrInvalidType       = errors.New("invalid command type")
+	ErrCountMismatch     = errors.New("count mismatch")
+)
+
+// DecodeError tells why and where decoding of a packet failed
+type DecodeError struct {
+	Reason error  // One of the Err* values of this package
+	Offset int    // Position in the decoded byte slice where the problem was found
+	Detail string // Human readable details
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%v at %d byte: %s", e.Reason, e.Offset, e.Detail)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Reason
+}
+
+func newDecodeError(reason error, offset int, format string, args ...any) *DecodeError {
+	return &DecodeError{
+		Reason: reason,
+		Offset: offset,
+		Detail: fmt.Sprintf(format, args...),
+	}
+}
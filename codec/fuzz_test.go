@@ -0,0 +1,62 @@
+package codec
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// Decoders must never panic and must always return a *DecodeError on invalid input
+
+func addHexSeeds(f *testing.F, seeds ...string) {
+	for _, seed := range seeds {
+		data, err := hex.DecodeString(seed)
+		if err != nil {
+			f.Fatalf("Incorrect seed. %v", err)
+		}
+		f.Add(data)
+	}
+}
+
+func checkDecodeError(t *testing.T, err error) {
+	var decodeError *DecodeError
+	if err != nil && !errors.As(err, &decodeError) {
+		t.Errorf("Error is not a DecodeError: %v", err)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	addHexSeeds(f,
+		"0067cafe016b000f3335303432343036333831373336338e01000001839ecd8a70000b5629e81c5451d0000000000000000000000b000500500000150400c800004502001d00000500422e970018000000cd13f000ce005d00430fd3000100f10000547e0000000001",
+		"0005cafe012804",
+	)
+
+	f.Fuzz(func(t *testing.T, packet []byte) {
+		_, err := Decode(packet)
+		checkDecodeError(t, err)
+	})
+}
+
+func FuzzDecodeTcp(f *testing.F) {
+	addHexSeeds(f,
+		"000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF",
+		"000000000000005F10020000016BDBC7833000000000000000000000000000000000000B05040200010000030002000B00270042563A00000000016BDBC7871800000000000000000000000000000000000B05040200010000030002000B00260042563A00000200005FB3",
+	)
+
+	f.Fuzz(func(t *testing.T, packet []byte) {
+		_, err := DecodeTcp("356307042441013", packet)
+		checkDecodeError(t, err)
+	})
+}
+
+func FuzzDecodeCommand(f *testing.F) {
+	addHexSeeds(f,
+		"00000000000000370C01060000002F4449313A31204449323A30204449333A302041494E313A302041494E323A313639323420444F313A3020444F323A3101000066E3",
+		"00000000000000100E011100000008035209308145225101000032AC",
+	)
+
+	f.Fuzz(func(t *testing.T, packet []byte) {
+		_, err := DecodeCommand(packet)
+		checkDecodeError(t, err)
+	})
+}
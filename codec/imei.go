@@ -0,0 +1,28 @@
+package codec
+
+// ValidateIMEI checks the Luhn checksum of a 15 digits long IMEI
+func ValidateIMEI(imei string) bool {
+	if len(imei) != 15 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < len(imei); i++ {
+		digit := int(imei[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+
+		// Every second digit is doubled, starting from the second one
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+	}
+
+	return sum%10 == 0
+}
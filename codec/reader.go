@@ -0,0 +1,78 @@
+package codec
+
+import (
+	"encoding/binary"
+)
+
+// reader reads big endian numbers from a byte slice and never reads beyond its end
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func newReader(data []byte) *reader {
+	return &reader{
+		data: data,
+	}
+}
+
+func (r *reader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.remaining() < n {
+		return nil, newDecodeError(ErrTruncated, r.pos, "%d bytes needed but only %d left", n, r.remaining())
+	}
+
+	value := r.data[r.pos : r.pos+n]
+	r.pos += n
+
+	return value, nil
+}
+
+func (r *reader) uint8() (uint8, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return b[0], nil
+}
+
+func (r *reader) uint16() (uint16, error) {
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint16(b), nil
+}
+
+func (r *reader) uint32() (uint32, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func (r *reader) uint64() (uint64, error) {
+	b, err := r.bytes(8)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// uintN reads a 1 or 2 bytes long unsigned number. Size of counters and IDs depends on the codec.
+func (r *reader) uintN(size int) (uint16, error) {
+	if size == 1 {
+		value, err := r.uint8()
+		return uint16(value), err
+	}
+
+	return r.uint16()
+}
@@ -11,15 +11,17 @@ type Config struct {
 	teltonikaConfig *TeltonikaConfig
 	metricsConfig   *MetricsConfig
 	udsServerConfig *UdsServerConfig
+	storageConfig   *StorageConfig
 }
 
-func NewConfig(log *logrus.Logger, influxConfig *InfluxConfig, teltonikaConfig *TeltonikaConfig, metricsConfig *MetricsConfig, udsServerConfig *UdsServerConfig) *Config {
+func NewConfig(log *logrus.Logger, influxConfig *InfluxConfig, teltonikaConfig *TeltonikaConfig, metricsConfig *MetricsConfig, udsServerConfig *UdsServerConfig, storageConfig *StorageConfig) *Config {
 	return &Config{
 		log:             log,
 		influxConfig:    influxConfig,
 		teltonikaConfig: teltonikaConfig,
 		metricsConfig:   metricsConfig,
 		udsServerConfig: udsServerConfig,
+		storageConfig:   storageConfig,
 	}
 }
 
@@ -39,6 +41,10 @@ func (c *Config) GetUdsServerConfig() *UdsServerConfig {
 	return c.udsServerConfig
 }
 
+func (c *Config) GetStorageConfig() *StorageConfig {
+	return c.storageConfig
+}
+
 func (c *Config) GetLogger() *logrus.Logger {
 	return c.log
 }
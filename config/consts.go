@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type MyKey struct {
 	KeyName string
 }
@@ -21,12 +23,41 @@ const (
 	InfluxConfigPassword                   = "password"
 	InfluxConfigDatabase                   = "database"
 	InfluxConfigMeasurement                = "measurement"
+	InfluxConfigVersion                    = "influxversion"
+	InfluxConfigToken                      = "token"
+	InfluxConfigOrg                        = "org"
+	InfluxConfigBucket                     = "bucket"
+	InfluxConfigPrecision                  = "precision"
+	InfluxConfigGzip                       = "gzip"
+	InfluxConfigBatchSize                  = "batchsize"
+	InfluxConfigRetries                    = "retries"
+	IOMapping                              = "iomapping"
 	TeltonikaListeningIp                   = "listenip"
 	TeltonikaListeningPort                 = "listenport"
+	TeltonikaListeningTcpPort              = "listentcpport"
+	TeltonikaCommandCodec                  = "commandcodec"
+	TeltonikaCommandCodecs                 = "commandcodecs"
+	TeltonikaDevices                       = "devices"
+	TeltonikaModels                        = "models"
+	TeltonikaDevicesFile                   = "devicesfile"
+	RegistryListen                         = "registrylisten"
+	RegistryToken                          = "registrytoken"
+	TeltonikaAckPolicy                     = "ackpolicy"
+	CaptureFileName                        = "capturefile"
+	CaptureMaxSize                         = "capturemaxsize"
+	CaptureMaxAge                          = "capturemaxage"
+	CaptureMaxFiles                        = "capturemaxfiles"
+	CaptureIMEIs                           = "captureimeis"
 	MetricsListeningIp                     = "metricsip"
 	MetricsListeningPort                   = "metricsport"
 	MetricsTeltonikaMetricsFileName        = "mp"
 	UdsServerConfigBasePath                = "udsbasepath"
+	StorageSinks                           = "sinks"
+	QueueDirectory                         = "queuedir"
+	QueueMaxSize                           = "queuemaxsize"
+	QueueSync                              = "queuesync"
+	QueueMinBackoff                        = "queueminbackoff"
+	QueueMaxBackoff                        = "queuemaxbackoff"
 	DefaultDebug                           = false
 	DefaultVerbose                         = false
 	DefaultInfluxDbUrl                     = "http://localhost:8086"
@@ -34,11 +65,35 @@ const (
 	DefaultInfluxDbMeasurementName         = "gps"
 	DefaultInfluxDbUserName                = AppName
 	DefaultInfluxDbPassword                = "123"
+	DefaultInfluxDbVersion                 = 1
+	DefaultInfluxDbToken                   = ""
+	DefaultInfluxDbOrg                     = ""
+	DefaultInfluxDbBucket                  = AppName
+	DefaultInfluxDbPrecision               = "ns"
+	DefaultInfluxDbGzip                    = false
+	DefaultInfluxDbBatchSize               = 5000 // points
+	DefaultInfluxDbRetries                 = 3
 	DefaultAllowedIMEIs                    = "350424063817363" // list, separated by comma
 	DefaultTeltonikaListeningIP            = "0.0.0.0"
 	DefaultTeltonikaListeningPort          = 9160
+	DefaultTeltonikaListeningTcpPort       = 9160
+	DefaultTeltonikaCommandCodec           = 12
+	DefaultTeltonikaAckPolicy              = AckPolicyImmediate
+	DefaultTeltonikaDevicesFile            = "" // devices section of the config file is used
+	DefaultRegistryListen                  = "" // registry API is disabled
+	DefaultRegistryToken                   = ""
+	DefaultCaptureFileName                 = ""  // capturing is disabled by default
+	DefaultCaptureMaxSize                  = 100 // megabytes
+	DefaultCaptureMaxAge                   = 24 * time.Hour
+	DefaultCaptureMaxFiles                 = 10
+	DefaultCaptureIMEIs                    = "" // list, separated by comma
 	DefaultMetricsListeningIP              = "0.0.0.0"
 	DefaultMetricsListeningPort            = 9161
 	DefaultMetricsTeltonikaMetricsFileName = AppName + ".met"
 	DefaultUdsServerConfigBasePath         = "/var/run/haltonika/"
+	DefaultQueueDirectory                  = ""   // durable queue is disabled by default
+	DefaultQueueMaxSize                    = 1024 // megabytes
+	DefaultQueueSync                       = true
+	DefaultQueueMinBackoff                 = time.Second
+	DefaultQueueMaxBackoff                 = 5 * time.Minute
 )
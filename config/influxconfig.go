@@ -1,9 +1,18 @@
 package config
 
 type InfluxConfig struct {
+	Version     int // 1 uses username, password and database, 2 uses token, organization and bucket (InfluxDB 2.x and 3.x)
 	Url         string
 	Username    string
 	Password    string
 	Database    string
+	Token       string
+	Org         string
+	Bucket      string
 	Measurement string
+	Precision   string // ns, us, ms or s
+	Gzip        bool   // Compress write requests, only with version 2
+	BatchSize   int    // Points written in one request
+	Retries     int    // Retries of write requests failed with a transient error
+	IOMapping   bool   // IO elements are written by their mapped names and types instead of IOID<n> integers
 }
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"github.com/halacs/haltonika/ioelement"
+	"time"
+)
+
+// StorageConfig tells where the decoded records are written
+type StorageConfig struct {
+	Sinks     []SinkConfig
+	Queue     QueueConfig
+	IOMapping ioelement.MappingConfig // names, types and scaling of the IO elements
+}
+
+// QueueConfig describes the durable queue in front of the sinks. Empty directory disables it.
+type QueueConfig struct {
+	Directory  string
+	MaxSize    int64 // bytes
+	Sync       bool  // Sync to disk after each record
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// SinkConfig describes one destination of the decoded records. Options depend on the type of the sink.
+type SinkConfig struct {
+	Name          string
+	Type          string
+	QueueSize     int                    // Number of messages waiting for the sink before new ones are dropped
+	FlushInterval time.Duration          // Time between two flushes of buffered messages
+	Options       map[string]interface{} `mapstructure:",remain"`
+}
+
+// Types of the supported sinks
+const (
+	SinkTypeInfluxDB = "influxdb"
+	SinkTypePostgres = "postgres"
+	SinkTypeMQTT     = "mqtt"
+	SinkTypeNATS     = "nats"
+	SinkTypeFile     = "file"
+	SinkTypeExec     = "exec"
+	SinkTypeSQLite   = "sqlite"
+)
@@ -1,9 +1,101 @@
 package config
 
+import (
+	"fmt"
+	"github.com/halacs/haltonika/profile"
+	"github.com/halacs/haltonika/registry"
+	"slices"
+	"time"
+)
+
 type TeltonikaConfig struct {
-	Host         string
-	Port         int
-	AllowedIMEIs []string
+	Host          string
+	Port          int
+	TcpPort       int
+	AllowedIMEIs  []string
+	CommandCodec  byte            // Codec used to send commands by default
+	CommandCodecs map[string]byte // Codec used to send commands by IMEI
+	AckPolicy     string          // AckPolicyImmediate or AckPolicyAfterPersist
+	Capture       CaptureConfig
+	Devices       *registry.Registry
+	Profiles      *profile.Profiles // by model
+	Registry      RegistryConfig
+}
+
+// RegistryConfig tells where the HTTP API of the device registry is served. Empty address disables the API.
+type RegistryConfig struct {
+	Listen string
+	Token  string // bearer token required by every request, the API is not started without it
+}
+
+// Model returns the model of a device or empty string if it is unknown
+func (c *TeltonikaConfig) Model(imei string) string {
+	device, _ := c.Devices.Device(imei)
+	return device.Model
+}
+
+// AllowedDevices returns the IMEIs of the imeilist and the device registry without duplicates
+func (c *TeltonikaConfig) AllowedDevices() []string {
+	allowed := make([]string, 0, len(c.AllowedIMEIs))
+	for _, imei := range append(slices.Clone(c.AllowedIMEIs), c.Devices.IMEIs()...) {
+		if !slices.Contains(allowed, imei) {
+			allowed = append(allowed, imei)
+		}
+	}
+
+	return allowed
+}
+
+// Profile returns the profile of the model of a device
+func (c *TeltonikaConfig) Profile(imei string) profile.Profile {
+	return c.Profiles.Profile(c.Model(imei))
+}
+
+// DeviceCommandCodec returns the codec used to send commands to a device.
+// The codec set by IMEI wins over the one of the model of the device, which wins over the default one.
+func (c *TeltonikaConfig) DeviceCommandCodec(imei string) byte {
+	if codecID, ok := c.CommandCodecs[imei]; ok {
+		return codecID
+	}
+	if codecID := c.Profile(imei).CommandCodec; codecID != 0 {
+		return codecID
+	}
+
+	return c.CommandCodec
+}
+
+// When received packets are acknowledged to the devices
+const (
+	AckPolicyImmediate    = "immediate"     // right after the packet is decoded
+	AckPolicyAfterPersist = "after-persist" // only after the packet is stored, so devices send it again on storage failure
+)
+
+// CaptureConfig tells where raw traffic of the devices is written. Empty file name disables capturing.
+type CaptureConfig struct {
+	FileName string
+	MaxSize  int64 // bytes
+	MaxAge   time.Duration
+	MaxFiles int
+	IMEIs    []string
+}
+
+// ParseCommandCodec validates the number of a codec which can be used to send commands
+func ParseCommandCodec(value int) (byte, error) {
+	// Codec 13 is used only by devices to send responses
+	if value != 12 && value != 14 {
+		return 0, fmt.Errorf("commands can be sent with codec 12 or 14, got %d", value)
+	}
+
+	return byte(value), nil
+}
+
+// ParseAckPolicy validates the policy telling when received packets are acknowledged
+func ParseAckPolicy(value string) (string, error) {
+	if value != AckPolicyImmediate && value != AckPolicyAfterPersist {
+		return "", fmt.Errorf("ACK policy must be %s or %s, got %s", AckPolicyImmediate, AckPolicyAfterPersist, value)
+	}
+
+	return value, nil
 }
 
 type MetricsConfig struct {
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/halacs/haltonika/inspect"
+	"github.com/halacs/haltonika/profile"
+	"github.com/spf13/pflag"
+	"os"
+	"strings"
+)
+
+// runDecode decodes a packet given as hex string or file and prints it in human readable form
+func runDecode(args []string) int {
+	flags := pflag.NewFlagSet("decode", pflag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: haltonika decode [flags] <hex|file>")
+		fmt.Fprintln(os.Stderr, "Decodes an UDP or TCP AVL packet, a command packet or a bare AVL data array.")
+		flags.PrintDefaults()
+	}
+	output := flags.String("output", "table", "Output format (table or json)")
+	imei := flags.String("imei", "", "IMEI of the device. TCP packets and bare AVL data do not contain it.")
+	model := flags.String("model", "", "Model of the device, e.g. FMB920 or FM3622. Selects the IO element names.")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", *output)
+		return 2
+	}
+
+	packet, err := readPacket(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read packet. %v\n", err)
+		return 1
+	}
+
+	// Only the built-in models are known without the config file
+	described, err := inspect.Describe(packet, *imei, profile.BuiltinDictionary(*model))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to decode packet. %v\n", err)
+		return 1
+	}
+
+	if *output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(described)
+	} else {
+		err = inspect.WriteTable(os.Stdout, described)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write output. %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// readPacket reads the packet from a file if it can be read, otherwise the argument itself is parsed as hex.
+// Files can contain the packet either in hex or in binary.
+func readPacket(arg string) ([]byte, error) {
+	// Long hex arguments can not even be opened as a file, e.g. their name is too long
+	content, err := os.ReadFile(arg) // #nosec G304
+	if err != nil {
+		content = []byte(arg)
+	}
+
+	// Whitespaces and line breaks are allowed in hex dumps
+	text := strings.Join(strings.Fields(string(content)), "")
+	text = strings.TrimPrefix(strings.ToLower(text), "0x")
+
+	packet, hexErr := hex.DecodeString(text)
+	if hexErr == nil {
+		return packet, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither a readable file nor a hex string. %v, %v", arg, err, hexErr)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadPacket(t *testing.T) {
+	// Longer than the maximum file name length
+	packet := bytes.Repeat([]byte{0x01, 0xe4, 0xca, 0xfe}, 100)
+	text := hex.EncodeToString(packet)
+
+	dir := t.TempDir()
+	hexFile := filepath.Join(dir, "packet.hex")
+	err := os.WriteFile(hexFile, []byte(strings.ToUpper(text[:200])+"\n"+text[200:]), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write file. %v", err)
+	}
+	binaryFile := filepath.Join(dir, "packet.bin")
+	err = os.WriteFile(binaryFile, packet, 0600)
+	if err != nil {
+		t.Fatalf("Failed to write file. %v", err)
+	}
+
+	testCases := []struct {
+		Name          string
+		Arg           string
+		ExpectedError bool
+	}{
+		{
+			Name: "Long hex argument",
+			Arg:  text,
+		},
+		{
+			Name: "Hex argument with prefix",
+			Arg:  "0x" + text,
+		},
+		{
+			Name: "Hex file",
+			Arg:  hexFile,
+		},
+		{
+			Name: "Binary file",
+			Arg:  binaryFile,
+		},
+		{
+			Name:          "Missing file",
+			Arg:           filepath.Join(dir, "missing.bin"),
+			ExpectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			actual, err := readPacket(testCase.Arg)
+			if (err != nil) != testCase.ExpectedError {
+				test.Fatalf("Wrong result! Expected error: %v Actual: %v", testCase.ExpectedError, err)
+			}
+			if !testCase.ExpectedError && !bytes.Equal(actual, packet) {
+				test.Errorf("Wrong packet! Expected: %x Actual: %x", packet, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package fmb920
+
+import (
+	"github.com/halacs/haltonika/capture"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/config"
+	"net"
+	"time"
+)
+
+// SetCapture enables writing all received and sent packets into the given capture. Nil disables capturing.
+func (s *Server) SetCapture(writer *capture.Writer) {
+	s.capture = writer
+}
+
+func (s *Server) capturePacket(direction capture.Direction, transport string, remote string, imei string, data []byte) {
+	if s.capture == nil {
+		return
+	}
+
+	err := s.capture.Write(capture.Packet{
+		Time:      time.Now(),
+		Direction: direction,
+		Transport: transport,
+		Remote:    remote,
+		IMEI:      imei,
+		Data:      data,
+	})
+	if err != nil {
+		config.GetLogger(s.ctx).Errorf("Failed to capture packet. %v", err)
+	}
+}
+
+// Captures an UDP packet. Sender is taken from the AVL packet itself or from the address it was sent from.
+func (s *Server) captureUdpPacket(direction capture.Direction, remote *net.UDPAddr, decodedAvl *codec.Decoded, data []byte) {
+	if s.capture == nil {
+		return
+	}
+
+	imei := ""
+	if decodedAvl != nil {
+		imei = decodedAvl.IMEI
+	} else if device, ok := s.getOnlineDeviceEndpoint(remote); ok {
+		imei = device.Imei
+	}
+
+	s.capturePacket(direction, capture.TransportUdp, remote.String(), imei, data)
+}
@@ -53,6 +53,9 @@ func (s *Server) addResentPackages(count uint64) {
 func (s *Server) isResentPackage(pkg *[]byte) bool {
 	log := config.GetLogger(s.ctx)
 
+	s.processedPacketsLock.Lock()
+	defer s.processedPacketsLock.Unlock()
+
 	hexBytes := hex.EncodeToString(*pkg)
 	ts, ok := s.processedPackets[hexBytes]
 	if ok {
@@ -63,7 +66,7 @@ func (s *Server) isResentPackage(pkg *[]byte) bool {
 	}
 
 	for p, ts := range s.processedPackets {
-		if !ts.Before(time.Now().Add(-1 * time.Hour)) {
+		if ts.Before(time.Now().Add(-1 * time.Hour)) {
 			delete(s.processedPackets, p)
 			log.Tracef("Packet removed from processed packet.")
 		}
@@ -7,31 +7,88 @@ import (
 )
 
 func (s *Server) markDeviceOnline(remote *net.UDPAddr, listener *net.UDPConn, imei string) error {
-	s.devices.Store(remote.String(), &DevicesWithTimeout{
+	s.storeOnlineDevice(&DevicesWithTimeout{
 		Imei:      imei,
 		Remote:    remote,
 		Listener:  listener,
 		Timestamp: time.Now(),
 	})
+	s.udpEndpoints.Store(remote.String(), imei)
 
-	s.udsServer.KeepAlive(imei)
+	return nil
+}
+
+func (s *Server) markTcpDeviceOnline(session *tcpSession) error {
+	s.storeOnlineDevice(&DevicesWithTimeout{
+		Imei:      session.imei,
+		Session:   session,
+		Timestamp: time.Now(),
+	})
 
 	return nil
 }
 
+// storeOnlineDevice stores the endpoint the device was seen on last. It is reported as connected if it was offline.
+func (s *Server) storeOnlineDevice(device *DevicesWithTimeout) {
+	previous, known := s.devices.Swap(device.Imei, device)
+	if !known {
+		s.reportDeviceEvent(device.Imei, DeviceConnected, device.transport(), device.address())
+	} else if item := previous.(*DevicesWithTimeout); item.Remote != nil && item.address() != device.address() {
+		// The device is not reachable on its former UDP address any more, e.g. its NAT mapping has changed
+		s.udpEndpoints.CompareAndDelete(item.Remote.String(), item.Imei)
+	}
+
+	s.udsServer.KeepAlive(device.Imei)
+}
+
+func (s *Server) markTcpDeviceOffline(session *tcpSession) {
+	log := config.GetLogger(s.ctx)
+
+	// The device might have been reconnected over a new session or over UDP meanwhile
+	value, ok := s.devices.Load(session.imei)
+	if !ok || value.(*DevicesWithTimeout).Session != session {
+		return
+	}
+
+	if s.devices.CompareAndDelete(session.imei, value) {
+		s.reportDeviceEvent(session.imei, DeviceDisconnected, TransportTCP, session.conn.RemoteAddr().String())
+		log.Debugf("Device with %s IMEI has been removed from map of online devices because its TCP connection is closed.", session.imei)
+	}
+}
+
 func (s *Server) cleanupDevicesOnline() {
 	log := config.GetLogger(s.ctx)
 
 	s.devices.Range(func(k, value any) bool {
 		item := value.(*DevicesWithTimeout)
-		if !item.Timestamp.Before(time.Now().Add(s.devicesByImeitimeout)) {
-			s.devices.Delete(k)
+		if item.Timestamp.Before(time.Now().Add(-s.devicesByImeitimeout)) {
+			if s.devices.CompareAndDelete(k, value) {
+				if item.Remote != nil {
+					s.udpEndpoints.CompareAndDelete(item.Remote.String(), item.Imei)
+				}
+				s.reportDeviceEvent(item.Imei, DeviceDisconnected, item.transport(), item.address())
+			}
 			log.Debugf("Device with %s IMEI has been removed from map of online devices. Item's timestamp: %v", item.Imei, item.Timestamp)
 		}
 		return true // continue map iteration
 	})
 }
 
+// reportDeviceEvent passes a device coming online or going offline to the callback if it is set
+func (s *Server) reportDeviceEvent(imei string, eventType string, transport string, address string) {
+	if s.deviceEventCallback == nil {
+		return
+	}
+
+	s.deviceEventCallback(s.ctx, DeviceEvent{
+		IMEI:          imei,
+		Type:          eventType,
+		Transport:     transport,
+		SourceAddress: address,
+		Time:          time.Now().UTC(),
+	})
+}
+
 // Periodically cleanup map of connected devices
 func (s *Server) startPeriodicCleanupOnlineDevices() {
 	go func() {
@@ -49,27 +106,22 @@ func (s *Server) startPeriodicCleanupOnlineDevices() {
 	}()
 }
 
+// getOnlineDevice returns the endpoint the device was seen on last
 func (s *Server) getOnlineDevice(imei string) (*DevicesWithTimeout, bool) {
-	var device *DevicesWithTimeout
-
-	// TODO: consider to store devices by IMEI to be more efficient but I have not much motivation to do so right now :)
-	s.devices.Range(func(k, value any) bool {
-		item := value.(*DevicesWithTimeout)
-		if item.Imei == imei {
-			device = item
-			return true // do NOT continue map iteration
-		}
-		return true // continue map iteration
-	})
+	value, ok := s.devices.Load(imei)
+	if !ok {
+		return nil, false
+	}
 
-	return device, device != nil
+	return value.(*DevicesWithTimeout), true
 }
 
+// getOnlineDeviceEndpoint returns the device sending packets from the UDP address
 func (s *Server) getOnlineDeviceEndpoint(remote *net.UDPAddr) (*DevicesWithTimeout, bool) {
-	value, ok := s.devices.Load(remote.String())
+	imei, ok := s.udpEndpoints.Load(remote.String())
 	if !ok {
 		return nil, false
 	}
 
-	return value.(*DevicesWithTimeout), true
+	return s.getOnlineDevice(imei.(string))
 }
@@ -0,0 +1,66 @@
+package fmb920
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/halacs/haltonika/capture"
+	"github.com/halacs/haltonika/codec"
+)
+
+// ReplayPacket feeds a captured packet through the same decoding and callback as live traffic. Only received AVL data
+// packets are processed, it returns false for any other packets and for resent ones. Server does not need to be started.
+// The callback is called synchronously, so the records of a device keep their order and its failures are returned.
+func (s *Server) ReplayPacket(packet capture.Packet) (bool, error) {
+	if packet.Direction != capture.Received {
+		return false, nil
+	}
+
+	// Data packets start with 4 zero bytes over TCP, like command packets over any transport
+	hasPreamble := len(packet.Data) > tcpHeaderLength && binary.BigEndian.Uint32(packet.Data) == 0
+
+	var decodedAvl codec.Decoded
+	var err error
+	switch packet.Transport {
+	case capture.TransportTcp:
+		// IMEI handshake or command response
+		if !hasPreamble || codec.IsCommandCodec(packet.Data[tcpHeaderLength]) {
+			return false, nil
+		}
+		decodedAvl, err = codec.DecodeTcp(packet.IMEI, packet.Data)
+	case capture.TransportUdp:
+		// Heartbeat or command response
+		if len(packet.Data) == 1 || hasPreamble {
+			return false, nil
+		}
+		decodedAvl, err = codec.Decode(packet.Data)
+	default:
+		return false, fmt.Errorf("unknown transport: %s", packet.Transport)
+	}
+	if err != nil {
+		s.addMalformedPackages(1)
+		return false, err
+	}
+
+	s.addReceivedPackages(1)
+
+	if !s.isAllowedIMEI(decodedAvl.IMEI) {
+		s.addRejectedPackages(1)
+		return false, fmt.Errorf("%s IMEI is not on the allow list", decodedAvl.IMEI)
+	}
+
+	// Resent packets are captured as many times as the device sent them, but they are processed only once
+	raw := []byte(packet.Data)
+	if s.isResentPackage(&raw) {
+		return false, nil
+	}
+
+	err = s.callback(s.ctx, TeltonikaMessage{
+		Decoded:       decodedAvl,
+		SourceAddress: packet.Remote,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to process packet of %s device. %v", decodedAvl.IMEI, err)
+	}
+
+	return true, nil
+}
@@ -1,9 +1,12 @@
 package fmb920
 
-import "strings"
+import (
+	"slices"
+	"strings"
+)
 
 func (s *Server) isAllowedIMEI(imei string) bool {
-	for _, actualIMEI := range s.allowedIMEIs {
+	for _, actualIMEI := range s.getAllowedIMEIs() {
 		if strings.EqualFold(imei, actualIMEI) {
 			return true
 		}
@@ -11,3 +14,18 @@ func (s *Server) isAllowedIMEI(imei string) bool {
 
 	return false
 }
+
+// SetAllowedIMEIs replaces the devices allowed to send data
+func (s *Server) SetAllowedIMEIs(imeis []string) {
+	s.allowedIMEIsLock.Lock()
+	defer s.allowedIMEIsLock.Unlock()
+
+	s.allowedIMEIs = slices.Clone(imeis)
+}
+
+func (s *Server) getAllowedIMEIs() []string {
+	s.allowedIMEIsLock.RLock()
+	defer s.allowedIMEIsLock.RUnlock()
+
+	return s.allowedIMEIs
+}
@@ -4,23 +4,24 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
-	"github.com/filipkroca/teltonikaparser"
+	"github.com/halacs/haltonika/capture"
+	"github.com/halacs/haltonika/codec"
 	"github.com/halacs/haltonika/config"
 	metrics2 "github.com/halacs/haltonika/metrics"
 	"github.com/halacs/haltonika/uds"
 	"github.com/sirupsen/logrus"
 	"net"
-	"slices"
 	"strings"
 	"sync"
 	"time"
 )
 
-func NewServer(ctx context.Context, wg *sync.WaitGroup, host string, port int, allowedIMEIs []string, udsServer uds.MultiServerInterface, metrics metrics2.TeltonikaMetricsInterface, callback PacketArrivedCallback) *Server {
+func NewServer(ctx context.Context, wg *sync.WaitGroup, host string, port int, tcpPort int, allowedIMEIs []string, udsServer uds.MultiServerInterface, metrics metrics2.TeltonikaMetricsInterface, callback PacketArrivedCallback) *Server {
 	server := &Server{
 		wg:                   wg,
 		host:                 host,
 		port:                 port,
+		tcpPort:              tcpPort,
 		callback:             callback,
 		ctx:                  ctx,
 		metrics:              metrics,
@@ -31,11 +32,45 @@ func NewServer(ctx context.Context, wg *sync.WaitGroup, host string, port int, a
 		//commandResponses:     make(chan string),
 		//commandRequests:      make(chan string, 1),
 		udsServer: udsServer,
+		ackPolicy: config.AckPolicyImmediate,
 	}
 
 	return server
 }
 
+// SetCommandCodecFunc sets the function telling the codec used to send commands to a device. It is asked before each command. Nil uses Codec 12.
+func (s *Server) SetCommandCodecFunc(commandCodec CommandCodecFunc) {
+	s.commandCodec = commandCodec
+}
+
+// SetAckPolicy sets when received packets are acknowledged, config.AckPolicyImmediate or config.AckPolicyAfterPersist
+func (s *Server) SetAckPolicy(policy string) {
+	s.ackPolicy = policy
+}
+
+// SetCommandResponseCallback sets the function getting the responses of the devices to commands. Nil disables it.
+func (s *Server) SetCommandResponseCallback(callback CommandResponseCallback) {
+	s.commandResponseCallback = callback
+}
+
+// SetCommandSentCallback sets the function getting the commands sent to the devices. Nil disables it.
+func (s *Server) SetCommandSentCallback(callback CommandSentCallback) {
+	s.commandSentCallback = callback
+}
+
+// SetDeviceEventCallback sets the function getting devices coming online and going offline. Nil disables it.
+func (s *Server) SetDeviceEventCallback(callback DeviceEventCallback) {
+	s.deviceEventCallback = callback
+}
+
+func (s *Server) getCommandCodec(imei string) byte {
+	if s.commandCodec == nil {
+		return codec.Codec12
+	}
+
+	return s.commandCodec(imei)
+}
+
 func (s *Server) sendCommandToDevice(imei string) error {
 	log := config.GetLogger(s.ctx).WithField("imei", imei)
 
@@ -46,6 +81,12 @@ func (s *Server) sendCommandToDevice(imei string) error {
 		return fmt.Errorf("failed to send command response to channel. %v", err)
 	}
 
+	// Devices connected over TCP get their commands immediately by their own session
+	device, ok := s.getOnlineDevice(imei)
+	if ok && device.Session != nil {
+		return nil
+	}
+
 	// Check if there is a command to be sent and if yes send it to the device who send FF just now
 	select {
 	case commandStr := <-commandRequests:
@@ -55,17 +96,10 @@ func (s *Server) sendCommandToDevice(imei string) error {
 		if !ok {
 			return fmt.Errorf("online device not found. %v", err)
 		}
-		listener := device.Listener
-		remote := device.Remote
 
-		command, err := teltonikaparser.EncodeCommandRequest(commandStr)
+		err = s.deliverCommand(device, commandStr)
 		if err != nil {
-			return fmt.Errorf("failed to encode command. %v", err)
-		}
-
-		err = s.sendBytes(listener, command, remote)
-		if err != nil {
-			return fmt.Errorf("failed to send commands's bytes out. %v", err)
+			return err
 		}
 
 		log.Infof("Command has been sent: %s", commandStr)
@@ -76,10 +110,59 @@ func (s *Server) sendCommandToDevice(imei string) error {
 	return nil
 }
 
+// SendCommand queues a command for a device like its UDS socket does. The command is sent when the device is online.
+func (s *Server) SendCommand(imei string, command string) error {
+	if s.localCtx == nil {
+		return fmt.Errorf("server is not running")
+	}
+
+	commandRequests, _, err := s.GetCommandRequestChannel(imei)
+	if err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			s.wg.Done()
+		}()
+
+		select {
+		case <-s.localCtx.Done():
+		case commandRequests <- command:
+		}
+	}()
+
+	return nil
+}
+
+// Encodes the command with the codec set for the device and sends it over the transport the device is connected with
+func (s *Server) deliverCommand(device *DevicesWithTimeout, commandStr string) error {
+	command, err := codec.EncodeCommandRequest(s.getCommandCodec(device.Imei), device.Imei, commandStr)
+	if err != nil {
+		return fmt.Errorf("failed to encode command. %v", err)
+	}
+
+	if device.Session != nil {
+		err = s.sendTcpBytes(device.Session, command)
+	} else {
+		err = s.sendBytes(device.Listener, command, device.Remote)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send commands's bytes out. %v", err)
+	}
+
+	if s.commandSentCallback != nil {
+		s.commandSentCallback(s.ctx, device.Imei, commandStr)
+	}
+
+	return nil
+}
+
 func (s *Server) Start() error {
 	log := config.GetLogger(s.ctx)
 
-	log.Infof("Start Teltonika server on %s:%d", s.host, s.port)
+	log.Infof("Start Teltonika UDP server on %s:%d", s.host, s.port)
 
 	s.localCtx, s.stopFunc = context.WithCancel(s.ctx)
 
@@ -116,18 +199,13 @@ func (s *Server) Start() error {
 				return
 			case <-t.C:
 				log.Tracef("Periodic command sending triggered")
-				for _, imei := range s.allowedIMEIs {
+				for _, imei := range s.getAllowedIMEIs() {
 					err := s.sendCommandToDevice(imei)
 					if err != nil {
 						log.Errorf("Failed to send command to device. %v", err)
 
 						// Send error back to the user
-						commandResponses, _, err2 := s.GetCommandResponseChannel(imei)
-						if err2 != nil {
-							log.Errorf("Failed to send command response to channel. %v", err2)
-						} else {
-							commandResponses <- err.Error()
-						}
+						s.forwardCommandResponse(imei, err.Error())
 					}
 				}
 			}
@@ -166,6 +244,14 @@ func (s *Server) Start() error {
 
 				log.Tracef("%d bytes long packet received: %s", size, hex.EncodeToString(buffer))
 
+				// Is it an AVL data package? Most of the packages should be AVL Data Package.
+				decodedAvl, errAvl := codec.Decode(buffer)
+				if errAvl != nil {
+					s.captureUdpPacket(capture.Received, remote, nil, buffer)
+				} else {
+					s.captureUdpPacket(capture.Received, remote, &decodedAvl, buffer)
+				}
+
 				// Is it a heartbeat package?
 				if size == 1 && strings.ToLower(hex.EncodeToString(buffer)) == "ff" {
 					value, ok := s.getOnlineDeviceEndpoint(remote)
@@ -179,11 +265,9 @@ func (s *Server) Start() error {
 					continue
 				}
 
-				// Is it an AVL data package? Most of the packages should be AVL Data Package.
-				decodedAvl, errAvl := teltonikaparser.Decode(&buffer)
 				if errAvl != nil {
 					// Is it a command response package?
-					commandResponse, errCmd := teltonikaparser.DecodeCommandResponse(&buffer)
+					commandResponse, errCmd := codec.DecodeCommand(buffer)
 					if errCmd != nil {
 						// Neither AVL Data Package nor Command Response Package
 						log.Errorf("Malformed packet received. Neither AVL Data Packer nor Command Response packet. Ignoring packet. AVL parser: %v. Command response parser: %v", errAvl, errCmd)
@@ -201,23 +285,9 @@ func (s *Server) Start() error {
 						continue
 					}
 
-					log.Debugf("Get command response from device with %s IMEI. Remote endpoint: %v Repsonse: %v", value.Imei, remote, string(commandResponse.Response))
-					s.addReceivedPackages(1) // Command Response Package !
-
-					// Forward command response for further processing
-					s.wg.Add(1)
-					go func() { // TODO is this the right way to send it back? Not sure...
-						defer func() {
-							s.wg.Done()
-						}()
+					log.Debugf("Get command response from device with %s IMEI. Remote endpoint: %v Repsonse: %v", value.Imei, remote, string(commandResponse.Payload))
 
-						commandResponses, _, err := s.GetCommandResponseChannel(value.Imei)
-						if err != nil {
-							log.Errorf("Failed to send command response to channel. %v", err)
-						} else {
-							commandResponses <- string(commandResponse.Response)
-						}
-					}()
+					s.handleCommandResponse(value.Imei, commandResponse)
 
 					continue
 				}
@@ -234,56 +304,159 @@ func (s *Server) Start() error {
 					continue
 				}
 
-				server, _ := s.udsServer.GetServer(decodedAvl.IMEI) // UdsServer is already started
-				if server == nil {
-					socketPath, err := s.startNewUdsServer(decodedAvl.IMEI)
-					if err != nil {
-						log.Errorf("Failed to start new UDS server. %v", err)
-					} else {
-						log.Infof("New UDS server has been started for %s device at %s", decodedAvl.IMEI, socketPath)
-					}
-				} else {
-					socketPath, err := server.GetSocketPath()
-					if err != nil {
-						log.Errorf("%v", err)
-					}
-					log.Tracef("UdsServer for %s device is running at %s. %v", decodedAvl.IMEI, socketPath, server)
-				}
+				s.ensureUdsServer(decodedAvl.IMEI)
 
 				err = s.markDeviceOnline(remote, listen, decodedAvl.IMEI)
 				if err != nil {
 					log.Errorf("Failed to mark device online. %v", err)
 				}
 
-				// Send response for an AVL
-				err = s.sendBytes(listen, decodedAvl.Response, remote)
-				if err != nil {
-					// just log the error and let the connection alive
-					log.Errorf("Failed to send response for a packet. %v Continue.", err)
-				}
+				// Response for an AVL is sent by the ACK policy
+				s.processAvlPacket(decodedAvl, buffer, remote.String(), func() error {
+					return s.sendBytes(listen, decodedAvl.Response, remote)
+				})
+			}
+		}
+	}()
 
-				// Process received packet on a separated thread
-				// TODO consider if all after receiving the packet can be done in a separated thread even the response sending
-				s.wg.Add(1)
-				go func() {
-					defer func() {
-						s.wg.Done()
-					}()
+	if s.tcpPort > 0 {
+		err = s.startTcpListener()
+		if err != nil {
+			return fmt.Errorf("failed to start TCP listener. %v", err)
+		}
+	}
 
-					if s.isResentPackage(&buffer) {
-						log.Warningf("Doubled packet received: %v", buffer)
-					}
+	return nil
+}
+
+// Starts UDS server of the given device if it is not running yet
+func (s *Server) ensureUdsServer(imei string) {
+	log := config.GetLogger(s.ctx)
+
+	server, _ := s.udsServer.GetServer(imei) // UdsServer is already started
+	if server == nil {
+		socketPath, err := s.startNewUdsServer(imei)
+		if err != nil {
+			log.Errorf("Failed to start new UDS server. %v", err)
+		} else {
+			log.Infof("New UDS server has been started for %s device at %s", imei, socketPath)
+		}
+	} else {
+		socketPath, err := server.GetSocketPath()
+		if err != nil {
+			log.Errorf("%v", err)
+		}
+		log.Tracef("UdsServer for %s device is running at %s. %v", imei, socketPath, server)
+	}
+}
+
+// Forward command response for further processing
+func (s *Server) forwardCommandResponse(imei string, response string) {
+	log := config.GetLogger(s.ctx)
+
+	s.wg.Add(1)
+	go func() { // TODO is this the right way to send it back? Not sure...
+		defer func() {
+			s.wg.Done()
+		}()
+
+		if s.commandResponseCallback != nil {
+			s.commandResponseCallback(s.ctx, imei, response)
+		}
+
+		commandResponses, _, err := s.GetCommandResponseChannel(imei)
+		if err != nil {
+			log.Errorf("Failed to send command response to channel. %v", err)
+		} else {
+			commandResponses <- response
+		}
+	}()
+}
+
+// Checks whether the response is for our command then forwards it to the user
+func (s *Server) handleCommandResponse(imei string, response codec.Command) {
+	log := config.GetLogger(s.ctx).WithField("imei", imei)
+
+	s.addReceivedPackages(1) // Command Response Package !
+
+	if response.CodecID == codec.Codec13 {
+		log.Debugf("Command response was generated by the device at %v", response.Timestamp)
+	}
+
+	if response.CodecID == codec.Codec14 && response.IMEI != imei {
+		log.Warningf("Command response is addressed to %s IMEI. Drop package.", response.IMEI)
+		s.forwardCommandResponse(imei, fmt.Sprintf("response addressed to %s IMEI was dropped", response.IMEI))
+		return
+	}
+
+	if response.IsNack() {
+		log.Warningf("Command was rejected by the device because it is not addressed to its IMEI")
+		s.forwardCommandResponse(imei, fmt.Sprintf("command was rejected by the device because it is not addressed to its IMEI (%s)", response.IMEI))
+		return
+	}
+
+	s.forwardCommandResponse(imei, string(response.Payload))
+}
+
+// processAvlPacket warns about resent packets and processes the packet on a separated thread
+func (s *Server) processAvlPacket(decodedAvl codec.Decoded, raw []byte, sourceAddress string, ack func() error) {
+	if s.isResentPackage(&raw) {
+		config.GetLogger(s.ctx).Warningf("Doubled packet received: %v", raw)
+	}
+
+	s.dispatchAvlPacket(decodedAvl, sourceAddress, ack)
+}
+
+/*
+Process received packet on a separated thread.
+The ack function sends the response to the device. It is called before processing with immediate ACK policy,
+or only if the callback succeeded with after-persist ACK policy. It is nil if the packet must not be acknowledged.
+*/
+func (s *Server) dispatchAvlPacket(decodedAvl codec.Decoded, sourceAddress string, ack func() error) {
+	log := config.GetLogger(s.ctx)
+
+	sendAck := func() {
+		if ack == nil {
+			return
+		}
+
+		err := ack()
+		if err != nil {
+			// just log the error and let the connection alive
+			log.Errorf("Failed to send response for a packet. %v Continue.", err)
+		}
+	}
 
-					// Send notification about the new decodedAvl packet
-					s.callback(s.ctx, TeltonikaMessage{
-						Decoded:       decodedAvl,
-						SourceAddress: remote.String(),
-					})
-				}()
+	afterPersist := s.ackPolicy == config.AckPolicyAfterPersist
+	if !afterPersist {
+		sendAck()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			s.wg.Done()
+		}()
+
+		// Send notification about the new decodedAvl packet
+		err := s.callback(s.ctx, TeltonikaMessage{
+			Decoded:       decodedAvl,
+			SourceAddress: sourceAddress,
+		})
+		if err != nil {
+			if afterPersist {
+				log.Errorf("Packet of %s device is not acknowledged so the device will send it again. %v", decodedAvl.IMEI, err)
+				return
 			}
+
+			log.Errorf("Failed to process packet of %s device. %v", decodedAvl.IMEI, err)
+			return
+		}
+
+		if afterPersist {
+			sendAck()
 		}
 	}()
-	return nil
 }
 
 func (s *Server) startNewUdsServer(imei string) (string, error) {
@@ -346,6 +519,8 @@ func (s *Server) sendBytes(listen *net.UDPConn, data []byte, remote *net.UDPAddr
 
 	log.Tracef("Sending %d bytes to %v: %s", len(data), remote, hex.EncodeToString(data))
 
+	s.captureUdpPacket(capture.Sent, remote, nil, data)
+
 	size, err := listen.WriteToUDP(data, remote)
 	if err != nil {
 		return err
@@ -372,7 +547,7 @@ func (s *Server) GetCommandResponseChannel(imei string) (ch chan string, created
 
 	created = false
 
-	if !slices.Contains(s.allowedIMEIs, imei) {
+	if !s.isAllowedIMEI(imei) {
 		return nil, created, fmt.Errorf("%s device ID is not on the allowed list", imei)
 	}
 
@@ -391,7 +566,7 @@ func (s *Server) GetCommandRequestChannel(imei string) (ch chan string, created
 
 	created = false
 
-	if !slices.Contains(s.allowedIMEIs, imei) {
+	if !s.isAllowedIMEI(imei) {
 		return nil, created, fmt.Errorf("%s device ID is not on the allowed list", imei)
 	}
 
@@ -3,12 +3,19 @@ package fmb920
 import (
 	"context"
 	"encoding/hex"
+	"fmt"
+	"github.com/halacs/haltonika/capture"
+	"github.com/halacs/haltonika/codec"
 	"github.com/halacs/haltonika/config"
 	"github.com/halacs/haltonika/metrics"
 	metrics2 "github.com/halacs/haltonika/metrics/impl"
 	"github.com/halacs/haltonika/uds"
 	"github.com/sirupsen/logrus"
+	"io"
 	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -18,6 +25,7 @@ var (
 	allowedIMEIs = []string{
 		"352094089397464",
 		"350424063817363",
+		"356307042441013",
 	}
 )
 
@@ -47,18 +55,43 @@ func recv(ctx context.Context, conn *net.UDPConn) (int, []byte) {
 	return size, buffer
 }
 
-func startServer(ctx context.Context, udsServer uds.MultiServerInterface, metrics metrics.TeltonikaMetricsInterface, callback PacketArrivedCallback) {
-	go func() {
-		log := config.GetLogger(ctx)
+func startServer(ctx context.Context, port int, udsServer uds.MultiServerInterface, metrics metrics.TeltonikaMetricsInterface, callback PacketArrivedCallback) *Server {
+	log := config.GetLogger(ctx)
 
-		var wg sync.WaitGroup
-		server := NewServer(ctx, &wg, "127.0.0.1", 9001, allowedIMEIs, udsServer, metrics, callback)
+	var wg sync.WaitGroup
+	server := NewServer(ctx, &wg, "127.0.0.1", port, port, allowedIMEIs, udsServer, metrics, callback)
 
-		err := server.Start()
-		if err != nil {
-			log.Errorf("Failed to start Teltonika server. %v", err)
-		}
-	}()
+	err := server.Start()
+	if err != nil {
+		log.Errorf("Failed to start Teltonika server. %v", err)
+	}
+
+	return server
+}
+
+func tcpHandshake(t *testing.T, conn net.Conn, imei string) {
+	handshake := []byte{0x00, byte(len(imei))}
+	handshake = append(handshake, imei...)
+	_, err := conn.Write(handshake)
+	if err != nil {
+		t.Fatalf("Write to server failed. %v", err)
+	}
+
+	buffer := make([]byte, 1)
+	_, err = io.ReadFull(conn, buffer)
+	if err != nil {
+		t.Fatalf("Failed to read handshake response. %v", err)
+	}
+	if buffer[0] != 0x01 {
+		t.Fatalf("IMEI was not accepted. Response: %x", buffer)
+	}
+}
+
+func newTestContext() context.Context {
+	log := logrus.New()
+	log.SetLevel(logrus.TraceLevel)
+	cfg := config.NewConfig(log, nil, nil, nil, nil, nil)
+	return context.WithValue(context.Background(), config.ContextConfigKey, cfg)
 }
 
 func TestConnect(t *testing.T) {
@@ -99,22 +132,20 @@ func TestConnect(t *testing.T) {
 		},
 	}
 
-	log := logrus.New()
-	log.SetLevel(logrus.TraceLevel)
-	cfg := config.NewConfig(log, nil, nil, nil, nil)
-	ctx := context.WithValue(context.Background(), config.ContextConfigKey, cfg)
+	ctx := newTestContext()
 
 	// Initialize metrics collector
 	var wg sync.WaitGroup
 	udsServer := &uds.MultiServerMock{} // TODO: is this fine this way? Double check it!
 	metrics := metrics2.NewMetrics(ctx, &wg, metricsFilename)
 	// Create callback function for decoded packets
-	callbackFunc := func(ctx context.Context, message TeltonikaMessage) {
+	callbackFunc := func(ctx context.Context, message TeltonikaMessage) error {
 		log2 := config.GetLogger(ctx)
 		log2.Infof("New decoded packet: %+v", message)
+		return nil
 	}
-	// Start server to be tested"cof
-	startServer(ctx, udsServer, metrics, callbackFunc)
+	// Start server to be tested
+	startServer(ctx, 9001, udsServer, metrics, callbackFunc)
 
 	for _, testCase := range testCases {
 		t.Run(testCase.Name, func(test *testing.T) {
@@ -158,3 +189,982 @@ func TestConnect(t *testing.T) {
 		})
 	}
 }
+
+func TestTcpConnect(t *testing.T) {
+	testCases := []struct {
+		Name              string
+		Imei              string
+		ExpectedHandshake string
+		Request           string
+		ExpectedResponse  string
+	}{
+		{
+			Name:              "Codec8 packet from Teltonika wiki",
+			Imei:              "356307042441013",
+			ExpectedHandshake: "01",
+			Request:           "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF",
+			ExpectedResponse:  "00000001",
+		},
+		{
+			Name:              "Codec8 Extended packet from Teltonika wiki",
+			Imei:              "356307042441013",
+			ExpectedHandshake: "01",
+			Request:           "000000000000004A8E010000016B412CEE000100000000000000000000000000000000010005000100010100010011001D00010010015E2C880002000B000000003544C87A000E000000001DD7E06A00000100002994",
+			ExpectedResponse:  "00000001",
+		},
+		{
+			Name:              "Codec16 packet from Teltonika wiki",
+			Imei:              "356307042441013",
+			ExpectedHandshake: "01",
+			Request:           "000000000000005F10020000016BDBC7833000000000000000000000000000000000000B05040200010000030002000B00270042563A00000000016BDBC7871800000000000000000000000000000000000B05040200010000030002000B00260042563A00000200005FB3",
+			ExpectedResponse:  "00000002",
+		},
+		{
+			Name:              "Wrong CRC",
+			Imei:              "356307042441013",
+			ExpectedHandshake: "01",
+			Request:           "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CE",
+			ExpectedResponse:  "",
+		},
+		{
+			Name:              "IMEI not on the allow list",
+			Imei:              "123456789012345",
+			ExpectedHandshake: "00",
+		},
+	}
+
+	ctx := newTestContext()
+
+	var wg sync.WaitGroup
+	udsServer := &uds.MultiServerMock{}
+	metrics := metrics2.NewMetrics(ctx, &wg, metricsFilename)
+	callbackFunc := func(ctx context.Context, message TeltonikaMessage) error {
+		log2 := config.GetLogger(ctx)
+		log2.Infof("New decoded packet: %+v", message)
+		return nil
+	}
+	startServer(ctx, 9002, udsServer, metrics, callbackFunc)
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			conn, err := net.Dial("tcp", "localhost:9002")
+			if err != nil {
+				test.Fatalf("Dial failed. %v", err)
+			}
+
+			// Ensure network connection will be always closed
+			defer func() {
+				err := conn.Close()
+				if err != nil {
+					test.Errorf("Failed to close network connection. %v", err)
+				}
+			}()
+
+			err = conn.SetDeadline(time.Now().Add(time.Second * 2))
+			if err != nil {
+				test.Fatalf("Failed to set deadline. %v", err)
+			}
+
+			// IMEI handshake
+			handshake := []byte{0x00, byte(len(testCase.Imei))}
+			handshake = append(handshake, testCase.Imei...)
+			_, err = conn.Write(handshake)
+			if err != nil {
+				test.Fatalf("Write to server failed. %v", err)
+			}
+
+			buffer := make([]byte, 1)
+			_, err = io.ReadFull(conn, buffer)
+			if err != nil {
+				test.Fatalf("Failed to read handshake response. %v", err)
+			}
+			if hex.EncodeToString(buffer) != testCase.ExpectedHandshake {
+				test.Fatalf("Wrong handshake response! Expected: %v Actual: %v", testCase.ExpectedHandshake, hex.EncodeToString(buffer))
+			}
+
+			if len(testCase.Request) == 0 {
+				return
+			}
+
+			data, err := hex.DecodeString(testCase.Request)
+			if err != nil {
+				test.Fatalf("Incorrect request data. %v", err)
+			}
+
+			_, err = conn.Write(data)
+			if err != nil {
+				test.Fatalf("Write to server failed. %v", err)
+			}
+
+			// Do we expect response?
+			if len(testCase.ExpectedResponse) > 0 {
+				buffer := make([]byte, len(testCase.ExpectedResponse)/2)
+				_, err = io.ReadFull(conn, buffer)
+				if err != nil {
+					test.Fatalf("Failed to read response. %v", err)
+				}
+				if hex.EncodeToString(buffer) != testCase.ExpectedResponse {
+					test.Errorf("Wrong reponse! Expected: %v Actual: %v", testCase.ExpectedResponse, hex.EncodeToString(buffer))
+				}
+			} else {
+				err = conn.SetReadDeadline(time.Now().Add(time.Millisecond * 500))
+				if err != nil {
+					test.Fatalf("Failed to set deadline. %v", err)
+				}
+
+				size, _ := conn.Read(buffer)
+				if size > 0 {
+					test.Errorf("No response was expected but got %d bytes", size)
+				}
+			}
+		})
+	}
+}
+
+func TestTcpCommand(t *testing.T) {
+	const imei = "356307042441013"
+
+	ctx := newTestContext()
+	udsServer := &uds.MultiServerMock{}
+	callbackFunc := func(ctx context.Context, message TeltonikaMessage) error { return nil }
+	server := startServer(ctx, 9003, udsServer, nil, callbackFunc)
+	sent := make(chan string, 1)
+	server.SetCommandSentCallback(func(ctx context.Context, imei string, command string) {
+		sent <- command
+	})
+
+	conn, err := net.Dial("tcp", "localhost:9003")
+	if err != nil {
+		t.Fatalf("Dial failed. %v", err)
+	}
+	defer func() {
+		err := conn.Close()
+		if err != nil {
+			t.Errorf("Failed to close network connection. %v", err)
+		}
+	}()
+
+	err = conn.SetDeadline(time.Now().Add(time.Second * 3))
+	if err != nil {
+		t.Fatalf("Failed to set deadline. %v", err)
+	}
+
+	tcpHandshake(t, conn, imei)
+
+	// Command typed by the user must be written onto the connection immediately
+	requests, _, err := server.GetCommandRequestChannel(imei)
+	if err != nil {
+		t.Fatalf("Failed to get command request channel. %v", err)
+	}
+	select {
+	case requests <- "getinfo":
+	case <-time.After(time.Second):
+		t.Fatalf("Command was not taken by the TCP session")
+	}
+
+	expectedRequest := "000000000000000f0c010500000007676574696e666f0100004312"
+	buffer := make([]byte, len(expectedRequest)/2)
+	_, err = io.ReadFull(conn, buffer)
+	if err != nil {
+		t.Fatalf("Failed to read command. %v", err)
+	}
+	if hex.EncodeToString(buffer) != expectedRequest {
+		t.Errorf("Wrong command! Expected: %v Actual: %v", expectedRequest, hex.EncodeToString(buffer))
+	}
+	select {
+	case command := <-sent:
+		if command != "getinfo" {
+			t.Errorf("Wrong sent command! Expected: %v Actual: %v", "getinfo", command)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Sent command was not reported")
+	}
+
+	// Response of the device must be forwarded to the user
+	response, err := hex.DecodeString("00000000000000370C01060000002F4449313A31204449323A30204449333A302041494E313A302041494E323A313639323420444F313A3020444F323A3101000066E3")
+	if err != nil {
+		t.Fatalf("Incorrect response data. %v", err)
+	}
+	_, err = conn.Write(response)
+	if err != nil {
+		t.Fatalf("Write to server failed. %v", err)
+	}
+
+	responses, _, err := server.GetCommandResponseChannel(imei)
+	if err != nil {
+		t.Fatalf("Failed to get command response channel. %v", err)
+	}
+	select {
+	case actual := <-responses:
+		expected := "DI1:1 DI2:0 DI3:0 AIN1:0 AIN2:16924 DO1:0 DO2:1"
+		if actual != expected {
+			t.Errorf("Wrong command response! Expected: %v Actual: %v", expected, actual)
+		}
+	case <-time.After(time.Second * 2):
+		t.Errorf("No command response arrived")
+	}
+}
+
+func TestTcpCommandStaleSession(t *testing.T) {
+	const imei = "356307042441013"
+
+	ctx := newTestContext()
+	udsServer := &uds.MultiServerMock{}
+	callbackFunc := func(ctx context.Context, message TeltonikaMessage) error { return nil }
+	server := startServer(ctx, 9009, udsServer, nil, callbackFunc)
+
+	// Device reconnects while its former connection is still open
+	var conns []net.Conn
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", "localhost:9009")
+		if err != nil {
+			t.Fatalf("Dial failed. %v", err)
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		err = conn.SetDeadline(time.Now().Add(time.Second * 3))
+		if err != nil {
+			t.Fatalf("Failed to set deadline. %v", err)
+		}
+
+		tcpHandshake(t, conn, imei)
+		conns = append(conns, conn)
+	}
+	stale, current := conns[0], conns[1]
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		device, ok := server.getOnlineDevice(imei)
+		if ok && device.Session != nil && device.Session.conn.RemoteAddr().String() == current.LocalAddr().String() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("New session is not stored")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Commands taken by any of the sessions must be written onto the current connection
+	requests, _, err := server.GetCommandRequestChannel(imei)
+	if err != nil {
+		t.Fatalf("Failed to get command request channel. %v", err)
+	}
+	expectedRequest := "000000000000000f0c010500000007676574696e666f0100004312"
+	for i := 0; i < 4; i++ {
+		select {
+		case requests <- "getinfo":
+		case <-time.After(time.Second):
+			t.Fatalf("Command was not taken by the TCP sessions")
+		}
+
+		buffer := make([]byte, len(expectedRequest)/2)
+		_, err = io.ReadFull(current, buffer)
+		if err != nil {
+			t.Fatalf("Failed to read command. %v", err)
+		}
+		if hex.EncodeToString(buffer) != expectedRequest {
+			t.Errorf("Wrong command! Expected: %v Actual: %v", expectedRequest, hex.EncodeToString(buffer))
+		}
+	}
+
+	err = stale.SetReadDeadline(time.Now().Add(time.Millisecond * 200))
+	if err != nil {
+		t.Fatalf("Failed to set deadline. %v", err)
+	}
+	size, _ := stale.Read(make([]byte, 1))
+	if size > 0 {
+		t.Errorf("No command was expected on the stale connection")
+	}
+}
+
+func TestTcpCommandCodec14(t *testing.T) {
+	const imei = "356307042441013"
+
+	ctx := newTestContext()
+	udsServer := &uds.MultiServerMock{}
+	callbackFunc := func(ctx context.Context, message TeltonikaMessage) error { return nil }
+	server := startServer(ctx, 9004, udsServer, nil, callbackFunc)
+	server.SetCommandCodecFunc(func(string) byte {
+		return codec.Codec14
+	})
+
+	conn, err := net.Dial("tcp", "localhost:9004")
+	if err != nil {
+		t.Fatalf("Dial failed. %v", err)
+	}
+	defer func() {
+		err := conn.Close()
+		if err != nil {
+			t.Errorf("Failed to close network connection. %v", err)
+		}
+	}()
+
+	err = conn.SetDeadline(time.Now().Add(time.Second * 3))
+	if err != nil {
+		t.Fatalf("Failed to set deadline. %v", err)
+	}
+
+	tcpHandshake(t, conn, imei)
+
+	requests, _, err := server.GetCommandRequestChannel(imei)
+	if err != nil {
+		t.Fatalf("Failed to get command request channel. %v", err)
+	}
+	select {
+	case requests <- "getver":
+	case <-time.After(time.Second):
+		t.Fatalf("Command was not taken by the TCP session")
+	}
+
+	// Header of the packet tells its length
+	header := make([]byte, 8)
+	_, err = io.ReadFull(conn, header)
+	if err != nil {
+		t.Fatalf("Failed to read command. %v", err)
+	}
+	rest := make([]byte, int(header[7])+4)
+	_, err = io.ReadFull(conn, rest)
+	if err != nil {
+		t.Fatalf("Failed to read command. %v", err)
+	}
+
+	command, err := codec.DecodeCommand(append(header, rest...))
+	if err != nil {
+		t.Fatalf("Failed to decode command. %v", err)
+	}
+	if command.CodecID != codec.Codec14 || command.IMEI != imei || string(command.Payload) != "getver" {
+		t.Errorf("Wrong command: %+v", command)
+	}
+
+	// Device rejects the command
+	nack, err := codec.EncodeCommand(codec.Command{
+		CodecID: codec.Codec14,
+		Type:    codec.CommandTypeNack,
+		IMEI:    imei,
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode nACK. %v", err)
+	}
+	_, err = conn.Write(nack)
+	if err != nil {
+		t.Fatalf("Write to server failed. %v", err)
+	}
+
+	responses, _, err := server.GetCommandResponseChannel(imei)
+	if err != nil {
+		t.Fatalf("Failed to get command response channel. %v", err)
+	}
+	select {
+	case actual := <-responses:
+		if !strings.Contains(actual, "rejected") {
+			t.Errorf("User must be informed about the rejected command. Actual: %v", actual)
+		}
+	case <-time.After(time.Second * 2):
+		t.Errorf("No command response arrived")
+	}
+}
+
+func TestGeneratedPackets(t *testing.T) {
+	records := []codec.AvlData{
+		{
+			UtimeMs:  1700000000000,
+			Utime:    1700000000,
+			Priority: 1,
+			Lat:      474979870,
+			Lng:      190402360,
+			Altitude: 110,
+			Angle:    90,
+			VisSat:   9,
+			Speed:    42,
+			EventID:  0xEF,
+			Elements: []codec.Element{
+				newElement(t, 0xEF, 1, 1),     // ignition
+				newElement(t, 0x42, 2, 12874), // external voltage
+			},
+		},
+	}
+
+	ctx := newTestContext()
+
+	var wg sync.WaitGroup
+	udsServer := &uds.MultiServerMock{}
+	metrics := metrics2.NewMetrics(ctx, &wg, metricsFilename)
+	messages := make(chan TeltonikaMessage, 10)
+	callbackFunc := func(ctx context.Context, message TeltonikaMessage) error {
+		messages <- message
+		return nil
+	}
+	startServer(ctx, 9005, udsServer, metrics, callbackFunc)
+
+	checkMessage := func(test *testing.T, codecID byte) {
+		select {
+		case message := <-messages:
+			if message.Decoded.CodecID != codecID {
+				test.Errorf("Wrong codec ID! Expected: %x Actual: %x", codecID, message.Decoded.CodecID)
+			}
+			if len(message.Decoded.Data) != 1 || message.Decoded.Data[0].Speed != records[0].Speed || len(message.Decoded.Data[0].Elements) != 2 {
+				test.Errorf("Wrong records! Expected: %+v Actual: %+v", records, message.Decoded.Data)
+			}
+		case <-time.After(time.Second * 2):
+			test.Errorf("Packet has not been processed")
+		}
+	}
+
+	// Every packet differs, otherwise they would be logged as resent packets
+	for i, codecID := range []byte{codec.Codec8, codec.Codec8Extended, codec.Codec16} {
+		records[0].UtimeMs += 1000
+
+		t.Run(fmt.Sprintf("UDP codec 0x%02X", codecID), func(test *testing.T) {
+			packet, err := codec.Encode("356307042441013", byte(i), codecID, records)
+			if err != nil {
+				test.Fatalf("Failed to encode packet. %v", err)
+			}
+
+			conn, err := net.Dial("udp", "localhost:9005")
+			if err != nil {
+				test.Fatalf("Dial failed. %v", err)
+			}
+			defer func() {
+				err := conn.Close()
+				if err != nil {
+					test.Errorf("Failed to close network connection. %v", err)
+				}
+			}()
+
+			err = conn.SetDeadline(time.Now().Add(time.Second * 2))
+			if err != nil {
+				test.Fatalf("Failed to set deadline. %v", err)
+			}
+
+			_, err = conn.Write(packet)
+			if err != nil {
+				test.Fatalf("Write to server failed. %v", err)
+			}
+
+			buffer := make([]byte, 7)
+			_, err = conn.Read(buffer)
+			if err != nil {
+				test.Fatalf("Failed to read response. %v", err)
+			}
+			expected := []byte{0x00, 0x05, 0xCA, 0xFE, 0x01, byte(i), 0x01}
+			if hex.EncodeToString(buffer) != hex.EncodeToString(expected) {
+				test.Errorf("Wrong reponse! Expected: %x Actual: %x", expected, buffer)
+			}
+
+			checkMessage(test, codecID)
+		})
+
+		records[0].UtimeMs += 1000
+
+		t.Run(fmt.Sprintf("TCP codec 0x%02X", codecID), func(test *testing.T) {
+			packet, err := codec.EncodeTcp(codecID, records)
+			if err != nil {
+				test.Fatalf("Failed to encode packet. %v", err)
+			}
+
+			conn, err := net.Dial("tcp", "localhost:9005")
+			if err != nil {
+				test.Fatalf("Dial failed. %v", err)
+			}
+			defer func() {
+				err := conn.Close()
+				if err != nil {
+					test.Errorf("Failed to close network connection. %v", err)
+				}
+			}()
+
+			err = conn.SetDeadline(time.Now().Add(time.Second * 2))
+			if err != nil {
+				test.Fatalf("Failed to set deadline. %v", err)
+			}
+
+			tcpHandshake(test, conn, "356307042441013")
+
+			_, err = conn.Write(packet)
+			if err != nil {
+				test.Fatalf("Write to server failed. %v", err)
+			}
+
+			buffer := make([]byte, 4)
+			_, err = io.ReadFull(conn, buffer)
+			if err != nil {
+				test.Fatalf("Failed to read response. %v", err)
+			}
+			if hex.EncodeToString(buffer) != "00000001" {
+				test.Errorf("Wrong reponse! Expected: 00000001 Actual: %x", buffer)
+			}
+
+			checkMessage(test, codecID)
+		})
+	}
+}
+
+func TestCapture(t *testing.T) {
+	const imei = "356307042441013"
+
+	ctx := newTestContext()
+	filename := filepath.Join(t.TempDir(), "capture.jsonl")
+
+	writer, err := capture.NewWriter(capture.Config{
+		Filename: filename,
+		IMEIs:    []string{imei},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create capture. %v", err)
+	}
+
+	var wg sync.WaitGroup
+	server := NewServer(ctx, &wg, "127.0.0.1", 9006, 9006, allowedIMEIs, &uds.MultiServerMock{}, nil, func(ctx context.Context, message TeltonikaMessage) error { return nil })
+	server.SetCapture(writer)
+	err = server.Start()
+	if err != nil {
+		t.Fatalf("Failed to start Teltonika server. %v", err)
+	}
+
+	records := []codec.AvlData{{UtimeMs: 1700000000000, Elements: []codec.Element{newElement(t, 0xEF, 1, 1)}}}
+
+	// Over UDP
+	udpPacket, err := codec.Encode(imei, 0x01, codec.Codec8, records)
+	if err != nil {
+		t.Fatalf("Failed to encode packet. %v", err)
+	}
+	udpConn, err := net.Dial("udp", "localhost:9006")
+	if err != nil {
+		t.Fatalf("Dial failed. %v", err)
+	}
+	defer func() {
+		err := udpConn.Close()
+		if err != nil {
+			t.Errorf("Failed to close network connection. %v", err)
+		}
+	}()
+	err = udpConn.SetDeadline(time.Now().Add(time.Second * 2))
+	if err != nil {
+		t.Fatalf("Failed to set deadline. %v", err)
+	}
+	_, err = udpConn.Write(udpPacket)
+	if err != nil {
+		t.Fatalf("Write to server failed. %v", err)
+	}
+	_, err = udpConn.Read(make([]byte, 7))
+	if err != nil {
+		t.Fatalf("Failed to read response. %v", err)
+	}
+
+	// Over TCP
+	records[0].UtimeMs += 1000
+	tcpPacket, err := codec.EncodeTcp(codec.Codec8, records)
+	if err != nil {
+		t.Fatalf("Failed to encode packet. %v", err)
+	}
+	tcpConn, err := net.Dial("tcp", "localhost:9006")
+	if err != nil {
+		t.Fatalf("Dial failed. %v", err)
+	}
+	defer func() {
+		err := tcpConn.Close()
+		if err != nil {
+			t.Errorf("Failed to close network connection. %v", err)
+		}
+	}()
+	err = tcpConn.SetDeadline(time.Now().Add(time.Second * 2))
+	if err != nil {
+		t.Fatalf("Failed to set deadline. %v", err)
+	}
+	tcpHandshake(t, tcpConn, imei)
+	_, err = tcpConn.Write(tcpPacket)
+	if err != nil {
+		t.Fatalf("Write to server failed. %v", err)
+	}
+	_, err = io.ReadFull(tcpConn, make([]byte, 4))
+	if err != nil {
+		t.Fatalf("Failed to read response. %v", err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("Failed to close capture. %v", err)
+	}
+
+	file, err := os.Open(filename) // #nosec G304
+	if err != nil {
+		t.Fatalf("Failed to open capture. %v", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	expected := []struct {
+		Direction capture.Direction
+		Transport string
+		Data      []byte
+	}{
+		{capture.Received, capture.TransportUdp, udpPacket},
+		{capture.Sent, capture.TransportUdp, []byte{0x00, 0x05, 0xCA, 0xFE, 0x01, 0x01, 0x01}},
+		{capture.Received, capture.TransportTcp, append([]byte{0x00, 0x0F}, imei...)},
+		{capture.Sent, capture.TransportTcp, []byte{0x01}},
+		{capture.Received, capture.TransportTcp, tcpPacket},
+		{capture.Sent, capture.TransportTcp, []byte{0x00, 0x00, 0x00, 0x01}},
+	}
+
+	reader := capture.NewReader(file)
+	for i, e := range expected {
+		packet, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Failed to read %d. packet. %v", i+1, err)
+		}
+		if packet.Direction != e.Direction || packet.Transport != e.Transport || packet.IMEI != imei || hex.EncodeToString(packet.Data) != hex.EncodeToString(e.Data) {
+			t.Errorf("Wrong %d. packet! Expected: %+v Actual: %+v", i+1, e, packet)
+		}
+	}
+}
+
+func TestReplayPacket(t *testing.T) {
+	const imei = "356307042441013"
+
+	records := []codec.AvlData{{UtimeMs: 1700000000000, Elements: []codec.Element{newElement(t, 0xEF, 1, 1)}}}
+	udpPacket, err := codec.Encode(imei, 0x01, codec.Codec8, records)
+	if err != nil {
+		t.Fatalf("Failed to encode packet. %v", err)
+	}
+	tcpPacket, err := codec.EncodeTcp(codec.Codec16, records)
+	if err != nil {
+		t.Fatalf("Failed to encode packet. %v", err)
+	}
+	commandResponse, err := hex.DecodeString("00000000000000370C01060000002F4449313A31204449323A30204449333A302041494E313A302041494E323A313639323420444F313A3020444F323A3101000066E3")
+	if err != nil {
+		t.Fatalf("Incorrect response data. %v", err)
+	}
+	failingPacket, err := codec.Encode(imei, 0x02, codec.Codec8, []codec.AvlData{{UtimeMs: 1700000001000}})
+	if err != nil {
+		t.Fatalf("Failed to encode packet. %v", err)
+	}
+
+	testCases := []struct {
+		Name             string
+		Packet           capture.Packet
+		ExpectedReplayed bool
+		ExpectedError    bool
+	}{
+		{"UDP AVL packet", capture.Packet{Direction: capture.Received, Transport: capture.TransportUdp, Data: udpPacket}, true, false},
+		{"TCP AVL packet", capture.Packet{Direction: capture.Received, Transport: capture.TransportTcp, IMEI: imei, Data: tcpPacket}, true, false},
+		{"Resent UDP AVL packet", capture.Packet{Direction: capture.Received, Transport: capture.TransportUdp, Data: udpPacket}, false, false},
+		{"Sent packet", capture.Packet{Direction: capture.Sent, Transport: capture.TransportUdp, Data: udpPacket}, false, false},
+		{"Heartbeat", capture.Packet{Direction: capture.Received, Transport: capture.TransportUdp, Data: []byte{0xFF}}, false, false},
+		{"IMEI handshake", capture.Packet{Direction: capture.Received, Transport: capture.TransportTcp, IMEI: imei, Data: append([]byte{0x00, 0x0F}, imei...)}, false, false},
+		{"Command response", capture.Packet{Direction: capture.Received, Transport: capture.TransportTcp, IMEI: imei, Data: commandResponse}, false, false},
+		{"Malformed packet", capture.Packet{Direction: capture.Received, Transport: capture.TransportUdp, Data: udpPacket[:20]}, false, true},
+		{"IMEI not on the allow list", capture.Packet{Direction: capture.Received, Transport: capture.TransportTcp, IMEI: "123456789012345", Data: tcpPacket}, false, true},
+		{"Failed by the sinks", capture.Packet{Direction: capture.Received, Transport: capture.TransportUdp, Data: failingPacket}, false, true},
+	}
+
+	ctx := newTestContext()
+	messages := make(chan TeltonikaMessage, len(testCases))
+	var wg sync.WaitGroup
+	server := NewServer(ctx, &wg, "", 0, 0, allowedIMEIs, nil, nil, func(ctx context.Context, message TeltonikaMessage) error {
+		if message.Decoded.Data[0].UtimeMs == 1700000001000 {
+			return fmt.Errorf("sink is not available")
+		}
+		messages <- message
+		return nil
+	})
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			replayed, err := server.ReplayPacket(testCase.Packet)
+			if replayed != testCase.ExpectedReplayed || (err != nil) != testCase.ExpectedError {
+				test.Errorf("Wrong result! Expected: %v, error: %v Actual: %v, %v", testCase.ExpectedReplayed, testCase.ExpectedError, replayed, err)
+			}
+		})
+	}
+
+	// Packets are processed by the time ReplayPacket returns
+	if len(messages) != 2 {
+		t.Errorf("Wrong number of processed packets! Expected: 2 Actual: %d", len(messages))
+	}
+}
+
+func TestAckAfterPersist(t *testing.T) {
+	udpRequest, err := hex.DecodeString("0067cafe016b000f3335303432343036333831373336338e01000001839ecd8a70000b5629e81c5451d0000000000000000000000b000500500000150400c800004502001d00000500422e970018000000cd13f000ce005d00430fd3000100f10000547e0000000001")
+	if err != nil {
+		t.Fatalf("Incorrect request data. %v", err)
+	}
+	tcpRequest, err := hex.DecodeString("000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF")
+	if err != nil {
+		t.Fatalf("Incorrect request data. %v", err)
+	}
+
+	ctx := newTestContext()
+
+	// Storage fails for every second packet
+	var lock sync.Mutex
+	calls := 0
+	callbackFunc := func(ctx context.Context, message TeltonikaMessage) error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		calls++
+		if calls%2 == 1 {
+			return fmt.Errorf("storage is down")
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	server := NewServer(ctx, &wg, "127.0.0.1", 9007, 9007, allowedIMEIs, &uds.MultiServerMock{}, nil, callbackFunc)
+	server.SetAckPolicy(config.AckPolicyAfterPersist)
+	err = server.Start()
+	if err != nil {
+		t.Fatalf("Failed to start Teltonika server. %v", err)
+	}
+	defer func() {
+		err := server.Stop()
+		if err != nil {
+			t.Errorf("Failed to stop Teltonika server. %v", err)
+		}
+	}()
+
+	// exchange sends the request and returns the response or nil if nothing arrived in time
+	exchange := func(test *testing.T, conn net.Conn, request []byte, size int) []byte {
+		_, err := conn.Write(request)
+		if err != nil {
+			test.Fatalf("Write to server failed. %v", err)
+		}
+
+		err = conn.SetReadDeadline(time.Now().Add(time.Millisecond * 500))
+		if err != nil {
+			test.Fatalf("Failed to set deadline. %v", err)
+		}
+
+		buffer := make([]byte, size)
+		_, err = io.ReadFull(conn, buffer)
+		if err != nil {
+			return nil
+		}
+		return buffer
+	}
+
+	checkRetransmission := func(test *testing.T, conn net.Conn, request []byte, expectedResponse string) {
+		response := exchange(test, conn, request, len(expectedResponse)/2)
+		if response != nil {
+			test.Errorf("Packet must not be acknowledged if storing failed. Actual: %x", response)
+		}
+
+		response = exchange(test, conn, request, len(expectedResponse)/2)
+		if hex.EncodeToString(response) != expectedResponse {
+			test.Errorf("Wrong reponse! Expected: %v Actual: %x", expectedResponse, response)
+		}
+	}
+
+	t.Run("UDP", func(test *testing.T) {
+		conn, err := net.Dial("udp", "localhost:9007")
+		if err != nil {
+			test.Fatalf("Dial failed. %v", err)
+		}
+		defer func() {
+			err := conn.Close()
+			if err != nil {
+				test.Errorf("Failed to close network connection. %v", err)
+			}
+		}()
+
+		checkRetransmission(test, conn, udpRequest, "0005cafe016b01")
+	})
+
+	t.Run("TCP", func(test *testing.T) {
+		conn, err := net.Dial("tcp", "localhost:9007")
+		if err != nil {
+			test.Fatalf("Dial failed. %v", err)
+		}
+		defer func() {
+			err := conn.Close()
+			if err != nil {
+				test.Errorf("Failed to close network connection. %v", err)
+			}
+		}()
+
+		tcpHandshake(test, conn, "356307042441013")
+		checkRetransmission(test, conn, tcpRequest, "00000001")
+	})
+}
+
+func TestDeviceEvents(t *testing.T) {
+	ctx := newTestContext()
+
+	events := make(chan DeviceEvent, 10)
+	var wg sync.WaitGroup
+	server := NewServer(ctx, &wg, "127.0.0.1", 9008, 9008, allowedIMEIs, &uds.MultiServerMock{}, nil, func(ctx context.Context, message TeltonikaMessage) error { return nil })
+	server.SetDeviceEventCallback(func(ctx context.Context, event DeviceEvent) {
+		events <- event
+	})
+	err := server.Start()
+	if err != nil {
+		t.Fatalf("Failed to start Teltonika server. %v", err)
+	}
+	defer func() {
+		err := server.Stop()
+		if err != nil {
+			t.Errorf("Failed to stop Teltonika server. %v", err)
+		}
+	}()
+
+	expectEvent := func(test *testing.T, expectedType string, expectedTransport string) {
+		select {
+		case event := <-events:
+			if event.IMEI != "356307042441013" || event.Type != expectedType || event.Transport != expectedTransport {
+				test.Errorf("Wrong event! Expected: %v over %v Actual: %+v", expectedType, expectedTransport, event)
+			}
+		case <-time.After(time.Second):
+			test.Fatalf("No %s event over %s", expectedType, expectedTransport)
+		}
+	}
+
+	conn, err := net.Dial("tcp", "localhost:9008")
+	if err != nil {
+		t.Fatalf("Dial failed. %v", err)
+	}
+	tcpHandshake(t, conn, "356307042441013")
+	expectEvent(t, DeviceConnected, TransportTCP)
+
+	err = conn.Close()
+	if err != nil {
+		t.Errorf("Failed to close network connection. %v", err)
+	}
+	expectEvent(t, DeviceDisconnected, TransportTCP)
+
+	// UDP devices go offline after they were silent for the timeout
+	remote := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+	err = server.markDeviceOnline(remote, nil, "356307042441013")
+	if err != nil {
+		t.Fatalf("Failed to mark device online. %v", err)
+	}
+	expectEvent(t, DeviceConnected, TransportUDP)
+
+	server.cleanupDevicesOnline()
+	if len(events) != 0 {
+		t.Errorf("Device must not go offline before the timeout")
+	}
+
+	item, _ := server.getOnlineDeviceEndpoint(remote)
+	item.Timestamp = time.Now().Add(-2 * server.devicesByImeitimeout)
+	server.cleanupDevicesOnline()
+	expectEvent(t, DeviceDisconnected, TransportUDP)
+}
+
+func TestOnlineDevicesByImei(t *testing.T) {
+	events := make(chan DeviceEvent, 10)
+	var wg sync.WaitGroup
+	server := NewServer(newTestContext(), &wg, "", 0, 0, allowedIMEIs, &uds.MultiServerMock{}, nil, nil)
+	server.SetDeviceEventCallback(func(ctx context.Context, event DeviceEvent) {
+		events <- event
+	})
+
+	// The NAT mapping of the device changes between two packets
+	first := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+	second := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5678}
+	for _, remote := range []*net.UDPAddr{first, second} {
+		err := server.markDeviceOnline(remote, nil, "356307042441013")
+		if err != nil {
+			t.Fatalf("Failed to mark device online. %v", err)
+		}
+	}
+
+	if len(events) != 1 {
+		t.Errorf("Wrong number of events! Expected: %v Actual: %v", 1, len(events))
+	}
+	<-events
+
+	device, ok := server.getOnlineDevice("356307042441013")
+	if !ok || device.Remote != second {
+		t.Errorf("Wrong endpoint of the device! Expected: %v Actual: %+v", second, device)
+	}
+	if _, ok := server.getOnlineDeviceEndpoint(first); ok {
+		t.Errorf("Device must not be found by its former address")
+	}
+	if _, ok := server.getOnlineDeviceEndpoint(second); !ok {
+		t.Errorf("Device must be found by its current address")
+	}
+
+	device.Timestamp = time.Now().Add(-2 * server.devicesByImeitimeout)
+	server.cleanupDevicesOnline()
+
+	if len(events) != 1 {
+		t.Fatalf("Wrong number of events! Expected: %v Actual: %v", 1, len(events))
+	}
+	event := <-events
+	if event.Type != DeviceDisconnected || event.SourceAddress != second.String() {
+		t.Errorf("Wrong event! Expected: %v from %v Actual: %+v", DeviceDisconnected, second, event)
+	}
+	if _, ok := server.getOnlineDeviceEndpoint(second); ok {
+		t.Errorf("Device must not be found after it went offline")
+	}
+}
+
+func TestSetAllowedIMEIs(t *testing.T) {
+	var wg sync.WaitGroup
+	server := NewServer(newTestContext(), &wg, "", 0, 0, allowedIMEIs, nil, nil, nil)
+
+	server.SetAllowedIMEIs([]string{"356307042441013", "123456789012345"})
+
+	testCases := []struct {
+		IMEI     string
+		Expected bool
+	}{
+		{IMEI: "352094089397464", Expected: false},
+		{IMEI: "356307042441013", Expected: true},
+		{IMEI: "123456789012345", Expected: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.IMEI, func(test *testing.T) {
+			allowed := server.isAllowedIMEI(testCase.IMEI)
+			if allowed != testCase.Expected {
+				test.Errorf("Wrong result! Expected: %v Actual: %v", testCase.Expected, allowed)
+			}
+		})
+	}
+
+	_, _, err := server.GetCommandRequestChannel("352094089397464")
+	if err == nil {
+		t.Errorf("Command channel must not be created for removed device")
+	}
+}
+
+// newElement creates an IO element and fails the test if it is invalid
+func newElement(t *testing.T, ioID uint16, size int, value uint64) codec.Element {
+	t.Helper()
+
+	element, err := codec.NewElement(ioID, size, value)
+	if err != nil {
+		t.Fatalf("Failed to create IO element. %v", err)
+	}
+
+	return element
+}
+
+func TestResentPackage(t *testing.T) {
+	var wg sync.WaitGroup
+	server := NewServer(newTestContext(), &wg, "", 0, 0, allowedIMEIs, nil, nil, nil)
+
+	first := []byte{0x01}
+	second := []byte{0x02}
+	for i, expected := range []bool{false, false, true, true} {
+		packet := first
+		if i == 1 {
+			packet = second
+		}
+		if server.isResentPackage(&packet) != expected {
+			t.Errorf("Wrong result of packet %d! Expected: %v", i, expected)
+		}
+	}
+
+	// Packets processed more than an hour ago are forgotten
+	server.processedPackets["03"] = time.Now().Add(-2 * time.Hour)
+	third := []byte{0x04}
+	_ = server.isResentPackage(&third)
+	if _, ok := server.processedPackets["03"]; ok {
+		t.Errorf("Old packet must be removed")
+	}
+}
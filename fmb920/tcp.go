@@ -0,0 +1,354 @@
+package fmb920
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/halacs/haltonika/capture"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/config"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// https://wiki.teltonika-gps.com/view/Teltonika_Data_Sending_Protocols#TCP
+const (
+	tcpImeiAccepted  = 0x01
+	tcpImeiRejected  = 0x00
+	tcpMaxImeiLength = 16
+	tcpHeaderLength  = 8         // 4 bytes preamble + 4 bytes data field length
+	tcpCrcLength     = 4         // CRC-16 is sent on 4 bytes
+	tcpMaxDataLength = 64 * 1024 // much more than any device sends in one packet
+	tcpWriteTimeout  = 10 * time.Second
+)
+
+func (s *Server) startTcpListener() error {
+	log := config.GetLogger(s.ctx)
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(s.host, strconv.Itoa(s.tcpPort)))
+	if err != nil {
+		return fmt.Errorf("failed to open listening socket. %v", err)
+	}
+
+	log.Infof("Start Teltonika TCP server on %s", listener.Addr())
+
+	// Closing the listener makes Accept() return when the server is stopped
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			s.wg.Done()
+		}()
+
+		<-s.localCtx.Done()
+
+		err := listener.Close()
+		if err != nil {
+			log.Errorf("failed to close TCP listening socket. %v", err)
+		}
+	}()
+
+	// start goroutine accepting incoming connections
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			s.wg.Done()
+		}()
+
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+
+				log.Errorf("Failed to accept TCP connection. %v", err)
+				continue
+			}
+
+			s.wg.Add(1)
+			go func() {
+				defer func() {
+					s.wg.Done()
+				}()
+
+				s.handleTcpConnection(conn)
+			}()
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) handleTcpConnection(conn net.Conn) {
+	log := config.GetLogger(s.ctx).WithField("remote", conn.RemoteAddr().String())
+
+	log.Debugf("New TCP connection accepted")
+
+	// Close the connection either when it is not needed anymore or when the server stops
+	connCtx, cancel := context.WithCancel(s.localCtx)
+	defer cancel()
+	go func() {
+		<-connCtx.Done()
+
+		err := conn.Close()
+		if err != nil {
+			log.Tracef("Failed to close TCP connection. %v", err)
+		}
+	}()
+
+	err := conn.SetReadDeadline(time.Now().Add(s.devicesByImeitimeout))
+	if err != nil {
+		log.Errorf("Failed to set read deadline. %v", err)
+		return
+	}
+
+	imei, err := s.receiveTcpImei(conn)
+	if err != nil {
+		log.Errorf("Failed to read IMEI of the device. %v", err)
+		s.addMalformedPackages(1)
+		return
+	}
+
+	log = log.WithField("imei", imei)
+	session := &tcpSession{
+		imei: imei,
+		conn: conn,
+	}
+
+	if !s.isAllowedIMEI(imei) {
+		log.Warningf("Connection rejected. %s IMEI is not on the allow list.", imei)
+		s.addRejectedPackages(1)
+
+		err = s.sendTcpBytes(session, []byte{tcpImeiRejected})
+		if err != nil {
+			log.Errorf("Failed to reject IMEI. %v", err)
+		}
+		return
+	}
+
+	err = s.sendTcpBytes(session, []byte{tcpImeiAccepted})
+	if err != nil {
+		log.Errorf("Failed to accept IMEI. %v", err)
+		return
+	}
+
+	log.Infof("Device with %s IMEI connected over TCP", imei)
+
+	s.ensureUdsServer(imei)
+
+	err = s.markTcpDeviceOnline(session)
+	if err != nil {
+		log.Errorf("Failed to mark device online. %v", err)
+	}
+	defer s.markTcpDeviceOffline(session)
+
+	// Deliver commands immediately while the connection is alive
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			s.wg.Done()
+		}()
+
+		s.handleTcpCommands(connCtx, session)
+	}()
+
+	// Reading incoming packets
+	for {
+		err := conn.SetReadDeadline(time.Now().Add(s.devicesByImeitimeout))
+		if err != nil {
+			log.Errorf("Failed to set read deadline. %v", err)
+			return
+		}
+
+		packet, err := s.receiveTcpPacket(conn)
+		if err != nil {
+			if errors.Is(err, io.EOF) || connCtx.Err() != nil {
+				log.Infof("TCP connection closed")
+				return
+			}
+
+			log.Errorf("Failed to read from TCP connection. Closing it. %v", err)
+			return
+		}
+
+		log.Tracef("%d bytes long packet received: %s", len(packet), hex.EncodeToString(packet))
+
+		s.capturePacket(capture.Received, capture.TransportTcp, conn.RemoteAddr().String(), session.imei, packet)
+
+		// Codec ID is the first byte of the data field
+		if codec.IsCommandCodec(packet[tcpHeaderLength]) {
+			s.processTcpCommandResponse(session, packet)
+		} else {
+			s.processTcpAvlPacket(session, packet)
+		}
+	}
+}
+
+/*
+Waits for commands of the device and writes them onto its connection. Every session of the device waits on the same
+channel, so a command taken by a stale session, e.g. a half-open connection, is sent over the session the device uses now.
+*/
+func (s *Server) handleTcpCommands(ctx context.Context, session *tcpSession) {
+	log := config.GetLogger(s.ctx).WithField("imei", session.imei)
+
+	commandRequests, _, err := s.GetCommandRequestChannel(session.imei)
+	if err != nil {
+		log.Errorf("Failed to get command request channel. %v", err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case commandStr := <-commandRequests:
+			log.Debugf("Command to be sent: %s", commandStr)
+
+			device, ok := s.getOnlineDevice(session.imei)
+			if !ok {
+				err = fmt.Errorf("%s device is not online", session.imei)
+			} else {
+				err = s.deliverCommand(device, commandStr)
+			}
+			if err != nil {
+				log.Errorf("Failed to send command to device. %v", err)
+
+				// Send error back to the user
+				s.forwardCommandResponse(session.imei, err.Error())
+				continue
+			}
+
+			log.Infof("Command has been sent: %s", commandStr)
+		}
+	}
+}
+
+func (s *Server) processTcpCommandResponse(session *tcpSession, packet []byte) {
+	log := config.GetLogger(s.ctx).WithField("imei", session.imei)
+
+	commandResponse, err := codec.DecodeCommand(packet)
+	if err != nil {
+		log.Errorf("Malformed command response packet received. Ignoring packet. %v", err)
+		s.addMalformedPackages(1)
+		return
+	}
+
+	log.Debugf("Get command response from device with %s IMEI. Repsonse: %v", session.imei, string(commandResponse.Payload))
+
+	s.handleCommandResponse(session.imei, commandResponse)
+}
+
+func (s *Server) processTcpAvlPacket(session *tcpSession, packet []byte) {
+	log := config.GetLogger(s.ctx).WithField("imei", session.imei)
+
+	decodedAvl, err := codec.DecodeTcp(session.imei, packet)
+	if err != nil {
+		// Packet is not acknowledged so the device will send it again
+		log.Errorf("Malformed packet received. Ignoring packet. %v", err)
+		s.addMalformedPackages(1)
+		return
+	}
+
+	// Got an AVL Data Package!
+
+	s.addReceivedPackages(1)
+
+	err = s.markTcpDeviceOnline(session)
+	if err != nil {
+		log.Errorf("Failed to mark device online. %v", err)
+	}
+
+	s.processAvlPacket(decodedAvl, packet, session.conn.RemoteAddr().String(), func() error {
+		return s.sendTcpBytes(session, decodedAvl.Response)
+	})
+}
+
+// Reads the 2 bytes length prefixed IMEI the device sends right after the connection is opened
+func (s *Server) receiveTcpImei(conn net.Conn) (string, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		return "", err
+	}
+
+	length := binary.BigEndian.Uint16(header)
+	if length == 0 || length > tcpMaxImeiLength {
+		return "", fmt.Errorf("invalid IMEI length: %d", length)
+	}
+
+	imei := make([]byte, length)
+	_, err = io.ReadFull(conn, imei)
+	if err != nil {
+		return "", err
+	}
+
+	s.addReceivedBytes(uint64(len(header) + len(imei))) // #nosec G115
+
+	s.capturePacket(capture.Received, capture.TransportTcp, conn.RemoteAddr().String(), string(imei), append(header, imei...))
+
+	return string(imei), nil
+}
+
+// Reads a whole TCP packet: preamble, data field length, data field and CRC
+func (s *Server) receiveTcpPacket(conn net.Conn) ([]byte, error) {
+	log := config.GetLogger(s.ctx)
+
+	header := make([]byte, tcpHeaderLength)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		return nil, err
+	}
+
+	preamble := binary.BigEndian.Uint32(header[0:4])
+	if preamble != 0 {
+		return nil, fmt.Errorf("wrong preamble: 0x%x", preamble)
+	}
+
+	dataLength := binary.BigEndian.Uint32(header[4:8])
+	if dataLength == 0 || dataLength > tcpMaxDataLength {
+		return nil, fmt.Errorf("invalid data field length: %d", dataLength)
+	}
+
+	packet := make([]byte, tcpHeaderLength+int(dataLength)+tcpCrcLength)
+	copy(packet, header)
+	_, err = io.ReadFull(conn, packet[tcpHeaderLength:])
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("%d bytes received from %v", len(packet), conn.RemoteAddr())
+
+	s.addReceivedBytes(uint64(len(packet))) // #nosec G115
+
+	return packet, nil
+}
+
+func (s *Server) sendTcpBytes(session *tcpSession, data []byte) error {
+	log := config.GetLogger(s.ctx)
+
+	log.Tracef("Sending %d bytes to %v: %s", len(data), session.conn.RemoteAddr(), hex.EncodeToString(data))
+
+	session.writeLock.Lock()
+	defer session.writeLock.Unlock()
+
+	s.capturePacket(capture.Sent, capture.TransportTcp, session.conn.RemoteAddr().String(), session.imei, data)
+
+	err := session.conn.SetWriteDeadline(time.Now().Add(tcpWriteTimeout))
+	if err != nil {
+		return fmt.Errorf("failed to set write deadline. %v", err)
+	}
+
+	size, err := session.conn.Write(data)
+	if err != nil {
+		return err
+	}
+
+	s.addSentBytes(uint64(size)) // #nosec G115
+	s.addSentPackages(1)
+
+	return nil
+}
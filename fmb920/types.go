@@ -2,7 +2,8 @@ package fmb920
 
 import (
 	"context"
-	"github.com/filipkroca/teltonikaparser"
+	"github.com/halacs/haltonika/capture"
+	"github.com/halacs/haltonika/codec"
 	metrics2 "github.com/halacs/haltonika/metrics"
 	"github.com/halacs/haltonika/uds"
 	"net"
@@ -11,7 +12,7 @@ import (
 )
 
 type TeltonikaMessage struct {
-	Decoded       teltonikaparser.Decoded
+	Decoded       codec.Decoded
 	SourceAddress string
 }
 
@@ -19,36 +20,119 @@ type DevicesWithTimeout struct {
 	Imei      string
 	Remote    *net.UDPAddr
 	Listener  *net.UDPConn
+	Session   *tcpSession // nil if device is connected over UDP
 	Timestamp time.Time
 }
 
+// transport tells if the device is connected over UDP or TCP
+func (d *DevicesWithTimeout) transport() string {
+	if d.Session != nil {
+		return TransportTCP
+	}
+
+	return TransportUDP
+}
+
+// address is the remote address the device is connected from
+func (d *DevicesWithTimeout) address() string {
+	if d.Session != nil {
+		return d.Session.conn.RemoteAddr().String()
+	}
+
+	return d.Remote.String()
+}
+
+// tcpSession represents a TCP connection of a device after a successful IMEI handshake
+type tcpSession struct {
+	imei      string
+	conn      net.Conn
+	writeLock sync.Mutex
+}
+
 /*
 PacketArrivedCallback function used to report new decoded Teltonika packet.
-If it returns with false, network connection will be closed. This can be used, for example, to reject unknown devices.
+Returned error means the packet was not stored. With after-persist ACK policy, such packets are not acknowledged.
 */
-type PacketArrivedCallback func(ctx context.Context, message TeltonikaMessage)
+type PacketArrivedCallback func(ctx context.Context, message TeltonikaMessage) error
+
+// CommandResponseCallback function used to report the responses of the devices to commands
+type CommandResponseCallback func(ctx context.Context, imei string, response string)
+
+// CommandSentCallback function used to report the commands sent to the devices
+type CommandSentCallback func(ctx context.Context, imei string, command string)
+
+// Types of DeviceEvent
+const (
+	DeviceConnected    = "connected"
+	DeviceDisconnected = "disconnected"
+)
+
+// Transports of the devices
+const (
+	TransportUDP = "udp"
+	TransportTCP = "tcp"
+)
+
+// DeviceEvent reports that a device came online or went offline
+type DeviceEvent struct {
+	IMEI          string
+	Type          string // DeviceConnected or DeviceDisconnected
+	Transport     string // TransportUDP or TransportTCP
+	SourceAddress string
+	Time          time.Time
+}
+
+// CommandCodecFunc function used to get the codec of the commands sent to a device by its IMEI
+type CommandCodecFunc func(imei string) byte
+
+// DeviceEventCallback function used to report devices coming online and going offline
+type DeviceEventCallback func(ctx context.Context, event DeviceEvent)
 
 type Server struct {
-	wg           *sync.WaitGroup
-	host         string
-	port         int
-	allowedIMEIs []string
-	callback     PacketArrivedCallback
-	metrics      metrics2.TeltonikaMetricsInterface
-	ctx          context.Context
-	localCtx     context.Context
-	stopFunc     context.CancelFunc
-	udsServer    uds.MultiServerInterface
+	wg        *sync.WaitGroup
+	host      string
+	port      int
+	tcpPort   int
+	callback  PacketArrivedCallback
+	metrics   metrics2.TeltonikaMetricsInterface
+	ctx       context.Context
+	localCtx  context.Context
+	stopFunc  context.CancelFunc
+	udsServer uds.MultiServerInterface
+
+	// Devices allowed to send data, changed by the device registry
+	allowedIMEIs     []string
+	allowedIMEIsLock sync.RWMutex
 
 	// To check if we receive a packet more times
-	processedPackets map[string]time.Time
+	processedPackets     map[string]time.Time
+	processedPacketsLock sync.Mutex
 
-	// Online devices by IMEI
+	// Online devices by IMEI and the IMEI of the devices by their UDP address
 	devices                       sync.Map
+	udpEndpoints                  sync.Map
 	devicesByImeitimeout          time.Duration
 	requestCommandChannelsByIMEI  sync.Map
 	responseCommandChannelsByIMEI sync.Map
 
+	// Codec used to send commands by IMEI, Codec 12 if it is not set
+	commandCodec CommandCodecFunc
+
+	// When received packets are acknowledged
+	ackPolicy string
+
+	// Reports command responses besides the UDS server if set
+	commandResponseCallback CommandResponseCallback
+
+	// Reports commands sent to the devices if set
+	commandSentCallback CommandSentCallback
+
+	// Reports devices coming online and going offline if set
+	deviceEventCallback DeviceEventCallback
+
+	// Writes raw traffic into file if enabled
+	capture *capture.Writer
+
 	//commandResponses chan string
 	//commandRequests  chan string
 }
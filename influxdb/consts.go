@@ -1,5 +1,12 @@
 package influxdb
 
+import "time"
+
 const (
-	SourceTag = "source"
+	SourceTag        = "source"
+	DefaultPrecision = "ns"
+	DefaultBatchSize = 5000 // points
+
+	retryDelay        = time.Second // before the first retry of a failed write request
+	maxPendingBatches = 10          // pending points are limited to this many batches
 )
@@ -2,37 +2,81 @@ package influxdb
 
 import (
 	"context"
-	"encoding/binary"
-	"encoding/hex"
 	"fmt"
-	"github.com/filipkroca/teltonikaparser"
+	"github.com/halacs/haltonika/codec"
 	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/ioelement"
+	"github.com/halacs/haltonika/registry"
+	"github.com/halacs/haltonika/sink"
 	_ "github.com/influxdata/influxdb1-client" // this is important because of the bug in go mod
 	client "github.com/influxdata/influxdb1-client/v2"
+	"sync"
 	"time"
 )
 
 type Connection struct {
 	ctx                context.Context
+	version            int
 	url                string
 	username           string
 	password           string
+	token              string
+	org                string
+	bucket             string
 	insecureSkipVerify bool
 	measurement        string
 	database           string
+	precision          string
+	gzip               bool
+	batchSize          int // points written in one request
+	retries            int // retries of a write request failed with a transient error
+	retryDelay         time.Duration
 
-	client client.Client
+	writer pointWriter // InfluxDB 1.x client library or InfluxDB 2.x and 3.x writer
+
+	// Converts the IO elements by the model of the device if set
+	ioMapper *ioelement.Mapper
+	models   func(imei string) string
+
+	// Attributes of the devices written as tags if set
+	devices *registry.Registry
+
+	// Points waiting to be written by Flush
+	pending     []*client.Point
+	pendingLock sync.Mutex
+	lost        uint64 // points rejected by InfluxDB
+
+	sink.HealthState
 }
 
 func NewConnection(ctx context.Context, cfg *config.InfluxConfig) *Connection {
+	precision := cfg.Precision
+	if precision == "" {
+		precision = DefaultPrecision
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
 	return &Connection{
 		ctx:                ctx,
+		version:            cfg.Version,
 		url:                cfg.Url,
 		username:           cfg.Username,
 		password:           cfg.Password,
+		token:              cfg.Token,
+		org:                cfg.Org,
+		bucket:             cfg.Bucket,
 		insecureSkipVerify: false,
 		measurement:        cfg.Measurement,
 		database:           cfg.Database,
+		precision:          precision,
+		gzip:               cfg.Gzip,
+		batchSize:          batchSize,
+		retries:            cfg.Retries,
+		retryDelay:         retryDelay,
 	}
 }
 
@@ -45,77 +89,112 @@ func (c *Connection) SetInsecureSkipVerify(insecureSkipVerify bool) {
 }
 
 func (c *Connection) Connect() error {
-	var err error
-
-	c.client, err = client.NewHTTPClient(client.HTTPConfig{
-		Addr:               c.url,
-		Username:           c.username,
-		Password:           c.password,
-		InsecureSkipVerify: c.insecureSkipVerify,
-	})
+	switch c.precision {
+	case "ns", "us", "ms", "s":
+	default:
+		return fmt.Errorf("precision must be ns, us, ms or s, got %q", c.precision)
+	}
 
-	if err != nil {
-		return fmt.Errorf("error creating InfluxDB Client. %v", err)
+	switch c.version {
+	case 0, 1:
+		v1, err := newV1Writer(c.url, c.username, c.password, c.database, c.precision, c.insecureSkipVerify)
+		if err != nil {
+			return fmt.Errorf("error creating InfluxDB Client. %v", err)
+		}
+		c.writer = v1
+	case 2:
+		v2, err := newV2Writer(c.url, c.token, c.org, c.bucket, c.precision, c.gzip, c.insecureSkipVerify)
+		if err != nil {
+			return fmt.Errorf("error creating InfluxDB v2 writer. %v", err)
+		}
+		c.writer = v2
+	default:
+		return fmt.Errorf("unsupported InfluxDB API version: %d", c.version)
 	}
 
 	return nil
 }
 
+// Close writes the pending points and closes the connection
 func (c *Connection) Close() error {
-	err := c.client.Close()
+	if c.writer == nil {
+		return nil
+	}
+
+	err := c.Flush()
+	c.writer.close()
 	if err != nil {
-		return fmt.Errorf("failed to close influxdb connection. %v", err)
+		return fmt.Errorf("failed to write pending points into influxdb. %v", err)
 	}
+
 	return nil
 }
 
-func (c *Connection) renderTags(record teltonikaparser.Decoded) map[string]string {
-	return map[string]string{
+func (c *Connection) renderTags(record codec.Decoded) map[string]string {
+	tags := map[string]string{
 		"IMEI":    record.IMEI,
 		"CodecID": fmt.Sprintf("%X", record.CodecID), // convert byte to hex number string
 	}
+
+	device, ok := c.devices.Device(record.IMEI)
+	if ok {
+		for k, v := range device.Tags() {
+			// Labels can not override the tags of the record
+			if _, ok := tags[k]; !ok {
+				tags[k] = v
+			}
+		}
+	}
+
+	return tags
 }
 
-func (c *Connection) renderFields(avlData teltonikaparser.AvlData) map[string]interface{} {
-	log := config.GetLogger(c.ctx)
+// SetDeviceRegistry makes the name, plate, group, owner and labels of the registered devices written as tags
+func (c *Connection) SetDeviceRegistry(devices *registry.Registry) {
+	c.devices = devices
+}
 
-	fields := map[string]interface{}{
-		"latitude":          float64(avlData.Lat) / 10000000.0,
-		"longitude":         float64(avlData.Lng) / 10000000.0,
-		"altitude":          avlData.Altitude,
-		"visiblesatellites": avlData.VisSat,
-		"angle":             avlData.Angle,
-		"speed":             avlData.Speed,
-		"priority":          avlData.Priority,
-		"eventID":           avlData.EventID,
-		"serverTime":        time.Now().UTC().Unix(),
-		//"originalTime":      int64(avlData.Utime), //c.renderTimesamp(avlData),
-	}
-
-	for _, element := range avlData.Elements {
-		IOID := element.IOID
-		if element.Length > 8 {
-			log.Warnf("IOID%d value long! Got %d bytes long IOID value! It will be save as hex string. Value: %s", IOID, element.Length, hex.EncodeToString(element.Value[:element.Length]))
-			fields[fmt.Sprintf("IOID%d", IOID)] = hex.EncodeToString(element.Value)
-		} else {
-			// raw must have 8 bytes to parse it as uint64 so add zero bytes with BigEndian if needed
-			addon := make([]byte, 8-element.Length)
-			raw := append(addon, element.Value...)
-
-			// parse byte array as integer
-			value := binary.BigEndian.Uint64(raw)
-			fields[fmt.Sprintf("IOID%d", IOID)] = int(value) // #nosec G115 TODO if value is not casted to int, influx drops parse error. Why?
-		}
+// FieldNames are the fields of the records besides the IO elements
+var FieldNames = []string{"latitude", "longitude", "altitude", "visiblesatellites", "angle", "speed", "priority", "eventID", "serverTime", "generationType"}
+
+// SetIOMapper makes IO elements written by their mapped names and types. Models returns the model of a device by its IMEI.
+func (c *Connection) SetIOMapper(mapper *ioelement.Mapper, models func(imei string) string) {
+	c.ioMapper = mapper
+	c.models = models
+}
+
+func (c *Connection) renderFields(avlData codec.AvlData, mapping *ioelement.Mapping) map[string]interface{} {
+	var fields map[string]interface{}
+	if mapping != nil {
+		fields = mapping.Values(avlData.Elements)
+	} else {
+		fields = sink.IOValues(c.ctx, avlData)
+	}
+
+	fields["latitude"] = sink.Latitude(avlData)
+	fields["longitude"] = sink.Longitude(avlData)
+	fields["altitude"] = avlData.Altitude
+	fields["visiblesatellites"] = avlData.VisSat
+	fields["angle"] = avlData.Angle
+	fields["speed"] = avlData.Speed
+	fields["priority"] = avlData.Priority
+	fields["eventID"] = avlData.EventID
+	fields["serverTime"] = time.Now().UTC().Unix()
+
+	// Only Codec 16 records tell why they were generated
+	if avlData.GenerationType != codec.GenerationUnsupported {
+		fields["generationType"] = avlData.GenerationType
 	}
 
 	return fields
 }
 
-func (c *Connection) renderTimesamp(avlData teltonikaparser.AvlData) time.Time {
-	return time.UnixMilli(int64(avlData.UtimeMs)) // #nosec G115
+func (c *Connection) renderTimesamp(avlData codec.AvlData) time.Time {
+	return sink.Timestamp(avlData)
 }
 
-func (c *Connection) insert(extraTags map[string]string, record teltonikaparser.Decoded) error {
+// renderPoints creates a point of each record with its own timestamp
+func (c *Connection) renderPoints(extraTags map[string]string, record codec.Decoded) ([]*client.Point, error) {
 	log := config.GetLogger(c.ctx)
 
 	tags := c.renderTags(record)
@@ -129,43 +208,67 @@ func (c *Connection) insert(extraTags map[string]string, record teltonikaparser.
 		tags[k] = v
 	}
 
-	batchPointsConfig := client.BatchPointsConfig{
-		Database: c.database,
-	}
-
-	bps, err := client.NewBatchPoints(batchPointsConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create new batch point config. %v", err)
+	var mapping *ioelement.Mapping
+	if c.ioMapper != nil {
+		deviceMapping := c.ioMapper.Mapping(c.models(record.IMEI))
+		mapping = &deviceMapping
 	}
 
 	log.Debugf("Processing %d AVL data record.", len(record.Data))
+	points := make([]*client.Point, 0, len(record.Data))
 	for _, data := range record.Data {
-		fields := c.renderFields(data)
+		fields := c.renderFields(data, mapping)
 		timestamp := c.renderTimesamp(data)
 
 		point, err := client.NewPoint(c.measurement, tags, fields, timestamp)
-		//point, err := client.NewPoint(c.measurement, tags, fields)
 		if err != nil {
-			return fmt.Errorf("failed to create new point. %v", err)
+			return nil, fmt.Errorf("failed to create new point. %v", err)
 		}
-		bps.AddPoint(point)
+		points = append(points, point)
 	}
-	log.Debugf("%d InfluxDB points are created.", len(bps.Points()))
+	log.Debugf("%d InfluxDB points are created.", len(points))
+
+	return points, nil
+}
 
-	if c.client == nil {
+// write sends the points in one request. Requests failed with a transient error are retried with exponential backoff.
+func (c *Connection) write(points []*client.Point) error {
+	log := config.GetLogger(c.ctx)
+
+	if c.writer == nil {
 		return fmt.Errorf("influxDB client must not be nil. Please check your influxdb connection")
 	}
 
-	err = c.client.Write(bps)
-	if err != nil {
-		return fmt.Errorf("failed to create write batch points into influxdb. %v", err)
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := c.writer.write(points)
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.retries || !isTransient(err) {
+			return fmt.Errorf("failed to write points into influxdb. %w", err)
+		}
+
+		log.Warningf("Failed to write %d points into influxdb. Retrying in %v. %v", len(points), delay, err)
+		select {
+		case <-time.After(delay):
+		case <-c.ctx.Done():
+			return fmt.Errorf("failed to write points into influxdb. %w", err)
+		}
+		delay *= 2
 	}
+}
 
-	return nil
+func (c *Connection) insert(extraTags map[string]string, record codec.Decoded) error {
+	points, err := c.renderPoints(extraTags, record)
+	if err != nil {
+		return err
+	}
 
+	return c.write(points)
 }
 
-func (c *Connection) InsertMessage(record teltonikaparser.Decoded, extraTags map[string]string) error {
+func (c *Connection) InsertMessage(record codec.Decoded, extraTags map[string]string) error {
 	err := c.insert(extraTags, record)
 	if err != nil {
 		return fmt.Errorf("influxdb insert was failed. %v", err)
@@ -3,7 +3,7 @@ package influxdb
 import (
 	"context"
 	"encoding/hex"
-	"github.com/filipkroca/teltonikaparser"
+	"github.com/halacs/haltonika/codec"
 	cfg "github.com/halacs/haltonika/config"
 	"github.com/sirupsen/logrus"
 	"testing"
@@ -28,7 +28,7 @@ func TestConnect(t *testing.T) {
 		Database:    cfg.DefaultInfluxDbDatabaseName,
 		Measurement: cfg.DefaultInfluxDbMeasurementName,
 	}
-	config := cfg.NewConfig(log, influxConfig, nil, nil, nil) // only the logger is needed in this natsio
+	config := cfg.NewConfig(log, influxConfig, nil, nil, nil, nil) // only the logger is needed in this natsio
 	ctx := context.WithValue(context.Background(), cfg.ContextConfigKey, config)
 
 	// Run all natsio cases as a separated network connection
@@ -46,7 +46,7 @@ func TestConnect(t *testing.T) {
 				test.Fail()
 			}
 
-			decoded, err := teltonikaparser.Decode(&byteRequest)
+			decoded, err := codec.Decode(byteRequest)
 			if err != nil {
 				test.Logf("Incorrect test case input! Failed to decode haltonika packet.")
 				test.Fail()
@@ -0,0 +1,204 @@
+package influxdb
+
+import (
+	"fmt"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/sink"
+	client "github.com/influxdata/influxdb1-client/v2"
+	"sync/atomic"
+)
+
+// SinkOptions overrides the global InfluxDB configuration for one sink
+type SinkOptions struct {
+	Version     int
+	Url         string
+	Username    string
+	Password    string
+	Database    string
+	Token       string
+	Org         string
+	Bucket      string
+	Measurement string
+	Precision   string
+	Gzip        *bool
+	BatchSize   int
+	Retries     *int
+	IOMapping   *bool
+}
+
+// ApplySinkOptions returns the InfluxDB configuration of a sink based on the global one
+func ApplySinkOptions(cfg config.InfluxConfig, options map[string]interface{}) (*config.InfluxConfig, error) {
+	var sinkOptions SinkOptions
+	err := sink.DecodeOptions(options, &sinkOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if sinkOptions.Version != 0 {
+		cfg.Version = sinkOptions.Version
+	}
+	if sinkOptions.Url != "" {
+		cfg.Url = sinkOptions.Url
+	}
+	if sinkOptions.Username != "" {
+		cfg.Username = sinkOptions.Username
+	}
+	if sinkOptions.Password != "" {
+		cfg.Password = sinkOptions.Password
+	}
+	if sinkOptions.Database != "" {
+		cfg.Database = sinkOptions.Database
+	}
+	if sinkOptions.Token != "" {
+		cfg.Token = sinkOptions.Token
+	}
+	if sinkOptions.Org != "" {
+		cfg.Org = sinkOptions.Org
+	}
+	if sinkOptions.Bucket != "" {
+		cfg.Bucket = sinkOptions.Bucket
+	}
+	if sinkOptions.Measurement != "" {
+		cfg.Measurement = sinkOptions.Measurement
+	}
+	if sinkOptions.Precision != "" {
+		cfg.Precision = sinkOptions.Precision
+	}
+	if sinkOptions.Gzip != nil {
+		cfg.Gzip = *sinkOptions.Gzip
+	}
+	if sinkOptions.BatchSize != 0 {
+		cfg.BatchSize = sinkOptions.BatchSize
+	}
+	if sinkOptions.Retries != nil {
+		cfg.Retries = *sinkOptions.Retries
+	}
+	if sinkOptions.IOMapping != nil {
+		cfg.IOMapping = *sinkOptions.IOMapping
+	}
+
+	return &cfg, nil
+}
+
+/*
+Write adds the records of the message with the source address tag to the pending points.
+Pending points are written when a batch is full or by Flush. Write fails only if too many points are pending.
+*/
+func (c *Connection) Write(message sink.Message) error {
+	log := config.GetLogger(c.ctx)
+
+	tags := map[string]string{
+		SourceTag: message.SourceAddress,
+	}
+
+	points, err := c.renderPoints(tags, message.Decoded)
+	if err != nil {
+		return fmt.Errorf("influxdb insert was failed. %v", err)
+	}
+
+	// Pending points are written first if InfluxDB is available again, otherwise the message has to be written later
+	if c.pendingFull() {
+		err = c.Flush()
+		if err != nil && c.pendingFull() {
+			return fmt.Errorf("%d points are waiting to be written into influxdb. %v", maxPendingBatches*c.batchSize, err)
+		}
+	}
+
+	c.pendingLock.Lock()
+	c.pending = append(c.pending, points...)
+	full := len(c.pending) >= c.batchSize
+	c.pendingLock.Unlock()
+
+	if full {
+		// Points are kept for the next flush
+		err = c.Flush()
+		if err != nil {
+			log.Errorf("Failed to write full batch into influxdb. %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Connection) pendingFull() bool {
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+
+	return len(c.pending) >= maxPendingBatches*c.batchSize
+}
+
+/*
+Flush writes the pending points in batches. Points of a failed batch are kept for the next flush, e.g. while InfluxDB
+is down or refuses the credentials. Batches rejected by InfluxDB are split to write their valid points, only the rejected
+points are dropped.
+*/
+func (c *Connection) Flush() error {
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+
+	var dropped int
+	var rejection error
+	for len(c.pending) > 0 {
+		batch := c.pending[:min(len(c.pending), c.batchSize)]
+
+		done, batchDropped, err := c.writeSplit(batch)
+		c.pending = c.pending[done:]
+		if batchDropped > 0 {
+			atomic.AddUint64(&c.lost, uint64(batchDropped)) // #nosec G115
+			dropped += batchDropped
+			rejection = err
+		}
+		if err != nil && !isRejected(err) {
+			c.SetLastError(err)
+			return err
+		}
+	}
+
+	// Do not keep the backing array of a large batch
+	c.pending = nil
+
+	if dropped > 0 {
+		c.SetLastError(rejection)
+		return fmt.Errorf("%d points are dropped. %v", dropped, rejection)
+	}
+	c.SetLastError(nil)
+
+	return nil
+}
+
+/*
+writeSplit writes the points and returns how many of them are done, written or dropped, from the beginning.
+If InfluxDB rejects some of the points, the batch is split into halves until the rejected points are found and dropped.
+Points stored by a partial write are written again, but they only overwrite themselves.
+*/
+func (c *Connection) writeSplit(points []*client.Point) (int, int, error) {
+	err := c.write(points)
+	if err == nil {
+		return len(points), 0, nil
+	}
+	if !isRejected(err) {
+		return 0, 0, err
+	}
+	if len(points) == 1 {
+		config.GetLogger(c.ctx).Errorf("%d points rejected by influxdb are dropped. %v", len(points), err)
+		return len(points), len(points), err
+	}
+
+	half := len(points) / 2
+	done, dropped, firstErr := c.writeSplit(points[:half])
+	if firstErr != nil && !isRejected(firstErr) {
+		return done, dropped, firstErr
+	}
+
+	restDone, restDropped, err := c.writeSplit(points[half:])
+	if err == nil {
+		err = firstErr
+	}
+
+	return done + restDone, dropped + restDropped, err
+}
+
+// Lost returns the number of points dropped because InfluxDB rejected them
+func (c *Connection) Lost() uint64 {
+	return atomic.LoadUint64(&c.lost)
+}
@@ -0,0 +1,400 @@
+package influxdb
+
+import (
+	"context"
+	"fmt"
+	cfg "github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/ioelement"
+	"github.com/halacs/haltonika/registry"
+	"github.com/halacs/haltonika/sink/sinktest"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeInflux answers write requests with the given statuses, then with 204. Accepted lines are recorded.
+// Requests containing a line with the reject text are refused like InfluxDB refuses invalid points.
+type fakeInflux struct {
+	lock     sync.Mutex
+	statuses []int
+	reject   string
+	requests int
+	lines    []string
+}
+
+func (f *fakeInflux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.requests++
+	if len(f.statuses) > 0 {
+		status := f.statuses[0]
+		f.statuses = f.statuses[1:]
+		w.WriteHeader(status)
+		switch status {
+		case http.StatusBadRequest:
+			_, _ = w.Write([]byte(`{"error":"unable to parse 'gps': missing fields"}`))
+		case http.StatusUnauthorized:
+			_, _ = w.Write([]byte(`{"error":"authorization failed"}`))
+		case http.StatusNotFound:
+			_, _ = w.Write([]byte(`{"error":"database not found: \"haltonika\""}`))
+		}
+		return
+	}
+
+	raw, _ := io.ReadAll(r.Body)
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	for _, line := range lines {
+		if f.reject != "" && strings.Contains(line, f.reject) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"partial write: field type conflict dropped=1"}`))
+			return
+		}
+	}
+
+	f.lines = append(f.lines, lines...)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeInflux) counts() (int, int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.requests, len(f.lines)
+}
+
+func newTestConnection(t *testing.T, serverUrl string, batchSize int, retries int) *Connection {
+	influxConfig := &cfg.InfluxConfig{
+		Url:         serverUrl,
+		Username:    "haltonika",
+		Password:    "secret",
+		Database:    "haltonika",
+		Measurement: "gps",
+		BatchSize:   batchSize,
+		Retries:     retries,
+	}
+	ctx := context.WithValue(context.Background(), cfg.ContextConfigKey, cfg.NewConfig(logrus.New(), influxConfig, nil, nil, nil, nil))
+
+	connection := NewConnection(ctx, influxConfig)
+	err := connection.Connect()
+	if err != nil {
+		t.Fatalf("Failed to connect. %v", err)
+	}
+
+	connection.retryDelay = time.Millisecond
+
+	return connection
+}
+
+func TestSinkBatching(t *testing.T) {
+	var path, username, password string
+	influx := &fakeInflux{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path + "?" + r.URL.RawQuery
+		username, password, _ = r.BasicAuth()
+		influx.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	connection := newTestConnection(t, server.URL, 10, 0)
+
+	// Points of more messages are written in one request
+	message := sinktest.NewMessage(t)
+	for i := 0; i < 2; i++ {
+		err := connection.Write(message)
+		if err != nil {
+			t.Fatalf("Failed to write message. %v", err)
+		}
+	}
+	requests, _ := influx.counts()
+	if requests != 0 {
+		t.Errorf("Points must be written only when the batch is full or flushed. Actual requests: %d", requests)
+	}
+
+	// Third message fills the batch
+	err := connection.Write(message)
+	if err != nil {
+		t.Fatalf("Failed to write message. %v", err)
+	}
+	err = connection.Flush()
+	if err != nil {
+		t.Fatalf("Failed to flush. %v", err)
+	}
+
+	requests, lines := influx.counts()
+	if requests != 2 || lines != 12 {
+		t.Errorf("Wrong number of requests and points! Expected: 2, 12 Actual: %d, %d", requests, lines)
+	}
+	if path != "/write?consistency=&db=haltonika&precision=ns&rp=" || username != "haltonika" || password != "secret" {
+		t.Errorf("Wrong v1 write request: %s %s:%s", path, username, password)
+	}
+
+	// Every record keeps its own timestamp and tags
+	timestamps := make(map[string]bool)
+	for _, line := range influx.lines[:4] {
+		if !strings.HasPrefix(line, "gps,CodecID=8,IMEI=352094089397464,source=127.0.0.1:1234 ") {
+			t.Errorf("Wrong measurement or tags: %v", line)
+		}
+		timestamps[line[strings.LastIndex(line, " ")+1:]] = true
+	}
+	if len(timestamps) != 4 {
+		t.Errorf("Records must keep their timestamps: %v", timestamps)
+	}
+
+	err = connection.Close()
+	if err != nil {
+		t.Errorf("Failed to close connection. %v", err)
+	}
+}
+
+func TestSinkRetry(t *testing.T) {
+	testCases := []struct {
+		Name             string
+		Statuses         []int
+		Retries          int
+		ExpectedError    bool
+		ExpectedRequests int
+		ExpectedLines    int // after the second flush
+	}{
+		{
+			Name:             "Retried",
+			Statuses:         []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			Retries:          2,
+			ExpectedRequests: 3,
+			ExpectedLines:    4,
+		},
+		{
+			Name:             "Kept for next flush",
+			Statuses:         []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			Retries:          1,
+			ExpectedError:    true,
+			ExpectedRequests: 3,
+			ExpectedLines:    4,
+		},
+		{
+			Name:             "Kept after refused credentials",
+			Statuses:         []int{http.StatusUnauthorized},
+			Retries:          2,
+			ExpectedError:    true,
+			ExpectedRequests: 2,
+			ExpectedLines:    4,
+		},
+		{
+			Name:             "Kept while database is missing",
+			Statuses:         []int{http.StatusNotFound},
+			Retries:          2,
+			ExpectedError:    true,
+			ExpectedRequests: 2,
+			ExpectedLines:    4,
+		},
+		{
+			Name:             "Split after rejection",
+			Statuses:         []int{http.StatusBadRequest},
+			Retries:          2,
+			ExpectedRequests: 3,
+			ExpectedLines:    4,
+		},
+		{
+			Name:             "Dropped after rejection",
+			Statuses:         []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest},
+			Retries:          2,
+			ExpectedError:    true,
+			ExpectedRequests: 7,
+			ExpectedLines:    0,
+		},
+	}
+
+	message := sinktest.NewMessage(t)
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			influx := &fakeInflux{statuses: testCase.Statuses}
+			server := httptest.NewServer(influx)
+			defer server.Close()
+
+			connection := newTestConnection(test, server.URL, 100, testCase.Retries)
+			err := connection.Write(message)
+			if err != nil {
+				test.Fatalf("Failed to write message. %v", err)
+			}
+
+			err = connection.Flush()
+			if (err != nil) != testCase.ExpectedError {
+				test.Errorf("Wrong flush result! Expected error: %v Actual: %v", testCase.ExpectedError, err)
+			}
+			if (connection.Health() != nil) != testCase.ExpectedError {
+				test.Errorf("Wrong health! Expected error: %v Actual: %v", testCase.ExpectedError, connection.Health())
+			}
+
+			err = connection.Flush()
+			if err != nil {
+				test.Errorf("Failed to flush again. %v", err)
+			}
+
+			requests, lines := influx.counts()
+			if requests != testCase.ExpectedRequests || lines != testCase.ExpectedLines {
+				test.Errorf("Wrong number of requests and points! Expected: %d, %d Actual: %d, %d", testCase.ExpectedRequests, testCase.ExpectedLines, requests, lines)
+			}
+		})
+	}
+}
+
+func TestSinkRecovery(t *testing.T) {
+	// Every write fails until the pending points fill the buffer, then InfluxDB becomes available again
+	statuses := make([]int, maxPendingBatches+1)
+	for i := range statuses {
+		statuses[i] = http.StatusServiceUnavailable
+	}
+	influx := &fakeInflux{statuses: statuses}
+	server := httptest.NewServer(influx)
+	defer server.Close()
+
+	// Every message fills a batch
+	connection := newTestConnection(t, server.URL, 4, 0)
+	message := sinktest.NewMessage(t)
+	for i := 0; i < maxPendingBatches; i++ {
+		err := connection.Write(message)
+		if err != nil {
+			t.Fatalf("Failed to write message. %v", err)
+		}
+	}
+
+	err := connection.Write(message)
+	if err == nil {
+		t.Fatalf("Message must be refused while the pending points can not be written")
+	}
+
+	// Pending points are written before the next message
+	err = connection.Write(message)
+	if err != nil {
+		t.Fatalf("Failed to write message after recovery. %v", err)
+	}
+
+	_, lines := influx.counts()
+	if lines != (maxPendingBatches+1)*4 {
+		t.Errorf("Wrong number of points! Expected: %d Actual: %d", (maxPendingBatches+1)*4, lines)
+	}
+}
+
+func TestSinkRejectedPoint(t *testing.T) {
+	message := sinktest.NewMessage(t)
+	influx := &fakeInflux{}
+	server := httptest.NewServer(influx)
+	defer server.Close()
+
+	connection := newTestConnection(t, server.URL, 100, 2)
+
+	// Third record is refused
+	points, err := connection.renderPoints(nil, message.Decoded)
+	if err != nil {
+		t.Fatalf("Failed to render points. %v", err)
+	}
+	influx.reject = fmt.Sprintf(" %d", points[2].UnixNano())
+
+	err = connection.Write(message)
+	if err != nil {
+		t.Fatalf("Failed to write message. %v", err)
+	}
+	err = connection.Flush()
+	if err == nil || connection.Health() == nil {
+		t.Errorf("Dropped point must be reported")
+	}
+
+	// 4 points are split into 2 + 2 and the second half into 1 + 1
+	requests, lines := influx.counts()
+	if requests != 5 || lines != 3 || connection.Lost() != 1 {
+		t.Errorf("Wrong number of requests, points and lost points! Expected: 5, 3, 1 Actual: %d, %d, %d", requests, lines, connection.Lost())
+	}
+
+	err = connection.Flush()
+	if err != nil || connection.Health() != nil {
+		t.Errorf("Nothing must be pending. %v", err)
+	}
+}
+
+func TestSinkIOMapping(t *testing.T) {
+	influx := &fakeInflux{}
+	server := httptest.NewServer(influx)
+	defer server.Close()
+
+	mapper, err := ioelement.NewMapper(func(string) ioelement.Dictionary { return ioelement.FMBXY }, ioelement.MappingConfig{
+		Unknown: ioelement.UnknownDrop,
+		Elements: map[uint16]ioelement.Override{
+			239: {Name: "ignition", Type: ioelement.FieldBool},
+		},
+		Models: map[string]ioelement.ModelMapping{
+			"FMB920": {Drop: []uint16{205, 206}},
+		},
+	}, FieldNames...)
+	if err != nil {
+		t.Fatalf("Failed to create mapper. %v", err)
+	}
+
+	connection := newTestConnection(t, server.URL, 100, 0)
+	connection.SetIOMapper(mapper, func(imei string) string {
+		return "FMB920"
+	})
+
+	err = connection.Write(sinktest.NewMessage(t))
+	if err != nil {
+		t.Fatalf("Failed to write message. %v", err)
+	}
+	err = connection.Flush()
+	if err != nil {
+		t.Fatalf("Failed to flush. %v", err)
+	}
+
+	line := influx.lines[3]
+	for _, expected := range []string{"ignition=true", "external_voltage=28721i", "gsm_signal=3i", "speed=88i", "gnss_pdop=0.9"} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("Missing %s field: %v", expected, line)
+		}
+	}
+	for _, unexpected := range []string{"IOID", "gsm_cell_id"} {
+		if strings.Contains(line, unexpected) {
+			t.Errorf("Unexpected %s field: %v", unexpected, line)
+		}
+	}
+}
+
+func TestSinkDeviceTags(t *testing.T) {
+	influx := &fakeInflux{}
+	server := httptest.NewServer(influx)
+	defer server.Close()
+
+	devices, err := registry.New(map[string]registry.Device{
+		"352094089397464": {Name: "Truck 1", Plate: "ABC-123", Labels: map[string]string{"depot": "north", "IMEI": "000000000000000"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create registry. %v", err)
+	}
+
+	connection := newTestConnection(t, server.URL, 100, 0)
+	connection.SetDeviceRegistry(devices)
+
+	err = connection.Write(sinktest.NewMessage(t))
+	if err != nil {
+		t.Fatalf("Failed to write message. %v", err)
+	}
+	err = connection.Flush()
+	if err != nil {
+		t.Fatalf("Failed to flush. %v", err)
+	}
+
+	line := influx.lines[0]
+	for _, expected := range []string{`Name=Truck\ 1`, "Plate=ABC-123", "depot=north", "IMEI=352094089397464"} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("Missing %s tag: %v", expected, line)
+		}
+	}
+	for _, unexpected := range []string{"Group=", "Owner=", "IMEI=000000000000000"} {
+		if strings.Contains(line, unexpected) {
+			t.Errorf("Unexpected %s tag: %v", unexpected, line)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package influxdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"fmt"
+	client "github.com/influxdata/influxdb1-client/v2"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	v2WritePath = "/api/v2/write"
+)
+
+// v2Writer writes points via the /api/v2/write endpoint of InfluxDB 2.x and 3.x using token authentication
+type v2Writer struct {
+	httpClient *http.Client
+	writeUrl   string
+	token      string
+	precision  string
+	gzip       bool
+}
+
+func newV2Writer(serverUrl string, token string, org string, bucket string, precision string, gzip bool, insecureSkipVerify bool) (*v2Writer, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("bucket must not be empty")
+	}
+
+	writeUrl, err := url.Parse(strings.TrimSuffix(serverUrl, "/") + v2WritePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid InfluxDB URL. %v", err)
+	}
+	if writeUrl.Scheme != "http" && writeUrl.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported protocol scheme of InfluxDB URL: %s", serverUrl)
+	}
+
+	// InfluxDB 3 ignores the organization
+	query := url.Values{}
+	if org != "" {
+		query.Set("org", org)
+	}
+	query.Set("bucket", bucket)
+	query.Set("precision", precision)
+	writeUrl.RawQuery = query.Encode()
+
+	return &v2Writer{
+		httpClient: &http.Client{
+			Timeout: writeTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify}, // #nosec G402 disabled by default
+			},
+		},
+		writeUrl:  writeUrl.String(),
+		token:     token,
+		precision: precision,
+		gzip:      gzip,
+	}, nil
+}
+
+func (w *v2Writer) write(points []*client.Point) error {
+	var body bytes.Buffer
+
+	var lines io.Writer = &body
+	var compressor *gzip.Writer
+	if w.gzip {
+		compressor = gzip.NewWriter(&body)
+		lines = compressor
+	}
+
+	for _, point := range points {
+		_, err := fmt.Fprintln(lines, point.PrecisionString(w.precision))
+		if err != nil {
+			return fmt.Errorf("failed to render point. %v", err)
+		}
+	}
+
+	if compressor != nil {
+		err := compressor.Close()
+		if err != nil {
+			return fmt.Errorf("failed to compress points. %v", err)
+		}
+	}
+
+	request, err := http.NewRequest(http.MethodPost, w.writeUrl, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create write request. %v", err)
+	}
+
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.token != "" {
+		request.Header.Set("Authorization", "Token "+w.token)
+	}
+	if w.gzip {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+
+	response, err := w.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to send write request. %w", err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+		return &writeError{
+			status:  response.StatusCode,
+			message: strings.TrimSpace(string(message)),
+		}
+	}
+
+	return nil
+}
+
+func (w *v2Writer) close() {
+	w.httpClient.CloseIdleConnections()
+}
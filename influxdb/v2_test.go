@@ -0,0 +1,151 @@
+package influxdb
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"github.com/halacs/haltonika/codec"
+	cfg "github.com/halacs/haltonika/config"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteV2(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		Gzip      bool
+		Precision string
+		Status    int
+		Expected  string // end of the first line
+		Fail      bool
+	}{
+		{
+			Name:      "Plain",
+			Precision: "ms",
+			Status:    http.StatusNoContent,
+			Expected:  " 1527149439000",
+		},
+		{
+			Name:      "Gzip",
+			Gzip:      true,
+			Precision: "s",
+			Status:    http.StatusNoContent,
+			Expected:  " 1527149439",
+		},
+		{
+			Name:      "Unauthorized",
+			Precision: "ns",
+			Status:    http.StatusUnauthorized,
+			Fail:      true,
+		},
+	}
+
+	packet, err := hex.DecodeString("0067cafe016b000f3335303432343036333831373336338e01000001839ecd8a70000b5629e81c5451d0000000000000000000000b000500500000150400c800004502001d00000500422e970018000000cd13f000ce005d00430fd3000100f10000547e0000000001")
+	if err != nil {
+		t.Fatalf("Incorrect packet. %v", err)
+	}
+	decoded, err := codec.Decode(packet)
+	if err != nil {
+		t.Fatalf("Failed to decode packet. %v", err)
+	}
+	decoded.Data[0].UtimeMs = 1527149439000
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			var body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v2/write" {
+					test.Errorf("Wrong path! Expected: /api/v2/write Actual: %v", r.URL.Path)
+				}
+				if r.URL.Query().Get("org") != "fleet" || r.URL.Query().Get("bucket") != "gps" || r.URL.Query().Get("precision") != testCase.Precision {
+					test.Errorf("Wrong query: %v", r.URL.RawQuery)
+				}
+				if r.Header.Get("Authorization") != "Token secret" {
+					test.Errorf("Wrong authorization header: %v", r.Header.Get("Authorization"))
+				}
+
+				var reader io.Reader = r.Body
+				if testCase.Gzip {
+					if r.Header.Get("Content-Encoding") != "gzip" {
+						test.Errorf("Content encoding must be gzip")
+					}
+					gzipReader, err := gzip.NewReader(r.Body)
+					if err != nil {
+						test.Errorf("Body is not compressed. %v", err)
+						return
+					}
+					reader = gzipReader
+				}
+				raw, _ := io.ReadAll(reader)
+				body = string(raw)
+
+				w.WriteHeader(testCase.Status)
+			}))
+			defer server.Close()
+
+			influxConfig := &cfg.InfluxConfig{
+				Version:     2,
+				Url:         server.URL,
+				Token:       "secret",
+				Org:         "fleet",
+				Bucket:      "gps",
+				Measurement: "gps",
+				Precision:   testCase.Precision,
+				Gzip:        testCase.Gzip,
+			}
+			ctx := context.WithValue(context.Background(), cfg.ContextConfigKey, cfg.NewConfig(logrus.New(), influxConfig, nil, nil, nil, nil))
+
+			connection := NewConnection(ctx, influxConfig)
+			err := connection.Connect()
+			if err != nil {
+				test.Fatalf("Failed to connect. %v", err)
+			}
+			defer func() {
+				_ = connection.Close()
+			}()
+
+			err = connection.InsertMessage(decoded, map[string]string{SourceTag: "127.0.0.1:1234"})
+			if testCase.Fail {
+				if err == nil {
+					test.Errorf("Write must fail with %d status", testCase.Status)
+				}
+				return
+			}
+			if err != nil {
+				test.Fatalf("Failed to write. %v", err)
+			}
+
+			line := strings.Split(strings.TrimSpace(body), "\n")[0]
+			if !strings.HasPrefix(line, "gps,CodecID=8E,IMEI=350424063817363,source=127.0.0.1:1234 ") {
+				test.Errorf("Wrong measurement or tags: %v", line)
+			}
+			if !strings.Contains(line, "IOID66=11927i") {
+				test.Errorf("IO element is missing: %v", line)
+			}
+			if !strings.HasSuffix(line, testCase.Expected) {
+				test.Errorf("Wrong timestamp! Expected suffix: %v Actual: %v", testCase.Expected, line)
+			}
+		})
+	}
+}
+
+func TestConnectV2Invalid(t *testing.T) {
+	ctx := context.WithValue(context.Background(), cfg.ContextConfigKey, cfg.NewConfig(logrus.New(), nil, nil, nil, nil, nil))
+
+	testCases := map[string]*cfg.InfluxConfig{
+		"Missing bucket":    {Version: 2, Url: "http://localhost:8086"},
+		"Invalid precision": {Version: 2, Url: "http://localhost:8086", Bucket: "gps", Precision: "m"},
+		"Invalid version":   {Version: 4, Url: "http://localhost:8086"},
+	}
+
+	for name, influxConfig := range testCases {
+		err := NewConnection(ctx, influxConfig).Connect()
+		if err == nil {
+			t.Errorf("%s: connect must fail", name)
+		}
+	}
+}
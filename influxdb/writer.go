@@ -0,0 +1,152 @@
+package influxdb
+
+import (
+	"errors"
+	"fmt"
+	client "github.com/influxdata/influxdb1-client/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	writeTimeout = 30 * time.Second
+	maxErrorBody = 1024 // bytes of the error response kept in the error message
+)
+
+// pointWriter sends points to InfluxDB in one request
+type pointWriter interface {
+	write(points []*client.Point) error
+	close()
+}
+
+// writeError is returned if InfluxDB refused the write request. Status is zero if the client library does not tell it.
+type writeError struct {
+	status  int
+	message string
+}
+
+func (e *writeError) Error() string {
+	if e.status == 0 {
+		return fmt.Sprintf("write request failed. %s", e.message)
+	}
+	if e.message == "" {
+		return fmt.Sprintf("write request failed with %d %s status", e.status, http.StatusText(e.status))
+	}
+
+	return fmt.Sprintf("write request failed with %d %s status. %s", e.status, http.StatusText(e.status), e.message)
+}
+
+// Errors of InfluxDB 1.x caused by some of the points. The client library gives only the message without the status code.
+var v1Rejections = []string{
+	"partial write",
+	"unable to parse",
+	"field type conflict",
+	"points beyond retention policy",
+	"max-values-per-tag limit exceeded",
+	"max series per database exceeded",
+}
+
+// Errors of InfluxDB 1.x which are not transient and are not caused by the points, so retrying them immediately does not help
+var v1Refusals = []string{
+	"authorization failed",
+	"database not found",
+	"user not found",
+}
+
+/*
+isTransient tells if the failed write request may succeed when it is retried right away.
+Network errors, timeouts, rate limiting and server errors are transient. Refused credentials, missing databases and
+rejected points are not.
+*/
+func isTransient(err error) bool {
+	var refused *writeError
+	if errors.As(err, &refused) {
+		if refused.status == 0 {
+			return !isRejected(err) && !containsAny(refused.message, v1Refusals)
+		}
+		return refused.status == http.StatusRequestTimeout || refused.status == http.StatusTooManyRequests || refused.status >= http.StatusInternalServerError
+	}
+
+	var urlError *url.Error
+	return errors.As(err, &urlError)
+}
+
+// isRejected tells if InfluxDB refused the request because of some of its points, so the others can be written without them.
+// Points of other failed requests are kept.
+func isRejected(err error) bool {
+	var refused *writeError
+	if !errors.As(err, &refused) {
+		return false
+	}
+	if refused.status == 0 {
+		return containsAny(refused.message, v1Rejections)
+	}
+
+	return refused.status == http.StatusBadRequest || refused.status == http.StatusRequestEntityTooLarge || refused.status == http.StatusUnprocessableEntity
+}
+
+func containsAny(message string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(message, substring) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// v1Writer writes points via the InfluxDB 1.x client library using username and password
+type v1Writer struct {
+	client    client.Client
+	database  string
+	precision string
+}
+
+func newV1Writer(serverUrl string, username string, password string, database string, precision string, insecureSkipVerify bool) (*v1Writer, error) {
+	httpClient, err := client.NewHTTPClient(client.HTTPConfig{
+		Addr:               serverUrl,
+		Username:           username,
+		Password:           password,
+		Timeout:            writeTimeout,
+		InsecureSkipVerify: insecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1Writer{
+		client:    httpClient,
+		database:  database,
+		precision: precision,
+	}, nil
+}
+
+func (w *v1Writer) write(points []*client.Point) error {
+	bps, err := client.NewBatchPoints(client.BatchPointsConfig{
+		Database:  w.database,
+		Precision: w.precision,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create new batch point config. %v", err)
+	}
+	bps.AddPoints(points)
+
+	err = w.client.Write(bps)
+	if err != nil {
+		var urlError *url.Error
+		if errors.As(err, &urlError) {
+			return fmt.Errorf("failed to send write request. %w", err)
+		}
+
+		// Other errors are the response of InfluxDB
+		return &writeError{message: strings.TrimSpace(err.Error())}
+	}
+
+	return nil
+}
+
+func (w *v1Writer) close() {
+	_ = w.client.Close()
+}
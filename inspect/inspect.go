@@ -0,0 +1,236 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/ioelement"
+	"time"
+)
+
+// Kinds of the described packets
+const (
+	KindAvl     = "avl"
+	KindCommand = "command"
+)
+
+// Transports of the described packets. Bare AVL data array has no transport.
+const (
+	TransportUdp = "udp"
+	TransportTcp = "tcp"
+)
+
+// Packet is the human readable description of a decoded packet
+type Packet struct {
+	Kind      string   `json:"kind"`
+	Transport string   `json:"transport,omitempty"`
+	Codec     string   `json:"codec"`
+	IMEI      string   `json:"imei,omitempty"`
+	Records   []Record `json:"records,omitempty"`
+	Command   *Command `json:"command,omitempty"`
+	Response  string   `json:"response,omitempty"` // ACK to be sent back to the device in hex
+}
+
+// Record is one AVL record of a packet
+type Record struct {
+	Timestamp      time.Time `json:"timestamp"`
+	Priority       string    `json:"priority"`
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	Altitude       int16     `json:"altitude"`
+	Angle          uint16    `json:"angle"`
+	Satellites     uint8     `json:"satellites"`
+	Speed          uint16    `json:"speed"`
+	EventID        uint16    `json:"eventId"`
+	Event          string    `json:"event,omitempty"`
+	GenerationType *uint8    `json:"generationType,omitempty"`
+	Elements       []Element `json:"elements"`
+}
+
+// Element is one IO element of a record. Name, value and unit are set only for the known elements.
+type Element struct {
+	ID    uint16      `json:"id"`
+	Raw   string      `json:"raw"`
+	Name  string      `json:"name,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+	Unit  string      `json:"unit,omitempty"`
+}
+
+// Command is a Codec 12, 13 or 14 command or response
+type Command struct {
+	Type      string     `json:"type"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	Payload   string     `json:"payload"`
+}
+
+// Describe decodes a packet and describes it using the IO element dictionary.
+// The packet can be an UDP or TCP AVL packet, a command packet or a bare AVL data array starting with the codec ID.
+// IMEI is used only for TCP and bare AVL data, because those packets do not contain it.
+func Describe(packet []byte, imei string, dictionary ioelement.Dictionary) (Packet, error) {
+	if len(packet) == 0 {
+		return Packet{}, errors.New("packet is empty")
+	}
+
+	switch {
+	case bytes.HasPrefix(packet, []byte{0, 0, 0, 0}):
+		// Command and TCP AVL packets share the same preamble, codec ID follows the data field length
+		if len(packet) > 8 && codec.IsCommandCodec(packet[8]) {
+			command, err := codec.DecodeCommand(packet)
+			if err != nil {
+				return Packet{}, err
+			}
+			return describeCommand(command), nil
+		}
+
+		decoded, err := codec.DecodeTcp(imei, packet)
+		if err != nil {
+			return Packet{}, err
+		}
+		return describeAvl(TransportTcp, decoded, dictionary), nil
+	case len(packet) > 4 && binary.BigEndian.Uint16(packet[2:4]) == 0xCAFE:
+		decoded, err := codec.Decode(packet)
+		if err != nil {
+			return Packet{}, err
+		}
+		return describeAvl(TransportUdp, decoded, dictionary), nil
+	default:
+		decoded, err := codec.DecodeAvlData(imei, packet)
+		if err != nil {
+			return Packet{}, err
+		}
+		return describeAvl("", decoded, dictionary), nil
+	}
+}
+
+func describeAvl(transport string, decoded codec.Decoded, dictionary ioelement.Dictionary) Packet {
+	packet := Packet{
+		Kind:      KindAvl,
+		Transport: transport,
+		Codec:     CodecName(decoded.CodecID),
+		IMEI:      decoded.IMEI,
+		Records:   make([]Record, 0, len(decoded.Data)),
+	}
+
+	// Bare AVL data is not acknowledged by itself
+	if transport != "" {
+		packet.Response = hex.EncodeToString(decoded.Response)
+	}
+
+	for _, data := range decoded.Data {
+		record := Record{
+			Timestamp:  time.UnixMilli(int64(data.UtimeMs)).UTC(), // #nosec G115
+			Priority:   priorityName(data.Priority),
+			Latitude:   float64(data.Lat) / 10000000,
+			Longitude:  float64(data.Lng) / 10000000,
+			Altitude:   data.Altitude,
+			Angle:      data.Angle,
+			Satellites: data.VisSat,
+			Speed:      data.Speed,
+			EventID:    data.EventID,
+			Elements:   make([]Element, 0, len(data.Elements)),
+		}
+
+		if data.GenerationType != codec.GenerationUnsupported {
+			generationType := data.GenerationType
+			record.GenerationType = &generationType
+		}
+
+		if definition, ok := dictionary.Lookup(data.EventID); ok {
+			record.Event = definition.Name
+		}
+
+		for _, element := range data.Elements {
+			record.Elements = append(record.Elements, describeElement(element, dictionary))
+		}
+
+		packet.Records = append(packet.Records, record)
+	}
+
+	return packet
+}
+
+func describeElement(element codec.Element, dictionary ioelement.Dictionary) Element {
+	described := Element{
+		ID:  element.IOID,
+		Raw: hex.EncodeToString(element.Value),
+	}
+
+	definition, ok := dictionary.Lookup(element.IOID)
+	if !ok {
+		return described
+	}
+
+	described.Name = definition.Name
+	described.Unit = definition.Unit
+
+	value, err := definition.Value(element.Value)
+	if err == nil {
+		described.Value = value
+	}
+
+	return described
+}
+
+func describeCommand(command codec.Command) Packet {
+	described := Command{
+		Payload: string(command.Payload),
+	}
+
+	switch command.Type {
+	case codec.CommandTypeRequest:
+		described.Type = "request"
+	case codec.CommandTypeResponse:
+		described.Type = "response"
+	case codec.CommandTypeNack:
+		described.Type = "nack"
+	}
+
+	if command.CodecID == codec.Codec13 {
+		timestamp := command.Timestamp.UTC()
+		described.Timestamp = &timestamp
+	}
+
+	return Packet{
+		Kind:      KindCommand,
+		Transport: TransportTcp,
+		Codec:     CodecName(command.CodecID),
+		IMEI:      command.IMEI,
+		Command:   &described,
+	}
+}
+
+// CodecName returns the name of the codec as it is used in the Teltonika documentation
+func CodecName(codecID byte) string {
+	switch codecID {
+	case codec.Codec8:
+		return "Codec8"
+	case codec.Codec8Extended:
+		return "Codec8 Extended"
+	case codec.Codec16:
+		return "Codec16"
+	case codec.Codec12:
+		return "Codec12"
+	case codec.Codec13:
+		return "Codec13"
+	case codec.Codec14:
+		return "Codec14"
+	default:
+		return fmt.Sprintf("0x%02X", codecID)
+	}
+}
+
+func priorityName(priority uint8) string {
+	switch priority {
+	case 0:
+		return "low"
+	case 1:
+		return "high"
+	case 2:
+		return "panic"
+	default:
+		return fmt.Sprint(priority)
+	}
+}
@@ -0,0 +1,134 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/halacs/haltonika/ioelement"
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	testCases := []struct {
+		Name              string
+		Packet            string
+		IMEI              string
+		ExpectedKind      string
+		ExpectedTransport string
+		ExpectedCodec     string
+		ExpectedIMEI      string
+		ExpectedRecords   int
+	}{
+		{
+			Name:              "UDP Codec8 Extended",
+			Packet:            "0067cafe016b000f3335303432343036333831373336338e01000001839ecd8a70000b5629e81c5451d0000000000000000000000b000500500000150400c800004502001d00000500422e970018000000cd13f000ce005d00430fd3000100f10000547e0000000001",
+			ExpectedKind:      KindAvl,
+			ExpectedTransport: TransportUdp,
+			ExpectedCodec:     "Codec8 Extended",
+			ExpectedIMEI:      "350424063817363",
+			ExpectedRecords:   1,
+		},
+		{
+			Name:              "TCP Codec8",
+			Packet:            "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF",
+			IMEI:              "356307042441013",
+			ExpectedKind:      KindAvl,
+			ExpectedTransport: TransportTcp,
+			ExpectedCodec:     "Codec8",
+			ExpectedIMEI:      "356307042441013",
+			ExpectedRecords:   1,
+		},
+		{
+			Name:              "AVL data Codec16",
+			Packet:            "10020000016BDBC7833000000000000000000000000000000000000B05040200010000030002000B00270042563A00000000016BDBC7871800000000000000000000000000000000000B05040200010000030002000B00260042563A000002",
+			ExpectedKind:      KindAvl,
+			ExpectedTransport: "",
+			ExpectedCodec:     "Codec16",
+			ExpectedRecords:   2,
+		},
+		{
+			Name:              "Codec14 nACK",
+			Packet:            "00000000000000100E011100000008035209308145225101000032AC",
+			ExpectedKind:      KindCommand,
+			ExpectedTransport: TransportTcp,
+			ExpectedCodec:     "Codec14",
+			ExpectedIMEI:      "352093081452251",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			packet, err := hex.DecodeString(testCase.Packet)
+			if err != nil {
+				test.Fatalf("Incorrect packet. %v", err)
+			}
+
+			described, err := Describe(packet, testCase.IMEI, ioelement.FMBXY)
+			if err != nil {
+				test.Fatalf("Failed to describe packet. %v", err)
+			}
+
+			if described.Kind != testCase.ExpectedKind {
+				test.Errorf("Wrong kind! Expected: %v Actual: %v", testCase.ExpectedKind, described.Kind)
+			}
+			if described.Transport != testCase.ExpectedTransport {
+				test.Errorf("Wrong transport! Expected: %v Actual: %v", testCase.ExpectedTransport, described.Transport)
+			}
+			if described.Codec != testCase.ExpectedCodec {
+				test.Errorf("Wrong codec! Expected: %v Actual: %v", testCase.ExpectedCodec, described.Codec)
+			}
+			if described.IMEI != testCase.ExpectedIMEI {
+				test.Errorf("Wrong IMEI! Expected: %v Actual: %v", testCase.ExpectedIMEI, described.IMEI)
+			}
+			if len(described.Records) != testCase.ExpectedRecords {
+				test.Errorf("Wrong number of records! Expected: %v Actual: %v", testCase.ExpectedRecords, len(described.Records))
+			}
+
+			var output bytes.Buffer
+			err = WriteTable(&output, described)
+			if err != nil {
+				test.Fatalf("Failed to write table. %v", err)
+			}
+			if !strings.Contains(output.String(), testCase.ExpectedCodec) {
+				test.Errorf("Codec is missing from the table:\n%s", output.String())
+			}
+		})
+	}
+}
+
+func TestDescribeElements(t *testing.T) {
+	packet, err := hex.DecodeString("000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF")
+	if err != nil {
+		t.Fatalf("Incorrect packet. %v", err)
+	}
+
+	described, err := Describe(packet, "", ioelement.Dictionary{
+		66: {ID: 66, Name: "External Voltage", Type: ioelement.Unsigned, Multiplier: 0.001, Unit: "V"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to describe packet. %v", err)
+	}
+
+	record := described.Records[0]
+	if record.Priority != "high" {
+		t.Errorf("Wrong priority! Expected: high Actual: %v", record.Priority)
+	}
+
+	for _, element := range record.Elements {
+		switch element.ID {
+		case 66:
+			if element.Name != "External Voltage" || element.Value != 24.079 || element.Unit != "V" || element.Raw != "5e0f" {
+				t.Errorf("Wrong known element: %+v", element)
+			}
+		default:
+			if element.Name != "" || element.Value != nil {
+				t.Errorf("Unknown element must have only raw value: %+v", element)
+			}
+		}
+	}
+
+	_, err = Describe([]byte{0x00, 0x00, 0x00, 0x00, 0x00}, "", ioelement.FMBXY)
+	if err == nil {
+		t.Errorf("Describing a truncated packet must fail")
+	}
+}
@@ -0,0 +1,76 @@
+package inspect
+
+import (
+	"fmt"
+	"github.com/halacs/haltonika/ioelement"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteTable writes the description of the packet as aligned text tables
+func WriteTable(w io.Writer, packet Packet) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Codec:\t%s\n", packet.Codec)
+	if packet.Transport != "" {
+		fmt.Fprintf(tw, "Transport:\t%s\n", packet.Transport)
+	}
+	if packet.IMEI != "" {
+		fmt.Fprintf(tw, "IMEI:\t%s\n", packet.IMEI)
+	}
+	if packet.Response != "" {
+		fmt.Fprintf(tw, "Response:\t%s\n", packet.Response)
+	}
+
+	if packet.Command != nil {
+		fmt.Fprintf(tw, "Type:\t%s\n", packet.Command.Type)
+		if packet.Command.Timestamp != nil {
+			fmt.Fprintf(tw, "Timestamp:\t%s\n", packet.Command.Timestamp.Format(time.RFC3339))
+		}
+		fmt.Fprintf(tw, "Payload:\t%s\n", packet.Command.Payload)
+	}
+
+	if packet.Records != nil {
+		fmt.Fprintf(tw, "Records:\t%d\n", len(packet.Records))
+	}
+
+	for i, record := range packet.Records {
+		fmt.Fprintf(tw, "\nRecord %d\n", i+1)
+		fmt.Fprintf(tw, "Timestamp:\t%s\n", record.Timestamp.Format(time.RFC3339Nano))
+		fmt.Fprintf(tw, "Priority:\t%s\n", record.Priority)
+		fmt.Fprintf(tw, "Position:\t%s, %s\n", formatCoordinate(record.Latitude), formatCoordinate(record.Longitude))
+		fmt.Fprintf(tw, "Altitude:\t%d m\n", record.Altitude)
+		fmt.Fprintf(tw, "Angle:\t%d°\n", record.Angle)
+		fmt.Fprintf(tw, "Satellites:\t%d\n", record.Satellites)
+		fmt.Fprintf(tw, "Speed:\t%d km/h\n", record.Speed)
+		if record.Event != "" {
+			fmt.Fprintf(tw, "Event:\t%d (%s)\n", record.EventID, record.Event)
+		} else {
+			fmt.Fprintf(tw, "Event:\t%d\n", record.EventID)
+		}
+		if record.GenerationType != nil {
+			fmt.Fprintf(tw, "Generation type:\t%d\n", *record.GenerationType)
+		}
+
+		fmt.Fprintln(tw, "\nID\tName\tRaw\tValue")
+		for _, element := range record.Elements {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", element.ID, element.Name, element.Raw, formatValue(element))
+		}
+	}
+
+	return tw.Flush()
+}
+
+func formatCoordinate(coordinate float64) string {
+	return strconv.FormatFloat(coordinate, 'f', 7, 64)
+}
+
+func formatValue(element Element) string {
+	if element.Value == nil {
+		return ""
+	}
+
+	return ioelement.FormatValue(element.Value, element.Unit)
+}
@@ -0,0 +1,134 @@
+// Code generated by gen.go from teltonikajson/FM11XY.json; DO NOT EDIT.
+
+package ioelement
+
+// FM11XY is the dictionary of the IO elements of FM11XY and FM12XY devices
+// https://wiki.teltonika-gps.com/view/FM1100_AVL_ID_List
+var FM11XY = Dictionary{
+	1:   {ID: 1, Name: "Digital Input Status 1", Bytes: 1, Type: Unsigned},
+	2:   {ID: 2, Name: "Digital Input Status 2", Bytes: 1, Type: Unsigned},
+	3:   {ID: 3, Name: "Digital Input Status 3", Bytes: 1, Type: Unsigned},
+	9:   {ID: 9, Name: "Analog Input 1", Bytes: 2, Type: Unsigned},
+	16:  {ID: 16, Name: "Total distance", Bytes: 4, Type: Unsigned},
+	21:  {ID: 21, Name: "GSM level", Bytes: 1, Type: Unsigned},
+	24:  {ID: 24, Name: "Speed", Bytes: 2, Type: Unsigned},
+	66:  {ID: 66, Name: "External Power Voltage", Bytes: 2, Type: Unsigned},
+	69:  {ID: 69, Name: "GPS Status", Bytes: 1, Type: Unsigned},
+	71:  {ID: 71, Name: "Dallas Temperature ID 4", Bytes: 8, Type: Hex},
+	72:  {ID: 72, Name: "Dallas Temperature 1", Bytes: 4, Type: Hex},
+	73:  {ID: 73, Name: "Dallas Temperature 2", Bytes: 4, Type: Hex},
+	74:  {ID: 74, Name: "Dallas Temperature 3", Bytes: 4, Type: Hex},
+	75:  {ID: 75, Name: "Dallas Temperature 4", Bytes: 4, Type: Hex},
+	76:  {ID: 76, Name: "Dallas Temperature ID 1", Bytes: 8, Type: Hex},
+	77:  {ID: 77, Name: "Dallas Temperature ID 2", Bytes: 8, Type: Hex},
+	78:  {ID: 78, Name: "iButton ID", Bytes: 8, Type: Hex},
+	79:  {ID: 79, Name: "Dallas Temperature ID 3", Bytes: 8, Type: Hex},
+	80:  {ID: 80, Name: "Data Mode", Bytes: 1, Type: Unsigned},
+	81:  {ID: 81, Name: "LVCAN Speed", Bytes: 1, Type: Unsigned},
+	82:  {ID: 82, Name: "LVCAN Accelerator Pedal Position", Bytes: 1, Type: Unsigned},
+	83:  {ID: 83, Name: "LVCAN Total Fuel Used", Bytes: 4, Type: Unsigned},
+	84:  {ID: 84, Name: "LVCAN Fuel Level (liters)", Bytes: 2, Type: Unsigned},
+	85:  {ID: 85, Name: "LVCAN Engine RPM", Bytes: 2, Type: Unsigned},
+	87:  {ID: 87, Name: "LVCAN Vehicle Distance", Bytes: 4, Type: Unsigned},
+	89:  {ID: 89, Name: "LVCAN Fuel Level (percentage)", Bytes: 1, Type: Unsigned},
+	90:  {ID: 90, Name: "LVCAN Door Status", Bytes: 2, Type: Unsigned},
+	100: {ID: 100, Name: "LVCAN Program Number", Bytes: 4, Type: Unsigned},
+	101: {ID: 101, Name: "LVC ModuleID", Bytes: 8, Type: Hex},
+	102: {ID: 102, Name: "LVC Engine Work Time", Bytes: 4, Type: Unsigned},
+	103: {ID: 103, Name: "LVC Engine Work Time (counted)", Bytes: 4, Type: Unsigned},
+	105: {ID: 105, Name: "LVC Total Mileage (counted)", Bytes: 4, Type: Unsigned},
+	107: {ID: 107, Name: "LVC Fuel Consumed (counted)", Bytes: 4, Type: Unsigned},
+	110: {ID: 110, Name: "LVC Fuel Rate", Bytes: 2, Type: Unsigned},
+	111: {ID: 111, Name: "LVC AdBlue Level (percent)", Bytes: 1, Type: Unsigned},
+	112: {ID: 112, Name: "LVC AdBlue Level (liters)", Bytes: 2, Type: Signed},
+	114: {ID: 114, Name: "LVC Engine Load", Bytes: 1, Type: Unsigned},
+	115: {ID: 115, Name: "LVC Engine Temperature", Bytes: 2, Type: Signed},
+	118: {ID: 118, Name: "LVC Axle 1 Load", Bytes: 2, Type: Unsigned},
+	119: {ID: 119, Name: "LVC Axle 2 Load", Bytes: 2, Type: Unsigned},
+	120: {ID: 120, Name: "LVC Axle 3 Load", Bytes: 2, Type: Unsigned},
+	121: {ID: 121, Name: "LVC Axle 4 Load", Bytes: 2, Type: Unsigned},
+	122: {ID: 122, Name: "LVC Axle 5 Load", Bytes: 2, Type: Unsigned},
+	123: {ID: 123, Name: "LVC Control State Flags", Bytes: 4, Type: Hex},
+	124: {ID: 124, Name: "LVC Agricultural Machinery Flags", Bytes: 8, Type: Hex},
+	125: {ID: 125, Name: "LVC Harvesting Time", Bytes: 4, Type: Unsigned},
+	126: {ID: 126, Name: "LVC Area of Harvest", Bytes: 4, Type: Unsigned},
+	127: {ID: 127, Name: "LVC Mowing Efficiency", Bytes: 4, Type: Unsigned},
+	128: {ID: 128, Name: "LVC Grain Mown Volume", Bytes: 4, Type: Unsigned},
+	129: {ID: 129, Name: "LVC Grain Moisture", Bytes: 2, Type: Unsigned},
+	130: {ID: 130, Name: "LVC Harvesting Drum RPM", Bytes: 2, Type: Unsigned},
+	131: {ID: 131, Name: "LVC Gap Under Harvesting Drum", Bytes: 1, Type: Unsigned},
+	132: {ID: 132, Name: "LVC Security State Flags", Bytes: 8, Type: Hex},
+	133: {ID: 133, Name: "LVC Tacho Total Vehicle Distance", Bytes: 4, Type: Unsigned},
+	134: {ID: 134, Name: "LVC Trip Distance", Bytes: 4, Type: Unsigned},
+	135: {ID: 135, Name: "LVC Tacho Vehicle Speed", Bytes: 2, Type: Unsigned},
+	136: {ID: 136, Name: "LVC Tacho Driver Card Presence", Bytes: 1, Type: Unsigned},
+	137: {ID: 137, Name: "LVC Driver1 States", Bytes: 1, Type: Unsigned},
+	138: {ID: 138, Name: "LVC Driver2 States", Bytes: 1, Type: Unsigned},
+	139: {ID: 139, Name: "LVC Driver1 Continuous Driving Time", Bytes: 2, Type: Unsigned},
+	140: {ID: 140, Name: "LVC Driver2 Continuous Driving Time", Bytes: 2, Type: Unsigned},
+	141: {ID: 141, Name: "LVC Driver1 Cumulative Break Time", Bytes: 2, Type: Unsigned},
+	142: {ID: 142, Name: "LVC Driver2 Cumulative", Bytes: 2, Type: Unsigned},
+	143: {ID: 143, Name: "LVC Driver1 Duration Of Selected Activity", Bytes: 2, Type: Unsigned},
+	144: {ID: 144, Name: "LVC Driver2 Duration Of Selected Activity", Bytes: 2, Type: Unsigned},
+	145: {ID: 145, Name: "LVC Driver1 Cumulative Driving Time", Bytes: 2, Type: Unsigned},
+	146: {ID: 146, Name: "LVC Driver2 Cumulative Driving Time", Bytes: 2, Type: Unsigned},
+	147: {ID: 147, Name: "LVC Driver1 ID High", Bytes: 8, Type: Hex},
+	148: {ID: 148, Name: "LVC Driver1 ID Low", Bytes: 8, Type: Hex},
+	149: {ID: 149, Name: "LVC Driver2 ID High", Bytes: 8, Type: Hex},
+	150: {ID: 150, Name: "LVC Driver2 ID Low", Bytes: 8, Type: Hex},
+	151: {ID: 151, Name: "LVC Battery Temperature", Bytes: 2, Type: Signed},
+	152: {ID: 152, Name: "LVC Battery Level (percent)", Bytes: 1, Type: Unsigned},
+	155: {ID: 155, Name: "Geofence zone 01", Bytes: 1, Type: Unsigned},
+	156: {ID: 156, Name: "Geofence zone 02", Bytes: 1, Type: Unsigned},
+	157: {ID: 157, Name: "Geofence zone 03", Bytes: 1, Type: Unsigned},
+	158: {ID: 158, Name: "Geofence zone 04", Bytes: 1, Type: Unsigned},
+	159: {ID: 159, Name: "Geofence zone 05", Bytes: 1, Type: Unsigned},
+	160: {ID: 160, Name: "LVC DTC Errors", Bytes: 1, Type: Unsigned},
+	161: {ID: 161, Name: "LVC Slope Of Arm", Bytes: 2, Type: Unsigned},
+	162: {ID: 162, Name: "LVC Rotation Of Arm", Bytes: 2, Type: Unsigned},
+	163: {ID: 163, Name: "LVC Eject Of Arm", Bytes: 2, Type: Unsigned},
+	164: {ID: 164, Name: "LVC Horizontal Distance Arm Vechicle", Bytes: 2, Type: Unsigned},
+	165: {ID: 165, Name: "LVC Height Arm Above Ground", Bytes: 2, Type: Unsigned},
+	166: {ID: 166, Name: "LVC Drill RPM", Bytes: 2, Type: Unsigned},
+	167: {ID: 167, Name: "LVC Amount Of Spread Salt Square Meter", Bytes: 2, Type: Unsigned},
+	168: {ID: 168, Name: "LVC Battery Voltage", Bytes: 2, Type: Unsigned},
+	169: {ID: 169, Name: "LVC Amount Spread Fine Grained Salt", Bytes: 4, Type: Unsigned},
+	170: {ID: 170, Name: "LVC Amount Spread Coarse Grained Salt", Bytes: 4, Type: Unsigned},
+	171: {ID: 171, Name: "LVC Amount Spread DiMix", Bytes: 4, Type: Unsigned},
+	172: {ID: 172, Name: "LVC Amount Spread Coarse Grained Calcium", Bytes: 4, Type: Unsigned},
+	173: {ID: 173, Name: "LVC Amount Spread Calcium Chloride", Bytes: 4, Type: Unsigned},
+	174: {ID: 174, Name: "LVC Amount Spread Sodium Chloride", Bytes: 4, Type: Unsigned},
+	175: {ID: 175, Name: "Auto Geofence", Bytes: 1, Type: Unsigned},
+	176: {ID: 176, Name: "LVC Amount Spread Magnesium Chloride", Bytes: 4, Type: Unsigned},
+	177: {ID: 177, Name: "LVC Amount Spread Gravel", Bytes: 4, Type: Unsigned},
+	178: {ID: 178, Name: "LVC Amount Spread Sand", Bytes: 4, Type: Unsigned},
+	179: {ID: 179, Name: "Digital Output 1 state", Bytes: 1, Type: Unsigned},
+	180: {ID: 180, Name: "Digital Output 2 state", Bytes: 1, Type: Unsigned},
+	181: {ID: 181, Name: "PDOP", Bytes: 2, Type: Unsigned},
+	182: {ID: 182, Name: "HDOP", Bytes: 2, Type: Unsigned},
+	183: {ID: 183, Name: "LVC Width Pouring Left", Bytes: 2, Type: Unsigned},
+	184: {ID: 184, Name: "LVC Width Pouring Right", Bytes: 2, Type: Unsigned},
+	185: {ID: 185, Name: "LVC Salt Spreader Work", Bytes: 4, Type: Unsigned},
+	186: {ID: 186, Name: "LVC Distance During Salting", Bytes: 4, Type: Unsigned},
+	187: {ID: 187, Name: "LVC Load Weight", Bytes: 4, Type: Unsigned},
+	188: {ID: 188, Name: "LVC Retarder Load", Bytes: 1, Type: Unsigned},
+	189: {ID: 189, Name: "LVC Cruise Time", Bytes: 4, Type: Unsigned},
+	190: {ID: 190, Name: "LVC CNG Status", Bytes: 1, Type: Unsigned},
+	191: {ID: 191, Name: "LVC CNG Used", Bytes: 4, Type: Unsigned},
+	192: {ID: 192, Name: "LVC CNG Level", Bytes: 2, Type: Unsigned},
+	193: {ID: 193, Name: "LVC Oil level", Bytes: 1, Type: Unsigned},
+	199: {ID: 199, Name: "Odometer/Trip Distance", Bytes: 4, Type: Unsigned},
+	200: {ID: 200, Name: "Deep Sleep", Bytes: 1, Type: Unsigned},
+	205: {ID: 205, Name: "Cell ID", Bytes: 2, Type: Unsigned},
+	206: {ID: 206, Name: "Area Code", Bytes: 2, Type: Unsigned},
+	239: {ID: 239, Name: "Ignition", Bytes: 1, Type: Unsigned},
+	240: {ID: 240, Name: "Movement Sensor", Bytes: 1, Type: Unsigned},
+	241: {ID: 241, Name: "GSM Operator Code", Bytes: 4, Type: Unsigned},
+	249: {ID: 249, Name: "Jamming", Bytes: 1, Type: Unsigned},
+	250: {ID: 250, Name: "Trip", Bytes: 1, Type: Unsigned},
+	251: {ID: 251, Name: "Immobilizer", Bytes: 1, Type: Unsigned},
+	252: {ID: 252, Name: "Authorized driving", Bytes: 1, Type: Unsigned},
+	253: {ID: 253, Name: "Green driving type", Bytes: 1, Type: Unsigned},
+	254: {ID: 254, Name: "Green driving value", Bytes: 1, Type: Unsigned},
+	255: {ID: 255, Name: "Over Speeding", Bytes: 1, Type: Unsigned},
+}
@@ -0,0 +1,144 @@
+// Code generated by gen.go from teltonikajson/FM36.json; DO NOT EDIT.
+
+package ioelement
+
+// FM36 is the dictionary of the IO elements of FM36XY devices
+// https://wiki.teltonika-gps.com/view/FM3612_AVL_ID_List
+var FM36 = Dictionary{
+	1:   {ID: 1, Name: "Digital Input Status 1", Bytes: 1, Type: Unsigned},
+	2:   {ID: 2, Name: "Digital Input Status 2", Bytes: 1, Type: Unsigned},
+	3:   {ID: 3, Name: "Digital Input Status 3", Bytes: 1, Type: Unsigned},
+	4:   {ID: 4, Name: "Digital Input Status 4", Bytes: 1, Type: Unsigned},
+	9:   {ID: 9, Name: "Analog Input 1", Bytes: 2, Type: Unsigned, Unit: "mV"},
+	10:  {ID: 10, Name: "Analog Input 2", Bytes: 2, Type: Unsigned, Unit: "mV"},
+	21:  {ID: 21, Name: "GSM level", Bytes: 1, Type: Unsigned},
+	24:  {ID: 24, Name: "Speed", Bytes: 2, Type: Unsigned, Unit: "km/h"},
+	66:  {ID: 66, Name: "External Power Voltage", Bytes: 2, Type: Unsigned, Unit: "mV"},
+	67:  {ID: 67, Name: "Battery Voltage", Bytes: 2, Type: Unsigned, Unit: "mV"},
+	68:  {ID: 68, Name: "Battery Current", Bytes: 2, Type: Unsigned, Unit: "mA"},
+	69:  {ID: 69, Name: "GNSS Status", Bytes: 1, Type: Unsigned},
+	72:  {ID: 72, Name: "Dallas Temperature 1", Bytes: 2, Type: Signed, Multiplier: 10, Unit: "°C"},
+	73:  {ID: 73, Name: "Dallas Temperature 2", Bytes: 2, Type: Signed, Multiplier: 10, Unit: "°C"},
+	74:  {ID: 74, Name: "Dallas Temperature 3", Bytes: 2, Type: Signed, Multiplier: 10, Unit: "°C"},
+	75:  {ID: 75, Name: "Dallas Temperature Sensor ID1", Bytes: 8, Type: Hex},
+	76:  {ID: 76, Name: "Dallas Temperature Sensor ID2", Bytes: 8, Type: Hex},
+	77:  {ID: 77, Name: "Dallas Temperature Sensor ID3", Bytes: 8, Type: Hex},
+	78:  {ID: 78, Name: "iButton ID", Bytes: 8, Type: Hex},
+	79:  {ID: 79, Name: "Network type", Bytes: 1, Type: Unsigned},
+	80:  {ID: 80, Name: "Working Mode", Bytes: 1, Type: Unsigned},
+	81:  {ID: 81, Name: "LVCAN Speed", Bytes: 1, Type: Unsigned, Unit: "km/h"},
+	82:  {ID: 82, Name: "LVCAN Accelerator Pedal Position", Bytes: 1, Type: Unsigned, Multiplier: 0.1, Unit: "%"},
+	83:  {ID: 83, Name: "LVCAN Fuel Consumed", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "Ltr"},
+	84:  {ID: 84, Name: "LVCAN Fuel Level (liters)", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "Ltr"},
+	85:  {ID: 85, Name: "LVCAN Engine RPM", Bytes: 2, Type: Unsigned},
+	87:  {ID: 87, Name: "LVCAN Total Mileage", Bytes: 4, Type: Unsigned, Unit: "m"},
+	89:  {ID: 89, Name: "LVCAN Fuel Level (percentage)", Bytes: 1, Type: Unsigned, Multiplier: 0.1, Unit: "%"},
+	90:  {ID: 90, Name: "LVCAN Door Status", Bytes: 2, Type: Unsigned},
+	99:  {ID: 99, Name: "Continuous odometer", Bytes: 4, Type: Unsigned, Unit: "m"},
+	100: {ID: 100, Name: "LVCAN Program Number", Bytes: 4, Type: Unsigned},
+	101: {ID: 101, Name: "LVCAN ModuleID", Bytes: 8, Type: Hex},
+	102: {ID: 102, Name: "LVCAN Engine Work Time", Bytes: 4, Type: Unsigned, Unit: "Min"},
+	103: {ID: 103, Name: "LVCAN Engine Work Time (counted)", Bytes: 4, Type: Unsigned, Unit: "Min"},
+	104: {ID: 104, Name: "LVCAN Total Mileage", Bytes: 4, Type: Unsigned, Unit: "m"},
+	105: {ID: 105, Name: "LVCAN Total Mileage (counted)", Bytes: 4, Type: Unsigned, Unit: "m"},
+	106: {ID: 106, Name: "LVCAN Fuel Consumed", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "Ltr"},
+	107: {ID: 107, Name: "LVCAN Fuel Consumed (counted)", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "Ltr"},
+	108: {ID: 108, Name: "LVCAN Fuel Level (percent)", Bytes: 1, Type: Unsigned, Multiplier: 0.1, Unit: "%"},
+	109: {ID: 109, Name: "LVCAN Fuel Level (liters)", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "Ltr"},
+	110: {ID: 110, Name: "LVCAN Fuel Rate", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "Ltr/h"},
+	111: {ID: 111, Name: "LVCAN AdBlue Level (percent)", Bytes: 1, Type: Unsigned, Multiplier: 0.1, Unit: "%"},
+	112: {ID: 112, Name: "LVCAN AdBlue Level (liters)", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "Ltr"},
+	113: {ID: 113, Name: "LVCAN Engine RPM", Bytes: 2, Type: Unsigned},
+	114: {ID: 114, Name: "LVCAN Engine Load", Bytes: 1, Type: Unsigned, Unit: "%"},
+	115: {ID: 115, Name: "LVCAN Engine Temperature", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	116: {ID: 116, Name: "LVCAN Accelerator Pedal Position", Bytes: 1, Type: Unsigned, Multiplier: 0.1, Unit: "%"},
+	117: {ID: 117, Name: "LVCAN Vehicle Speed", Bytes: 1, Type: Unsigned, Unit: "km/h"},
+	118: {ID: 118, Name: "LVCAN Axle 1 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	119: {ID: 119, Name: "LVCAN Axle 2 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	120: {ID: 120, Name: "LVCAN Axle 3 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	121: {ID: 121, Name: "LVCAN Axle 4 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	122: {ID: 122, Name: "LVCAN Axle 5 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	123: {ID: 123, Name: "LVCAN Control State Flags", Bytes: 4, Type: Unsigned},
+	124: {ID: 124, Name: "LVCAN Agricultural Machinery Flags", Bytes: 8, Type: Hex},
+	125: {ID: 125, Name: "LVCAN Harvesting Time", Bytes: 4, Type: Unsigned, Unit: "min"},
+	126: {ID: 126, Name: "LVCAN Area of Harvest", Bytes: 4, Type: Unsigned, Unit: "m2"},
+	127: {ID: 127, Name: "LVCAN Mowing Efficiency", Bytes: 4, Type: Unsigned, Unit: "m2/h"},
+	128: {ID: 128, Name: "LVCAN Grain Mown Volume", Bytes: 4, Type: Unsigned, Unit: "kg"},
+	129: {ID: 129, Name: "LVCAN Grain Moisture", Bytes: 1, Type: Unsigned},
+	130: {ID: 130, Name: "LVCAN Harvesting Drum RPM", Bytes: 2, Type: Unsigned},
+	131: {ID: 131, Name: "LVCAN Gap Under Harvesting Drum", Bytes: 1, Type: Unsigned, Unit: "mm"},
+	132: {ID: 132, Name: "LVCAN Security State Flags", Bytes: 8, Type: Hex},
+	133: {ID: 133, Name: "LVCAN Tacho Total Vehicle Distance", Bytes: 4, Type: Unsigned, Unit: "m"},
+	134: {ID: 134, Name: "LVCAN Trip Distance", Bytes: 4, Type: Unsigned, Unit: "m"},
+	135: {ID: 135, Name: "LVCAN Tacho Vehicle Speed", Bytes: 2, Type: Unsigned, Unit: "km/h"},
+	136: {ID: 136, Name: "LVCAN Tacho Driver Card Presence", Bytes: 1, Type: Unsigned},
+	137: {ID: 137, Name: "LVCAN Driver1 States", Bytes: 1, Type: Unsigned},
+	138: {ID: 138, Name: "LVCAN Driver2 States", Bytes: 1, Type: Unsigned},
+	139: {ID: 139, Name: "LVCAN Driver1 Continuous Driving Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	140: {ID: 140, Name: "LVCAN Driver2 Continuous Driving Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	141: {ID: 141, Name: "LVCAN Driver1 Cumulative Break Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	142: {ID: 142, Name: "LVCAN Driver2 Cumulative Break Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	143: {ID: 143, Name: "LVCAN Driver1 Duration Of Selected Activity", Bytes: 2, Type: Unsigned, Unit: "min"},
+	144: {ID: 144, Name: "LVCAN Driver2 Duration Of Selected Activity", Bytes: 2, Type: Unsigned, Unit: "min"},
+	145: {ID: 145, Name: "LVCAN Driver1 Cumulative Driving Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	146: {ID: 146, Name: "LVCAN Driver2 Cumulative Driving Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	147: {ID: 147, Name: "LVCAN Driver1 ID High", Bytes: 8, Type: Hex, Unit: "min"},
+	148: {ID: 148, Name: "LVCAN Driver1 ID Low", Bytes: 8, Type: Hex, Unit: "min"},
+	149: {ID: 149, Name: "LVCAN Driver2 ID High", Bytes: 8, Type: Hex, Unit: "min"},
+	150: {ID: 150, Name: "LVCAN Driver2 ID Low", Bytes: 8, Type: Hex, Unit: "min"},
+	151: {ID: 151, Name: "LVCAN Battery Temperature", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	152: {ID: 152, Name: "LVCAN Battery Level (percent)", Bytes: 1, Type: Unsigned, Multiplier: 0.1, Unit: "%"},
+	155: {ID: 155, Name: "Geofence zone 01", Bytes: 1, Type: Unsigned},
+	156: {ID: 156, Name: "Geofence zone 02", Bytes: 1, Type: Unsigned},
+	157: {ID: 157, Name: "Geofence zone 03", Bytes: 1, Type: Unsigned},
+	158: {ID: 158, Name: "Geofence zone 04", Bytes: 1, Type: Unsigned},
+	159: {ID: 159, Name: "Geofence zone 05", Bytes: 1, Type: Unsigned},
+	160: {ID: 160, Name: "LVCAN DTC Errors", Bytes: 1, Type: Unsigned},
+	161: {ID: 161, Name: "LVCAN Slope of Arm", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°"},
+	162: {ID: 162, Name: "LVCAN Rotation of Arm", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°"},
+	163: {ID: 163, Name: "LVCAN Eject of Arm", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "m"},
+	164: {ID: 164, Name: "LVCAN Horizontal Dist Arm Vechicle", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "m"},
+	165: {ID: 165, Name: "LVCAN Height Arm Above Ground", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "m"},
+	166: {ID: 166, Name: "LVC Drill RPM", Bytes: 2, Type: Unsigned},
+	167: {ID: 167, Name: "LVC Amount Of Spread Salt Square Meter", Bytes: 2, Type: Unsigned, Multiplier: 0.01, Unit: "m"},
+	168: {ID: 168, Name: "LVC Battery Voltage", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "V"},
+	169: {ID: 169, Name: "LVC Amount Spread Fine Grained Salt", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "t"},
+	170: {ID: 170, Name: "LVCAN Amount Spread Coarse Grained Salt", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "t"},
+	171: {ID: 171, Name: "LVCAN Amount Spread DiMix", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "t"},
+	172: {ID: 172, Name: "LVCAN Amount Spread Coarse Grained Calc", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "m3"},
+	173: {ID: 173, Name: "LVCAN Amount Spread Calcium Chloride", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "m3"},
+	174: {ID: 174, Name: "LVCAN Amount Spread Sodium Chloride", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "m3"},
+	175: {ID: 175, Name: "Auto Geofence", Bytes: 1, Type: Unsigned},
+	176: {ID: 176, Name: "LVCAN Amount Spread Magnesium Chloride", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "m3"},
+	177: {ID: 177, Name: "Idling", Bytes: 1, Type: Unsigned},
+	178: {ID: 178, Name: "LVCAN Amount Spread Sand", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "t"},
+	179: {ID: 179, Name: "Digital Output 1 state", Bytes: 1, Type: Unsigned},
+	180: {ID: 180, Name: "Digital Output 2 state", Bytes: 1, Type: Unsigned},
+	181: {ID: 181, Name: "PDOP", Bytes: 2, Type: Unsigned, Multiplier: 0.1},
+	182: {ID: 182, Name: "HDOP", Bytes: 2, Type: Unsigned, Multiplier: 0.1},
+	183: {ID: 183, Name: "LVCAN Width Pouring Left", Bytes: 2, Type: Unsigned, Multiplier: 0.01, Unit: "m"},
+	184: {ID: 184, Name: "LVCAN Width Pouring Right", Bytes: 2, Type: Unsigned, Multiplier: 0.01, Unit: "m"},
+	185: {ID: 185, Name: "LVCAN Salt Spreader Work Hours", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "h"},
+	186: {ID: 186, Name: "LVCAN Distance During Salting", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "km"},
+	187: {ID: 187, Name: "LVCAN Load Weight", Bytes: 4, Type: Unsigned, Unit: "kg"},
+	188: {ID: 188, Name: "LVC Retarder Load", Bytes: 1, Type: Unsigned, Unit: "%"},
+	189: {ID: 189, Name: "LVC Cruise Time", Bytes: 4, Type: Unsigned, Unit: "min"},
+	190: {ID: 190, Name: "LVC CNG Status", Bytes: 1, Type: Unsigned},
+	191: {ID: 191, Name: "LVC CNG Used", Bytes: 4, Type: Unsigned, Unit: "kg"},
+	192: {ID: 192, Name: "LVC CNG Level", Bytes: 2, Type: Unsigned, Unit: "%"},
+	193: {ID: 193, Name: "LVCAN Amount Spread Gravel", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "t"},
+	199: {ID: 199, Name: "Odometer Value (Virtual Odometer)", Bytes: 4, Type: Unsigned, Unit: "m"},
+	200: {ID: 200, Name: "Deep Sleep", Bytes: 1, Type: Unsigned},
+	205: {ID: 205, Name: "Cell ID", Bytes: 4, Type: Unsigned},
+	206: {ID: 206, Name: "Area Code", Bytes: 2, Type: Unsigned},
+	239: {ID: 239, Name: "Ignition", Bytes: 1, Type: Unsigned},
+	240: {ID: 240, Name: "Movement Sensor", Bytes: 1, Type: Unsigned},
+	241: {ID: 241, Name: "GSM Operator Code", Bytes: 4, Type: Unsigned},
+	249: {ID: 249, Name: "Jamming detection", Bytes: 1, Type: Unsigned},
+	250: {ID: 250, Name: "Trip", Bytes: 1, Type: Unsigned},
+	251: {ID: 251, Name: "Immobilizer", Bytes: 1, Type: Unsigned},
+	252: {ID: 252, Name: "Authorized driving", Bytes: 1, Type: Unsigned},
+	253: {ID: 253, Name: "Green driving type", Bytes: 1, Type: Unsigned},
+	254: {ID: 254, Name: "Green driving value", Bytes: 1, Type: Unsigned},
+	255: {ID: 255, Name: "Over Speeding", Bytes: 1, Type: Unsigned},
+}
@@ -0,0 +1,406 @@
+// Code generated by gen.go from teltonikajson/FM64.json; DO NOT EDIT.
+
+package ioelement
+
+// FM64 is the dictionary of the IO elements of FM6XYZ devices
+// https://wiki.teltonika-gps.com/view/FM6300_AVL_ID_List
+var FM64 = Dictionary{
+	1:     {ID: 1, Name: "Digital Input 1", Bytes: 1, Type: Unsigned},
+	2:     {ID: 2, Name: "Digital Input 2", Bytes: 1, Type: Unsigned},
+	3:     {ID: 3, Name: "Digital Input 3", Bytes: 1, Type: Unsigned},
+	4:     {ID: 4, Name: "Digital Input 4", Bytes: 1, Type: Unsigned},
+	5:     {ID: 5, Name: "Dallas Temperature ID 5", Bytes: 8, Type: Hex},
+	6:     {ID: 6, Name: "Dallas Temperature 5", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	7:     {ID: 7, Name: "Dallas Temperature ID 5", Bytes: 8, Type: Hex},
+	8:     {ID: 8, Name: "Dallas Temperature 5", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	9:     {ID: 9, Name: "Analog Input 1", Bytes: 2, Type: Unsigned, Multiplier: 0.001, Unit: "V"},
+	10:    {ID: 10, Name: "Analog Input 2", Bytes: 2, Type: Unsigned, Multiplier: 0.001, Unit: "V"},
+	11:    {ID: 11, Name: "Analog Input 3", Bytes: 2, Type: Unsigned, Multiplier: 0.001, Unit: "V"},
+	12:    {ID: 12, Name: "Program Number", Bytes: 4, Type: Unsigned},
+	13:    {ID: 13, Name: "Module ID", Bytes: 8, Type: Hex},
+	14:    {ID: 14, Name: "Engine Worktime", Bytes: 4, Type: Unsigned, Unit: "min"},
+	15:    {ID: 15, Name: "Engine Worktime (counted)", Bytes: 4, Type: Unsigned, Unit: "min"},
+	16:    {ID: 16, Name: "Total Mileage (counted)", Bytes: 4, Type: Unsigned, Unit: "m"},
+	17:    {ID: 17, Name: "Fuel Consumed (counted)", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "l"},
+	18:    {ID: 18, Name: "Fuel Rate", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "l/h"},
+	19:    {ID: 19, Name: "AdBlue Level Percent", Bytes: 1, Type: Unsigned, Multiplier: 0.1, Unit: "%"},
+	20:    {ID: 20, Name: "AdBlue Level Liters", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "l"},
+	21:    {ID: 21, Name: "GSM Signal", Bytes: 1, Type: Unsigned},
+	22:    {ID: 22, Name: "Data Mode", Bytes: 1, Type: Unsigned},
+	23:    {ID: 23, Name: "Engine Load", Bytes: 1, Type: Unsigned, Unit: "%"},
+	24:    {ID: 24, Name: "Speed", Bytes: 2, Type: Unsigned, Unit: "km/h"},
+	25:    {ID: 25, Name: "Engine Temperature", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	26:    {ID: 26, Name: "Axle 1 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	27:    {ID: 27, Name: "Axle 2 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	28:    {ID: 28, Name: "Axle 3 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	29:    {ID: 29, Name: "Axle 4 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	30:    {ID: 30, Name: "Vehicle Speed", Bytes: 1, Type: Unsigned, Unit: "km/h"},
+	31:    {ID: 31, Name: "Accelerator Pedal Position", Bytes: 1, Type: Unsigned, Multiplier: 0.1, Unit: "%"},
+	32:    {ID: 32, Name: "Axle 5 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	33:    {ID: 33, Name: "Fuel Consumed", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "l"},
+	34:    {ID: 34, Name: "Fuel Level Liters", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "l"},
+	35:    {ID: 35, Name: "Engine RPM", Bytes: 2, Type: Unsigned, Unit: "rpm"},
+	36:    {ID: 36, Name: "Total Mileage", Bytes: 4, Type: Unsigned, Unit: "m"},
+	37:    {ID: 37, Name: "Fuel Level Percent", Bytes: 1, Type: Unsigned, Multiplier: 0.1, Unit: "%"},
+	38:    {ID: 38, Name: "Control State Flags", Bytes: 4, Type: Unsigned},
+	39:    {ID: 39, Name: "Agricultural Machinery Flags", Bytes: 8, Type: Hex},
+	40:    {ID: 40, Name: "Harvesting Time", Bytes: 4, Type: Unsigned, Unit: "min"},
+	41:    {ID: 41, Name: "Area of Harvest", Bytes: 4, Type: Unsigned, Unit: "m2"},
+	42:    {ID: 42, Name: "Mowing Efficiency", Bytes: 4, Type: Unsigned, Unit: "m2/h"},
+	43:    {ID: 43, Name: "Grain Mown Volume", Bytes: 4, Type: Unsigned, Unit: "kg"},
+	44:    {ID: 44, Name: "Grain Moisture", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "%"},
+	45:    {ID: 45, Name: "Harvesting Drum RPM", Bytes: 2, Type: Unsigned, Unit: "rpm"},
+	46:    {ID: 46, Name: "Gap Under Harvesting Drum", Bytes: 1, Type: Unsigned, Unit: "mm"},
+	47:    {ID: 47, Name: "Security State Flags", Bytes: 8, Type: Hex},
+	48:    {ID: 48, Name: "Tacho Data Source", Bytes: 1, Type: Unsigned},
+	50:    {ID: 50, Name: "Digital Output 3", Bytes: 1, Type: Unsigned},
+	51:    {ID: 51, Name: "Digital Output 4", Bytes: 1, Type: Unsigned},
+	56:    {ID: 56, Name: "Driver 1 Continuous Driving Time", Bytes: 2, Type: Unsigned},
+	57:    {ID: 57, Name: "Driver 2 Continuous Driving Time", Bytes: 2, Type: Unsigned},
+	58:    {ID: 58, Name: "Driver 1 Cumulative Break Time", Bytes: 2, Type: Unsigned},
+	59:    {ID: 59, Name: "Driver 2 Cumulative Break Time", Bytes: 2, Type: Unsigned},
+	60:    {ID: 60, Name: "Driver 1 Selected Activity Duration", Bytes: 2, Type: Unsigned},
+	61:    {ID: 61, Name: "Driver 2 Selected Activity Duration", Bytes: 2, Type: Unsigned},
+	62:    {ID: 62, Name: "Dallas Temperature ID 1", Bytes: 8, Type: Hex},
+	63:    {ID: 63, Name: "Dallas Temperature ID 2", Bytes: 8, Type: Hex},
+	64:    {ID: 64, Name: "Dallas Temperature ID 3", Bytes: 8, Type: Hex},
+	65:    {ID: 65, Name: "Dallas Temperature ID 4", Bytes: 8, Type: Hex},
+	66:    {ID: 66, Name: "External Voltage", Bytes: 2, Type: Unsigned, Multiplier: 0.001, Unit: "mV"},
+	67:    {ID: 67, Name: "Battery Voltage", Bytes: 2, Type: Unsigned, Multiplier: 0.001, Unit: "mV"},
+	68:    {ID: 68, Name: "Battery Current", Bytes: 2, Type: Unsigned, Unit: "mA"},
+	69:    {ID: 69, Name: "Driver 1 Cumulative Driving Time", Bytes: 2, Type: Unsigned},
+	70:    {ID: 70, Name: "PCB Temperature", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	71:    {ID: 71, Name: "GNSS Status", Bytes: 1, Type: Unsigned},
+	72:    {ID: 72, Name: "Dallas Temperature 1", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	73:    {ID: 73, Name: "Dallas Temperature 2", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	74:    {ID: 74, Name: "Dallas Temperature 3", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	75:    {ID: 75, Name: "Dallas Temperature 4", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	76:    {ID: 76, Name: "Fuel Counter", Bytes: 4, Type: Unsigned},
+	77:    {ID: 77, Name: "Driver 2 Cumulative Driving Time", Bytes: 2, Type: Unsigned},
+	78:    {ID: 78, Name: "iButton", Bytes: 8, Type: Hex},
+	79:    {ID: 79, Name: "Brake Switch", Bytes: 1, Type: Unsigned},
+	80:    {ID: 80, Name: "Wheel Based Speed", Bytes: 4, Type: Unsigned, Unit: "km/h"},
+	81:    {ID: 81, Name: "Cruise Control Active", Bytes: 1, Type: Unsigned},
+	82:    {ID: 82, Name: "Clutch Switch", Bytes: 1, Type: Unsigned},
+	83:    {ID: 83, Name: "PTO State", Bytes: 1, Type: Unsigned},
+	84:    {ID: 84, Name: "Acceleration Pedal Position", Bytes: 4, Type: Unsigned, Unit: "%"},
+	85:    {ID: 85, Name: "Engine Current Load", Bytes: 1, Type: Unsigned, Unit: "%"},
+	86:    {ID: 86, Name: "Engine Total Fuel Used", Bytes: 4, Type: Unsigned, Unit: "l"},
+	87:    {ID: 87, Name: "Fuel Level", Bytes: 4, Type: Unsigned, Unit: "%"},
+	88:    {ID: 88, Name: "Engine Speed", Bytes: 4, Type: Unsigned, Unit: "rpm"},
+	89:    {ID: 89, Name: "Axle weight 1", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	90:    {ID: 90, Name: "Axle weight 2", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	91:    {ID: 91, Name: "Axle weight 3", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	92:    {ID: 92, Name: "Axle weight 4", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	93:    {ID: 93, Name: "Axle weight 5", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	94:    {ID: 94, Name: "Axle weight 6", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	95:    {ID: 95, Name: "Axle weight 7", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	96:    {ID: 96, Name: "Axle weight 8", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	97:    {ID: 97, Name: "Axle weight 9", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	98:    {ID: 98, Name: "Axle weight 10", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	99:    {ID: 99, Name: "Axle weight 11", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	100:   {ID: 100, Name: "Axle weight 12", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	101:   {ID: 101, Name: "Axle weight 13", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	102:   {ID: 102, Name: "Axle weight 14", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	103:   {ID: 103, Name: "Axle weight 15", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	104:   {ID: 104, Name: "Engine Total Hours Of Operation", Bytes: 4, Type: Unsigned, Unit: "h"},
+	108:   {ID: 108, Name: "LVCAN Driver2 ID High", Bytes: 8, Type: Hex},
+	109:   {ID: 109, Name: "SW-version supported", Bytes: 4, Type: Hex},
+	110:   {ID: 110, Name: "Diagnostics Supported", Bytes: 1, Type: Unsigned},
+	111:   {ID: 111, Name: "Requests supported", Bytes: 1, Type: Unsigned},
+	113:   {ID: 113, Name: "Service Distance", Bytes: 4, Type: Signed, Unit: "km"},
+	122:   {ID: 122, Name: "Direction Indication", Bytes: 1, Type: Unsigned},
+	123:   {ID: 123, Name: "Tachograph Performance", Bytes: 1, Type: Unsigned},
+	124:   {ID: 124, Name: "Handling Info", Bytes: 1, Type: Unsigned},
+	125:   {ID: 125, Name: "System Event", Bytes: 1, Type: Unsigned},
+	127:   {ID: 127, Name: "Engine Coolant Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	128:   {ID: 128, Name: "Ambient Air Temperature", Bytes: 2, Type: Signed, Unit: "°C"},
+	135:   {ID: 135, Name: "Fuel Rate", Bytes: 4, Type: Unsigned, Unit: "l/h"},
+	136:   {ID: 136, Name: "Instantaneous Fuel Economy", Bytes: 4, Type: Unsigned, Unit: "km/l"},
+	137:   {ID: 137, Name: "PTO Drive Engagement", Bytes: 1, Type: Unsigned},
+	138:   {ID: 138, Name: "High Resolution Engine Total Fuel Used", Bytes: 4, Type: Unsigned, Unit: "l or ml"},
+	139:   {ID: 139, Name: "Gross Combination Vehicle Weight", Bytes: 4, Type: Unsigned, Unit: "kg"},
+	140:   {ID: 140, Name: "LVCAN Driver2 ID Low", Bytes: 8, Type: Hex},
+	141:   {ID: 141, Name: "Battery Temperature", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	142:   {ID: 142, Name: "Battery Level Percent", Bytes: 1, Type: Unsigned, Multiplier: 0.1, Unit: "%"},
+	143:   {ID: 143, Name: "Door Status", Bytes: 2, Type: Unsigned},
+	144:   {ID: 144, Name: "SD Status", Bytes: 1, Type: Unsigned},
+	145:   {ID: 145, Name: "Manual CAN 00", Type: Hex},
+	146:   {ID: 146, Name: "Manual CAN 01", Type: Hex},
+	147:   {ID: 147, Name: "Manual CAN 02", Type: Hex},
+	148:   {ID: 148, Name: "Manual CAN 03", Type: Hex},
+	149:   {ID: 149, Name: "Manual CAN 04", Type: Hex},
+	150:   {ID: 150, Name: "Manual CAN 05", Type: Hex},
+	151:   {ID: 151, Name: "Manual CAN 06", Type: Hex},
+	152:   {ID: 152, Name: "Manual CAN 07", Type: Hex},
+	153:   {ID: 153, Name: "Manual CAN 08", Type: Hex},
+	154:   {ID: 154, Name: "Manual CAN 09", Type: Hex},
+	155:   {ID: 155, Name: "Geofence zone 01", Bytes: 1, Type: Unsigned},
+	156:   {ID: 156, Name: "Geofence zone 02", Bytes: 1, Type: Unsigned},
+	157:   {ID: 157, Name: "Geofence zone 03", Bytes: 1, Type: Unsigned},
+	158:   {ID: 158, Name: "Geofence zone 04", Bytes: 1, Type: Unsigned},
+	159:   {ID: 159, Name: "Geofence zone 05", Bytes: 1, Type: Unsigned},
+	160:   {ID: 160, Name: "Geofence zone 06", Bytes: 1, Type: Unsigned},
+	161:   {ID: 161, Name: "Geofence zone 07", Bytes: 1, Type: Unsigned},
+	162:   {ID: 162, Name: "Geofence zone 08", Bytes: 1, Type: Unsigned},
+	163:   {ID: 163, Name: "Geofence zone 09", Bytes: 1, Type: Unsigned},
+	164:   {ID: 164, Name: "Geofence zone 10", Bytes: 1, Type: Unsigned},
+	165:   {ID: 165, Name: "Geofence zone 11", Bytes: 1, Type: Unsigned},
+	166:   {ID: 166, Name: "Geofence zone 12", Bytes: 1, Type: Unsigned},
+	167:   {ID: 167, Name: "Geofence zone 13", Bytes: 1, Type: Unsigned},
+	168:   {ID: 168, Name: "Geofence zone 14", Bytes: 1, Type: Unsigned},
+	169:   {ID: 169, Name: "Geofence zone 15", Bytes: 1, Type: Unsigned},
+	170:   {ID: 170, Name: "Geofence zone 16", Bytes: 1, Type: Unsigned},
+	171:   {ID: 171, Name: "Geofence zone 17", Bytes: 1, Type: Unsigned},
+	172:   {ID: 172, Name: "Geofence zone 18", Bytes: 1, Type: Unsigned},
+	173:   {ID: 173, Name: "Geofence zone 19", Bytes: 1, Type: Unsigned},
+	174:   {ID: 174, Name: "Geofence zone 20", Bytes: 1, Type: Unsigned},
+	175:   {ID: 175, Name: "Auto Geofence", Bytes: 1, Type: Unsigned},
+	176:   {ID: 176, Name: "DTC Errors", Bytes: 1, Type: Unsigned},
+	177:   {ID: 177, Name: "DTC Codes", Bytes: 8, Type: Hex},
+	178:   {ID: 178, Name: "Network Type", Bytes: 1, Type: Unsigned},
+	179:   {ID: 179, Name: "Digital Output 1", Bytes: 1, Type: Unsigned},
+	180:   {ID: 180, Name: "Digital Output 2", Bytes: 1, Type: Unsigned},
+	181:   {ID: 181, Name: "GNSS PDOP", Bytes: 2, Type: Unsigned},
+	182:   {ID: 182, Name: "GNSS HDOP", Bytes: 2, Type: Unsigned},
+	183:   {ID: 183, Name: "Drive Recognize", Bytes: 1, Type: Unsigned},
+	184:   {ID: 184, Name: "Driver 1 Working State", Bytes: 1, Type: Unsigned},
+	185:   {ID: 185, Name: "Driver 2 Working State", Bytes: 1, Type: Unsigned},
+	186:   {ID: 186, Name: "Tachograph Over Speed", Bytes: 1, Type: Unsigned},
+	187:   {ID: 187, Name: "Driver 1 Card Presence", Bytes: 1, Type: Unsigned},
+	188:   {ID: 188, Name: "Driver 2 Card Presence", Bytes: 1, Type: Unsigned},
+	189:   {ID: 189, Name: "Driver 1 Time Related States", Bytes: 1, Type: Unsigned},
+	190:   {ID: 190, Name: "Driver 2 Time Related States", Bytes: 1, Type: Unsigned},
+	191:   {ID: 191, Name: "Vehicle Speed", Bytes: 2, Type: Unsigned, Unit: "km/h"},
+	192:   {ID: 192, Name: "Odometer", Bytes: 4, Type: Unsigned, Unit: "m"},
+	193:   {ID: 193, Name: "Trip Distance", Bytes: 4, Type: Unsigned, Unit: "m"},
+	194:   {ID: 194, Name: "Timestamp", Bytes: 4, Type: Unsigned},
+	195:   {ID: 195, Name: "Driver 1 ID MSB", Bytes: 8, Type: Hex},
+	196:   {ID: 196, Name: "Driver 1 ID LSB", Bytes: 8, Type: Hex},
+	197:   {ID: 197, Name: "Driver 2 ID MSB", Bytes: 8, Type: Hex},
+	198:   {ID: 198, Name: "Driver 2 ID LSB", Bytes: 8, Type: Hex},
+	199:   {ID: 199, Name: "Trip Odometer", Bytes: 4, Type: Unsigned, Unit: "m"},
+	200:   {ID: 200, Name: "Sleep Mode", Bytes: 1, Type: Unsigned},
+	201:   {ID: 201, Name: "LLS 1 Fuel Level", Bytes: 2, Type: Signed, Unit: "kvants or ltr"},
+	202:   {ID: 202, Name: "LLS 1 Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	203:   {ID: 203, Name: "LLS 2 Fuel Level", Bytes: 2, Type: Signed, Unit: "kvants or ltr"},
+	204:   {ID: 204, Name: "LLS 2 Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	205:   {ID: 205, Name: "GSM Cell ID", Bytes: 4, Type: Unsigned},
+	206:   {ID: 206, Name: "GSM Area Code", Bytes: 2, Type: Unsigned},
+	207:   {ID: 207, Name: "RFID", Bytes: 8, Type: Hex},
+	208:   {ID: 208, Name: "Ultrasonic Software Status 1", Bytes: 1, Type: Unsigned},
+	209:   {ID: 209, Name: "Ultrasonic Software Status 2", Bytes: 1, Type: Unsigned},
+	210:   {ID: 210, Name: "LLS 3 Fuel Level", Bytes: 2, Type: Signed, Unit: "kvants or ltr"},
+	211:   {ID: 211, Name: "LLS 3 Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	212:   {ID: 212, Name: "LLS 4 Fuel Level", Bytes: 2, Type: Signed, Unit: "kvants or ltr"},
+	213:   {ID: 213, Name: "LLS 4 Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	214:   {ID: 214, Name: "LLS 5 Fuel Level", Bytes: 2, Type: Signed, Unit: "kvants or ltr"},
+	215:   {ID: 215, Name: "LLS 5 Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	216:   {ID: 216, Name: "Total Odometer", Bytes: 4, Type: Unsigned, Unit: "m"},
+	217:   {ID: 217, Name: "RFID COM2", Bytes: 8, Type: Hex},
+	218:   {ID: 218, Name: "IMSI", Bytes: 8, Type: Hex},
+	219:   {ID: 219, Name: "CCID Part1", Bytes: 8, Type: Hex},
+	220:   {ID: 220, Name: "CCID Part2", Bytes: 8, Type: Hex},
+	221:   {ID: 221, Name: "CCID Part3", Bytes: 8, Type: Hex},
+	222:   {ID: 222, Name: "Card 1 Issuing Member State", Bytes: 1, Type: Unsigned},
+	223:   {ID: 223, Name: "Card 2 Issuing Member State", Bytes: 1, Type: Unsigned},
+	224:   {ID: 224, Name: "Ultrasonic Fuel Level 1", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "mm"},
+	225:   {ID: 225, Name: "Ultrasonic Fuel Level 2", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "mm"},
+	226:   {ID: 226, Name: "CNG Status", Bytes: 1, Type: Unsigned},
+	227:   {ID: 227, Name: "CNG Used", Bytes: 4, Type: Unsigned, Unit: "kg"},
+	228:   {ID: 228, Name: "CNG Level", Bytes: 2, Type: Unsigned, Unit: "%"},
+	229:   {ID: 229, Name: "LVCAN Driver1 ID High", Bytes: 8, Type: Hex},
+	230:   {ID: 230, Name: "LVCAN Driver1 ID Low", Bytes: 8, Type: Hex},
+	231:   {ID: 231, Name: "Vehicle Registration Number Part1", Bytes: 8, Type: Hex},
+	232:   {ID: 232, Name: "Vehicle Registration Number Part2", Bytes: 8, Type: Hex},
+	233:   {ID: 233, Name: "Vehicle Identification Number Part1", Bytes: 8, Type: Hex},
+	234:   {ID: 234, Name: "Vehicle Identification Number Part2", Bytes: 8, Type: Hex},
+	235:   {ID: 235, Name: "Vehicle Identification Number Part3", Bytes: 1, Type: Unsigned},
+	236:   {ID: 236, Name: "Axis X", Bytes: 2, Type: Signed, Unit: "mG"},
+	237:   {ID: 237, Name: "Axis Y", Bytes: 2, Type: Signed, Unit: "mG"},
+	238:   {ID: 238, Name: "Axis Z", Bytes: 2, Type: Signed, Unit: "mG"},
+	239:   {ID: 239, Name: "Ignition", Bytes: 1, Type: Unsigned},
+	240:   {ID: 240, Name: "Movement", Bytes: 1, Type: Unsigned},
+	241:   {ID: 241, Name: "Active GSM Operator", Bytes: 4, Type: Unsigned},
+	242:   {ID: 242, Name: "Data Limit Hit", Bytes: 1, Type: Unsigned},
+	243:   {ID: 243, Name: "Idling", Bytes: 1, Type: Unsigned},
+	245:   {ID: 245, Name: "Analog Input 4", Bytes: 2, Type: Unsigned, Multiplier: 0.001, Unit: "V"},
+	246:   {ID: 246, Name: "Towing", Bytes: 1, Type: Unsigned},
+	247:   {ID: 247, Name: "Crash Detection", Bytes: 1, Type: Unsigned},
+	248:   {ID: 248, Name: "Geofence Zone Over Speeding", Bytes: 1, Type: Unsigned},
+	249:   {ID: 249, Name: "Jamming", Bytes: 1, Type: Unsigned},
+	250:   {ID: 250, Name: "Trip", Bytes: 1, Type: Unsigned},
+	251:   {ID: 251, Name: "Immobilizer", Bytes: 1, Type: Unsigned},
+	252:   {ID: 252, Name: "Authorized Driving", Bytes: 1, Type: Unsigned},
+	253:   {ID: 253, Name: "Green Driving Type", Bytes: 1, Type: Unsigned},
+	254:   {ID: 254, Name: "Green Driving Value", Bytes: 1, Type: Unsigned, Multiplier: 0.01, Unit: "G or rad"},
+	255:   {ID: 255, Name: "Over Speeding", Bytes: 1, Type: Unsigned, Unit: "km/h"},
+	288:   {ID: 288, Name: "Sound Type", Bytes: 1, Type: Unsigned},
+	289:   {ID: 289, Name: "Pedestrian In Danger Zone", Bytes: 1, Type: Unsigned},
+	290:   {ID: 290, Name: "Pedestrian Forward Collision Warning", Bytes: 1, Type: Unsigned},
+	291:   {ID: 291, Name: "Time Indicator", Bytes: 1, Type: Unsigned},
+	292:   {ID: 292, Name: "Error Valid", Bytes: 1, Type: Unsigned},
+	293:   {ID: 293, Name: "Error Code", Bytes: 1, Type: Unsigned},
+	294:   {ID: 294, Name: "Zero Speed", Bytes: 1, Type: Unsigned},
+	295:   {ID: 295, Name: "Headway Valid", Bytes: 1, Type: Unsigned},
+	296:   {ID: 296, Name: "Headway Measurement", Bytes: 1, Type: Unsigned, Multiplier: 0.1},
+	297:   {ID: 297, Name: "LDW Off", Bytes: 1, Type: Unsigned},
+	298:   {ID: 298, Name: "Left LDW On", Bytes: 1, Type: Unsigned},
+	299:   {ID: 299, Name: "Right LDW On", Bytes: 1, Type: Unsigned},
+	300:   {ID: 300, Name: "Maintanance", Bytes: 1, Type: Unsigned},
+	301:   {ID: 301, Name: "Fail Safe", Bytes: 1, Type: Unsigned},
+	302:   {ID: 302, Name: "FCW On", Bytes: 1, Type: Unsigned},
+	303:   {ID: 303, Name: "TSR Enabled", Bytes: 1, Type: Unsigned},
+	304:   {ID: 304, Name: "Headway Warning Repeat", Bytes: 1, Type: Unsigned},
+	305:   {ID: 305, Name: "Headway Warning Level", Bytes: 1, Type: Unsigned},
+	306:   {ID: 306, Name: "TSR Warning Level", Bytes: 1, Type: Unsigned},
+	307:   {ID: 307, Name: "Tamper Alert", Bytes: 1, Type: Unsigned},
+	308:   {ID: 308, Name: "High Beam", Bytes: 1, Type: Unsigned},
+	309:   {ID: 309, Name: "Low Beam", Bytes: 1, Type: Unsigned},
+	310:   {ID: 310, Name: "Wipers", Bytes: 1, Type: Unsigned},
+	311:   {ID: 311, Name: "Right Signal", Bytes: 1, Type: Unsigned},
+	312:   {ID: 312, Name: "Left Signal", Bytes: 1, Type: Unsigned},
+	313:   {ID: 313, Name: "Brake Signal", Bytes: 1, Type: Unsigned},
+	314:   {ID: 314, Name: "Wipers Available", Bytes: 1, Type: Unsigned},
+	315:   {ID: 315, Name: "Low Beam Available", Bytes: 1, Type: Unsigned},
+	316:   {ID: 316, Name: "High Beam Available", Bytes: 1, Type: Unsigned},
+	317:   {ID: 317, Name: "Speed Available", Bytes: 1, Type: Unsigned},
+	318:   {ID: 318, Name: "Speed", Bytes: 1, Type: Unsigned, Unit: "km/h"},
+	319:   {ID: 319, Name: "TSR 1", Bytes: 8, Type: Hex},
+	320:   {ID: 320, Name: "TSR 2", Bytes: 8, Type: Hex},
+	321:   {ID: 321, Name: "TSR 3", Bytes: 8, Type: Hex},
+	322:   {ID: 322, Name: "TSR 4", Bytes: 8, Type: Hex},
+	323:   {ID: 323, Name: "TSR 5", Bytes: 8, Type: Hex},
+	324:   {ID: 324, Name: "TSR 6", Bytes: 8, Type: Hex},
+	325:   {ID: 325, Name: "TSR 7", Bytes: 8, Type: Hex},
+	326:   {ID: 326, Name: "TSR VO", Bytes: 8, Type: Hex},
+	327:   {ID: 327, Name: "Geofence zone 21", Bytes: 1, Type: Unsigned},
+	328:   {ID: 328, Name: "Geofence zone 22", Bytes: 1, Type: Unsigned},
+	329:   {ID: 329, Name: "Geofence zone 23", Bytes: 1, Type: Unsigned},
+	330:   {ID: 330, Name: "Geofence zone 24", Bytes: 1, Type: Unsigned},
+	331:   {ID: 331, Name: "Geofence zone 25", Bytes: 1, Type: Unsigned},
+	332:   {ID: 332, Name: "Geofence zone 26", Bytes: 1, Type: Unsigned},
+	333:   {ID: 333, Name: "Geofence zone 27", Bytes: 1, Type: Unsigned},
+	334:   {ID: 334, Name: "Geofence zone 28", Bytes: 1, Type: Unsigned},
+	335:   {ID: 335, Name: "Geofence zone 29", Bytes: 1, Type: Unsigned},
+	336:   {ID: 336, Name: "Geofence zone 30", Bytes: 1, Type: Unsigned},
+	337:   {ID: 337, Name: "Geofence zone 31", Bytes: 1, Type: Unsigned},
+	338:   {ID: 338, Name: "Geofence zone 32", Bytes: 1, Type: Unsigned},
+	339:   {ID: 339, Name: "Geofence zone 33", Bytes: 1, Type: Unsigned},
+	340:   {ID: 340, Name: "Geofence zone 34", Bytes: 1, Type: Unsigned},
+	341:   {ID: 341, Name: "Geofence zone 35", Bytes: 1, Type: Unsigned},
+	342:   {ID: 342, Name: "Geofence zone 36", Bytes: 1, Type: Unsigned},
+	343:   {ID: 343, Name: "Geofence zone 37", Bytes: 1, Type: Unsigned},
+	344:   {ID: 344, Name: "Geofence zone 38", Bytes: 1, Type: Unsigned},
+	345:   {ID: 345, Name: "Geofence zone 39", Bytes: 1, Type: Unsigned},
+	346:   {ID: 346, Name: "Geofence zone 40", Bytes: 1, Type: Unsigned},
+	347:   {ID: 347, Name: "Geofence zone 41", Bytes: 1, Type: Unsigned},
+	348:   {ID: 348, Name: "Geofence zone 42", Bytes: 1, Type: Unsigned},
+	349:   {ID: 349, Name: "Geofence zone 43", Bytes: 1, Type: Unsigned},
+	350:   {ID: 350, Name: "Geofence zone 44", Bytes: 1, Type: Unsigned},
+	351:   {ID: 351, Name: "Geofence zone 45", Bytes: 1, Type: Unsigned},
+	352:   {ID: 352, Name: "Geofence zone 46", Bytes: 1, Type: Unsigned},
+	353:   {ID: 353, Name: "Geofence zone 47", Bytes: 1, Type: Unsigned},
+	354:   {ID: 354, Name: "Geofence zone 48", Bytes: 1, Type: Unsigned},
+	355:   {ID: 355, Name: "Geofence zone 49", Bytes: 1, Type: Unsigned},
+	356:   {ID: 356, Name: "Geofence zone 50", Bytes: 1, Type: Unsigned},
+	358:   {ID: 358, Name: "Custom Scenario 1", Bytes: 1, Type: Unsigned},
+	359:   {ID: 359, Name: "Custom Scenario 2", Bytes: 1, Type: Unsigned},
+	360:   {ID: 360, Name: "Custom Scenario 3", Bytes: 1, Type: Unsigned},
+	361:   {ID: 361, Name: "Custom Scenario 4", Bytes: 1, Type: Unsigned},
+	362:   {ID: 362, Name: "Trace Order", Bytes: 2, Type: Unsigned},
+	380:   {ID: 380, Name: "Manual CAN 10", Type: Hex},
+	381:   {ID: 381, Name: "Manual CAN 11", Type: Hex},
+	382:   {ID: 382, Name: "Manual CAN 12", Type: Hex},
+	383:   {ID: 383, Name: "Manual CAN 13", Type: Hex},
+	384:   {ID: 384, Name: "Manual CAN 14", Type: Hex},
+	385:   {ID: 385, Name: "Manual CAN 15", Type: Hex},
+	386:   {ID: 386, Name: "Manual CAN 16", Type: Hex},
+	387:   {ID: 387, Name: "Manual CAN 17", Type: Hex},
+	388:   {ID: 388, Name: "Manual CAN 18", Type: Hex},
+	389:   {ID: 389, Name: "Manual CAN 19", Type: Hex},
+	390:   {ID: 390, Name: "External Sensor Temperature 0", Bytes: 2, Type: Signed, Unit: "°C"},
+	391:   {ID: 391, Name: "External Sensor Temperature 1", Bytes: 2, Type: Signed, Unit: "°C"},
+	392:   {ID: 392, Name: "External Sensor Temperature 2", Bytes: 2, Type: Signed, Unit: "°C"},
+	393:   {ID: 393, Name: "External Sensor Temperature 3", Bytes: 2, Type: Signed, Unit: "°C"},
+	394:   {ID: 394, Name: "External Sensor Temperature 4", Bytes: 2, Type: Signed, Unit: "°C"},
+	395:   {ID: 395, Name: "External Sensor Temperature 5", Bytes: 2, Type: Signed, Unit: "°C"},
+	400:   {ID: 400, Name: "Total Tires", Bytes: 1, Type: Unsigned},
+	401:   {ID: 401, Name: "Total Axels", Bytes: 1, Type: Unsigned},
+	410:   {ID: 410, Name: "Tire 1", Bytes: 8, Type: Hex},
+	411:   {ID: 411, Name: "Tire 2", Bytes: 8, Type: Hex},
+	412:   {ID: 412, Name: "Tire 3", Bytes: 8, Type: Hex},
+	413:   {ID: 413, Name: "Tire 4", Bytes: 8, Type: Hex},
+	414:   {ID: 414, Name: "Tire 5", Bytes: 8, Type: Hex},
+	415:   {ID: 415, Name: "Tire 6", Bytes: 8, Type: Hex},
+	416:   {ID: 416, Name: "Tire 7", Bytes: 8, Type: Hex},
+	417:   {ID: 417, Name: "Tire 8", Bytes: 8, Type: Hex},
+	418:   {ID: 418, Name: "Tire 9", Bytes: 8, Type: Hex},
+	419:   {ID: 419, Name: "Tire 10", Bytes: 8, Type: Hex},
+	420:   {ID: 420, Name: "Tire 11", Bytes: 8, Type: Hex},
+	421:   {ID: 421, Name: "Tire 12", Bytes: 8, Type: Hex},
+	422:   {ID: 422, Name: "Tire 13", Bytes: 8, Type: Hex},
+	423:   {ID: 423, Name: "Tire 14", Bytes: 8, Type: Hex},
+	424:   {ID: 424, Name: "Tire 15", Bytes: 8, Type: Hex},
+	425:   {ID: 425, Name: "Tire 16", Bytes: 8, Type: Hex},
+	426:   {ID: 426, Name: "Tire 17", Bytes: 8, Type: Hex},
+	427:   {ID: 427, Name: "Tire 18", Bytes: 8, Type: Hex},
+	428:   {ID: 428, Name: "Tire 19", Bytes: 8, Type: Hex},
+	429:   {ID: 429, Name: "Tire 20", Bytes: 8, Type: Hex},
+	430:   {ID: 430, Name: "Tire 21", Bytes: 8, Type: Hex},
+	431:   {ID: 431, Name: "Tire 22", Bytes: 8, Type: Hex},
+	432:   {ID: 432, Name: "Tire 23", Bytes: 8, Type: Hex},
+	433:   {ID: 433, Name: "Tire 24", Bytes: 8, Type: Hex},
+	10298: {ID: 10298, Name: "Manual CAN 20", Type: Hex},
+	10299: {ID: 10299, Name: "Manual CAN 21", Type: Hex},
+	10300: {ID: 10300, Name: "Manual CAN 22", Type: Hex},
+	10301: {ID: 10301, Name: "Manual CAN 23", Type: Hex},
+	10302: {ID: 10302, Name: "Manual CAN 24", Type: Hex},
+	10303: {ID: 10303, Name: "Manual CAN 25", Type: Hex},
+	10304: {ID: 10304, Name: "Manual CAN 26", Type: Hex},
+	10305: {ID: 10305, Name: "Manual CAN 27", Type: Hex},
+	10306: {ID: 10306, Name: "Manual CAN 28", Type: Hex},
+	10307: {ID: 10307, Name: "Manual CAN 29", Type: Hex},
+	10308: {ID: 10308, Name: "Manual CAN 30", Type: Hex},
+	10309: {ID: 10309, Name: "Manual CAN 31", Type: Hex},
+	10310: {ID: 10310, Name: "Manual CAN 32", Type: Hex},
+	10311: {ID: 10311, Name: "Manual CAN 33", Type: Hex},
+	10312: {ID: 10312, Name: "Manual CAN 34", Type: Hex},
+	10313: {ID: 10313, Name: "Manual CAN 35", Type: Hex},
+	10314: {ID: 10314, Name: "Manual CAN 36", Type: Hex},
+	10315: {ID: 10315, Name: "Manual CAN 37", Type: Hex},
+	10316: {ID: 10316, Name: "Manual CAN 38", Type: Hex},
+	10317: {ID: 10317, Name: "Manual CAN 39", Type: Hex},
+	10318: {ID: 10318, Name: "Manual CAN 40", Type: Hex},
+	10319: {ID: 10319, Name: "Manual CAN 41", Type: Hex},
+	10320: {ID: 10320, Name: "Manual CAN 42", Type: Hex},
+	10321: {ID: 10321, Name: "Manual CAN 43", Type: Hex},
+	10322: {ID: 10322, Name: "Manual CAN 44", Type: Hex},
+	10323: {ID: 10323, Name: "Manual CAN 45", Type: Hex},
+	10324: {ID: 10324, Name: "Manual CAN 46", Type: Hex},
+	10325: {ID: 10325, Name: "Manual CAN 47", Type: Hex},
+	10326: {ID: 10326, Name: "Manual CAN 48", Type: Hex},
+	10327: {ID: 10327, Name: "Manual CAN 49", Type: Hex},
+	10328: {ID: 10328, Name: "Manual CAN 50", Type: Hex},
+	10329: {ID: 10329, Name: "Manual CAN 51", Type: Hex},
+	10330: {ID: 10330, Name: "Manual CAN 52", Type: Hex},
+	10331: {ID: 10331, Name: "Manual CAN 53", Type: Hex},
+	10332: {ID: 10332, Name: "Manual CAN 54", Type: Hex},
+	10333: {ID: 10333, Name: "Manual CAN 55", Type: Hex},
+	10334: {ID: 10334, Name: "Manual CAN 56", Type: Hex},
+	10335: {ID: 10335, Name: "Manual CAN 57", Type: Hex},
+	10336: {ID: 10336, Name: "Manual CAN 58", Type: Hex},
+	10337: {ID: 10337, Name: "Manual CAN 59", Type: Hex},
+	10338: {ID: 10338, Name: "Manual CAN 60", Type: Hex},
+	10339: {ID: 10339, Name: "Manual CAN 61", Type: Hex},
+	10340: {ID: 10340, Name: "Manual CAN 62", Type: Hex},
+	10341: {ID: 10341, Name: "Manual CAN 63", Type: Hex},
+	10342: {ID: 10342, Name: "Manual CAN 64", Type: Hex},
+	10343: {ID: 10343, Name: "Manual CAN 65", Type: Hex},
+	10344: {ID: 10344, Name: "Manual CAN 66", Type: Hex},
+	10345: {ID: 10345, Name: "Manual CAN 67", Type: Hex},
+	10346: {ID: 10346, Name: "Manual CAN 68", Type: Hex},
+	10347: {ID: 10347, Name: "Manual CAN 69", Type: Hex},
+	10348: {ID: 10348, Name: "Fuel level 2", Bytes: 4, Type: Unsigned, Unit: "%"},
+	10349: {ID: 10349, Name: "MIL indicator", Bytes: 1, Type: Unsigned},
+}
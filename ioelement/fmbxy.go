@@ -0,0 +1,267 @@
+// Code generated by gen.go from teltonikajson/FMBXY.json; DO NOT EDIT.
+
+package ioelement
+
+// FMBXY is the dictionary of the IO elements of FMB, FMC, FMM and other current devices like FMB920
+// https://wiki.teltonika-gps.com/view/FMB920_Teltonika_Data_Sending_Parameters_ID
+var FMBXY = Dictionary{
+	1:   {ID: 1, Name: "Digital Input 1", Bytes: 1, Type: Unsigned},
+	2:   {ID: 2, Name: "Digital Input 2", Bytes: 1, Type: Unsigned},
+	3:   {ID: 3, Name: "Digital Input 3", Bytes: 1, Type: Unsigned},
+	4:   {ID: 4, Name: "Pulse Counter Din1", Bytes: 4, Type: Unsigned},
+	5:   {ID: 5, Name: "Pulse Counter Din2", Bytes: 4, Type: Unsigned},
+	6:   {ID: 6, Name: "Analog Input 2", Bytes: 2, Type: Unsigned, Unit: "mV"},
+	7:   {ID: 7, Name: "Records In Flash", Bytes: 2, Type: Unsigned},
+	8:   {ID: 8, Name: "Authorized iButton", Bytes: 8, Type: Hex},
+	9:   {ID: 9, Name: "Analog Input 1", Bytes: 2, Type: Unsigned, Unit: "mV"},
+	10:  {ID: 10, Name: "SD Status", Bytes: 1, Type: Unsigned},
+	11:  {ID: 11, Name: "ICCID1", Bytes: 8, Type: Hex},
+	12:  {ID: 12, Name: "Fuel Used GPS", Bytes: 4, Type: Unsigned, Unit: "ml"},
+	13:  {ID: 13, Name: "Fuel Rate GPS", Bytes: 2, Type: Unsigned, Multiplier: 100, Unit: "l/h,*100"},
+	14:  {ID: 14, Name: "ICCID2", Bytes: 8, Type: Hex},
+	15:  {ID: 15, Name: "Eco Score", Bytes: 2, Type: Unsigned, Multiplier: 0.01},
+	16:  {ID: 16, Name: "Total Odometer", Bytes: 4, Type: Unsigned},
+	17:  {ID: 17, Name: "Axis X", Bytes: 2, Type: Signed, Unit: "mG"},
+	18:  {ID: 18, Name: "Axis Y", Bytes: 2, Type: Signed, Unit: "mG"},
+	19:  {ID: 19, Name: "Axis Z", Bytes: 2, Type: Signed, Unit: "mG"},
+	20:  {ID: 20, Name: "BLE 2 Battery Voltage", Bytes: 1, Type: Unsigned, Unit: "%"},
+	21:  {ID: 21, Name: "GSM Signal", Bytes: 1, Type: Unsigned},
+	22:  {ID: 22, Name: "BLE 3 Battery Voltage", Bytes: 1, Type: Unsigned, Unit: "%"},
+	23:  {ID: 23, Name: "BLE 4 Battery Voltage", Bytes: 1, Type: Unsigned, Unit: "%"},
+	24:  {ID: 24, Name: "Speed", Bytes: 2, Type: Unsigned, Unit: "km/h"},
+	25:  {ID: 25, Name: "BLE 1 Temperature", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	26:  {ID: 26, Name: "BLE 2 Temperature", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	27:  {ID: 27, Name: "BLE 3 Temperature", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	28:  {ID: 28, Name: "BLE 4 Temperature", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	29:  {ID: 29, Name: "BLE 1 Battery Voltage", Bytes: 1, Type: Unsigned, Unit: "%"},
+	30:  {ID: 30, Name: "Number of DTC", Bytes: 1, Type: Unsigned},
+	31:  {ID: 31, Name: "Engine Load", Bytes: 1, Type: Unsigned, Unit: "%"},
+	32:  {ID: 32, Name: "Coolant Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	33:  {ID: 33, Name: "Short Fuel Trim", Bytes: 1, Type: Signed, Unit: "%"},
+	34:  {ID: 34, Name: "Fuel pressure", Bytes: 2, Type: Unsigned, Unit: "kPa"},
+	35:  {ID: 35, Name: "Intake MAP", Bytes: 1, Type: Unsigned, Unit: "kPa"},
+	36:  {ID: 36, Name: "Engine RPM", Bytes: 2, Type: Unsigned, Unit: "rpm"},
+	37:  {ID: 37, Name: "Vehicle Speed", Bytes: 1, Type: Unsigned, Unit: "km/h"},
+	38:  {ID: 38, Name: "Timing Advance", Bytes: 2, Type: Signed, Unit: "°"},
+	39:  {ID: 39, Name: "Intake Air Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	40:  {ID: 40, Name: "MAF", Bytes: 2, Type: Unsigned, Multiplier: 0.01, Unit: "g/sec"},
+	41:  {ID: 41, Name: "Throttle Position", Bytes: 1, Type: Unsigned, Unit: "%"},
+	42:  {ID: 42, Name: "Run Time Since Engine Start", Bytes: 2, Type: Unsigned, Unit: "s"},
+	43:  {ID: 43, Name: "Distance Traveled MIL On", Bytes: 2, Type: Unsigned, Unit: "km"},
+	44:  {ID: 44, Name: "Relative Fuel Rail Pressure", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "kPa"},
+	45:  {ID: 45, Name: "Direct Fuel Rail Pressure", Bytes: 2, Type: Unsigned, Multiplier: 10, Unit: "kPa"},
+	46:  {ID: 46, Name: "Commanded EGR", Bytes: 1, Type: Unsigned, Unit: "%"},
+	47:  {ID: 47, Name: "EGR Error", Bytes: 1, Type: Signed, Unit: "%"},
+	48:  {ID: 48, Name: "Fuel Level", Bytes: 1, Type: Unsigned, Unit: "%"},
+	49:  {ID: 49, Name: "Distance Since Codes Clear", Bytes: 2, Type: Unsigned, Unit: "km"},
+	50:  {ID: 50, Name: "Barometric Pressure", Bytes: 1, Type: Unsigned, Unit: "kPa"},
+	51:  {ID: 51, Name: "Control Module Voltage", Bytes: 2, Type: Unsigned, Unit: "mV"},
+	52:  {ID: 52, Name: "Absolute Load Value", Bytes: 2, Type: Unsigned, Unit: "%"},
+	53:  {ID: 53, Name: "Ambient Air Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	54:  {ID: 54, Name: "Time Run With MIL On", Bytes: 2, Type: Unsigned, Unit: "min"},
+	55:  {ID: 55, Name: "Time Since Codes Cleared", Bytes: 2, Type: Unsigned, Unit: "min"},
+	56:  {ID: 56, Name: "Absolute Fuel Rail Pressure", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "kPa"},
+	57:  {ID: 57, Name: "Hybrid battery pack life", Bytes: 1, Type: Unsigned, Unit: "%"},
+	58:  {ID: 58, Name: "Engine Oil Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	59:  {ID: 59, Name: "Fuel Injection Timing", Bytes: 2, Type: Signed, Multiplier: 0.01, Unit: "°"},
+	60:  {ID: 60, Name: "Fuel Rate", Bytes: 2, Type: Unsigned, Multiplier: 0.01, Unit: "l/100km"},
+	61:  {ID: 61, Name: "Geofence zone 06", Bytes: 1, Type: Unsigned},
+	62:  {ID: 62, Name: "Geofence zone 07", Bytes: 1, Type: Unsigned},
+	63:  {ID: 63, Name: "Geofence zone 08", Bytes: 1, Type: Unsigned},
+	64:  {ID: 64, Name: "Geofence zone 09", Bytes: 1, Type: Unsigned},
+	65:  {ID: 65, Name: "Geofence zone 10", Bytes: 1, Type: Unsigned},
+	66:  {ID: 66, Name: "External Voltage", Bytes: 2, Type: Unsigned, Unit: "mV"},
+	67:  {ID: 67, Name: "Battery Voltage", Bytes: 2, Type: Unsigned, Unit: "mV"},
+	68:  {ID: 68, Name: "Battery Current", Bytes: 2, Type: Unsigned, Unit: "mA"},
+	69:  {ID: 69, Name: "GNSS Status", Bytes: 1, Type: Unsigned},
+	70:  {ID: 70, Name: "Geofence zone 11", Bytes: 1, Type: Unsigned},
+	71:  {ID: 71, Name: "Dallas Temperature ID 4", Bytes: 8, Type: Hex},
+	72:  {ID: 72, Name: "Dallas Temperature 1", Bytes: 4, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	73:  {ID: 73, Name: "Dallas Temperature 2", Bytes: 4, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	74:  {ID: 74, Name: "Dallas Temperature 3", Bytes: 4, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	75:  {ID: 75, Name: "Dallas Temperature 4", Bytes: 4, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	76:  {ID: 76, Name: "Dallas Temperature ID 1", Bytes: 8, Type: Hex},
+	77:  {ID: 77, Name: "Dallas Temperature ID 2", Bytes: 8, Type: Hex},
+	78:  {ID: 78, Name: "iButton", Bytes: 8, Type: Hex},
+	79:  {ID: 79, Name: "Dallas Temperature ID 3", Bytes: 8, Type: Hex},
+	80:  {ID: 80, Name: "Data Mode", Bytes: 1, Type: Unsigned},
+	81:  {ID: 81, Name: "Vehicle Speed", Bytes: 1, Type: Unsigned, Unit: "km/h"},
+	82:  {ID: 82, Name: "Accelerator Pedal Position", Bytes: 1, Type: Unsigned, Unit: "%"},
+	83:  {ID: 83, Name: "Fuel Consumed", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "l"},
+	84:  {ID: 84, Name: "Fuel Level", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "l"},
+	85:  {ID: 85, Name: "Engine RPM", Bytes: 2, Type: Unsigned, Unit: "rpm"},
+	86:  {ID: 86, Name: "BLE 1 Humidity", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "%RH"},
+	87:  {ID: 87, Name: "Total Mileage", Bytes: 4, Type: Unsigned, Unit: "m"},
+	88:  {ID: 88, Name: "Geofence zone 12", Bytes: 1, Type: Unsigned},
+	89:  {ID: 89, Name: "Fuel Level", Bytes: 1, Type: Unsigned, Unit: "%"},
+	90:  {ID: 90, Name: "Door Status", Bytes: 2, Type: Unsigned},
+	91:  {ID: 91, Name: "Geofence zone 13", Bytes: 1, Type: Unsigned},
+	92:  {ID: 92, Name: "Geofence zone 14", Bytes: 1, Type: Unsigned},
+	93:  {ID: 93, Name: "Geofence zone 15", Bytes: 1, Type: Unsigned},
+	94:  {ID: 94, Name: "Geofence zone 16", Bytes: 1, Type: Unsigned},
+	95:  {ID: 95, Name: "Geofence zone 17", Bytes: 1, Type: Unsigned},
+	96:  {ID: 96, Name: "Geofence zone 18", Bytes: 1, Type: Unsigned},
+	97:  {ID: 97, Name: "Geofence zone 19", Bytes: 1, Type: Unsigned},
+	98:  {ID: 98, Name: "Geofence zone 20", Bytes: 1, Type: Unsigned},
+	99:  {ID: 99, Name: "Geofence zone 21", Bytes: 1, Type: Unsigned},
+	100: {ID: 100, Name: "Program Number", Bytes: 4, Type: Unsigned},
+	101: {ID: 101, Name: "Module ID", Bytes: 8, Type: Hex},
+	102: {ID: 102, Name: "Engine Worktime", Bytes: 4, Type: Unsigned, Unit: "min"},
+	103: {ID: 103, Name: "Engine Worktime (counted)", Bytes: 4, Type: Unsigned, Unit: "min"},
+	104: {ID: 104, Name: "BLE 2 Humidity", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "%RH"},
+	105: {ID: 105, Name: "Total Mileage (counted)", Bytes: 4, Type: Unsigned, Unit: "m"},
+	106: {ID: 106, Name: "BLE 3 Humidity", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "%RH"},
+	107: {ID: 107, Name: "Fuel Consumed (counted)", Bytes: 4, Type: Unsigned, Multiplier: 0.1, Unit: "l"},
+	108: {ID: 108, Name: "BLE 4 Humidity", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "%RH"},
+	109: {ID: 109, Name: "Delimiter", Type: Hex},
+	110: {ID: 110, Name: "Fuel Rate", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "l/h"},
+	111: {ID: 111, Name: "AdBlue Level", Bytes: 1, Type: Unsigned, Unit: "%"},
+	112: {ID: 112, Name: "AdBlue Level", Bytes: 2, Type: Unsigned, Multiplier: 0.1, Unit: "l"},
+	113: {ID: 113, Name: "Battery Level", Bytes: 1, Type: Unsigned, Unit: "%"},
+	114: {ID: 114, Name: "Engine Load", Bytes: 1, Type: Unsigned, Unit: "%"},
+	115: {ID: 115, Name: "Engine Temperature", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	116: {ID: 116, Name: "Charger Connected", Bytes: 1, Type: Unsigned},
+	117: {ID: 117, Name: "Driving Direction", Bytes: 1, Type: Unsigned},
+	118: {ID: 118, Name: "Axle 1 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	119: {ID: 119, Name: "Axle 2 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	120: {ID: 120, Name: "Axle 3 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	121: {ID: 121, Name: "Axle 4 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	122: {ID: 122, Name: "Axle 5 Load", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	123: {ID: 123, Name: "Control State Flags", Bytes: 4, Type: Unsigned},
+	124: {ID: 124, Name: "Agricultural Machinery Flags", Bytes: 8, Type: Hex},
+	125: {ID: 125, Name: "Harvesting Time", Bytes: 4, Type: Unsigned, Unit: "min"},
+	126: {ID: 126, Name: "Area of Harvest", Bytes: 4, Type: Unsigned, Unit: "m^2"},
+	127: {ID: 127, Name: "LVC Mowing Efficiency", Bytes: 4, Type: Unsigned, Unit: "m^2/h"},
+	128: {ID: 128, Name: "Grain Mown Volume", Bytes: 4, Type: Unsigned, Unit: "kg"},
+	129: {ID: 129, Name: "Grain Moisture", Bytes: 1, Type: Unsigned, Unit: "%"},
+	130: {ID: 130, Name: "Harvesting Drum RPM", Bytes: 2, Type: Unsigned, Unit: "rpm"},
+	131: {ID: 131, Name: "Gap Under Harvesting Drum", Bytes: 1, Type: Unsigned, Unit: "mm"},
+	132: {ID: 132, Name: "Security State Flags", Bytes: 8, Type: Hex},
+	133: {ID: 133, Name: "Tacho Total Distance", Bytes: 4, Type: Unsigned, Unit: "m"},
+	134: {ID: 134, Name: "Trip Distance", Bytes: 4, Type: Unsigned, Unit: "m"},
+	135: {ID: 135, Name: "Tacho Vehicle Speed", Bytes: 2, Type: Unsigned, Unit: "km/h"},
+	136: {ID: 136, Name: "Tacho Driver Card Presence", Bytes: 1, Type: Unsigned},
+	137: {ID: 137, Name: "Driver 1 States", Bytes: 1, Type: Unsigned},
+	138: {ID: 138, Name: "Driver 2 States", Bytes: 1, Type: Unsigned},
+	139: {ID: 139, Name: "Driver 1 Driving Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	140: {ID: 140, Name: "Driver 2 Driving Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	141: {ID: 141, Name: "Driver 1 Break Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	142: {ID: 142, Name: "Driver 2 Break Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	143: {ID: 143, Name: "Driver 1 Activity Duration", Bytes: 2, Type: Unsigned, Unit: "min"},
+	144: {ID: 144, Name: "Driver 2 Activity Duration", Bytes: 2, Type: Unsigned, Unit: "min"},
+	145: {ID: 145, Name: "Driver1 Driving Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	146: {ID: 146, Name: "Driver2 Driving Time", Bytes: 2, Type: Unsigned, Unit: "min"},
+	147: {ID: 147, Name: "Driver 1 ID High", Bytes: 8, Type: Hex},
+	148: {ID: 148, Name: "Driver 1 ID Low", Bytes: 8, Type: Hex},
+	149: {ID: 149, Name: "Driver 2 ID High", Bytes: 8, Type: Hex},
+	150: {ID: 150, Name: "Driver 2 ID Low", Bytes: 8, Type: Hex},
+	151: {ID: 151, Name: "Battery Temperature", Bytes: 2, Type: Signed, Multiplier: 0.1, Unit: "°C"},
+	152: {ID: 152, Name: "Battery Level", Bytes: 1, Type: Unsigned, Unit: "%"},
+	153: {ID: 153, Name: "Geofence zone 22", Bytes: 1, Type: Unsigned},
+	154: {ID: 154, Name: "Geofence zone 23", Bytes: 1, Type: Unsigned},
+	155: {ID: 155, Name: "Geofence zone 01", Bytes: 1, Type: Unsigned},
+	156: {ID: 156, Name: "Geofence zone 02", Bytes: 1, Type: Unsigned},
+	157: {ID: 157, Name: "Geofence zone 03", Bytes: 1, Type: Unsigned},
+	158: {ID: 158, Name: "Geofence zone 04", Bytes: 1, Type: Unsigned},
+	159: {ID: 159, Name: "Geofence zone 05", Bytes: 1, Type: Unsigned},
+	160: {ID: 160, Name: "DTC Faults", Bytes: 1, Type: Unsigned},
+	161: {ID: 161, Name: "Slope Of Arm", Bytes: 1, Type: Signed, Unit: "°"},
+	162: {ID: 162, Name: "Rotation Of Arm", Bytes: 1, Type: Signed, Unit: "°"},
+	163: {ID: 163, Name: "Eject Of Arm", Bytes: 2, Type: Unsigned, Unit: "m"},
+	164: {ID: 164, Name: "Horizontal Distance Arm", Bytes: 2, Type: Unsigned, Unit: "m"},
+	165: {ID: 165, Name: "Height Arm Above Ground", Bytes: 2, Type: Unsigned, Unit: "m"},
+	166: {ID: 166, Name: "Drill RPM", Bytes: 2, Type: Unsigned, Unit: "rpm"},
+	167: {ID: 167, Name: "Spread Salt", Bytes: 2, Type: Unsigned, Unit: "g/m^2"},
+	168: {ID: 168, Name: "Battery Voltage", Bytes: 2, Type: Unsigned, Unit: "V"},
+	169: {ID: 169, Name: "Spread Fine Grained Salt", Bytes: 4, Type: Unsigned, Unit: "T"},
+	170: {ID: 170, Name: "Coarse Grained Salt", Bytes: 4, Type: Unsigned, Unit: "T"},
+	171: {ID: 171, Name: "Spread DiMix", Bytes: 4, Type: Unsigned, Unit: "T"},
+	172: {ID: 172, Name: "Spread Coarse Grained Calcium", Bytes: 4, Type: Unsigned, Unit: "m^3"},
+	173: {ID: 173, Name: "Spread Calcium Chloride", Bytes: 4, Type: Unsigned, Unit: "m^3"},
+	174: {ID: 174, Name: "Spread Sodium Chloride", Bytes: 4, Type: Unsigned, Unit: "m^3"},
+	175: {ID: 175, Name: "Auto Geofence", Bytes: 1, Type: Unsigned},
+	176: {ID: 176, Name: "Spread Magnesium Chloride", Bytes: 4, Type: Unsigned, Unit: "m^3"},
+	177: {ID: 177, Name: "Amount Of Spread Gravel", Bytes: 4, Type: Unsigned, Unit: "T"},
+	178: {ID: 178, Name: "Amount Of Spread Sand", Bytes: 4, Type: Unsigned, Unit: "T"},
+	179: {ID: 179, Name: "Digital Output 1", Bytes: 1, Type: Unsigned},
+	180: {ID: 180, Name: "Digital Output 2", Bytes: 1, Type: Unsigned},
+	181: {ID: 181, Name: "GNSS PDOP", Bytes: 2, Type: Unsigned, Multiplier: 0.1},
+	182: {ID: 182, Name: "GNSS HDOP", Bytes: 2, Type: Unsigned, Multiplier: 0.1},
+	183: {ID: 183, Name: "Width Pouring Left", Bytes: 2, Type: Unsigned, Unit: "m"},
+	184: {ID: 184, Name: "Width Pouring Right", Bytes: 2, Type: Unsigned, Unit: "m"},
+	185: {ID: 185, Name: "Salt Spreader Working Hours", Bytes: 4, Type: Unsigned, Unit: "h"},
+	186: {ID: 186, Name: "Distance During Salting", Bytes: 4, Type: Unsigned, Unit: "km"},
+	187: {ID: 187, Name: "Load Weight", Bytes: 4, Type: Unsigned, Unit: "kg"},
+	188: {ID: 188, Name: "Retarder Load", Bytes: 1, Type: Unsigned, Unit: "%"},
+	189: {ID: 189, Name: "Cruise Time", Bytes: 4, Type: Unsigned, Unit: "min"},
+	190: {ID: 190, Name: "Geofence zone 24", Bytes: 1, Type: Unsigned},
+	191: {ID: 191, Name: "Geofence zone 25", Bytes: 1, Type: Unsigned},
+	192: {ID: 192, Name: "Geofence zone 26", Bytes: 1, Type: Unsigned},
+	193: {ID: 193, Name: "Geofence zone 27", Bytes: 1, Type: Unsigned},
+	194: {ID: 194, Name: "Geofence zone 28", Bytes: 1, Type: Unsigned},
+	195: {ID: 195, Name: "Geofence zone 29", Bytes: 1, Type: Unsigned},
+	196: {ID: 196, Name: "Geofence zone 30", Bytes: 1, Type: Unsigned},
+	197: {ID: 197, Name: "Geofence zone 31", Bytes: 1, Type: Unsigned},
+	198: {ID: 198, Name: "Geofence zone 32", Bytes: 1, Type: Unsigned},
+	199: {ID: 199, Name: "Trip Odometer", Bytes: 4, Type: Unsigned},
+	200: {ID: 200, Name: "Sleep Mode", Bytes: 1, Type: Unsigned},
+	201: {ID: 201, Name: "LLS 1 Fuel Level", Bytes: 2, Type: Unsigned, Unit: "kvants or ltr"},
+	202: {ID: 202, Name: "LLS 1 Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	203: {ID: 203, Name: "LLS 2 Fuel Level", Bytes: 2, Type: Unsigned, Unit: "kvants or ltr"},
+	204: {ID: 204, Name: "LLS 2 Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	205: {ID: 205, Name: "GSM Cell ID", Bytes: 2, Type: Unsigned},
+	206: {ID: 206, Name: "GSM Area Code", Bytes: 2, Type: Unsigned},
+	207: {ID: 207, Name: "RFID", Bytes: 8, Type: Hex},
+	208: {ID: 208, Name: "Geofence zone 33", Bytes: 1, Type: Unsigned},
+	209: {ID: 209, Name: "Geofence zone 34", Bytes: 1, Type: Unsigned},
+	210: {ID: 210, Name: "LLS 3 Fuel Level", Bytes: 2, Type: Unsigned, Unit: "kvants or ltr"},
+	211: {ID: 211, Name: "LLS 3 Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	212: {ID: 212, Name: "LLS 4 Fuel Level", Bytes: 2, Type: Unsigned, Unit: "kvants or ltr"},
+	213: {ID: 213, Name: "LLS 4 Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	214: {ID: 214, Name: "LLS 5 Fuel Level", Bytes: 2, Type: Signed, Unit: "kvants or ltr"},
+	215: {ID: 215, Name: "LLS 5 Temperature", Bytes: 1, Type: Signed, Unit: "°C"},
+	216: {ID: 216, Name: "Geofence zone 35", Bytes: 1, Type: Unsigned},
+	217: {ID: 217, Name: "Geofence zone 36", Bytes: 1, Type: Unsigned},
+	218: {ID: 218, Name: "Geofence zone 37", Bytes: 1, Type: Unsigned},
+	219: {ID: 219, Name: "Geofence zone 38", Bytes: 1, Type: Unsigned},
+	220: {ID: 220, Name: "Geofence zone 39", Bytes: 1, Type: Unsigned},
+	221: {ID: 221, Name: "Geofence zone 40", Bytes: 1, Type: Unsigned},
+	222: {ID: 222, Name: "Geofence zone 41", Bytes: 1, Type: Unsigned},
+	223: {ID: 223, Name: "Geofence zone 42", Bytes: 1, Type: Unsigned},
+	224: {ID: 224, Name: "Geofence zone 43", Bytes: 1, Type: Unsigned},
+	225: {ID: 225, Name: "Geofence zone 44", Bytes: 1, Type: Unsigned},
+	226: {ID: 226, Name: "Geofence zone 45", Bytes: 1, Type: Unsigned},
+	227: {ID: 227, Name: "Geofence zone 46", Bytes: 1, Type: Unsigned},
+	228: {ID: 228, Name: "Geofence zone 47", Bytes: 1, Type: Unsigned},
+	229: {ID: 229, Name: "Geofence zone 48", Bytes: 1, Type: Unsigned},
+	230: {ID: 230, Name: "Geofence zone 49", Bytes: 1, Type: Unsigned},
+	231: {ID: 231, Name: "Geofence zone 50", Bytes: 1, Type: Unsigned},
+	232: {ID: 232, Name: "CNG Status", Bytes: 1, Type: Unsigned},
+	233: {ID: 233, Name: "CNG Used", Bytes: 2, Type: Unsigned, Unit: "kg"},
+	234: {ID: 234, Name: "CNG Level", Bytes: 2, Type: Unsigned, Unit: "%"},
+	235: {ID: 235, Name: "Engine Oil Level", Bytes: 1, Type: Unsigned},
+	236: {ID: 236, Name: "Alarm", Bytes: 1, Type: Unsigned},
+	237: {ID: 237, Name: "Network Type", Bytes: 1, Type: Unsigned},
+	238: {ID: 238, Name: "User ID", Bytes: 8, Type: Hex},
+	239: {ID: 239, Name: "Ignition", Bytes: 1, Type: Unsigned},
+	240: {ID: 240, Name: "Movement", Bytes: 1, Type: Unsigned},
+	241: {ID: 241, Name: "Active GSM Operator", Bytes: 4, Type: Unsigned},
+	242: {ID: 242, Name: "ManDown", Bytes: 1, Type: Unsigned},
+	243: {ID: 243, Name: "Green driving event duration", Bytes: 2, Type: Unsigned, Unit: "ms"},
+	244: {ID: 244, Name: "DIN2/AIN2 spec event", Bytes: 1, Type: Unsigned, Unit: "deg/s"},
+	245: {ID: 245, Name: "Gyroscope axis", Bytes: 8, Type: Hex, Unit: "deg/s"},
+	246: {ID: 246, Name: "Towing", Bytes: 1, Type: Unsigned},
+	247: {ID: 247, Name: "Crash detection", Bytes: 1, Type: Unsigned},
+	248: {ID: 248, Name: "Immobilizer", Bytes: 1, Type: Unsigned},
+	249: {ID: 249, Name: "Jamming", Bytes: 1, Type: Unsigned},
+	250: {ID: 250, Name: "Trip", Bytes: 1, Type: Unsigned},
+	251: {ID: 251, Name: "Idling", Bytes: 1, Type: Unsigned},
+	252: {ID: 252, Name: "Unplug", Bytes: 1, Type: Unsigned},
+	253: {ID: 253, Name: "Green driving type", Bytes: 1, Type: Unsigned},
+	254: {ID: 254, Name: "Green driving value", Bytes: 1, Type: Unsigned, Unit: "G or rad"},
+	255: {ID: 255, Name: "Over Speeding", Bytes: 1, Type: Unsigned, Unit: "km/h"},
+	256: {ID: 256, Name: "VIN", Bytes: 17, Type: ASCII},
+	281: {ID: 281, Name: "Fault Codes", Type: ASCII},
+	303: {ID: 303, Name: "Instant Movement", Bytes: 1, Type: Unsigned},
+	381: {ID: 381, Name: "Ground Sense", Bytes: 1, Type: Unsigned},
+}
@@ -0,0 +1,145 @@
+//go:build ignore
+
+// gen generates the dictionaries of the device families from the teltonikajson files
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// family is a generated dictionary
+type family struct {
+	Name    string // name of the dictionary and of its JSON file
+	File    string // generated Go file
+	Comment string
+}
+
+var families = []family{
+	{
+		Name:    "FMBXY",
+		File:    "fmbxy.go",
+		Comment: "// FMBXY is the dictionary of the IO elements of FMB, FMC, FMM and other current devices like FMB920\n// https://wiki.teltonika-gps.com/view/FMB920_Teltonika_Data_Sending_Parameters_ID",
+	},
+	{
+		Name:    "FM64",
+		File:    "fm64.go",
+		Comment: "// FM64 is the dictionary of the IO elements of FM6XYZ devices\n// https://wiki.teltonika-gps.com/view/FM6300_AVL_ID_List",
+	},
+	{
+		Name:    "FM36",
+		File:    "fm36.go",
+		Comment: "// FM36 is the dictionary of the IO elements of FM36XY devices\n// https://wiki.teltonika-gps.com/view/FM3612_AVL_ID_List",
+	},
+	{
+		Name:    "FM11XY",
+		File:    "fm11xy.go",
+		Comment: "// FM11XY is the dictionary of the IO elements of FM11XY and FM12XY devices\n// https://wiki.teltonika-gps.com/view/FM1100_AVL_ID_List",
+	},
+}
+
+// element is an IO element in the teltonikajson files
+type element struct {
+	PropertyName    string
+	Bytes           string
+	Type            string
+	Multiplier      string
+	Units           string
+	FinalConversion string
+}
+
+var number = regexp.MustCompile(`[0-9]+(\.[0-9]+)?`)
+
+func main() {
+	for _, f := range families {
+		err := generate(f)
+		if err != nil {
+			log.Fatalf("Failed to generate %s. %v", f.File, err)
+		}
+	}
+}
+
+func generate(f family) error {
+	content, err := os.ReadFile(filepath.Join("teltonikajson", f.Name+".json"))
+	if err != nil {
+		return err
+	}
+
+	var elements map[string]element
+	err = json.Unmarshal(content, &elements)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int, 0, len(elements))
+	for key := range elements {
+		id, err := strconv.ParseUint(key, 10, 16)
+		if err != nil {
+			return fmt.Errorf("invalid IO element ID: %s", key)
+		}
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "// Code generated by gen.go from teltonikajson/%s.json; DO NOT EDIT.\n\n", f.Name)
+	fmt.Fprintf(&buffer, "package ioelement\n\n%s\nvar %s = Dictionary{\n", f.Comment, f.Name)
+	for _, id := range ids {
+		buffer.WriteString(definition(id, elements[strconv.Itoa(id)]))
+	}
+	buffer.WriteString("}\n")
+
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(f.File, source, 0600)
+}
+
+// definition renders the Definition literal of an IO element
+func definition(id int, e element) string {
+	fields := []string{
+		fmt.Sprintf("ID: %d", id),
+		fmt.Sprintf("Name: %q", strings.TrimSpace(e.PropertyName)),
+	}
+
+	// Variable length elements are listed as Variable or as a spreadsheet date, e.g. 43678, in the source
+	size, err := strconv.Atoi(e.Bytes)
+	if err == nil && size > 0 && size <= 64 {
+		fields = append(fields, fmt.Sprintf("Bytes: %d", size))
+	}
+
+	switch {
+	case e.Type == "String" || e.FinalConversion == "toString":
+		fields = append(fields, "Type: ASCII")
+	case e.FinalConversion == "to[]byte" || size == 0:
+		fields = append(fields, "Type: Hex")
+	case e.Type == "Signed" || strings.HasPrefix(e.FinalConversion, "toInt"):
+		fields = append(fields, "Type: Signed")
+	default:
+		fields = append(fields, "Type: Unsigned")
+	}
+
+	// Multipliers are written with decimal comma in some files and with a description in a few ones
+	multiplier, err := strconv.ParseFloat(number.FindString(strings.ReplaceAll(e.Multiplier, ",", ".")), 64)
+	if err == nil && multiplier != 0 && multiplier != 1 {
+		fields = append(fields, "Multiplier: "+strconv.FormatFloat(multiplier, 'f', -1, 64))
+	}
+
+	unit := strings.TrimSpace(e.Units)
+	if unit != "" && unit != "-" {
+		fields = append(fields, fmt.Sprintf("Unit: %q", unit))
+	}
+
+	return fmt.Sprintf("%d: {%s},\n", id, strings.Join(fields, ", "))
+}
@@ -0,0 +1,117 @@
+package ioelement
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:generate go run gen.go
+
+// Type tells how the raw value of an IO element is interpreted
+type Type string
+
+const (
+	Unsigned Type = "unsigned"
+	Signed   Type = "signed"
+	Hex      Type = "hex"
+	ASCII    Type = "ascii"
+)
+
+// Definition describes an IO element of a device model
+type Definition struct {
+	ID         uint16
+	Name       string
+	Bytes      int // size of the raw value, zero if its length is variable
+	Type       Type
+	Multiplier float64 // Raw numeric value is multiplied by this. Zero means 1.
+	Unit       string
+}
+
+// Dictionary holds the known IO elements by their IDs
+type Dictionary map[uint16]Definition
+
+// Families of devices sharing the same IO elements
+const (
+	FamilyFMBXY  = "fmbxy"  // FMB, FMC, FMM and other current devices
+	FamilyFM64   = "fm64"   // former FM63XY and FM64XY devices
+	FamilyFM36   = "fm36"   // former FM36XY devices
+	FamilyFM11XY = "fm11xy" // former FM11XY and FM12XY devices
+)
+
+// Dictionaries holds the dictionary of each family
+var Dictionaries = map[string]Dictionary{
+	FamilyFMBXY:  FMBXY,
+	FamilyFM64:   FM64,
+	FamilyFM36:   FM36,
+	FamilyFM11XY: FM11XY,
+}
+
+// Lookup returns the definition of an IO element if it is known
+func (d Dictionary) Lookup(id uint16) (Definition, bool) {
+	definition, ok := d[id]
+	return definition, ok
+}
+
+// Value converts the raw bytes of an IO element. Numbers are returned as int64, uint64 or float64 if they have multiplier.
+// Hex and ASCII values are returned as string.
+func (d Definition) Value(raw []byte) (interface{}, error) {
+	switch d.Type {
+	case Hex:
+		return hex.EncodeToString(raw), nil
+	case ASCII:
+		return strings.TrimRight(string(raw), "\x00"), nil
+	case Signed, Unsigned, "":
+		if len(raw) > 8 {
+			return nil, fmt.Errorf("%d bytes long value can not be a number", len(raw))
+		}
+
+		// Value is padded to 8 bytes to parse it as uint64
+		padded := make([]byte, 8)
+		copy(padded[8-len(raw):], raw)
+		unsigned := binary.BigEndian.Uint64(padded)
+
+		if d.Type == Signed {
+			// Sign extension from the original size
+			shift := 64 - 8*len(raw)
+			signed := int64(unsigned<<shift) >> shift // #nosec G115 two's complement
+			if d.Multiplier != 0 && d.Multiplier != 1 {
+				return float64(signed) * d.Multiplier, nil
+			}
+			return signed, nil
+		}
+
+		if d.Multiplier != 0 && d.Multiplier != 1 {
+			return float64(unsigned) * d.Multiplier, nil
+		}
+		return unsigned, nil
+	default:
+		return nil, fmt.Errorf("unknown type: %s", d.Type)
+	}
+}
+
+// Format converts the raw bytes of an IO element to human readable text with its unit
+func (d Definition) Format(raw []byte) string {
+	value, err := d.Value(raw)
+	if err != nil {
+		return hex.EncodeToString(raw)
+	}
+
+	return FormatValue(value, d.Unit)
+}
+
+// FormatValue converts a value returned by Definition.Value to text with its unit
+func FormatValue(value interface{}, unit string) string {
+	text := fmt.Sprint(value)
+	if number, ok := value.(float64); ok {
+		text = strconv.FormatFloat(number, 'f', -1, 64)
+	}
+
+	if unit != "" {
+		return text + " " + unit
+	}
+
+	return text
+}
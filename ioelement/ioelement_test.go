@@ -0,0 +1,192 @@
+package ioelement
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValue(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		Definition Definition
+		Raw        []byte
+		Expected   interface{}
+		Formatted  string
+	}{
+		{
+			Name:       "Unsigned",
+			Definition: Definition{Type: Unsigned, Unit: "m"},
+			Raw:        []byte{0x01, 0x02},
+			Expected:   uint64(258),
+			Formatted:  "258 m",
+		},
+		{
+			Name:       "Signed",
+			Definition: Definition{Type: Signed, Unit: "mG"},
+			Raw:        []byte{0xFF, 0xF6},
+			Expected:   int64(-10),
+			Formatted:  "-10 mG",
+		},
+		{
+			Name:       "Multiplier",
+			Definition: Definition{Type: Unsigned, Multiplier: 0.001, Unit: "V"},
+			Raw:        []byte{0x2E, 0x97},
+			Expected:   11.927,
+			Formatted:  "11.927 V",
+		},
+		{
+			Name:       "Hex",
+			Definition: Definition{Type: Hex},
+			Raw:        []byte{0xAB, 0xCD},
+			Expected:   "abcd",
+			Formatted:  "abcd",
+		},
+		{
+			Name:       "ASCII",
+			Definition: Definition{Type: ASCII},
+			Raw:        []byte("4729.9,01902.4\x00"),
+			Expected:   "4729.9,01902.4",
+			Formatted:  "4729.9,01902.4",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			value, err := testCase.Definition.Value(testCase.Raw)
+			if err != nil {
+				test.Fatalf("Failed to convert value. %v", err)
+			}
+
+			if value != testCase.Expected {
+				test.Errorf("Wrong value! Expected: %v (%T) Actual: %v (%T)", testCase.Expected, testCase.Expected, value, value)
+			}
+
+			formatted := testCase.Definition.Format(testCase.Raw)
+			if formatted != testCase.Formatted {
+				test.Errorf("Wrong formatted value! Expected: %v Actual: %v", testCase.Formatted, formatted)
+			}
+		})
+	}
+
+	_, err := Definition{Type: Unsigned}.Value(make([]byte, 9))
+	if err == nil {
+		t.Errorf("Value longer than 8 bytes must not be converted to number")
+	}
+}
+
+func TestFMBXY(t *testing.T) {
+	for family, dictionary := range Dictionaries {
+		for id, definition := range dictionary {
+			if definition.ID != id {
+				t.Errorf("Wrong ID of %s in %s! Expected: %v Actual: %v", definition.Name, family, id, definition.ID)
+			}
+		}
+	}
+
+	definition, ok := FMBXY.Lookup(66)
+	if !ok || definition.Name != "External Voltage" {
+		t.Errorf("Wrong definition of IO element 66: %+v", definition)
+	}
+}
+
+func TestGenerated(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		Dictionary Dictionary
+		Count      int
+		Samples    []Definition
+	}{
+		{
+			Name:       "FMBXY",
+			Dictionary: FMBXY,
+			Count:      259,
+			Samples: []Definition{
+				{ID: 11, Name: "ICCID1", Bytes: 8, Type: Hex},
+				{ID: 17, Name: "Axis X", Bytes: 2, Type: Signed, Unit: "mG"},
+				{ID: 66, Name: "External Voltage", Bytes: 2, Type: Unsigned, Unit: "mV"},
+				{ID: 256, Name: "VIN", Bytes: 17, Type: ASCII},
+				{ID: 281, Name: "Fault Codes", Type: ASCII},
+			},
+		},
+		{
+			Name:       "FM64",
+			Dictionary: FM64,
+			Count:      398,
+			Samples: []Definition{
+				{ID: 9, Name: "Analog Input 1", Bytes: 2, Type: Unsigned, Multiplier: 0.001, Unit: "V"},
+				{ID: 145, Name: "Manual CAN 00", Type: Hex},
+				{ID: 254, Name: "Green Driving Value", Bytes: 1, Type: Unsigned, Multiplier: 0.01, Unit: "G or rad"},
+			},
+		},
+		{
+			Name:       "FM36",
+			Dictionary: FM36,
+			Count:      136,
+			Samples: []Definition{
+				{ID: 1, Name: "Digital Input Status 1", Bytes: 1, Type: Unsigned},
+				{ID: 21, Name: "GSM level", Bytes: 1, Type: Unsigned},
+			},
+		},
+		{
+			Name:       "FM11XY",
+			Dictionary: FM11XY,
+			Count:      126,
+			Samples: []Definition{
+				{ID: 21, Name: "GSM level", Bytes: 1, Type: Unsigned},
+				{ID: 72, Name: "Dallas Temperature 1", Bytes: 4, Type: Hex},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			content, err := os.ReadFile(filepath.Join("teltonikajson", testCase.Name+".json"))
+			if err != nil {
+				test.Fatalf("Failed to read source. %v", err)
+			}
+
+			var source map[string]struct {
+				PropertyName string
+				Bytes        string
+			}
+			err = json.Unmarshal(content, &source)
+			if err != nil {
+				test.Fatalf("Failed to parse source. %v", err)
+			}
+
+			if len(testCase.Dictionary) != testCase.Count || len(source) != testCase.Count {
+				test.Errorf("Wrong number of IO elements! Expected: %v Actual: %v, %v in source", testCase.Count, len(testCase.Dictionary), len(source))
+			}
+
+			for key, element := range source {
+				id, err := strconv.ParseUint(key, 10, 16)
+				if err != nil {
+					test.Fatalf("Invalid IO element ID: %s", key)
+				}
+
+				definition, ok := testCase.Dictionary.Lookup(uint16(id))
+				if !ok {
+					test.Errorf("Missing IO element %d", id)
+					continue
+				}
+				if definition.Name != strings.TrimSpace(element.PropertyName) {
+					test.Errorf("Wrong name of IO element %d! Expected: %v Actual: %v", id, element.PropertyName, definition.Name)
+				}
+				if definition.Bytes != 0 && strconv.Itoa(definition.Bytes) != element.Bytes {
+					test.Errorf("Wrong size of IO element %d! Expected: %v Actual: %v", id, element.Bytes, definition.Bytes)
+				}
+			}
+
+			for _, sample := range testCase.Samples {
+				definition, _ := testCase.Dictionary.Lookup(sample.ID)
+				if definition != sample {
+					test.Errorf("Wrong definition of IO element %d! Expected: %+v Actual: %+v", sample.ID, sample, definition)
+				}
+			}
+		})
+	}
+}
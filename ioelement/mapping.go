@@ -0,0 +1,364 @@
+package ioelement
+
+import (
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FieldType is the type of the stored value of an IO element
+type FieldType string
+
+const (
+	FieldBool   FieldType = "bool"
+	FieldInt    FieldType = "int"
+	FieldFloat  FieldType = "float"
+	FieldString FieldType = "string"
+)
+
+// What happens with the IO elements which are neither in the dictionary nor in the overrides
+const (
+	UnknownKeep = "keep" // stored by IOID<n> names as integers or hex strings
+	UnknownDrop = "drop"
+)
+
+// Override changes how an IO element is stored. Empty fields keep the value of the dictionary.
+type Override struct {
+	Name       string    // name of the stored field, e.g. ignition
+	Type       FieldType // type of the stored value
+	Encoding   Type      // how the raw bytes are read: unsigned, signed, hex or ascii
+	Multiplier *float64
+	Unit       *string
+	Drop       bool // the IO element is not stored
+}
+
+// ModelMapping holds the overrides of a device model on top of the global ones
+type ModelMapping struct {
+	Elements map[uint16]Override
+	Drop     []uint16 // IDs of IO elements which are not stored for this model
+}
+
+// MappingConfig is the iomapping section of the config file
+type MappingConfig struct {
+	Enabled  bool
+	Unknown  string // UnknownKeep or UnknownDrop
+	Elements map[uint16]Override
+	Models   map[string]ModelMapping // by model name, case-insensitive
+}
+
+// Field tells how an IO element is stored
+type Field struct {
+	Definition
+	Field string
+	Type  FieldType
+}
+
+// Mapping converts the IO elements of a device model to named and typed fields
+type Mapping struct {
+	fields      map[uint16]Field
+	dropped     map[uint16]bool
+	dropUnknown bool
+}
+
+/*
+Mapper holds the mapping of each device model and the default one for the devices without model. Mappings of the models
+without overrides are created when they are used first, so models set after startup get their own dictionary as well.
+*/
+type Mapper struct {
+	dictionaries DictionaryFunc
+	cfg          MappingConfig
+	reserved     map[string]bool
+
+	defaults   Mapping
+	models     map[string]Mapping // by lowercase model name
+	modelsLock sync.Mutex
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// FieldName converts the name of an IO element to a field name, e.g. "BLE Temperature #1" to ble_temperature_1
+func FieldName(name string) string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// DictionaryFunc returns the dictionary of a device model. The empty model stands for the devices without a known model.
+type DictionaryFunc func(model string) Dictionary
+
+/*
+NewMapper creates the mappings of the device models with overrides, so invalid overrides are reported at startup.
+Each model is mapped with its own dictionary. Default names colliding with the reserved ones get io_ prefix.
+*/
+func NewMapper(dictionaries DictionaryFunc, cfg MappingConfig, reserved ...string) (*Mapper, error) {
+	if cfg.Unknown != "" && cfg.Unknown != UnknownKeep && cfg.Unknown != UnknownDrop {
+		return nil, fmt.Errorf("unknown must be %s or %s, got %s", UnknownKeep, UnknownDrop, cfg.Unknown)
+	}
+
+	reservedNames := make(map[string]bool, len(reserved))
+	for _, name := range reserved {
+		reservedNames[name] = true
+	}
+
+	defaults, err := newMapping(dictionaries(""), cfg, nil, reservedNames)
+	if err != nil {
+		return nil, err
+	}
+
+	mapper := &Mapper{
+		dictionaries: dictionaries,
+		cfg:          cfg,
+		reserved:     reservedNames,
+		defaults:     defaults,
+		models:       make(map[string]Mapping, len(cfg.Models)),
+	}
+
+	// Models by their lowercase names, viper lowercases the keys anyway
+	for model, modelMapping := range cfg.Models {
+		modelMapping := modelMapping
+		mapping, err := newMapping(dictionaries(model), cfg, &modelMapping, reservedNames)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mapping of %s model. %v", model, err)
+		}
+		mapper.models[strings.ToLower(model)] = mapping
+	}
+
+	return mapper, nil
+}
+
+func newMapping(dictionary Dictionary, cfg MappingConfig, model *ModelMapping, reserved map[string]bool) (Mapping, error) {
+	mapping := Mapping{
+		fields:      make(map[uint16]Field, len(dictionary)),
+		dropped:     make(map[uint16]bool),
+		dropUnknown: cfg.Unknown == UnknownDrop,
+	}
+
+	// Some dictionaries have the same name for more IO elements, e.g. Fuel Level of OBD and CAN, so the names of the
+	// higher IDs get their ID as suffix
+	ids := make([]uint16, 0, len(dictionary))
+	for id := range dictionary {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	defaultNames := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		definition := dictionary[id]
+		field := Field{
+			Definition: definition,
+			Field:      FieldName(definition.Name),
+			Type:       defaultFieldType(definition),
+		}
+		if reserved[field.Field] {
+			field.Field = "io_" + field.Field
+		}
+		if defaultNames[field.Field] {
+			field.Field = fmt.Sprintf("%s_%d", field.Field, id)
+		}
+		defaultNames[field.Field] = true
+		mapping.fields[id] = field
+	}
+
+	// Model specific overrides are applied after the global ones
+	overrides := []map[uint16]Override{cfg.Elements}
+	if model != nil {
+		overrides = append(overrides, model.Elements)
+	}
+	for _, elements := range overrides {
+		for id, override := range elements {
+			field, err := applyOverride(mapping.fields[id], id, override, reserved)
+			if err != nil {
+				return Mapping{}, fmt.Errorf("invalid mapping of IO element %d. %v", id, err)
+			}
+			mapping.fields[id] = field
+			mapping.dropped[id] = override.Drop
+		}
+	}
+	if model != nil {
+		for _, id := range model.Drop {
+			mapping.dropped[id] = true
+		}
+	}
+
+	// Names must be unique, otherwise values would overwrite each other
+	names := make(map[string]uint16, len(mapping.fields))
+	for id, field := range mapping.fields {
+		if mapping.dropped[id] {
+			continue
+		}
+		other, ok := names[field.Field]
+		if ok {
+			return Mapping{}, fmt.Errorf("IO elements %d and %d have the same name: %s", min(id, other), max(id, other), field.Field)
+		}
+		names[field.Field] = id
+	}
+
+	return mapping, nil
+}
+
+// defaultFieldType is float for scaled numbers, int for other numbers and string for hex and ASCII values
+func defaultFieldType(definition Definition) FieldType {
+	switch definition.Type {
+	case Hex, ASCII:
+		return FieldString
+	default:
+		if definition.Multiplier != 0 && definition.Multiplier != 1 {
+			return FieldFloat
+		}
+		return FieldInt
+	}
+}
+
+func applyOverride(field Field, id uint16, override Override, reserved map[string]bool) (Field, error) {
+	// IO elements missing from the dictionary are unsigned integers by default
+	if field.Field == "" {
+		field = Field{
+			Definition: Definition{ID: id, Name: fmt.Sprintf("IO element %d", id), Type: Unsigned},
+			Field:      fmt.Sprintf("IOID%d", id),
+			Type:       FieldInt,
+		}
+	}
+
+	if override.Name != "" {
+		if reserved[override.Name] {
+			return Field{}, fmt.Errorf("name %s is used by the record", override.Name)
+		}
+		field.Field = override.Name
+	}
+	if override.Encoding != "" {
+		switch override.Encoding {
+		case Unsigned, Signed, Hex, ASCII:
+		default:
+			return Field{}, fmt.Errorf("unknown encoding: %s", override.Encoding)
+		}
+		field.Definition.Type = override.Encoding
+	}
+	if override.Multiplier != nil {
+		field.Multiplier = *override.Multiplier
+	}
+	if override.Unit != nil {
+		field.Unit = *override.Unit
+	}
+
+	switch override.Type {
+	case "":
+		if override.Encoding != "" || override.Multiplier != nil {
+			field.Type = defaultFieldType(field.Definition)
+		}
+	case FieldBool, FieldInt, FieldFloat, FieldString:
+		field.Type = override.Type
+	default:
+		return Field{}, fmt.Errorf("unknown type: %s", override.Type)
+	}
+
+	return field, nil
+}
+
+// Mapping returns the mapping of a device model. The default mapping is used for the empty model.
+func (m *Mapper) Mapping(model string) Mapping {
+	model = strings.ToLower(model)
+	if model == "" {
+		return m.defaults
+	}
+
+	m.modelsLock.Lock()
+	defer m.modelsLock.Unlock()
+
+	mapping, ok := m.models[model]
+	if ok {
+		return mapping
+	}
+
+	// Models without overrides can not be invalid
+	mapping, err := newMapping(m.dictionaries(model), m.cfg, &ModelMapping{}, m.reserved)
+	if err != nil {
+		return m.defaults
+	}
+	m.models[model] = mapping
+
+	return mapping
+}
+
+// Field returns how an IO element is stored. False if it is dropped or unknown.
+func (m Mapping) Field(id uint16) (Field, bool) {
+	if m.dropped[id] {
+		return Field{}, false
+	}
+
+	field, ok := m.fields[id]
+	return field, ok
+}
+
+// Values converts the IO elements to fields. Values which can not be converted are kept by their IOID<n> names.
+func (m Mapping) Values(elements []codec.Element) map[string]interface{} {
+	values := make(map[string]interface{}, len(elements))
+	for _, element := range elements {
+		raw := element.Value
+
+		field, ok := m.Field(element.IOID)
+		if !ok {
+			if m.dropped[element.IOID] || m.dropUnknown {
+				continue
+			}
+			field = Field{Definition: Definition{Type: Unsigned}, Field: fmt.Sprintf("IOID%d", element.IOID), Type: FieldInt}
+			if len(raw) > 8 {
+				field.Definition.Type = Hex
+				field.Type = FieldString
+			}
+		}
+
+		value, err := field.Convert(raw)
+		if err != nil {
+			values[fmt.Sprintf("IOID%d", element.IOID)] = fmt.Sprintf("%x", raw)
+			continue
+		}
+		values[field.Field] = value
+	}
+
+	return values
+}
+
+// Convert reads the raw bytes and converts them to the type of the field: bool, int64, float64 or string
+func (f Field) Convert(raw []byte) (interface{}, error) {
+	value, err := f.Value(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch f.Type {
+	case FieldString:
+		return FormatValue(value, ""), nil
+	case FieldBool, FieldInt, FieldFloat, "":
+	default:
+		return nil, fmt.Errorf("unknown type: %s", f.Type)
+	}
+
+	var number float64
+	switch v := value.(type) {
+	case uint64:
+		number = float64(v)
+	case int64:
+		number = float64(v)
+	case float64:
+		number = v
+	default:
+		return nil, fmt.Errorf("%s value can not be converted to %s", f.Definition.Type, f.Type)
+	}
+
+	switch f.Type {
+	case FieldBool:
+		return number != 0, nil
+	case FieldFloat:
+		return number, nil
+	default:
+		if unsigned, ok := value.(uint64); ok && unsigned <= math.MaxInt64 {
+			return int64(unsigned), nil
+		}
+		if signed, ok := value.(int64); ok {
+			return signed, nil
+		}
+		return int64(math.Round(number)), nil
+	}
+}
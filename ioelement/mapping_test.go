@@ -0,0 +1,241 @@
+package ioelement
+
+import (
+	"github.com/halacs/haltonika/codec"
+	"testing"
+)
+
+func TestFieldName(t *testing.T) {
+	testCases := map[string]string{
+		"Ignition":           "ignition",
+		"External Voltage":   "external_voltage",
+		"BLE Temperature #1": "ble_temperature_1",
+		"Fuel Rate GPS":      "fuel_rate_gps",
+	}
+
+	for name, expected := range testCases {
+		actual := FieldName(name)
+		if actual != expected {
+			t.Errorf("Wrong field name of %s! Expected: %v Actual: %v", name, expected, actual)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Field    Field
+		Raw      []byte
+		Expected interface{}
+	}{
+		{
+			Name:     "Bool",
+			Field:    Field{Definition: Definition{Type: Unsigned}, Type: FieldBool},
+			Raw:      []byte{0x01},
+			Expected: true,
+		},
+		{
+			Name:     "Int",
+			Field:    Field{Definition: Definition{Type: Signed}, Type: FieldInt},
+			Raw:      []byte{0xFF, 0xF6},
+			Expected: int64(-10),
+		},
+		{
+			Name:     "Rounded int",
+			Field:    Field{Definition: Definition{Type: Unsigned, Multiplier: 0.1}, Type: FieldInt},
+			Raw:      []byte{0x00, 0x1A},
+			Expected: int64(3),
+		},
+		{
+			Name:     "Float",
+			Field:    Field{Definition: Definition{Type: Unsigned, Multiplier: 0.001}, Type: FieldFloat},
+			Raw:      []byte{0x2E, 0x97},
+			Expected: 11.927,
+		},
+		{
+			Name:     "String of number",
+			Field:    Field{Definition: Definition{Type: Unsigned}, Type: FieldString},
+			Raw:      []byte{0x01, 0x02},
+			Expected: "258",
+		},
+		{
+			Name:     "Hex string",
+			Field:    Field{Definition: Definition{Type: Hex}, Type: FieldString},
+			Raw:      []byte{0xAB, 0xCD},
+			Expected: "abcd",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			value, err := testCase.Field.Convert(testCase.Raw)
+			if err != nil {
+				test.Fatalf("Failed to convert value. %v", err)
+			}
+
+			if value != testCase.Expected {
+				test.Errorf("Wrong value! Expected: %v (%T) Actual: %v (%T)", testCase.Expected, testCase.Expected, value, value)
+			}
+		})
+	}
+
+	_, err := Field{Definition: Definition{Type: Hex}, Type: FieldBool}.Convert([]byte{0x01})
+	if err == nil {
+		t.Errorf("Hex value must not be converted to bool")
+	}
+}
+
+// fmbxy maps every model with the FMBXY dictionary
+func fmbxy(string) Dictionary {
+	return FMBXY
+}
+
+func TestMapper(t *testing.T) {
+	multiplier := 0.1
+	dictionaries := func(model string) Dictionary {
+		if model == "fm3622" {
+			return FM36
+		}
+		return FMBXY
+	}
+	mapper, err := NewMapper(dictionaries, MappingConfig{
+		Elements: map[uint16]Override{
+			239:  {Name: "ignition", Type: FieldBool},
+			1000: {Name: "fuel_sensor", Multiplier: &multiplier},
+		},
+		Models: map[string]ModelMapping{
+			"FMC130": {
+				Elements: map[uint16]Override{
+					239: {Name: "engine_on", Type: FieldBool},
+				},
+				Drop: []uint16{66},
+			},
+		},
+	}, "speed")
+	if err != nil {
+		t.Fatalf("Failed to create mapper. %v", err)
+	}
+
+	elements := []codec.Element{
+		{Length: 1, IOID: 239, Value: []byte{0x01}},
+		{Length: 2, IOID: 66, Value: []byte{0x2E, 0x97}},
+		{Length: 2, IOID: 24, Value: []byte{0x00, 0x32}},
+		{Length: 2, IOID: 1000, Value: []byte{0x00, 0x1A}},
+		{Length: 1, IOID: 2000, Value: []byte{0x07}},
+		{Length: 2, IOID: 10, Value: []byte{0x04, 0xB0}},
+	}
+
+	testCases := []struct {
+		Name     string
+		Model    string
+		Expected map[string]interface{}
+	}{
+		{
+			Name:  "Default",
+			Model: "",
+			Expected: map[string]interface{}{
+				"ignition":         true,
+				"external_voltage": int64(11927),
+				"io_speed":         int64(50), // record has speed field
+				"fuel_sensor":      2.6,
+				"IOID2000":         int64(7),
+				"sd_status":        int64(1200),
+			},
+		},
+		{
+			Name:  "Model",
+			Model: "fmc130",
+			Expected: map[string]interface{}{
+				"engine_on":   true,
+				"io_speed":    int64(50),
+				"fuel_sensor": 2.6,
+				"IOID2000":    int64(7),
+				"sd_status":   int64(1200),
+			},
+		},
+		{
+			Name:  "Model without overrides gets its own dictionary",
+			Model: "FM3622",
+			Expected: map[string]interface{}{
+				"ignition":               true,
+				"external_power_voltage": int64(11927),
+				"io_speed":               int64(50),
+				"fuel_sensor":            2.6,
+				"IOID2000":               int64(7),
+				"analog_input_2":         int64(1200),
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			values := mapper.Mapping(testCase.Model).Values(elements)
+			if len(values) != len(testCase.Expected) {
+				test.Errorf("Wrong values! Expected: %v Actual: %v", testCase.Expected, values)
+			}
+			for name, expected := range testCase.Expected {
+				if values[name] != expected {
+					test.Errorf("Wrong %s value! Expected: %v (%T) Actual: %v (%T)", name, expected, expected, values[name], values[name])
+				}
+			}
+		})
+	}
+
+	// IO elements of the same name get their IDs as suffix except the lowest one
+	for id, expected := range map[uint16]string{48: "fuel_level", 84: "fuel_level_84", 89: "fuel_level_89"} {
+		field, _ := mapper.Mapping("").Field(id)
+		if field.Field != expected {
+			t.Errorf("Wrong name of IO element %d! Expected: %v Actual: %v", id, expected, field.Field)
+		}
+	}
+
+	dropUnknown, err := NewMapper(fmbxy, MappingConfig{Unknown: UnknownDrop})
+	if err != nil {
+		t.Fatalf("Failed to create mapper. %v", err)
+	}
+	values := dropUnknown.Mapping("").Values(elements)
+	if _, ok := values["IOID2000"]; ok || len(values) != 4 {
+		t.Errorf("Unknown IO elements must be dropped: %v", values)
+	}
+}
+
+func TestMapperInvalid(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Config MappingConfig
+	}{
+		{
+			Name:   "Unknown type",
+			Config: MappingConfig{Elements: map[uint16]Override{239: {Type: "number"}}},
+		},
+		{
+			Name:   "Unknown encoding",
+			Config: MappingConfig{Elements: map[uint16]Override{239: {Encoding: "bcd"}}},
+		},
+		{
+			Name:   "Same name",
+			Config: MappingConfig{Elements: map[uint16]Override{239: {Name: "movement"}}},
+		},
+		{
+			Name:   "Reserved name",
+			Config: MappingConfig{Elements: map[uint16]Override{239: {Name: "speed"}}},
+		},
+		{
+			Name:   "Same name in model",
+			Config: MappingConfig{Models: map[string]ModelMapping{"FMB920": {Elements: map[uint16]Override{66: {Name: "battery_voltage"}}}}},
+		},
+		{
+			Name:   "Unknown handling",
+			Config: MappingConfig{Unknown: "ignore"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			_, err := NewMapper(fmbxy, testCase.Config, "speed")
+			if err == nil {
+				test.Errorf("Invalid mapping must be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package jetstream
+
+import (
+	"context"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/sink"
+	"time"
+)
+
+// EnvelopeVersion is increased on incompatible changes of the envelopes
+const EnvelopeVersion = 1
+
+// TypeRecord is the type of RecordEnvelope. DeviceEventEnvelope has the type of the event, e.g. connected or disconnected.
+const TypeRecord = "record"
+
+// Position of a record
+type Position struct {
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Altitude   int16   `json:"altitude"`
+	Angle      uint16  `json:"angle"`
+	Satellites uint8   `json:"satellites"`
+	Speed      uint16  `json:"speed"`
+}
+
+// RecordEnvelope is published for each decoded record
+type RecordEnvelope struct {
+	Version        int                    `json:"version"`
+	Type           string                 `json:"type"`
+	IMEI           string                 `json:"imei"`
+	Time           time.Time              `json:"time"`
+	ServerTime     time.Time              `json:"serverTime"`
+	Source         string                 `json:"source"`
+	Codec          byte                   `json:"codec"`
+	Priority       uint8                  `json:"priority"`
+	EventID        uint16                 `json:"eventID"`
+	GenerationType *uint8                 `json:"generationType,omitempty"` // Codec 16 only
+	Position       Position               `json:"position"`
+	IO             map[string]interface{} `json:"io"` // by IOID<n> keys
+}
+
+// DeviceEventEnvelope is published when a device comes online or goes offline
+type DeviceEventEnvelope struct {
+	Version    int       `json:"version"`
+	Type       string    `json:"type"`
+	IMEI       string    `json:"imei"`
+	ServerTime time.Time `json:"serverTime"`
+	Source     string    `json:"source"`
+	Transport  string    `json:"transport"`
+}
+
+func newRecordEnvelope(ctx context.Context, message sink.Message, record codec.AvlData, now time.Time) RecordEnvelope {
+	envelope := RecordEnvelope{
+		Version:    EnvelopeVersion,
+		Type:       TypeRecord,
+		IMEI:       message.Decoded.IMEI,
+		Time:       sink.Timestamp(record).UTC(),
+		ServerTime: now,
+		Source:     message.SourceAddress,
+		Codec:      message.Decoded.CodecID,
+		Priority:   record.Priority,
+		EventID:    record.EventID,
+		Position: Position{
+			Latitude:   sink.Latitude(record),
+			Longitude:  sink.Longitude(record),
+			Altitude:   record.Altitude,
+			Angle:      record.Angle,
+			Satellites: record.VisSat,
+			Speed:      record.Speed,
+		},
+		IO: sink.IOValues(ctx, record),
+	}
+
+	if record.GenerationType != codec.GenerationUnsupported {
+		generationType := record.GenerationType
+		envelope.GenerationType = &generationType
+	}
+
+	return envelope
+}
+
+func newDeviceEventEnvelope(event sink.DeviceEvent) DeviceEventEnvelope {
+	return DeviceEventEnvelope{
+		Version:    EnvelopeVersion,
+		Type:       event.Type,
+		IMEI:       event.IMEI,
+		ServerTime: event.Time,
+		Source:     event.SourceAddress,
+		Transport:  event.Transport,
+	}
+}
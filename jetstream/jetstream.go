@@ -0,0 +1,348 @@
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/sink"
+	"github.com/nats-io/nats.go"
+	natsjs "github.com/nats-io/nats.go/jetstream"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultURL           = "nats://localhost:4222"
+	DefaultSubjectPrefix = config.AppName
+	DefaultStream        = "HALTONIKA"
+	dialTimeout          = 5 * time.Second
+	ackTimeout           = 10 * time.Second
+
+	// Subjects under <prefix>.<imei>.
+	SubjectRecord = "record"
+	SubjectDevice = "device"
+)
+
+// Options of a NATS JetStream sink
+type Options struct {
+	URL                string // nats:// or tls:// URL, may contain user and password
+	Name               string // name of the connection shown by the server
+	Username           string
+	Password           string
+	Token              string
+	SubjectPrefix      string
+	Stream             string // JetStream stream storing the subjects
+	CreateStream       bool   // create the stream if it does not exist
+	CACert             string // file of the CA certificates to verify the server
+	ClientCert         string // file of the client certificate
+	ClientKey          string // file of the key of the client certificate
+	InsecureSkipVerify bool
+}
+
+// ParseOptions decodes the options of a NATS JetStream sink
+func ParseOptions(options map[string]interface{}) (Options, error) {
+	parsed := Options{
+		URL:           DefaultURL,
+		Name:          config.AppName,
+		SubjectPrefix: DefaultSubjectPrefix,
+		Stream:        DefaultStream,
+		CreateStream:  true,
+	}
+
+	err := sink.DecodeOptions(options, &parsed)
+	if err != nil {
+		return Options{}, err
+	}
+
+	parsed.SubjectPrefix = strings.TrimSuffix(parsed.SubjectPrefix, ".")
+	if parsed.SubjectPrefix == "" || strings.ContainsAny(parsed.SubjectPrefix, " \t*>") {
+		return Options{}, fmt.Errorf("invalid subject prefix: %q", parsed.SubjectPrefix)
+	}
+	if parsed.Stream == "" || strings.ContainsAny(parsed.Stream, " \t.*>") {
+		return Options{}, fmt.Errorf("invalid stream name: %q", parsed.Stream)
+	}
+	if (parsed.ClientCert == "") != (parsed.ClientKey == "") {
+		return Options{}, fmt.Errorf("clientcert and clientkey must be set together")
+	}
+
+	return parsed, nil
+}
+
+// natsOptions returns the options of the connection to the server
+func (o Options) natsOptions() ([]nats.Option, error) {
+	options := []nats.Option{
+		nats.Name(o.Name),
+		nats.Timeout(dialTimeout),
+		nats.MaxReconnects(-1),
+	}
+
+	if o.Username != "" {
+		options = append(options, nats.UserInfo(o.Username, o.Password))
+	}
+	if o.Token != "" {
+		options = append(options, nats.Token(o.Token))
+	}
+
+	// tls:// URLs without certificates are verified by the system CA certificates
+	if o.CACert != "" || o.ClientCert != "" || o.InsecureSkipVerify {
+		tlsConfig, err := sink.NewTLSConfig(o.CACert, o.ClientCert, o.ClientKey, o.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, nats.Secure(tlsConfig))
+	}
+
+	return options, nil
+}
+
+/*
+Publisher publishes the records and device events into NATS JetStream. It implements sink.Sink.
+Each device has its own subjects, so records of a device keep their order for consumers filtering by IMEI.
+*/
+type Publisher struct {
+	ctx     context.Context
+	options Options
+
+	conn     *nats.Conn
+	js       natsjs.JetStream
+	connLock sync.Mutex
+
+	sink.HealthState
+}
+
+func NewPublisher(ctx context.Context, options Options) *Publisher {
+	return &Publisher{
+		ctx:     ctx,
+		options: options,
+	}
+}
+
+// Connect connects to the server and creates the stream if needed. Connection is restored by the client library if it is lost.
+func (p *Publisher) Connect() error {
+	_, err := p.jetStream()
+	return err
+}
+
+// jetStream returns the JetStream context of the open connection or opens a new one
+func (p *Publisher) jetStream() (natsjs.JetStream, error) {
+	p.connLock.Lock()
+	defer p.connLock.Unlock()
+
+	if p.conn != nil && !p.conn.IsClosed() {
+		return p.js, nil
+	}
+
+	log := config.GetLogger(p.ctx)
+
+	options, err := p.options.natsOptions()
+	if err != nil {
+		return nil, err
+	}
+	options = append(options,
+		nats.DisconnectErrHandler(func(conn *nats.Conn, err error) {
+			log.Warningf("Disconnected from NATS server %s. %v", p.options.URL, err)
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			log.Infof("Reconnected to NATS server %s", conn.ConnectedUrlRedacted())
+		}),
+	)
+
+	conn, err := nats.Connect(p.options.URL, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS server %s. %v", p.options.URL, err)
+	}
+
+	js, err := natsjs.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	err = p.ensureStream(js)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	log.Infof("Connected to NATS server %s", p.options.URL)
+	p.conn = conn
+	p.js = js
+
+	return js, nil
+}
+
+// ensureStream checks that the stream exists and creates it if it is enabled
+func (p *Publisher) ensureStream(js natsjs.JetStream) error {
+	ctx, cancel := context.WithTimeout(p.ctx, ackTimeout)
+	defer cancel()
+
+	_, err := js.Stream(ctx, p.options.Stream)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, natsjs.ErrStreamNotFound) || !p.options.CreateStream {
+		return fmt.Errorf("failed to get %s stream. %v", p.options.Stream, err)
+	}
+
+	_, err = js.CreateStream(ctx, natsjs.StreamConfig{
+		Name:     p.options.Stream,
+		Subjects: []string{p.options.SubjectPrefix + ".>"},
+		Storage:  natsjs.FileStorage,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s stream. %v", p.options.Stream, err)
+	}
+
+	config.GetLogger(p.ctx).Infof("%s stream is created for %s.> subjects", p.options.Stream, p.options.SubjectPrefix)
+
+	return nil
+}
+
+// waitAck waits until JetStream stored the message
+func waitAck(future natsjs.PubAckFuture, timeout <-chan time.Time) error {
+	select {
+	case <-future.Ok():
+		return nil
+	case err := <-future.Err():
+		return err
+	case <-timeout:
+		return fmt.Errorf("no reply from NATS server in %v", ackTimeout)
+	}
+}
+
+func (p *Publisher) Close() error {
+	p.connLock.Lock()
+	defer p.connLock.Unlock()
+
+	if p.conn != nil {
+		_ = p.conn.FlushTimeout(ackTimeout)
+		p.conn.Close()
+	}
+
+	return nil
+}
+
+// Subject returns the subject of a device, e.g. haltonika.350424063817363.record
+func (p *Publisher) Subject(imei string, subject string) string {
+	return p.options.SubjectPrefix + "." + imei + "." + subject
+}
+
+// Write publishes the records of the message in order and waits until JetStream stored them
+func (p *Publisher) Write(message sink.Message) error {
+	err := p.write(message)
+	p.SetLastError(err)
+
+	return err
+}
+
+func (p *Publisher) write(message sink.Message) error {
+	js, err := p.jetStream()
+	if err != nil {
+		return err
+	}
+
+	imei := message.Decoded.IMEI
+	now := time.Now().UTC()
+
+	acks := make([]natsjs.PubAckFuture, 0, len(message.Decoded.Data))
+	for _, record := range message.Decoded.Data {
+		envelope := newRecordEnvelope(p.ctx, message, record, now)
+		raw, err := json.Marshal(envelope)
+		if err != nil {
+			return fmt.Errorf("failed to serialize record. %v", err)
+		}
+
+		msg := nats.NewMsg(p.Subject(imei, SubjectRecord))
+		msg.Data = raw
+		future, err := js.PublishMsgAsync(msg, natsjs.WithMsgID(msgID(imei, record)))
+		if err != nil {
+			return fmt.Errorf("failed to publish record. %v", err)
+		}
+		acks = append(acks, future)
+	}
+
+	timeout := time.NewTimer(ackTimeout)
+	defer timeout.Stop()
+
+	for _, future := range acks {
+		err = waitAck(future, timeout.C)
+		if err != nil {
+			return fmt.Errorf("record is not stored. %v", err)
+		}
+	}
+
+	return nil
+}
+
+/*
+msgID returns the deduplication ID of a record. Resent records get the same ID, while records of the same millisecond
+differ in their hash.
+*/
+func msgID(imei string, record codec.AvlData) string {
+	hash := fnv.New64a()
+	_, _ = fmt.Fprintf(hash, "%+v", record)
+
+	return fmt.Sprintf("%s-%d-%x", imei, record.UtimeMs, hash.Sum64())
+}
+
+/*
+WriteDeviceEvent publishes a device coming online or going offline. It does not wait for JetStream to store it,
+so slow servers do not hold up receiving packets. Events are dropped if the server is not connected.
+*/
+func (p *Publisher) WriteDeviceEvent(event sink.DeviceEvent) error {
+	p.connLock.Lock()
+	conn := p.conn
+	js := p.js
+	p.connLock.Unlock()
+
+	if conn == nil || !conn.IsConnected() {
+		return fmt.Errorf("not connected to NATS server %s", p.options.URL)
+	}
+
+	raw, err := json.Marshal(newDeviceEventEnvelope(event))
+	if err != nil {
+		return fmt.Errorf("failed to serialize device event. %v", err)
+	}
+
+	msg := nats.NewMsg(p.Subject(event.IMEI, SubjectDevice))
+	msg.Data = raw
+	future, err := js.PublishMsgAsync(msg)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		err := waitAck(future, time.After(ackTimeout))
+		if err != nil {
+			config.GetLogger(p.ctx).Errorf("Failed to store %s event of %s device. %v", event.Type, event.IMEI, err)
+		}
+	}()
+
+	return nil
+}
+
+// Flush does nothing because Write waits until every record is stored
+func (p *Publisher) Flush() error {
+	return nil
+}
+
+// Health tells if the server is connected and returns the error of the last write
+func (p *Publisher) Health() error {
+	p.connLock.Lock()
+	conn := p.conn
+	p.connLock.Unlock()
+
+	if conn == nil {
+		return fmt.Errorf("not connected to NATS server %s", p.options.URL)
+	}
+	if !conn.IsConnected() {
+		return fmt.Errorf("not connected to NATS server %s. %v", p.options.URL, conn.Status())
+	}
+
+	return p.HealthState.Health()
+}
@@ -0,0 +1,316 @@
+package jetstream
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/sink"
+	"github.com/halacs/haltonika/sink/sinktest"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMessage is a message published to fakeNats
+type fakeMessage struct {
+	Subject string
+	Header  string
+	Payload []byte
+}
+
+// fakeNats is a NATS server with JetStream accepting the messages of one stream
+type fakeNats struct {
+	listener  net.Listener
+	lock      sync.Mutex
+	stream    string // created stream, empty if there is none
+	subjects  []string
+	published []fakeMessage
+	reject    bool // answer publishes with no responders
+}
+
+func newFakeNats(t *testing.T) *fakeNats {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen. %v", err)
+	}
+
+	server := &fakeNats{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+func (f *fakeNats) URL() string {
+	return "nats://" + f.listener.Addr().String()
+}
+
+func (f *fakeNats) messages() []fakeMessage {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return append([]fakeMessage(nil), f.published...)
+}
+
+func (f *fakeNats) serve(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// Subscription IDs by subject. Replies are sent to the inbox subscriptions of the client with * wildcard.
+	subscriptions := make(map[string]string)
+	reply := func(subject string, header string, payload []byte) {
+		sid := subscriptions[subject]
+		for pattern, id := range subscriptions {
+			if strings.HasSuffix(pattern, "*") && strings.HasPrefix(subject, strings.TrimSuffix(pattern, "*")) {
+				sid = id
+			}
+		}
+		if header != "" {
+			_, _ = fmt.Fprintf(conn, "HMSG %s %s %d %d\r\n%s%s\r\n", subject, sid, len(header), len(header)+len(payload), header, payload)
+			return
+		}
+		_, _ = fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", subject, sid, len(payload), payload)
+	}
+
+	reader := bufio.NewReader(conn)
+	_, _ = fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"proto\":1,\"headers\":true,\"max_payload\":1048576}\r\n")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "PING":
+			_, _ = fmt.Fprintf(conn, "PONG\r\n")
+		case "SUB":
+			subscriptions[fields[1]] = fields[len(fields)-1]
+		case "PUB", "HPUB":
+			headerSize := 0
+			if fields[0] == "HPUB" {
+				headerSize, _ = strconv.Atoi(fields[len(fields)-2])
+			}
+			total, _ := strconv.Atoi(fields[len(fields)-1])
+			data := make([]byte, total+2)
+			_, err = io.ReadFull(reader, data)
+			if err != nil {
+				return
+			}
+
+			response := f.handle(fakeMessage{Subject: fields[1], Header: string(data[:headerSize]), Payload: data[headerSize:total]})
+			if len(fields) < 4 || (fields[0] == "HPUB" && len(fields) < 5) {
+				continue // no reply subject
+			}
+			if response == nil {
+				reply(fields[2], "NATS/1.0 503\r\n\r\n", nil)
+				continue
+			}
+			reply(fields[2], "", response)
+		}
+	}
+}
+
+// handle returns the reply of JetStream or nil if there is no responder
+func (f *fakeNats) handle(message fakeMessage) []byte {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch {
+	case strings.HasPrefix(message.Subject, "$JS.API.STREAM.INFO."):
+		if f.stream == "" {
+			return []byte(`{"error":{"code":404,"err_code":10059,"description":"stream not found"}}`)
+		}
+		return []byte(`{"config":{}}`)
+	case strings.HasPrefix(message.Subject, "$JS.API.STREAM.CREATE."):
+		var request struct {
+			Name     string   `json:"name"`
+			Subjects []string `json:"subjects"`
+		}
+		_ = json.Unmarshal(message.Payload, &request)
+		f.stream = request.Name
+		f.subjects = request.Subjects
+		return []byte(`{"config":{}}`)
+	case f.reject || f.stream == "":
+		return nil
+	default:
+		f.published = append(f.published, message)
+		return []byte(fmt.Sprintf(`{"stream":%q,"seq":%d}`, f.stream, len(f.published)))
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		Options        map[string]interface{}
+		ExpectedPrefix string
+		ExpectedError  bool
+	}{
+		{
+			Name:           "Defaults",
+			Options:        map[string]interface{}{},
+			ExpectedPrefix: "haltonika",
+		},
+		{
+			Name:           "Custom prefix",
+			Options:        map[string]interface{}{"url": "tls://nats:4222", "subjectprefix": "fleet.telemetry.", "stream": "FLEET"},
+			ExpectedPrefix: "fleet.telemetry",
+		},
+		{
+			Name:          "Wildcard in prefix",
+			Options:       map[string]interface{}{"subjectprefix": "fleet.*"},
+			ExpectedError: true,
+		},
+		{
+			Name:          "Dot in stream name",
+			Options:       map[string]interface{}{"stream": "fleet.records"},
+			ExpectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			options, err := ParseOptions(testCase.Options)
+			if (err != nil) != testCase.ExpectedError {
+				test.Fatalf("Wrong result! Expected error: %v Actual: %v", testCase.ExpectedError, err)
+			}
+			if err == nil && options.SubjectPrefix != testCase.ExpectedPrefix {
+				test.Errorf("Wrong subject prefix! Expected: %v Actual: %v", testCase.ExpectedPrefix, options.SubjectPrefix)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	server := newFakeNats(t)
+	defer func() {
+		_ = server.listener.Close()
+	}()
+
+	options, err := ParseOptions(map[string]interface{}{"url": server.URL()})
+	if err != nil {
+		t.Fatalf("Failed to parse options. %v", err)
+	}
+
+	publisher := NewPublisher(sinktest.NewContext(), options)
+	err = publisher.Connect()
+	if err != nil {
+		t.Fatalf("Failed to connect. %v", err)
+	}
+	defer func() {
+		_ = publisher.Close()
+	}()
+
+	server.lock.Lock()
+	if server.stream != DefaultStream || len(server.subjects) != 1 || server.subjects[0] != "haltonika.>" {
+		t.Errorf("Wrong stream! Expected: %v with haltonika.> subjects Actual: %v with %v subjects", DefaultStream, server.stream, server.subjects)
+	}
+	server.lock.Unlock()
+
+	// Codec 8 packet with 4 records
+	message := sinktest.NewMessage(t)
+	decoded := message.Decoded
+
+	err = publisher.Write(message)
+	if err != nil {
+		t.Fatalf("Failed to write message. %v", err)
+	}
+
+	messages := server.messages()
+	if len(messages) != len(decoded.Data) {
+		t.Fatalf("Wrong number of published records! Expected: %v Actual: %v", len(decoded.Data), len(messages))
+	}
+
+	// Records are published in order with deduplication ID
+	for i, message := range messages {
+		record := decoded.Data[i]
+		if message.Subject != "haltonika.352094089397464.record" {
+			t.Errorf("Wrong subject! Expected: haltonika.352094089397464.record Actual: %v", message.Subject)
+		}
+
+		expectedID := "Nats-Msg-Id: " + msgID("352094089397464", record)
+		if !strings.Contains(message.Header, expectedID) {
+			t.Errorf("Wrong header! Expected: %v Actual: %v", expectedID, message.Header)
+		}
+
+		var envelope RecordEnvelope
+		err = json.Unmarshal(message.Payload, &envelope)
+		if err != nil {
+			t.Fatalf("Invalid envelope. %v", err)
+		}
+		if envelope.Version != EnvelopeVersion || envelope.Type != TypeRecord || envelope.IMEI != decoded.IMEI || envelope.Codec != decoded.CodecID ||
+			!envelope.Time.Equal(sink.Timestamp(record)) || envelope.Position.Speed != record.Speed || envelope.Source != "127.0.0.1:1234" {
+			t.Errorf("Wrong envelope: %+v", envelope)
+		}
+		if len(envelope.IO) != len(record.Elements) {
+			t.Errorf("Wrong number of IO elements! Expected: %v Actual: %v", len(record.Elements), len(envelope.IO))
+		}
+	}
+
+	err = publisher.WriteDeviceEvent(sink.DeviceEvent{IMEI: decoded.IMEI, Type: "connected", Transport: "tcp", SourceAddress: "127.0.0.1:1234", Time: time.Now()})
+	if err != nil {
+		t.Fatalf("Failed to write device event. %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(server.messages()) == len(decoded.Data) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	messages = server.messages()
+	last := messages[len(messages)-1]
+	if last.Subject != "haltonika.352094089397464.device" || !strings.Contains(string(last.Payload), `"type":"connected"`) {
+		t.Errorf("Wrong device event: %s %s", last.Subject, last.Payload)
+	}
+
+	if publisher.Health() != nil {
+		t.Errorf("Publisher must be healthy. %v", publisher.Health())
+	}
+
+	// Records not stored by JetStream are failed writes
+	server.lock.Lock()
+	server.reject = true
+	server.lock.Unlock()
+
+	err = publisher.Write(sink.Message{Decoded: decoded, SourceAddress: "127.0.0.1:1234"})
+	if err == nil {
+		t.Errorf("Write must fail if there is no stream")
+	}
+	if publisher.Health() == nil {
+		t.Errorf("Publisher must be unhealthy after a failed write")
+	}
+}
+
+func TestMsgID(t *testing.T) {
+	record := codec.AvlData{UtimeMs: 1528069090050, EventID: 240, Elements: []codec.Element{{Length: 1, IOID: 240, Value: []byte{1}}}}
+	resent := record
+	resent.Elements = []codec.Element{{Length: 1, IOID: 240, Value: []byte{1}}}
+	other := record
+	other.EventID = 239
+
+	id := msgID("352094089397464", record)
+	if !strings.HasPrefix(id, "352094089397464-1528069090050-") {
+		t.Errorf("Wrong message ID: %v", id)
+	}
+	if msgID("352094089397464", resent) != id {
+		t.Errorf("Resent record must have the same message ID")
+	}
+	if msgID("352094089397464", other) == id {
+		t.Errorf("Records of the same millisecond must have different message IDs")
+	}
+}
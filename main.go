@@ -4,23 +4,31 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"github.com/halacs/haltonika/capture"
 	"github.com/halacs/haltonika/config"
 	"github.com/halacs/haltonika/fmb920"
-	influxdb2 "github.com/halacs/haltonika/influxdb"
 	m "github.com/halacs/haltonika/metrics"
 	mi "github.com/halacs/haltonika/metrics/impl"
+	"github.com/halacs/haltonika/profile"
+	"github.com/halacs/haltonika/registry"
 	"github.com/halacs/haltonika/uds"
 	"github.com/halacs/haltonika/version"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
-func parseConfig() *config.Config {
+// parseConfig reads the configuration from the config file, environment variables and the given arguments.
+// Subcommands can add their own flags which are not written into the config file.
+func parseConfig(args []string, subcommandFlags *pflag.FlagSet) *config.Config {
 	// Initialize logger
 	log := config.NewLogger()
 
@@ -52,9 +60,36 @@ func parseConfig() *config.Config {
 	flag.String(config.InfluxConfigPassword, config.DefaultInfluxDbPassword, "InfluxDB password")
 	flag.String(config.InfluxConfigDatabase, config.DefaultInfluxDbDatabaseName, "InfluxDB database name")
 	flag.String(config.InfluxConfigMeasurement, config.DefaultInfluxDbMeasurementName, "Name of the Influxdb measurement")
+	flag.Int(config.InfluxConfigVersion, config.DefaultInfluxDbVersion, "InfluxDB API version. 1 uses username, password and database, 2 uses token, org and bucket (InfluxDB 2.x and 3.x)")
+	flag.String(config.InfluxConfigToken, config.DefaultInfluxDbToken, "InfluxDB API token (version 2)")
+	flag.String(config.InfluxConfigOrg, config.DefaultInfluxDbOrg, "InfluxDB organization (version 2)")
+	flag.String(config.InfluxConfigBucket, config.DefaultInfluxDbBucket, "InfluxDB bucket (version 2)")
+	flag.String(config.InfluxConfigPrecision, config.DefaultInfluxDbPrecision, "Precision of InfluxDB timestamps (ns, us, ms or s)")
+	flag.Bool(config.InfluxConfigGzip, config.DefaultInfluxDbGzip, "Compress InfluxDB write requests with gzip (version 2)")
+	flag.Int(config.InfluxConfigBatchSize, config.DefaultInfluxDbBatchSize, "Maximum number of points written into InfluxDB in one request. Points are written when a batch is full or at the flush interval of the sink")
+	flag.Int(config.InfluxConfigRetries, config.DefaultInfluxDbRetries, "Retries of InfluxDB write requests failed with a transient error (network error, timeout, 429 or 5xx status)")
 	// Teltonika server configs
 	flag.String(config.TeltonikaListeningIp, config.DefaultTeltonikaListeningIP, "Teltonika server listening IP address (IPv4 or IPv6)")
 	flag.Int(config.TeltonikaListeningPort, config.DefaultTeltonikaListeningPort, "Teltonika server listening UDP port")
+	flag.Int(config.TeltonikaListeningTcpPort, config.DefaultTeltonikaListeningTcpPort, "Teltonika server listening TCP port (0 disables TCP)")
+	flag.Int(config.TeltonikaCommandCodec, config.DefaultTeltonikaCommandCodec, "Codec used to send commands to devices (12 or 14). Can be overridden by IMEI in the commandcodecs section of the config file")
+	flag.String(config.TeltonikaAckPolicy, config.DefaultTeltonikaAckPolicy, "When received packets are acknowledged: immediate or after-persist. after-persist acknowledges only stored packets so devices send them again on storage failure")
+	// Device registry configs
+	flag.String(config.TeltonikaDevicesFile, config.DefaultTeltonikaDevicesFile, "YAML file of the device registry. It replaces the devices section of the config file and keeps the changes made via the registry API.")
+	flag.String(config.RegistryListen, config.DefaultRegistryListen, "Address of the HTTP API of the device registry, e.g. 127.0.0.1:8091. Empty disables the API.")
+	flag.String(config.RegistryToken, config.DefaultRegistryToken, "Bearer token required by the registry API. The API is not started without it.")
+	// Packet capture configs
+	flag.String(config.CaptureFileName, config.DefaultCaptureFileName, "File where all received and sent packets are captured. Empty disables capturing.")
+	flag.Int(config.CaptureMaxSize, config.DefaultCaptureMaxSize, "Capture file is rotated after it reached this size in megabytes (0 disables)")
+	flag.Duration(config.CaptureMaxAge, config.DefaultCaptureMaxAge, "Capture file is rotated after it is this old (0 disables)")
+	flag.Int(config.CaptureMaxFiles, config.DefaultCaptureMaxFiles, "Number of rotated capture files to keep (0 keeps all)")
+	flag.String(config.CaptureIMEIs, config.DefaultCaptureIMEIs, "Capture packets only of these devices. Separated by comma. Empty captures all packets.")
+	// Durable queue configs
+	flag.String(config.QueueDirectory, config.DefaultQueueDirectory, "Directory of the durable queue in front of the sinks. Empty disables the queue.")
+	flag.Int(config.QueueMaxSize, config.DefaultQueueMaxSize, "Maximum size of the durable queue in megabytes (0 means unlimited)")
+	flag.Bool(config.QueueSync, config.DefaultQueueSync, "Sync the durable queue to disk after each record")
+	flag.Duration(config.QueueMinBackoff, config.DefaultQueueMinBackoff, "Initial delay before retrying a failed sink write")
+	flag.Duration(config.QueueMaxBackoff, config.DefaultQueueMaxBackoff, "Maximum delay before retrying a failed sink write")
 	// Metrics server configs
 	flag.String(config.MetricsListeningIp, config.DefaultMetricsListeningIP, "Metrics server listening IP address (IPv4 or IPv6)")
 	flag.Int(config.MetricsListeningPort, config.DefaultMetricsListeningPort, "Metrics server listening port")
@@ -63,11 +98,17 @@ func parseConfig() *config.Config {
 	flag.String(config.UdsServerConfigBasePath, config.DefaultUdsServerConfigBasePath, "Directory where unix domain sockets for each devices will be opened")
 
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
-	pflag.Parse()
 	err = viper.BindPFlags(pflag.CommandLine)
 	if err != nil {
 		log.Errorf("Failed to bindPFlags. %v", err)
 	}
+	if subcommandFlags != nil {
+		pflag.CommandLine.AddFlagSet(subcommandFlags)
+		if subcommandFlags.Usage != nil {
+			pflag.CommandLine.Usage = subcommandFlags.Usage
+		}
+	}
+	_ = pflag.CommandLine.Parse(args)
 
 	verbose := viper.GetBool(config.Verbose)
 	debug := viper.GetBool(config.Debug)
@@ -81,19 +122,87 @@ func parseConfig() *config.Config {
 
 	// Initialize cfg
 	influxConfig := &config.InfluxConfig{
+		Version:     viper.GetInt(config.InfluxConfigVersion),
 		Url:         viper.GetString(config.InfluxConfigUrl),
 		Username:    viper.GetString(config.InfluxConfigUsername),
 		Password:    viper.GetString(config.InfluxConfigPassword),
 		Database:    viper.GetString(config.InfluxConfigDatabase),
+		Token:       viper.GetString(config.InfluxConfigToken),
+		Org:         viper.GetString(config.InfluxConfigOrg),
+		Bucket:      viper.GetString(config.InfluxConfigBucket),
 		Measurement: viper.GetString(config.InfluxConfigMeasurement),
+		Precision:   viper.GetString(config.InfluxConfigPrecision),
+		Gzip:        viper.GetBool(config.InfluxConfigGzip),
+		BatchSize:   viper.GetInt(config.InfluxConfigBatchSize),
+		Retries:     viper.GetInt(config.InfluxConfigRetries),
 	}
 
 	allowedIMEIs := strings.Split(viper.GetString(config.AllowedIMEIs), ",")
 
+	commandCodec, err := config.ParseCommandCodec(viper.GetInt(config.TeltonikaCommandCodec))
+	if err != nil {
+		log.Fatalf("Invalid default command codec. %v", err)
+		os.Exit(1)
+	}
+
+	commandCodecs := make(map[string]byte)
+	for imei, value := range viper.GetStringMapString(config.TeltonikaCommandCodecs) {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid command codec for %s device. %v", imei, err)
+			os.Exit(1)
+		}
+		codecID, err := config.ParseCommandCodec(number)
+		if err != nil {
+			log.Fatalf("Invalid command codec for %s device. %v", imei, err)
+			os.Exit(1)
+		}
+		commandCodecs[imei] = codecID
+	}
+
+	devices := loadDevices(log)
+
+	models := make(map[string]profile.Config)
+	err = viper.UnmarshalKey(config.TeltonikaModels, &models)
+	if err != nil {
+		log.Fatalf("Invalid models configuration. %v", err)
+		os.Exit(1)
+	}
+	profiles, err := profile.NewProfiles(models)
+	if err != nil {
+		log.Fatalf("Invalid models configuration. %v", err)
+		os.Exit(1)
+	}
+
+	ackPolicy, err := config.ParseAckPolicy(viper.GetString(config.TeltonikaAckPolicy))
+	if err != nil {
+		log.Fatalf("Invalid ACK policy. %v", err)
+		os.Exit(1)
+	}
+
 	teltonikaConfig := &config.TeltonikaConfig{
-		Host:         viper.GetString(config.TeltonikaListeningIp),
-		Port:         viper.GetInt(config.TeltonikaListeningPort),
-		AllowedIMEIs: allowedIMEIs,
+		Host:          viper.GetString(config.TeltonikaListeningIp),
+		Port:          viper.GetInt(config.TeltonikaListeningPort),
+		TcpPort:       viper.GetInt(config.TeltonikaListeningTcpPort),
+		AllowedIMEIs:  allowedIMEIs,
+		CommandCodec:  commandCodec,
+		CommandCodecs: commandCodecs,
+		AckPolicy:     ackPolicy,
+		Devices:       devices,
+		Profiles:      profiles,
+		Registry: config.RegistryConfig{
+			Listen: viper.GetString(config.RegistryListen),
+			Token:  viper.GetString(config.RegistryToken),
+		},
+		Capture: config.CaptureConfig{
+			FileName: viper.GetString(config.CaptureFileName),
+			MaxSize:  viper.GetInt64(config.CaptureMaxSize) * 1024 * 1024,
+			MaxAge:   viper.GetDuration(config.CaptureMaxAge),
+			MaxFiles: viper.GetInt(config.CaptureMaxFiles),
+		},
+	}
+	if captureIMEIs := viper.GetString(config.CaptureIMEIs); captureIMEIs != "" {
+		teltonikaConfig.Capture.IMEIs = strings.Split(captureIMEIs, ",")
 	}
 
 	metricsConfig := &config.MetricsConfig{
@@ -106,6 +215,44 @@ func parseConfig() *config.Config {
 		BasePath: viper.GetString(config.UdsServerConfigBasePath),
 	}
 
+	storageConfig := &config.StorageConfig{
+		Queue: config.QueueConfig{
+			Directory:  viper.GetString(config.QueueDirectory),
+			MaxSize:    viper.GetInt64(config.QueueMaxSize) * 1024 * 1024,
+			Sync:       viper.GetBool(config.QueueSync),
+			MinBackoff: viper.GetDuration(config.QueueMinBackoff),
+			MaxBackoff: viper.GetDuration(config.QueueMaxBackoff),
+		},
+	}
+	err = viper.UnmarshalKey(config.StorageSinks, &storageConfig.Sinks)
+	if err != nil {
+		log.Fatalf("Invalid sinks configuration. %v", err)
+		os.Exit(1)
+	}
+	err = viper.UnmarshalKey(config.IOMapping, &storageConfig.IOMapping)
+	if err != nil {
+		log.Fatalf("Invalid IO mapping configuration. %v", err)
+		os.Exit(1)
+	}
+	influxConfig.IOMapping = storageConfig.IOMapping.Enabled
+
+	if len(storageConfig.Sinks) == 0 {
+		// InfluxDB configured by the global flags is used if there are no sinks in the config file
+		storageConfig.Sinks = []config.SinkConfig{
+			{Type: config.SinkTypeInfluxDB},
+		}
+	}
+	for i := range storageConfig.Sinks {
+		if storageConfig.Sinks[i].Name == "" {
+			storageConfig.Sinks[i].Name = storageConfig.Sinks[i].Type
+		}
+	}
+
+	cfg := config.NewConfig(log, influxConfig, teltonikaConfig, metricsConfig, udsServerConfig, storageConfig)
+	if subcommandFlags != nil {
+		return cfg
+	}
+
 	err = viper.SafeWriteConfig()
 	if err != nil {
 		if _, ok := err.(viper.ConfigFileAlreadyExistsError); ok {
@@ -117,22 +264,10 @@ func parseConfig() *config.Config {
 		log.Info("Config has been file created")
 	}
 
-	cfg := config.NewConfig(log, influxConfig, teltonikaConfig, metricsConfig, udsServerConfig)
 	return cfg
 }
 
-func initializeInfluxDB(ctx context.Context, log *logrus.Logger, cfg *config.InfluxConfig) *influxdb2.Connection {
-	influxdb := influxdb2.NewConnection(ctx, cfg)
-	err := influxdb.Connect()
-	if err != nil {
-		log.Fatalf("Failed to open influxdb connection. %v", err)
-		os.Exit(1)
-	}
-
-	return influxdb
-}
-
-func initializeMetricServer(ctx context.Context, log *logrus.Logger, wg *sync.WaitGroup, cfg *config.MetricsConfig) *mi.Metrics {
+func initializeMetricServer(ctx context.Context, log *logrus.Logger, wg *sync.WaitGroup, cfg *config.MetricsConfig, providers ...m.MetricProvider) *mi.Metrics {
 	metrics := mi.NewMetrics(ctx, wg, cfg.TeltonikaMetricsFileName)
 	defer func() {
 		err := metrics.Close()
@@ -149,9 +284,9 @@ func initializeMetricServer(ctx context.Context, log *logrus.Logger, wg *sync.Wa
 		fmt.Sprintf("host=%s", hostname),
 	}
 
-	metricsServer := m.NewServer(ctx, wg, cfg, tags, []m.MetricProvider{
+	metricsServer := m.NewServer(ctx, wg, cfg, tags, append([]m.MetricProvider{
 		metrics,
-	})
+	}, providers...))
 	metricsServer.Start()
 
 	return metrics
@@ -166,28 +301,132 @@ func initializeUdsServer(ctx context.Context, log *logrus.Logger, cfg *config.Ud
 	return udsMultiServer
 }
 
+// loadDevices reads the device registry from the devices file if it is set, otherwise from the devices section of the config file
+func loadDevices(log *logrus.Logger) *registry.Registry {
+	if fileName := viper.GetString(config.TeltonikaDevicesFile); fileName != "" {
+		if viper.IsSet(config.TeltonikaDevices) {
+			log.Warnf("Devices section of the config file is ignored, because devices are read from %s", fileName)
+		}
+
+		devices, err := registry.Load(fileName)
+		if err != nil {
+			log.Fatalf("Failed to load device registry. %v", err)
+			os.Exit(1)
+		}
+
+		return devices
+	}
+
+	configured := make(map[string]registry.Device)
+	err := viper.UnmarshalKey(config.TeltonikaDevices, &configured)
+	if err != nil {
+		log.Fatalf("Invalid devices configuration. %v", err)
+		os.Exit(1)
+	}
+
+	devices, err := registry.New(configured)
+	if err != nil {
+		log.Fatalf("Invalid devices configuration. %v", err)
+		os.Exit(1)
+	}
+
+	return devices
+}
+
+// initializeRegistryServer serves the API of the device registry until the context is cancelled
+func initializeRegistryServer(ctx context.Context, log *logrus.Logger, wg *sync.WaitGroup, devices *registry.Registry, cfg config.RegistryConfig) {
+	if cfg.Listen == "" {
+		return
+	}
+	if cfg.Token == "" {
+		log.Errorf("Token of the device registry API is not set. Device registry API is disabled.")
+		return
+	}
+
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		log.Errorf("Failed to listen on %s. Device registry API is disabled. %v", cfg.Listen, err)
+		return
+	}
+
+	httpServer := &http.Server{
+		Handler:           devices.Handler(cfg.Token),
+		ReadHeaderTimeout: 5 * time.Second, // Potential Slowloris Attack if not set
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		log.Infof("Start device registry API on %s", listener.Addr())
+		err := httpServer.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Errorf("Error in device registry API. %v", err)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+}
+
+func initializeCapture(log *logrus.Logger, cfg config.CaptureConfig) *capture.Writer {
+	if cfg.FileName == "" {
+		return nil
+	}
+
+	writer, err := capture.NewWriter(capture.Config{
+		Filename: cfg.FileName,
+		MaxSize:  cfg.MaxSize,
+		MaxAge:   cfg.MaxAge,
+		MaxFiles: cfg.MaxFiles,
+		IMEIs:    cfg.IMEIs,
+	})
+	if err != nil {
+		log.Errorf("Failed to open packet capture. Capturing is disabled. %v", err)
+		return nil
+	}
+
+	log.Infof("Packets are captured into %s", cfg.FileName)
+
+	return writer
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if subcommand, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(subcommand(os.Args[2:]))
+		}
+	}
+
 	var wg sync.WaitGroup
 
-	cfg := parseConfig()
+	cfg := parseConfig(os.Args[1:], nil)
 
 	log := cfg.GetLogger()
 	log.Infof("Haltonika version %s (%s)", version.Version, version.BuildDate)
 	log.Tracef("Used InfluxDB client configuration: %+v", cfg.GetInfluxConfig())
 	log.Tracef("Used Teltonika server configuration: %+v", cfg.GetTeltonikaConfig())
 	log.Tracef("Used metrics configuration: %+v", cfg.GetMetricsConfig())
+	log.Tracef("Used storage configuration: %+v", cfg.GetStorageConfig())
 
 	ctxSignals, _ := signal.NotifyContext(context.Background(), os.Interrupt)
 	ctx := context.WithValue(ctxSignals, config.ContextConfigKey, cfg)
 
-	influxdb := initializeInfluxDB(ctx, log, cfg.GetInfluxConfig())
+	dispatcher := initializeSinks(ctx, log, cfg, true)
 	defer func() {
-		err := influxdb.Close()
+		err := dispatcher.Close()
 		if err != nil {
-			log.Errorf("Failed to close influxdb connection. %v", err)
+			log.Errorf("Failed to close sinks. %v", err)
 		}
 	}()
-	metrics := initializeMetricServer(ctx, log, &wg, cfg.GetMetricsConfig())
+	metrics := initializeMetricServer(ctx, log, &wg, cfg.GetMetricsConfig(), dispatcher)
 	udsMultiServer := initializeUdsServer(ctx, log, cfg.GetUdsServerConfig())
 	defer func() {
 		err := udsMultiServer.Stop()
@@ -196,21 +435,33 @@ func main() {
 		}
 	}()
 
-	// Initialize new Teltonika server
-	server := fmb920.NewServer(ctx, &wg, cfg.GetTeltonikaConfig().Host, cfg.GetTeltonikaConfig().Port, cfg.GetTeltonikaConfig().AllowedIMEIs, udsMultiServer, metrics, func(ctx context.Context, message fmb920.TeltonikaMessage) {
-		log := cfg.GetLogger()
+	captureWriter := initializeCapture(log, cfg.GetTeltonikaConfig().Capture)
+	if captureWriter != nil {
+		defer func() {
+			err := captureWriter.Close()
+			if err != nil {
+				log.Errorf("Failed to close packet capture. %v", err)
+			}
+		}()
+	}
 
-		log.Debugf("PACKET ARRIVED: %+v", message)
+	// Initialize new Teltonika server
+	server := fmb920.NewServer(ctx, &wg, cfg.GetTeltonikaConfig().Host, cfg.GetTeltonikaConfig().Port, cfg.GetTeltonikaConfig().TcpPort, cfg.GetTeltonikaConfig().AllowedDevices(), udsMultiServer, metrics, newSinkCallback(dispatcher, cfg.GetTeltonikaConfig().AckPolicy == config.AckPolicyAfterPersist))
+	server.SetCommandCodecFunc(cfg.GetTeltonikaConfig().DeviceCommandCodec)
+	server.SetAckPolicy(cfg.GetTeltonikaConfig().AckPolicy)
+	server.SetCommandResponseCallback(newCommandResponseCallback(dispatcher))
+	server.SetCommandSentCallback(newCommandSentCallback(dispatcher))
+	server.SetDeviceEventCallback(newDeviceEventCallback(dispatcher))
+	dispatcher.SetCommandHandler(server.SendCommand)
+	server.SetCapture(captureWriter)
 
-		// Insert new record into InfluxDB
-		tags := map[string]string{
-			influxdb2.SourceTag: message.SourceAddress,
-		}
-		err := influxdb.InsertMessage(message.Decoded, tags)
-		if err != nil {
-			log.Errorf("Failed to close influxdb connection. %v", err)
-		}
+	// Devices added or removed via the registry API are allowed or rejected and announced immediately
+	cfg.GetTeltonikaConfig().Devices.SetChangeCallback(func() {
+		allowed := cfg.GetTeltonikaConfig().AllowedDevices()
+		server.SetAllowedIMEIs(allowed)
+		dispatcher.DeviceList(allowed)
 	})
+	initializeRegistryServer(ctx, log, &wg, cfg.GetTeltonikaConfig().Devices, cfg.GetTeltonikaConfig().Registry)
 	defer func() {
 		err := server.Stop()
 		if err != nil {
@@ -0,0 +1,242 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/ioelement"
+	"github.com/halacs/haltonika/sink"
+	"strconv"
+)
+
+const (
+	DefaultDiscoveryPrefix = "homeassistant"
+	homeAssistantOnline    = "online"
+)
+
+// Home Assistant MQTT discovery
+// https://www.home-assistant.io/integrations/mqtt/#mqtt-discovery
+
+// discoveryDevice groups the entities of a tracker into one Home Assistant device
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+}
+
+// discoveryConfig is published to the config topic of an entity
+type discoveryConfig struct {
+	Name                string          `json:"name"`
+	UniqueID            string          `json:"unique_id"`
+	StateTopic          string          `json:"state_topic,omitempty"`
+	ValueTemplate       string          `json:"value_template,omitempty"`
+	JsonAttributesTopic string          `json:"json_attributes_topic,omitempty"`
+	SourceType          string          `json:"source_type,omitempty"`
+	DeviceClass         string          `json:"device_class,omitempty"`
+	StateClass          string          `json:"state_class,omitempty"`
+	UnitOfMeasurement   string          `json:"unit_of_measurement,omitempty"`
+	Icon                string          `json:"icon,omitempty"`
+	PayloadOn           string          `json:"payload_on,omitempty"`
+	PayloadOff          string          `json:"payload_off,omitempty"`
+	Device              discoveryDevice `json:"device"`
+}
+
+// discoveryEntity describes an entity created for each tracker
+type discoveryEntity struct {
+	Component string // Home Assistant component, e.g. sensor
+	ObjectID  string
+	Config    discoveryConfig // without topics, IDs and device
+	Topic     string          // state topic of the device
+	IOID      uint16          // IO element shown by the entity, zero if it is not an IO element
+}
+
+// discoveryEntities are the entities of each tracker. Position is shown by a device_tracker using the attributes of the position topic.
+var discoveryEntities = []discoveryEntity{
+	{
+		Component: "device_tracker",
+		ObjectID:  "location",
+		Config:    discoveryConfig{Name: "Location", SourceType: "gps", Icon: "mdi:car"},
+		Topic:     TopicPosition,
+	},
+	{
+		Component: "sensor",
+		ObjectID:  "speed",
+		Config:    discoveryConfig{Name: "Speed", ValueTemplate: "{{ value_json.speed }}", DeviceClass: "speed", StateClass: "measurement", UnitOfMeasurement: "km/h"},
+		Topic:     TopicPosition,
+	},
+	{
+		Component: "sensor",
+		ObjectID:  "battery_voltage",
+		Config:    discoveryConfig{Name: "Battery voltage", DeviceClass: "voltage", StateClass: "measurement"},
+		Topic:     TopicIO,
+		IOID:      67,
+	},
+	{
+		Component: "sensor",
+		ObjectID:  "external_voltage",
+		Config:    discoveryConfig{Name: "External voltage", DeviceClass: "voltage", StateClass: "measurement"},
+		Topic:     TopicIO,
+		IOID:      66,
+	},
+	{
+		Component: "binary_sensor",
+		ObjectID:  "ignition",
+		Config:    discoveryConfig{Name: "Ignition", Icon: "mdi:engine", PayloadOn: "1", PayloadOff: "0"},
+		Topic:     TopicIO,
+		IOID:      239,
+	},
+	{
+		Component: "sensor",
+		ObjectID:  "gsm_signal",
+		Config:    discoveryConfig{Name: "GSM signal", StateClass: "measurement", Icon: "mdi:signal"},
+		Topic:     TopicIO,
+		IOID:      21,
+	},
+}
+
+// ioValueTemplate renders the value of an IO element with the multiplier of the FMBXY dictionary.
+// Records without the IO element keep the previous state.
+func ioValueTemplate(id uint16) string {
+	key := sink.IOKey(id)
+	value := "value_json.elements." + key
+
+	definition, ok := ioelement.FMBXY.Lookup(id)
+	if ok && definition.Multiplier != 0 && definition.Multiplier != 1 {
+		value = fmt.Sprintf("(%s * %s) | round(3)", value, strconv.FormatFloat(definition.Multiplier, 'f', -1, 64))
+	}
+
+	return fmt.Sprintf("{%% if '%s' in value_json.elements %%}{{ %s }}{%% else %%}{{ this.state }}{%% endif %%}", key, value)
+}
+
+// discoveryConfigs returns the discovery configs of the entities of a tracker by their config topics
+func (p *Publisher) discoveryConfigs(imei string) map[string]discoveryConfig {
+	device := discoveryDevice{
+		Identifiers:  []string{config.AppName + "_" + imei},
+		Name:         imei,
+		Manufacturer: "Teltonika",
+	}
+
+	configs := make(map[string]discoveryConfig, len(discoveryEntities))
+	for _, entity := range discoveryEntities {
+		entityConfig := entity.Config
+		entityConfig.UniqueID = config.AppName + "_" + imei + "_" + entity.ObjectID
+		entityConfig.Device = device
+
+		if entity.Component == "device_tracker" {
+			entityConfig.JsonAttributesTopic = p.Topic(imei, entity.Topic)
+		} else {
+			entityConfig.StateTopic = p.Topic(imei, entity.Topic)
+		}
+
+		if entity.IOID != 0 {
+			entityConfig.ValueTemplate = ioValueTemplate(entity.IOID)
+
+			definition, ok := ioelement.FMBXY.Lookup(entity.IOID)
+			if ok && entityConfig.UnitOfMeasurement == "" {
+				entityConfig.UnitOfMeasurement = definition.Unit
+			}
+		}
+
+		topic := fmt.Sprintf("%s/%s/%s/%s/config", p.options.DiscoveryPrefix, entity.Component, imei, entity.ObjectID)
+		configs[topic] = entityConfig
+	}
+
+	return configs
+}
+
+// SetDiscoveryIMEIs sets the trackers announced to Home Assistant when the publisher connects
+func (p *Publisher) SetDiscoveryIMEIs(imeis []string) {
+	p.discoveryLock.Lock()
+	defer p.discoveryLock.Unlock()
+
+	p.discoveryIMEIs = imeis
+}
+
+// WriteDeviceList announces the trackers added to the device registry and removes the deleted ones from Home Assistant
+func (p *Publisher) WriteDeviceList(imeis []string) error {
+	if !p.options.HomeAssistant {
+		return nil
+	}
+
+	p.discoveryLock.Lock()
+	kept := make(map[string]bool, len(imeis))
+	for _, imei := range imeis {
+		kept[imei] = true
+	}
+	var removed []string
+	for _, imei := range p.discoveryIMEIs {
+		if !kept[imei] {
+			removed = append(removed, imei)
+		}
+	}
+	p.discoveryIMEIs = imeis
+	p.discoveryLock.Unlock()
+
+	// Everything is published when the connection is restored
+	if p.client == nil || !p.client.IsConnected() {
+		return nil
+	}
+
+	// Empty retained config removes the entity from Home Assistant
+	var tokens []paho.Token
+	for _, imei := range removed {
+		for topic := range p.discoveryConfigs(imei) {
+			tokens = append(tokens, p.client.Publish(topic, p.options.QoS, true, []byte{}))
+		}
+	}
+	err := p.wait(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to remove Home Assistant discovery. %v", err)
+	}
+
+	p.publishDiscovery()
+
+	return nil
+}
+
+// subscribeHomeAssistantStatus announces the trackers again when Home Assistant comes online, because it may have lost them
+func (p *Publisher) subscribeHomeAssistantStatus(client paho.Client) {
+	log := config.GetLogger(p.ctx)
+
+	topic := p.options.DiscoveryPrefix + "/status"
+	token := client.Subscribe(topic, p.options.QoS, func(client paho.Client, message paho.Message) {
+		if string(message.Payload()) == homeAssistantOnline {
+			// Publishing must not block the message handler
+			go p.publishDiscovery()
+		}
+	})
+	if !token.WaitTimeout(publishTimeout) || token.Error() != nil {
+		log.Errorf("Failed to subscribe to %s topic. %v", topic, token.Error())
+	}
+}
+
+// publishDiscovery publishes the retained discovery configs of all trackers
+func (p *Publisher) publishDiscovery() {
+	log := config.GetLogger(p.ctx)
+
+	p.discoveryLock.Lock()
+	imeis := p.discoveryIMEIs
+	p.discoveryLock.Unlock()
+
+	var tokens []paho.Token
+	for _, imei := range imeis {
+		for topic, entityConfig := range p.discoveryConfigs(imei) {
+			raw, err := json.Marshal(entityConfig)
+			if err != nil {
+				log.Errorf("Failed to serialize discovery config of %s. %v", topic, err)
+				continue
+			}
+
+			tokens = append(tokens, p.client.Publish(topic, p.options.QoS, true, raw))
+		}
+	}
+
+	err := p.wait(tokens)
+	if err != nil {
+		log.Errorf("Failed to publish Home Assistant discovery. %v", err)
+		return
+	}
+
+	log.Infof("Home Assistant discovery is published for %d trackers", len(imeis))
+}
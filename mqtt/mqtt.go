@@ -0,0 +1,244 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/sink"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBroker      = "tcp://localhost:1883"
+	DefaultTopicPrefix = config.AppName
+	DefaultQoS         = 1
+	publishTimeout     = 10 * time.Second
+	connectTimeout     = 30 * time.Second
+)
+
+// Options of an MQTT sink
+type Options struct {
+	Broker             string // tcp://, ssl://, ws:// or wss:// URL
+	ClientID           string
+	Username           string
+	Password           string
+	TopicPrefix        string
+	QoS                byte
+	Retain             bool   // retain the last position of each device
+	CACert             string // file of the CA certificates to verify the broker
+	ClientCert         string // file of the client certificate
+	ClientKey          string // file of the key of the client certificate
+	InsecureSkipVerify bool
+	HomeAssistant      bool   // publish Home Assistant discovery of the trackers
+	DiscoveryPrefix    string // discovery prefix of Home Assistant
+}
+
+// ParseOptions decodes the options of an MQTT sink
+func ParseOptions(options map[string]interface{}) (Options, error) {
+	parsed := Options{
+		Broker:          DefaultBroker,
+		TopicPrefix:     DefaultTopicPrefix,
+		QoS:             DefaultQoS,
+		Retain:          true,
+		DiscoveryPrefix: DefaultDiscoveryPrefix,
+	}
+
+	err := sink.DecodeOptions(options, &parsed)
+	if err != nil {
+		return Options{}, err
+	}
+	if parsed.QoS > 2 {
+		return Options{}, fmt.Errorf("QoS must be 0, 1 or 2, got %d", parsed.QoS)
+	}
+	if (parsed.ClientCert == "") != (parsed.ClientKey == "") {
+		return Options{}, fmt.Errorf("clientcert and clientkey must be set together")
+	}
+
+	parsed.TopicPrefix = strings.TrimSuffix(parsed.TopicPrefix, "/")
+	parsed.DiscoveryPrefix = strings.TrimSuffix(parsed.DiscoveryPrefix, "/")
+
+	return parsed, nil
+}
+
+// Publisher publishes the records to an MQTT broker under per-device topics. It implements sink.Sink.
+type Publisher struct {
+	ctx     context.Context
+	options Options
+	client  paho.Client
+
+	// Trackers announced to Home Assistant
+	discoveryIMEIs []string
+	discoveryLock  sync.Mutex
+
+	sink.HealthState
+}
+
+func NewPublisher(ctx context.Context, options Options) *Publisher {
+	return &Publisher{
+		ctx:     ctx,
+		options: options,
+	}
+}
+
+// Connect connects to the broker. Connection is restored automatically if it is lost later.
+func (p *Publisher) Connect() error {
+	log := config.GetLogger(p.ctx)
+
+	clientOptions := paho.NewClientOptions().
+		AddBroker(p.options.Broker).
+		SetClientID(p.options.ClientID).
+		SetUsername(p.options.Username).
+		SetPassword(p.options.Password).
+		SetAutoReconnect(true).
+		SetConnectTimeout(connectTimeout).
+		SetConnectionLostHandler(func(client paho.Client, err error) {
+			log.Errorf("Connection to MQTT broker %s is lost. %v", p.options.Broker, err)
+		}).
+		SetOnConnectHandler(func(client paho.Client) {
+			log.Infof("Connected to MQTT broker %s", p.options.Broker)
+
+			if p.options.HomeAssistant {
+				p.subscribeHomeAssistantStatus(client)
+				p.publishDiscovery()
+			}
+		})
+
+	tlsConfig, err := p.newTlsConfig()
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+
+	p.client = paho.NewClient(clientOptions)
+	token := p.client.Connect()
+	if !token.WaitTimeout(connectTimeout) {
+		return fmt.Errorf("connecting to MQTT broker %s timed out", p.options.Broker)
+	}
+	if token.Error() != nil {
+		return fmt.Errorf("failed to connect to MQTT broker %s. %v", p.options.Broker, token.Error())
+	}
+
+	return nil
+}
+
+func (p *Publisher) newTlsConfig() (*tls.Config, error) {
+	if p.options.CACert == "" && p.options.ClientCert == "" && !p.options.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	return sink.NewTLSConfig(p.options.CACert, p.options.ClientCert, p.options.ClientKey, p.options.InsecureSkipVerify)
+}
+
+func (p *Publisher) Close() error {
+	if p.client != nil {
+		p.client.Disconnect(uint(publishTimeout.Milliseconds()))
+	}
+
+	return nil
+}
+
+// Write publishes the position, IO elements and event of each record of the message
+func (p *Publisher) Write(message sink.Message) error {
+	imei := message.Decoded.IMEI
+	now := time.Now().UTC()
+
+	var tokens []paho.Token
+	for _, record := range message.Decoded.Data {
+		position := newPosition(imei, message.SourceAddress, record, now)
+		tokens = append(tokens, p.publish(imei, TopicPosition, p.options.Retain, position))
+
+		io := newIO(p.ctx, imei, record)
+		if len(io.Elements) > 0 {
+			tokens = append(tokens, p.publish(imei, TopicIO, false, io))
+		}
+
+		event, ok := newEvent(io.Elements, imei, record)
+		if ok {
+			tokens = append(tokens, p.publish(imei, TopicEvent, false, event))
+		}
+	}
+
+	err := p.wait(tokens)
+	p.SetLastError(err)
+
+	return err
+}
+
+// WriteCommandResponse publishes the response of the device to a command
+func (p *Publisher) WriteCommandResponse(imei string, response string) error {
+	token := p.publish(imei, TopicCommandResponse, false, CommandResponse{
+		IMEI:     imei,
+		Time:     time.Now().UTC(),
+		Response: response,
+	})
+
+	return p.wait([]paho.Token{token})
+}
+
+// Flush does nothing because every message is published by Write
+func (p *Publisher) Flush() error {
+	return nil
+}
+
+// Health tells if the broker is connected and returns the error of the last publish
+func (p *Publisher) Health() error {
+	if p.client == nil || !p.client.IsConnectionOpen() {
+		return fmt.Errorf("not connected to MQTT broker %s", p.options.Broker)
+	}
+
+	return p.HealthState.Health()
+}
+
+// Topic returns the full topic of a device, e.g. haltonika/350424063817363/position
+func (p *Publisher) Topic(imei string, topic string) string {
+	return p.options.TopicPrefix + "/" + imei + "/" + topic
+}
+
+func (p *Publisher) publish(imei string, topic string, retain bool, payload interface{}) paho.Token {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return failedToken(fmt.Errorf("failed to serialize %s payload. %v", topic, err))
+	}
+
+	return p.client.Publish(p.Topic(imei, topic), p.options.QoS, retain, raw)
+}
+
+// wait waits for the published messages to be delivered according to their QoS
+func (p *Publisher) wait(tokens []paho.Token) error {
+	deadline := time.Now().Add(publishTimeout)
+	for _, token := range tokens {
+		if !token.WaitTimeout(time.Until(deadline)) {
+			return fmt.Errorf("publishing to MQTT broker timed out")
+		}
+		if token.Error() != nil {
+			return fmt.Errorf("failed to publish to MQTT broker. %v", token.Error())
+		}
+	}
+
+	return nil
+}
+
+// errorToken is a completed token of a message which could not be published
+type errorToken struct {
+	err  error
+	done chan struct{}
+}
+
+func failedToken(err error) paho.Token {
+	done := make(chan struct{})
+	close(done)
+
+	return &errorToken{err: err, done: done}
+}
+
+func (t *errorToken) Wait() bool                     { return true }
+func (t *errorToken) WaitTimeout(time.Duration) bool { return true }
+func (t *errorToken) Done() <-chan struct{}          { return t.done }
+func (t *errorToken) Error() error                   { return t.err }
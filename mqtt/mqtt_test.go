@@ -0,0 +1,271 @@
+package mqtt
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/sink"
+	"github.com/halacs/haltonika/sink/sinktest"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestMessage(t *testing.T) sink.Message {
+	// Codec 8 Extended record generated by IO element 66 (external voltage) with 11 IO elements
+	packet, err := hex.DecodeString("0067cafe016b000f3335303432343036333831373336338e01000001839ecd8a70000b5629e81c5451d0000000000000000000000b000500500000150400c800004502001d00000500422e970018000000cd13f000ce005d00430fd3000100f10000547e0000000001")
+	if err != nil {
+		t.Fatalf("Incorrect packet. %v", err)
+	}
+	decoded, err := codec.Decode(packet)
+	if err != nil {
+		t.Fatalf("Failed to decode packet. %v", err)
+	}
+	decoded.Data[0].EventID = 66
+
+	return sink.Message{
+		Decoded:       decoded,
+		SourceAddress: "127.0.0.1:1234",
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		Options        map[string]interface{}
+		ExpectedPrefix string
+		ExpectedQoS    byte
+		ExpectedError  bool
+	}{
+		{
+			Name:           "Defaults",
+			Options:        map[string]interface{}{},
+			ExpectedPrefix: "haltonika",
+			ExpectedQoS:    1,
+		},
+		{
+			Name:           "Custom",
+			Options:        map[string]interface{}{"broker": "ssl://broker:8883", "topicprefix": "fleet/", "qos": 2, "retain": false},
+			ExpectedPrefix: "fleet",
+			ExpectedQoS:    2,
+		},
+		{
+			Name:          "Invalid QoS",
+			Options:       map[string]interface{}{"qos": 3},
+			ExpectedError: true,
+		},
+		{
+			Name:          "Client certificate without key",
+			Options:       map[string]interface{}{"clientcert": "client.pem"},
+			ExpectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			options, err := ParseOptions(testCase.Options)
+			if (err != nil) != testCase.ExpectedError {
+				test.Fatalf("Wrong result! Expected error: %v Actual: %v", testCase.ExpectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if options.TopicPrefix != testCase.ExpectedPrefix || options.QoS != testCase.ExpectedQoS {
+				test.Errorf("Wrong options! Expected: %v, %v Actual: %v, %v", testCase.ExpectedPrefix, testCase.ExpectedQoS, options.TopicPrefix, options.QoS)
+			}
+		})
+	}
+}
+
+func TestPayloads(t *testing.T) {
+	message := newTestMessage(t)
+	record := message.Decoded.Data[0]
+
+	position := newPosition(message.Decoded.IMEI, message.SourceAddress, record, time.Now())
+	if !position.Time.Equal(sink.Timestamp(record)) || position.Latitude != sink.Latitude(record) || position.Speed != record.Speed {
+		t.Errorf("Wrong position: %+v", position)
+	}
+	if position.GenerationType != nil {
+		t.Errorf("Generation type must be set only with Codec 16")
+	}
+
+	io := newIO(sinktest.NewContext(), message.Decoded.IMEI, record)
+	if len(io.Elements) != 11 || io.Elements["IOID66"] != 11927 {
+		t.Errorf("Wrong IO elements: %v", io.Elements)
+	}
+
+	event, ok := newEvent(io.Elements, message.Decoded.IMEI, record)
+	if !ok || event.Key != "IOID66" || event.Value != 11927 {
+		t.Errorf("Wrong event: %+v", event)
+	}
+
+	record.EventID = 0
+	_, ok = newEvent(io.Elements, message.Decoded.IMEI, record)
+	if ok {
+		t.Errorf("Records not generated by an event must not have event")
+	}
+}
+
+// TestPublish needs an MQTT broker, e.g. docker run --rm -d -p 1883:1883 eclipse-mosquitto mosquitto -c /mosquitto-no-auth.conf
+func TestPublish(t *testing.T) {
+	broker := os.Getenv("HALTONIKA_TEST_MQTT")
+	if broker == "" {
+		broker = DefaultBroker
+	}
+	conn, err := net.DialTimeout("tcp", strings.TrimPrefix(broker, "tcp://"), time.Second)
+	if err != nil {
+		t.Skipf("MQTT broker is not available at %s. %v", broker, err)
+	}
+	_ = conn.Close()
+
+	prefix := fmt.Sprintf("haltonika-test-%d", time.Now().UnixNano())
+	message := newTestMessage(t)
+
+	// Subscribe to all topics of the device
+	var lock sync.Mutex
+	received := make(map[string][]byte)
+	subscriber := paho.NewClient(paho.NewClientOptions().AddBroker(broker))
+	token := subscriber.Connect()
+	if !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("Failed to connect subscriber. %v", token.Error())
+	}
+	defer subscriber.Disconnect(100)
+
+	token = subscriber.Subscribe(prefix+"/#", 1, func(client paho.Client, m paho.Message) {
+		lock.Lock()
+		defer lock.Unlock()
+		received[m.Topic()] = m.Payload()
+	})
+	if !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("Failed to subscribe. %v", token.Error())
+	}
+
+	publisher := NewPublisher(sinktest.NewContext(), Options{Broker: broker, TopicPrefix: prefix, QoS: 1, Retain: false})
+	err = publisher.Connect()
+	if err != nil {
+		t.Fatalf("Failed to connect. %v", err)
+	}
+	defer func() {
+		_ = publisher.Close()
+	}()
+
+	err = publisher.Write(message)
+	if err != nil {
+		t.Fatalf("Failed to publish message. %v", err)
+	}
+	err = publisher.WriteCommandResponse(message.Decoded.IMEI, "Ver:03.27.07_00")
+	if err != nil {
+		t.Fatalf("Failed to publish command response. %v", err)
+	}
+	if publisher.Health() != nil {
+		t.Errorf("Publisher must be healthy. %v", publisher.Health())
+	}
+
+	topics := []string{TopicPosition, TopicIO, TopicEvent, TopicCommandResponse}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		lock.Lock()
+		count := len(received)
+		lock.Unlock()
+		if count == len(topics) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	for _, topic := range topics {
+		payload, ok := received[publisher.Topic(message.Decoded.IMEI, topic)]
+		if !ok {
+			t.Errorf("Nothing was published to %s topic", topic)
+			continue
+		}
+
+		var decoded map[string]interface{}
+		err = json.Unmarshal(payload, &decoded)
+		if err != nil || decoded["imei"] != message.Decoded.IMEI {
+			t.Errorf("Wrong payload of %s topic: %s", topic, payload)
+		}
+	}
+}
+
+func TestDiscoveryConfigs(t *testing.T) {
+	imei := "350424063817363"
+	publisher := NewPublisher(sinktest.NewContext(), Options{TopicPrefix: "fleet", DiscoveryPrefix: DefaultDiscoveryPrefix})
+	configs := publisher.discoveryConfigs(imei)
+
+	testCases := []struct {
+		Topic         string
+		StateTopic    string
+		ValueTemplate string
+		Unit          string
+	}{
+		{
+			Topic: "homeassistant/device_tracker/350424063817363/location/config",
+		},
+		{
+			Topic:         "homeassistant/sensor/350424063817363/speed/config",
+			StateTopic:    "fleet/350424063817363/position",
+			ValueTemplate: "{{ value_json.speed }}",
+			Unit:          "km/h",
+		},
+		{
+			Topic:         "homeassistant/sensor/350424063817363/external_voltage/config",
+			StateTopic:    "fleet/350424063817363/io",
+			ValueTemplate: "{% if 'IOID66' in value_json.elements %}{{ value_json.elements.IOID66 }}{% else %}{{ this.state }}{% endif %}",
+			Unit:          "mV",
+		},
+		{
+			Topic:         "homeassistant/binary_sensor/350424063817363/ignition/config",
+			StateTopic:    "fleet/350424063817363/io",
+			ValueTemplate: "{% if 'IOID239' in value_json.elements %}{{ value_json.elements.IOID239 }}{% else %}{{ this.state }}{% endif %}",
+		},
+	}
+
+	if len(configs) != len(discoveryEntities) {
+		t.Errorf("Wrong number of entities! Expected: %v Actual: %v", len(discoveryEntities), len(configs))
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Topic, func(test *testing.T) {
+			entityConfig, ok := configs[testCase.Topic]
+			if !ok {
+				test.Fatalf("Missing entity")
+			}
+			if entityConfig.StateTopic != testCase.StateTopic || entityConfig.ValueTemplate != testCase.ValueTemplate || entityConfig.UnitOfMeasurement != testCase.Unit {
+				test.Errorf("Wrong entity! Expected: %v, %v, %v Actual: %v, %v, %v", testCase.StateTopic, testCase.ValueTemplate, testCase.Unit,
+					entityConfig.StateTopic, entityConfig.ValueTemplate, entityConfig.UnitOfMeasurement)
+			}
+			if entityConfig.Device.Identifiers[0] != "haltonika_"+imei || !strings.HasPrefix(entityConfig.UniqueID, "haltonika_"+imei+"_") {
+				test.Errorf("Wrong device: %+v", entityConfig)
+			}
+		})
+	}
+
+	tracker := configs["homeassistant/device_tracker/350424063817363/location/config"]
+	if tracker.JsonAttributesTopic != "fleet/350424063817363/position" || tracker.SourceType != "gps" {
+		t.Errorf("Wrong device tracker: %+v", tracker)
+	}
+}
+
+func TestWriteDeviceList(t *testing.T) {
+	publisher := NewPublisher(sinktest.NewContext(), Options{HomeAssistant: true, DiscoveryPrefix: DefaultDiscoveryPrefix})
+	publisher.SetDiscoveryIMEIs([]string{"350424063817363", "352094089397464"})
+
+	// Trackers of the changed registry are announced when the publisher connects
+	err := publisher.WriteDeviceList([]string{"352094089397464", "356307042441013"})
+	if err != nil {
+		t.Fatalf("Failed to write device list. %v", err)
+	}
+
+	expected := []string{"352094089397464", "356307042441013"}
+	if fmt.Sprint(publisher.discoveryIMEIs) != fmt.Sprint(expected) {
+		t.Errorf("Wrong trackers! Expected: %v Actual: %v", expected, publisher.discoveryIMEIs)
+	}
+}
@@ -0,0 +1,104 @@
+package mqtt
+
+import (
+	"context"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/sink"
+	"time"
+)
+
+// Topics under <prefix>/<imei>/
+const (
+	TopicPosition        = "position"
+	TopicIO              = "io"
+	TopicEvent           = "event"
+	TopicCommandResponse = "command/response"
+)
+
+// Position is published to the position topic for each record
+type Position struct {
+	IMEI           string    `json:"imei"`
+	Time           time.Time `json:"time"`
+	ServerTime     time.Time `json:"serverTime"`
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	Altitude       int16     `json:"altitude"`
+	Angle          uint16    `json:"angle"`
+	Satellites     uint8     `json:"satellites"`
+	Speed          uint16    `json:"speed"`
+	Priority       uint8     `json:"priority"`
+	EventID        uint16    `json:"eventID"`
+	GenerationType *uint8    `json:"generationType,omitempty"` // Codec 16 only
+	Source         string    `json:"source"`
+}
+
+// IO is published to the io topic for each record with IO elements
+type IO struct {
+	IMEI     string                 `json:"imei"`
+	Time     time.Time              `json:"time"`
+	Elements map[string]interface{} `json:"elements"` // by IOID<n> keys
+}
+
+// Event is published to the event topic for records generated by an IO element event
+type Event struct {
+	IMEI    string      `json:"imei"`
+	Time    time.Time   `json:"time"`
+	EventID uint16      `json:"eventID"`
+	Key     string      `json:"key"`
+	Value   interface{} `json:"value,omitempty"` // value of the IO element which generated the event
+}
+
+// CommandResponse is published to the command/response topic when a device answers a command
+type CommandResponse struct {
+	IMEI     string    `json:"imei"`
+	Time     time.Time `json:"time"`
+	Response string    `json:"response"`
+}
+
+func newPosition(imei string, source string, record codec.AvlData, now time.Time) Position {
+	position := Position{
+		IMEI:       imei,
+		Time:       sink.Timestamp(record).UTC(),
+		ServerTime: now,
+		Latitude:   sink.Latitude(record),
+		Longitude:  sink.Longitude(record),
+		Altitude:   record.Altitude,
+		Angle:      record.Angle,
+		Satellites: record.VisSat,
+		Speed:      record.Speed,
+		Priority:   record.Priority,
+		EventID:    record.EventID,
+		Source:     source,
+	}
+
+	if record.GenerationType != codec.GenerationUnsupported {
+		generationType := record.GenerationType
+		position.GenerationType = &generationType
+	}
+
+	return position
+}
+
+func newIO(ctx context.Context, imei string, record codec.AvlData) IO {
+	return IO{
+		IMEI:     imei,
+		Time:     sink.Timestamp(record).UTC(),
+		Elements: sink.IOValues(ctx, record),
+	}
+}
+
+// newEvent returns false if the record was not generated by an event
+func newEvent(elements map[string]interface{}, imei string, record codec.AvlData) (Event, bool) {
+	if record.EventID == 0 {
+		return Event{}, false
+	}
+
+	key := sink.IOKey(record.EventID)
+	return Event{
+		IMEI:    imei,
+		Time:    sink.Timestamp(record).UTC(),
+		EventID: record.EventID,
+		Key:     key,
+		Value:   elements[key],
+	}, true
+}
@@ -0,0 +1,73 @@
+package pipe
+
+import (
+	"context"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/sink"
+	"time"
+)
+
+// Types of the lines written into the standard input of the program
+const (
+	TypeRecord          = "record"
+	TypeCommandResponse = "commandResponse"
+)
+
+// RecordLine is written into the standard input of the program for each record
+type RecordLine struct {
+	Type           string                 `json:"type"`
+	IMEI           string                 `json:"imei"`
+	Time           time.Time              `json:"time"`
+	ServerTime     time.Time              `json:"serverTime"`
+	Source         string                 `json:"source"`
+	Latitude       float64                `json:"latitude"`
+	Longitude      float64                `json:"longitude"`
+	Altitude       int16                  `json:"altitude"`
+	Angle          uint16                 `json:"angle"`
+	Satellites     uint8                  `json:"satellites"`
+	Speed          uint16                 `json:"speed"`
+	Priority       uint8                  `json:"priority"`
+	EventID        uint16                 `json:"eventID"`
+	GenerationType *uint8                 `json:"generationType,omitempty"` // Codec 16 only
+	IO             map[string]interface{} `json:"io"`                       // by IOID<n> keys
+}
+
+// CommandResponseLine is written into the standard input of the program when a device answers a command
+type CommandResponseLine struct {
+	Type     string    `json:"type"`
+	IMEI     string    `json:"imei"`
+	Time     time.Time `json:"time"`
+	Response string    `json:"response"`
+}
+
+// CommandRequest is read from the standard output of the program to send a command to a device
+type CommandRequest struct {
+	IMEI    string `json:"imei"`
+	Command string `json:"command"`
+}
+
+func newRecordLine(ctx context.Context, message sink.Message, record codec.AvlData, now time.Time) RecordLine {
+	line := RecordLine{
+		Type:       TypeRecord,
+		IMEI:       message.Decoded.IMEI,
+		Time:       sink.Timestamp(record).UTC(),
+		ServerTime: now,
+		Source:     message.SourceAddress,
+		Latitude:   sink.Latitude(record),
+		Longitude:  sink.Longitude(record),
+		Altitude:   record.Altitude,
+		Angle:      record.Angle,
+		Satellites: record.VisSat,
+		Speed:      record.Speed,
+		Priority:   record.Priority,
+		EventID:    record.EventID,
+		IO:         sink.IOValues(ctx, record),
+	}
+
+	if record.GenerationType != codec.GenerationUnsupported {
+		generationType := record.GenerationType
+		line.GenerationType = &generationType
+	}
+
+	return line
+}
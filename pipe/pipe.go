@@ -0,0 +1,393 @@
+package pipe
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/sink"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+	writeTimeout      = 10 * time.Second
+	stopTimeout       = 5 * time.Second
+
+	// Process running at least this long is considered healthy, so the backoff starts again from the minimum
+	stableRunTime = time.Minute
+)
+
+// Options of an exec sink
+type Options struct {
+	Command      []string // program and its arguments
+	Dir          string   // working directory of the program
+	ReadCommands bool     // read command requests from the standard output of the program
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+}
+
+// ParseOptions decodes the options of an exec sink
+func ParseOptions(options map[string]interface{}) (Options, error) {
+	parsed := Options{
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+	}
+
+	err := sink.DecodeOptions(options, &parsed)
+	if err != nil {
+		return Options{}, err
+	}
+
+	if len(parsed.Command) == 0 || parsed.Command[0] == "" {
+		return Options{}, fmt.Errorf("command must be specified")
+	}
+	if parsed.MinBackoff <= 0 || parsed.MaxBackoff < parsed.MinBackoff {
+		return Options{}, fmt.Errorf("backoff must be positive and minbackoff must not be greater than maxbackoff")
+	}
+
+	return parsed, nil
+}
+
+/*
+Exec streams the records into the standard input of an external program as newline-delimited JSON. It implements sink.Sink.
+The program is restarted with exponential backoff when it exits. Its standard error is logged.
+*/
+type Exec struct {
+	ctx          context.Context
+	options      Options
+	writeTimeout time.Duration
+
+	stop      context.CancelFunc
+	stopped   chan struct{}
+	stdin     *os.File // nil while the program is not running
+	process   *os.Process
+	exitErr   error // why the program is not running
+	handler   sink.CommandHandler
+	restarts  uint64
+	lock      sync.Mutex // guards the fields above
+	writeLock sync.Mutex // lines of concurrent writes must not be mixed
+}
+
+func NewExec(ctx context.Context, options Options) *Exec {
+	return &Exec{
+		ctx:          ctx,
+		options:      options,
+		writeTimeout: writeTimeout,
+		stopped:      make(chan struct{}),
+		exitErr:      errors.New("program is not started"),
+	}
+}
+
+// Start starts the program and keeps it running until Close
+func (e *Exec) Start() error {
+	_, err := exec.LookPath(e.options.Command[0])
+	if err != nil {
+		return fmt.Errorf("program not found. %v", err)
+	}
+
+	ctx, stop := context.WithCancel(e.ctx)
+	e.stop = stop
+
+	started := make(chan struct{})
+	go e.supervise(ctx, started)
+	<-started
+
+	return nil
+}
+
+// supervise runs the program again and again with backoff until the context is cancelled
+func (e *Exec) supervise(ctx context.Context, started chan<- struct{}) {
+	log := config.GetLogger(e.ctx)
+	defer close(e.stopped)
+
+	backoff := e.options.MinBackoff
+	for {
+		begin := time.Now()
+		err := e.run(ctx, started)
+		started = nil
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(begin) >= stableRunTime {
+			backoff = e.options.MinBackoff
+		}
+		log.Errorf("Program %s exited. Restarting it in %v. %v", e.options.Command[0], backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, e.options.MaxBackoff)
+
+		e.lock.Lock()
+		e.restarts++
+		e.lock.Unlock()
+	}
+}
+
+// run runs the program once and returns when it exited. Started is closed when the program is started or failed to start.
+func (e *Exec) run(ctx context.Context, started chan<- struct{}) error {
+	log := config.GetLogger(e.ctx)
+
+	notifyStarted := func() {
+		if started != nil {
+			close(started)
+		}
+	}
+
+	// Own pipe is used for stdin to have write deadlines
+	stdinReader, stdin, err := os.Pipe()
+	if err != nil {
+		e.setExited(err)
+		notifyStarted()
+		return err
+	}
+
+	// #nosec G204 command is set by the administrator in the config file
+	cmd := exec.Command(e.options.Command[0], e.options.Command[1:]...)
+	cmd.Dir = e.options.Dir
+	cmd.Stdin = stdinReader
+
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		var stderr io.ReadCloser
+		stderr, err = cmd.StderrPipe()
+		if err == nil {
+			go e.logOutput(stderr)
+			go e.readStdout(stdout)
+			err = cmd.Start()
+		}
+	}
+	_ = stdinReader.Close()
+	if err != nil {
+		_ = stdin.Close()
+		e.setExited(err)
+		notifyStarted()
+		return fmt.Errorf("failed to start program. %v", err)
+	}
+
+	log.Infof("Program %s is started with %d PID", e.options.Command[0], cmd.Process.Pid)
+
+	e.lock.Lock()
+	e.stdin = stdin
+	e.process = cmd.Process
+	e.exitErr = nil
+	e.lock.Unlock()
+	notifyStarted()
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	select {
+	case err = <-exited:
+	case <-ctx.Done():
+		// Closed stdin tells the program to finish, it is killed if it does not
+		e.setExited(errors.New("program is stopped"))
+		_ = stdin.Close()
+
+		select {
+		case err = <-exited:
+		case <-time.After(stopTimeout):
+			_ = cmd.Process.Kill()
+			err = <-exited
+		}
+
+		return err
+	}
+
+	if err == nil {
+		err = errors.New("program exited")
+	}
+	e.setExited(err)
+	_ = stdin.Close()
+
+	return err
+}
+
+func (e *Exec) setExited(err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.stdin = nil
+	e.process = nil
+	e.exitErr = err
+}
+
+// logOutput logs the standard error of the program
+func (e *Exec) logOutput(output io.Reader) {
+	log := config.GetLogger(e.ctx)
+
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		log.Warnf("%s: %s", e.options.Command[0], scanner.Text())
+	}
+}
+
+// readStdout sends the command requests read from the standard output of the program if it is enabled, otherwise logs it
+func (e *Exec) readStdout(output io.Reader) {
+	log := config.GetLogger(e.ctx)
+
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !e.options.ReadCommands {
+			log.Infof("%s: %s", e.options.Command[0], line)
+			continue
+		}
+
+		var request CommandRequest
+		err := json.Unmarshal(line, &request)
+		if err != nil || request.IMEI == "" || request.Command == "" {
+			log.Errorf("Invalid command request from %s: %s", e.options.Command[0], line)
+			continue
+		}
+
+		e.lock.Lock()
+		handler := e.handler
+		e.lock.Unlock()
+
+		if handler == nil {
+			log.Errorf("Command request to %s device is dropped, commands can not be sent yet", request.IMEI)
+			continue
+		}
+
+		err = handler(request.IMEI, request.Command)
+		if err != nil {
+			log.Errorf("Failed to send %q command to %s device. %v", request.Command, request.IMEI, err)
+			continue
+		}
+		log.Infof("%q command is queued for %s device", request.Command, request.IMEI)
+	}
+}
+
+// SetCommandHandler sets the function sending the command requests of the program
+func (e *Exec) SetCommandHandler(handler sink.CommandHandler) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.handler = handler
+}
+
+// Write writes one line per record into the standard input of the program
+func (e *Exec) Write(message sink.Message) error {
+	now := time.Now().UTC()
+
+	var lines []byte
+	for _, record := range message.Decoded.Data {
+		line, err := json.Marshal(newRecordLine(e.ctx, message, record, now))
+		if err != nil {
+			return fmt.Errorf("failed to serialize record. %v", err)
+		}
+		lines = append(lines, line...)
+		lines = append(lines, '\n')
+	}
+
+	return e.writeLines(lines)
+}
+
+// WriteCommandResponse writes the responses of the devices into the standard input of the program if it sends commands
+func (e *Exec) WriteCommandResponse(imei string, response string) error {
+	if !e.options.ReadCommands {
+		return nil
+	}
+
+	line, err := json.Marshal(CommandResponseLine{
+		Type:     TypeCommandResponse,
+		IMEI:     imei,
+		Time:     time.Now().UTC(),
+		Response: response,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize command response. %v", err)
+	}
+
+	return e.writeLines(append(line, '\n'))
+}
+
+func (e *Exec) writeLines(lines []byte) error {
+	e.writeLock.Lock()
+	defer e.writeLock.Unlock()
+
+	e.lock.Lock()
+	stdin, exitErr := e.stdin, e.exitErr
+	e.lock.Unlock()
+
+	if stdin == nil {
+		return fmt.Errorf("program is not running. %v", exitErr)
+	}
+
+	err := stdin.SetWriteDeadline(time.Now().Add(e.writeTimeout))
+	if err != nil {
+		return err
+	}
+
+	n, err := stdin.Write(lines)
+	if err != nil {
+		if n > 0 {
+			// Nothing can be written after a truncated line, so the program is restarted
+			e.kill(fmt.Errorf("line is truncated. %v", err))
+		}
+		return fmt.Errorf("failed to write to program. %v", err)
+	}
+
+	return nil
+}
+
+// kill stops the running program immediately, the supervisor starts it again
+func (e *Exec) kill(reason error) {
+	e.lock.Lock()
+	stdin, process := e.stdin, e.process
+	e.lock.Unlock()
+
+	if stdin == nil {
+		return
+	}
+
+	config.GetLogger(e.ctx).Errorf("Killing program %s. %v", e.options.Command[0], reason)
+	e.setExited(reason)
+	_ = stdin.Close()
+	_ = process.Kill()
+}
+
+// Flush does nothing because Write writes the records immediately
+func (e *Exec) Flush() error {
+	return nil
+}
+
+// Close stops the program
+func (e *Exec) Close() error {
+	if e.stop == nil {
+		return nil
+	}
+
+	e.stop()
+	<-e.stopped
+
+	return nil
+}
+
+// Health returns why the program is not running
+func (e *Exec) Health() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.stdin == nil {
+		return fmt.Errorf("program is not running. %v", e.exitErr)
+	}
+
+	return nil
+}
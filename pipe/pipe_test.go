@@ -0,0 +1,229 @@
+package pipe
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/halacs/haltonika/sink/sinktest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitFor polls the condition until it is true or a few seconds passed
+func waitFor(condition func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return false
+}
+
+func TestParseOptions(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Options       map[string]interface{}
+		ExpectedError bool
+	}{
+		{
+			Name:    "Defaults",
+			Options: map[string]interface{}{"command": []interface{}{"/usr/local/bin/consumer.py"}},
+		},
+		{
+			Name:    "All options",
+			Options: map[string]interface{}{"command": []interface{}{"python3", "consumer.py", "--verbose"}, "dir": "/opt/consumer", "readcommands": true, "minbackoff": "500ms", "maxbackoff": "5m"},
+		},
+		{
+			Name:          "Missing command",
+			Options:       map[string]interface{}{},
+			ExpectedError: true,
+		},
+		{
+			Name:          "Wrong backoff",
+			Options:       map[string]interface{}{"command": []interface{}{"cat"}, "minbackoff": "1m", "maxbackoff": "1s"},
+			ExpectedError: true,
+		},
+		{
+			Name:          "Unknown option",
+			Options:       map[string]interface{}{"command": []interface{}{"cat"}, "args": "-u"},
+			ExpectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			_, err := ParseOptions(testCase.Options)
+			if (err != nil) != testCase.ExpectedError {
+				test.Errorf("Wrong result! Expected error: %v Actual: %v", testCase.ExpectedError, err)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "records.jsonl")
+	exec := NewExec(sinktest.NewContext(), Options{
+		Command:    []string{"sh", "-c", "cat > " + output},
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+	})
+	err := exec.Start()
+	if err != nil {
+		t.Fatalf("Failed to start program. %v", err)
+	}
+
+	err = exec.Write(sinktest.NewMessage(t))
+	if err != nil {
+		t.Fatalf("Failed to write message. %v", err)
+	}
+
+	// Program finishes writing when its stdin is closed
+	err = exec.Close()
+	if err != nil {
+		t.Fatalf("Failed to close. %v", err)
+	}
+
+	content, err := os.ReadFile(output) // #nosec G304 file of the test
+	if err != nil {
+		t.Fatalf("Failed to read output. %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Wrong number of lines! Expected: %v Actual: %v", 4, len(lines))
+	}
+
+	var line RecordLine
+	err = json.Unmarshal([]byte(lines[0]), &line)
+	if err != nil {
+		t.Fatalf("Failed to parse line. %v", err)
+	}
+	if line.Type != TypeRecord || line.IMEI != "352094089397464" || line.Source != "127.0.0.1:1234" {
+		t.Errorf("Wrong line: %s", lines[0])
+	}
+	if line.IO["IOID66"] != float64(28632) {
+		t.Errorf("Wrong IO value! Expected: %v Actual: %v", 28632, line.IO["IOID66"])
+	}
+}
+
+func TestReadCommands(t *testing.T) {
+	type request struct {
+		imei    string
+		command string
+	}
+	requests := make(chan request, 10)
+
+	exec := NewExec(sinktest.NewContext(), Options{
+		// Invalid line is skipped, then the responses are echoed back as requests
+		Command:      []string{"sh", "-c", `echo 'not json'; echo '{"imei":"352094089397464","command":"getinfo"}'; while read -r line; do echo '{"imei":"352094089397464","command":"getver"}'; done`},
+		ReadCommands: true,
+		MinBackoff:   DefaultMinBackoff,
+		MaxBackoff:   DefaultMaxBackoff,
+	})
+	exec.SetCommandHandler(func(imei, command string) error {
+		requests <- request{imei, command}
+		return nil
+	})
+	err := exec.Start()
+	if err != nil {
+		t.Fatalf("Failed to start program. %v", err)
+	}
+	defer func() {
+		_ = exec.Close()
+	}()
+
+	err = exec.WriteCommandResponse("352094089397464", "Ver:03.25.14")
+	if err != nil {
+		t.Fatalf("Failed to write command response. %v", err)
+	}
+
+	for _, expected := range []request{{"352094089397464", "getinfo"}, {"352094089397464", "getver"}} {
+		select {
+		case actual := <-requests:
+			if actual != expected {
+				t.Errorf("Wrong command request! Expected: %v Actual: %v", expected, actual)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Command request is not received: %v", expected)
+		}
+	}
+}
+
+func TestRestart(t *testing.T) {
+	exec := NewExec(sinktest.NewContext(), Options{
+		Command:    []string{"sh", "-c", "read -r line"},
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+	})
+	err := exec.Start()
+	if err != nil {
+		t.Fatalf("Failed to start program. %v", err)
+	}
+	defer func() {
+		_ = exec.Close()
+	}()
+
+	if exec.Health() != nil {
+		t.Errorf("Started program must be healthy. %v", exec.Health())
+	}
+
+	// Program exits after the first line
+	err = exec.Write(sinktest.NewMessage(t))
+	if err != nil {
+		t.Fatalf("Failed to write message. %v", err)
+	}
+
+	restarted := waitFor(func() bool {
+		exec.lock.Lock()
+		defer exec.lock.Unlock()
+		return exec.restarts > 0 && exec.stdin != nil
+	})
+	if !restarted {
+		t.Errorf("Program must be restarted. %v", exec.Health())
+	}
+}
+
+func TestRestartAfterTruncatedLine(t *testing.T) {
+	// First program does not read its standard input, the restarted one does
+	exec := NewExec(sinktest.NewContext(), Options{
+		Command:    []string{"sh", "-c", "if [ -e started ]; then cat > /dev/null; else touch started; exec sleep 60; fi"},
+		Dir:        t.TempDir(),
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+	})
+	exec.writeTimeout = 100 * time.Millisecond
+	err := exec.Start()
+	if err != nil {
+		t.Fatalf("Failed to start program. %v", err)
+	}
+	defer func() {
+		_ = exec.Close()
+	}()
+
+	// Line is longer than the pipe buffer, so only its beginning is written before the deadline
+	line := append(bytes.Repeat([]byte("x"), 1024*1024), '\n')
+	err = exec.writeLines(line)
+	if err == nil {
+		t.Fatalf("Write must fail when the program does not read")
+	}
+
+	restarted := waitFor(func() bool {
+		exec.lock.Lock()
+		defer exec.lock.Unlock()
+		return exec.restarts > 0 && exec.stdin != nil
+	})
+	if !restarted {
+		t.Errorf("Program must be restarted after a truncated line. %v", exec.Health())
+	}
+}
+
+func TestStartMissingProgram(t *testing.T) {
+	exec := NewExec(sinktest.NewContext(), Options{Command: []string{"haltonika-missing-program"}})
+	err := exec.Start()
+	if err == nil {
+		t.Errorf("Missing program must not be started")
+	}
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrationLockID serializes migrations of more Haltonika instances sharing the same database
+const migrationLockID = 0x48414c54 // HALT
+
+/*
+migrations upgrade the schema step by step. The index of a migration plus one is its version.
+Applied migrations must never be changed, new ones have to be appended.
+*/
+var migrations = [][]string{
+	// 1: devices, sessions and positions
+	{
+		`CREATE EXTENSION IF NOT EXISTS postgis`,
+		`CREATE TABLE devices (
+			imei        text PRIMARY KEY,
+			first_seen  timestamptz NOT NULL,
+			last_seen   timestamptz NOT NULL,
+			last_source text NOT NULL
+		)`,
+		`CREATE TABLE sessions (
+			id         bigserial PRIMARY KEY,
+			imei       text NOT NULL REFERENCES devices (imei),
+			source     text NOT NULL,
+			started_at timestamptz NOT NULL,
+			last_seen  timestamptz NOT NULL,
+			packets    bigint NOT NULL DEFAULT 0,
+			records    bigint NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX sessions_imei_started_at_idx ON sessions (imei, started_at DESC)`,
+		`CREATE TABLE positions (
+			imei            text NOT NULL REFERENCES devices (imei),
+			time            timestamptz NOT NULL,
+			session_id      bigint REFERENCES sessions (id),
+			server_time     timestamptz NOT NULL,
+			codec_id        smallint NOT NULL,
+			priority        smallint NOT NULL,
+			location        geography(Point, 4326) NOT NULL,
+			altitude        integer NOT NULL,
+			angle           integer NOT NULL,
+			satellites      integer NOT NULL,
+			speed           integer NOT NULL,
+			event_id        integer NOT NULL,
+			generation_type smallint,
+			io              jsonb NOT NULL,
+			PRIMARY KEY (imei, time)
+		)`,
+		`CREATE INDEX positions_location_idx ON positions USING gist (location)`,
+		`CREATE INDEX positions_io_idx ON positions USING gin (io)`,
+	},
+}
+
+// migrate applies the migrations which are not applied yet in one transaction
+func migrate(ctx context.Context, db *sql.DB) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction. %v", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock migrations. %v", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    integer PRIMARY KEY,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations table. %v", err)
+	}
+
+	var version int
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schema version. %v", err)
+	}
+	if version > len(migrations) {
+		return 0, fmt.Errorf("schema version %d is newer than the latest known version %d", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		for _, statement := range migrations[i] {
+			_, err = tx.ExecContext(ctx, statement)
+			if err != nil {
+				return 0, fmt.Errorf("failed to apply migration %d. %v", i+1, err)
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, i+1)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record migration %d. %v", i+1, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit migrations. %v", err)
+	}
+
+	return len(migrations) - version, nil
+}
@@ -0,0 +1,111 @@
+package profile
+
+import (
+	"fmt"
+	"github.com/halacs/haltonika/ioelement"
+	"sort"
+	"strings"
+)
+
+// Profile tells how the devices of a model are handled
+type Profile struct {
+	Family       string // family of the IO elements, one of the ioelement.Dictionaries
+	CommandCodec byte   // codec used to send commands, zero uses the default one
+}
+
+// Config is a model in the models section of the config file. Empty fields keep the built-in values.
+type Config struct {
+	Family       string
+	CommandCodec int
+}
+
+// builtin profiles by the prefixes of the model names. Former FM devices do not support Codec 14.
+// FM63XY devices share the IO elements of FM64XY ones.
+var builtin = map[string]Profile{
+	"fm11": {Family: ioelement.FamilyFM11XY, CommandCodec: 12},
+	"fm12": {Family: ioelement.FamilyFM11XY, CommandCodec: 12},
+	"fm36": {Family: ioelement.FamilyFM36, CommandCodec: 12},
+	"fm63": {Family: ioelement.FamilyFM64, CommandCodec: 12},
+	"fm64": {Family: ioelement.FamilyFM64, CommandCodec: 12},
+}
+
+// defaultProfile is used for the unknown models. FMB, FMC, FMM, FMT, FMU and FMP devices share the FMBXY IO elements.
+var defaultProfile = Profile{Family: ioelement.FamilyFMBXY}
+
+// Profiles holds the profiles of the configured models on top of the built-in ones
+type Profiles struct {
+	models map[string]Profile // by lowercase model name
+}
+
+// NewProfiles validates the configured models
+func NewProfiles(configs map[string]Config) (*Profiles, error) {
+	profiles := &Profiles{
+		models: make(map[string]Profile, len(configs)),
+	}
+
+	// Sorted to report always the same error
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		cfg := configs[name]
+		profile := builtinProfile(name)
+
+		if cfg.Family != "" {
+			family := strings.ToLower(cfg.Family)
+			if _, ok := ioelement.Dictionaries[family]; !ok {
+				return nil, fmt.Errorf("unknown family of %s model: %s", name, cfg.Family)
+			}
+			profile.Family = family
+		}
+
+		switch cfg.CommandCodec {
+		case 0:
+		case 12, 14: // Codec 13 is used only by devices to send responses
+			profile.CommandCodec = byte(cfg.CommandCodec)
+		default:
+			return nil, fmt.Errorf("commands can be sent with codec 12 or 14, got %d for %s model", cfg.CommandCodec, name)
+		}
+
+		profiles.models[strings.ToLower(name)] = profile
+	}
+
+	return profiles, nil
+}
+
+// builtinProfile returns the built-in profile of a model
+func builtinProfile(model string) Profile {
+	model = strings.ToLower(model)
+	for prefix, profile := range builtin {
+		if strings.HasPrefix(model, prefix) {
+			return profile
+		}
+	}
+
+	return defaultProfile
+}
+
+// Profile returns the profile of a model, case-insensitive. Nil profiles hold the built-in ones only.
+func (p *Profiles) Profile(model string) Profile {
+	if p != nil {
+		profile, ok := p.models[strings.ToLower(model)]
+		if ok {
+			return profile
+		}
+	}
+
+	return builtinProfile(model)
+}
+
+// Dictionary returns the IO dictionary of a model. It can be used as ioelement.DictionaryFunc.
+func (p *Profiles) Dictionary(model string) ioelement.Dictionary {
+	return ioelement.Dictionaries[p.Profile(model).Family]
+}
+
+// BuiltinDictionary returns the IO dictionary of a built-in model. It can be used as ioelement.DictionaryFunc without the models section.
+func BuiltinDictionary(model string) ioelement.Dictionary {
+	return ioelement.Dictionaries[builtinProfile(model).Family]
+}
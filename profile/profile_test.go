@@ -0,0 +1,83 @@
+package profile
+
+import (
+	"github.com/halacs/haltonika/ioelement"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	profiles, err := NewProfiles(map[string]Config{
+		"fmc130":  {CommandCodec: 14},
+		"FM1120":  {CommandCodec: 0},
+		"tracker": {Family: "FM36"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create profiles. %v", err)
+	}
+
+	testCases := []struct {
+		Name     string
+		Model    string
+		Expected Profile
+	}{
+		{Name: "Unknown model", Model: "", Expected: Profile{Family: ioelement.FamilyFMBXY}},
+		{Name: "FMB", Model: "FMB920", Expected: Profile{Family: ioelement.FamilyFMBXY}},
+		{Name: "FMM", Model: "FMM00A", Expected: Profile{Family: ioelement.FamilyFMBXY}},
+		{Name: "Configured codec", Model: "FMC130", Expected: Profile{Family: ioelement.FamilyFMBXY, CommandCodec: 14}},
+		{Name: "FM63", Model: "FM6300", Expected: Profile{Family: ioelement.FamilyFM64, CommandCodec: 12}},
+		{Name: "FM64", Model: "FM6420", Expected: Profile{Family: ioelement.FamilyFM64, CommandCodec: 12}},
+		{Name: "FM36", Model: "FM3622", Expected: Profile{Family: ioelement.FamilyFM36, CommandCodec: 12}},
+		{Name: "FM12", Model: "FM1202", Expected: Profile{Family: ioelement.FamilyFM11XY, CommandCodec: 12}},
+		{Name: "Configured keeps built-in values", Model: "fm1120", Expected: Profile{Family: ioelement.FamilyFM11XY, CommandCodec: 12}},
+		{Name: "Configured family", Model: "Tracker", Expected: Profile{Family: ioelement.FamilyFM36}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			profile := profiles.Profile(testCase.Model)
+			if profile != testCase.Expected {
+				test.Errorf("Wrong profile! Expected: %v Actual: %v", testCase.Expected, profile)
+			}
+		})
+	}
+
+	// Analog input 2 in FM devices, SD status in FMB devices
+	definition, _ := profiles.Dictionary("FM3622").Lookup(10)
+	if definition.Name != "Analog Input 2" {
+		t.Errorf("Wrong dictionary of FM3622! Actual: %v", definition.Name)
+	}
+
+	var builtinOnly *Profiles
+	if builtinOnly.Profile("FM1100") != builtin["fm11"] {
+		t.Errorf("Nil profiles must return the built-in ones")
+	}
+
+	// Configured models are not known by the built-in dictionaries
+	definition, _ = BuiltinDictionary("Tracker").Lookup(10)
+	if definition.Name != "SD Status" {
+		t.Errorf("Wrong built-in dictionary of Tracker! Actual: %v", definition.Name)
+	}
+	definition, _ = BuiltinDictionary("FM6320").Lookup(19)
+	if definition.Name != "AdBlue Level Percent" {
+		t.Errorf("Wrong built-in dictionary of FM6320! Actual: %v", definition.Name)
+	}
+}
+
+func TestProfileInvalid(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Config map[string]Config
+	}{
+		{Name: "Unknown family", Config: map[string]Config{"fmb920": {Family: "fmz"}}},
+		{Name: "Response codec", Config: map[string]Config{"fmb920": {CommandCodec: 13}}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			_, err := NewProfiles(testCase.Config)
+			if err == nil {
+				test.Errorf("Invalid profiles must be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package registry
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxBodySize limits the size of a device sent to the API
+const maxBodySize = 64 * 1024
+
+/*
+Handler serves
+  - GET /api/registry/devices: all devices
+  - GET /api/registry/devices/<imei>: a device
+  - PUT /api/registry/devices/<imei>: adds or replaces a device given as JSON
+  - DELETE /api/registry/devices/<imei>: removes a device
+
+Every request requires the token as bearer token, because devices have owners and plates. Requests are refused if the
+token is empty.
+*/
+func (r *Registry) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/registry/devices", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", req.Method))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, r.Devices())
+	})
+	mux.HandleFunc("/api/registry/devices/", func(w http.ResponseWriter, req *http.Request) {
+		imei := strings.TrimPrefix(req.URL.Path, "/api/registry/devices/")
+		if imei == "" || strings.Contains(imei, "/") {
+			writeError(w, http.StatusNotFound, fmt.Errorf("unknown path %s", req.URL.Path))
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			r.getDevice(w, imei)
+		case http.MethodPut:
+			r.putDevice(w, req, imei)
+		case http.MethodDelete:
+			r.deleteDevice(w, imei)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", req.Method))
+		}
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(req, token) {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid token"))
+			return
+		}
+
+		mux.ServeHTTP(w, req)
+	})
+}
+
+func authorized(req *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	given := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func (r *Registry) getDevice(w http.ResponseWriter, imei string) {
+	device, ok := r.Device(imei)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("device %s is not found", imei))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, device)
+}
+
+func (r *Registry) putDevice(w http.ResponseWriter, req *http.Request, imei string) {
+	var device Device
+	decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&device)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid device. %v", err))
+		return
+	}
+
+	// IMEI of the path is used, the body may omit it
+	if device.IMEI != "" && device.IMEI != imei {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("IMEI of the device does not match the path"))
+		return
+	}
+	device.IMEI = imei
+
+	err = validate(device)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := r.Put(device)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, device)
+}
+
+func (r *Registry) deleteDevice(w http.ResponseWriter, imei string) {
+	deleted, err := r.Delete(imei)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !deleted {
+		writeError(w, http.StatusNotFound, fmt.Errorf("device %s is not found", imei))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,274 @@
+package registry
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Device describes a device in the registry
+type Device struct {
+	IMEI   string            `json:"imei" yaml:"-" mapstructure:"-"`
+	Model  string            `json:"model,omitempty" yaml:"model,omitempty"` // e.g. FMB920, selects the IO dictionary, the IO mapping and the command codec of the model
+	Name   string            `json:"name,omitempty" yaml:"name,omitempty"`   // e.g. name of the vehicle
+	Plate  string            `json:"plate,omitempty" yaml:"plate,omitempty"` // license plate of the vehicle
+	Group  string            `json:"group,omitempty" yaml:"group,omitempty"`
+	Owner  string            `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// file is the content of the devices file
+type file struct {
+	Devices map[string]Device `yaml:"devices"`
+}
+
+// Registry holds the devices by IMEI. Devices changed by Put and Delete are saved into the devices file if there is any.
+type Registry struct {
+	devices  map[string]Device
+	fileName string // empty keeps the changes in memory only
+	onChange func()
+	lock     sync.RWMutex
+}
+
+// New creates a registry of the given devices by IMEI
+func New(devices map[string]Device) (*Registry, error) {
+	registry := &Registry{
+		devices: make(map[string]Device, len(devices)),
+	}
+
+	for imei, device := range devices {
+		device.IMEI = imei
+		err := validate(device)
+		if err != nil {
+			return nil, err
+		}
+		registry.devices[imei] = device
+	}
+
+	return registry, nil
+}
+
+// Load reads the registry from a YAML file. A missing file is an empty registry, the file is created by the first change.
+func Load(fileName string) (*Registry, error) {
+	var content file
+
+	raw, err := os.ReadFile(fileName) // #nosec G304 file name is set by the administrator in the config file
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read devices file. %v", err)
+	}
+	if err == nil {
+		err = yaml.Unmarshal(raw, &content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse devices file. %v", err)
+		}
+	}
+
+	registry, err := New(content.Devices)
+	if err != nil {
+		return nil, fmt.Errorf("invalid devices file. %v", err)
+	}
+	registry.fileName = fileName
+
+	return registry, nil
+}
+
+// validate checks the IMEI and the labels of a device
+func validate(device Device) error {
+	if len(device.IMEI) != 15 {
+		return fmt.Errorf("IMEI must be 15 digits long, got %s", device.IMEI)
+	}
+	for _, digit := range device.IMEI {
+		if digit < '0' || digit > '9' {
+			return fmt.Errorf("IMEI must contain digits only, got %s", device.IMEI)
+		}
+	}
+	for key := range device.Labels {
+		if key == "" {
+			return fmt.Errorf("labels of %s device must not have empty keys", device.IMEI)
+		}
+	}
+
+	return nil
+}
+
+// SetChangeCallback sets the function called after Put and Delete
+func (r *Registry) SetChangeCallback(onChange func()) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.onChange = onChange
+}
+
+// Device returns a device by its IMEI. Nil registry is empty.
+func (r *Registry) Device(imei string) (Device, bool) {
+	if r == nil {
+		return Device{}, false
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	device, ok := r.devices[imei]
+	return device, ok
+}
+
+// Devices returns all devices ordered by IMEI
+func (r *Registry) Devices() []Device {
+	if r == nil {
+		return nil
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	devices := make([]Device, 0, len(r.devices))
+	for _, device := range r.devices {
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].IMEI < devices[j].IMEI
+	})
+
+	return devices
+}
+
+// IMEIs returns the IMEIs of all devices in ascending order
+func (r *Registry) IMEIs() []string {
+	devices := r.Devices()
+
+	imeis := make([]string, 0, len(devices))
+	for _, device := range devices {
+		imeis = append(imeis, device.IMEI)
+	}
+
+	return imeis
+}
+
+// Put adds or replaces a device. Returns true if the device is new.
+func (r *Registry) Put(device Device) (bool, error) {
+	err := validate(device)
+	if err != nil {
+		return false, err
+	}
+
+	r.lock.Lock()
+	_, exists := r.devices[device.IMEI]
+	previous := r.devices
+	r.devices = copyDevices(r.devices)
+	r.devices[device.IMEI] = device
+
+	err = r.save()
+	if err != nil {
+		r.devices = previous
+	}
+	onChange := r.onChange
+	r.lock.Unlock()
+
+	if err != nil {
+		return false, err
+	}
+	if onChange != nil {
+		onChange()
+	}
+
+	return !exists, nil
+}
+
+// Delete removes a device. Returns false if the device is not in the registry.
+func (r *Registry) Delete(imei string) (bool, error) {
+	r.lock.Lock()
+	_, exists := r.devices[imei]
+	if !exists {
+		r.lock.Unlock()
+		return false, nil
+	}
+
+	previous := r.devices
+	r.devices = copyDevices(r.devices)
+	delete(r.devices, imei)
+
+	err := r.save()
+	if err != nil {
+		r.devices = previous
+	}
+	onChange := r.onChange
+	r.lock.Unlock()
+
+	if err != nil {
+		return false, err
+	}
+	if onChange != nil {
+		onChange()
+	}
+
+	return true, nil
+}
+
+func copyDevices(devices map[string]Device) map[string]Device {
+	copied := make(map[string]Device, len(devices)+1)
+	for imei, device := range devices {
+		copied[imei] = device
+	}
+
+	return copied
+}
+
+// save writes the devices into the devices file atomically. It has to be called with the lock held.
+func (r *Registry) save() error {
+	if r.fileName == "" {
+		return nil
+	}
+
+	raw, err := yaml.Marshal(file{Devices: r.devices})
+	if err != nil {
+		return fmt.Errorf("failed to serialize devices. %v", err)
+	}
+
+	// Renaming a temporary file does not leave a half written file behind on failure
+	temp, err := os.CreateTemp(filepath.Dir(r.fileName), filepath.Base(r.fileName)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save devices file. %v", err)
+	}
+	defer func() {
+		_ = os.Remove(temp.Name())
+	}()
+
+	_, err = temp.Write(raw)
+	if err == nil {
+		err = temp.Sync()
+	}
+	closeErr := temp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), r.fileName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save devices file. %v", err)
+	}
+
+	return nil
+}
+
+// Tags returns the attributes of the device as storage tags: Name, Plate, Group and Owner with the labels. Empty values are skipped.
+func (d Device) Tags() map[string]string {
+	tags := make(map[string]string, len(d.Labels)+4)
+	for key, value := range d.Labels {
+		if value != "" {
+			tags[key] = value
+		}
+	}
+
+	// Attributes win over the labels with the same keys
+	for key, value := range map[string]string{"Name": d.Name, "Plate": d.Plate, "Group": d.Group, "Owner": d.Owner} {
+		if value != "" {
+			tags[key] = value
+		}
+	}
+
+	return tags
+}
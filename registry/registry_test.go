@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testIMEI = "352094089397464"
+
+// request sends a request to the API and returns the status code
+func request(handler http.Handler, method string, path string, body string, token string) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	return recorder.Code
+}
+
+func TestLoad(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "devices.yaml")
+	err := os.WriteFile(fileName, []byte(`devices:
+  352094089397464:
+    model: FMB920
+    name: Truck 1
+    plate: ABC-123
+    labels:
+      depot: north
+`), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write devices file. %v", err)
+	}
+
+	registry, err := Load(fileName)
+	if err != nil {
+		t.Fatalf("Failed to load registry. %v", err)
+	}
+
+	device, ok := registry.Device(testIMEI)
+	if !ok {
+		t.Fatalf("Device is not loaded")
+	}
+	if device.IMEI != testIMEI || device.Model != "FMB920" || device.Name != "Truck 1" || device.Labels["depot"] != "north" {
+		t.Errorf("Wrong device! Actual: %+v", device)
+	}
+
+	// Changes are saved into the file
+	_, err = registry.Put(Device{IMEI: "356307042441013", Name: "Van"})
+	if err != nil {
+		t.Fatalf("Failed to put device. %v", err)
+	}
+	_, err = registry.Delete(testIMEI)
+	if err != nil {
+		t.Fatalf("Failed to delete device. %v", err)
+	}
+
+	reloaded, err := Load(fileName)
+	if err != nil {
+		t.Fatalf("Failed to reload registry. %v", err)
+	}
+	imeis := reloaded.IMEIs()
+	if len(imeis) != 1 || imeis[0] != "356307042441013" {
+		t.Errorf("Wrong devices after reload! Expected: %v Actual: %v", []string{"356307042441013"}, imeis)
+	}
+
+	// Missing file is an empty registry
+	missing, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || len(missing.IMEIs()) != 0 {
+		t.Errorf("Missing file must be an empty registry. %v", err)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	testCases := []struct {
+		Name    string
+		Devices map[string]Device
+	}{
+		{Name: "Short IMEI", Devices: map[string]Device{"12345": {}}},
+		{Name: "Not a number", Devices: map[string]Device{"35209408939746A": {}}},
+		{Name: "Empty label key", Devices: map[string]Device{testIMEI: {Labels: map[string]string{"": "north"}}}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			_, err := New(testCase.Devices)
+			if err == nil {
+				test.Errorf("Invalid devices must be rejected")
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	device := Device{Name: "Truck 1", Group: "north", Labels: map[string]string{"depot": "budapest", "Name": "label", "empty": ""}}
+	expected := map[string]string{"Name": "Truck 1", "Group": "north", "depot": "budapest"}
+
+	tags := device.Tags()
+	if len(tags) != len(expected) {
+		t.Errorf("Wrong tags! Expected: %v Actual: %v", expected, tags)
+	}
+	for key, value := range expected {
+		if tags[key] != value {
+			t.Errorf("Wrong %s tag! Expected: %v Actual: %v", key, value, tags[key])
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	registry, err := New(nil)
+	if err != nil {
+		t.Fatalf("Failed to create registry. %v", err)
+	}
+	changes := 0
+	registry.SetChangeCallback(func() {
+		changes++
+	})
+	handler := registry.Handler("secret")
+
+	testCases := []struct {
+		Name     string
+		Method   string
+		Path     string
+		Body     string
+		Token    string
+		Expected int
+	}{
+		{Name: "Missing device", Method: http.MethodGet, Path: "/api/registry/devices/" + testIMEI, Token: "secret", Expected: http.StatusNotFound},
+		{Name: "Put without token", Method: http.MethodPut, Path: "/api/registry/devices/" + testIMEI, Body: `{"name": "Truck 1"}`, Expected: http.StatusUnauthorized},
+		{Name: "Create", Method: http.MethodPut, Path: "/api/registry/devices/" + testIMEI, Body: `{"name": "Truck 1", "labels": {"depot": "north"}}`, Token: "secret", Expected: http.StatusCreated},
+		{Name: "Replace", Method: http.MethodPut, Path: "/api/registry/devices/" + testIMEI, Body: `{"name": "Truck 2"}`, Token: "secret", Expected: http.StatusOK},
+		{Name: "Unknown field", Method: http.MethodPut, Path: "/api/registry/devices/" + testIMEI, Body: `{"color": "red"}`, Token: "secret", Expected: http.StatusBadRequest},
+		{Name: "Other IMEI in body", Method: http.MethodPut, Path: "/api/registry/devices/" + testIMEI, Body: `{"imei": "356307042441013"}`, Token: "secret", Expected: http.StatusBadRequest},
+		{Name: "Invalid IMEI", Method: http.MethodPut, Path: "/api/registry/devices/12345", Body: `{}`, Token: "secret", Expected: http.StatusBadRequest},
+		{Name: "Get", Method: http.MethodGet, Path: "/api/registry/devices/" + testIMEI, Token: "secret", Expected: http.StatusOK},
+		{Name: "Get without token", Method: http.MethodGet, Path: "/api/registry/devices/" + testIMEI, Expected: http.StatusUnauthorized},
+		{Name: "List", Method: http.MethodGet, Path: "/api/registry/devices", Token: "secret", Expected: http.StatusOK},
+		{Name: "List with wrong token", Method: http.MethodGet, Path: "/api/registry/devices", Token: "wrong", Expected: http.StatusUnauthorized},
+		{Name: "Post", Method: http.MethodPost, Path: "/api/registry/devices", Token: "secret", Expected: http.StatusMethodNotAllowed},
+		{Name: "Delete", Method: http.MethodDelete, Path: "/api/registry/devices/" + testIMEI, Token: "secret", Expected: http.StatusNoContent},
+		{Name: "Delete missing", Method: http.MethodDelete, Path: "/api/registry/devices/" + testIMEI, Token: "secret", Expected: http.StatusNotFound},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			status := request(handler, testCase.Method, testCase.Path, testCase.Body, testCase.Token)
+			if status != testCase.Expected {
+				test.Errorf("Wrong status! Expected: %v Actual: %v", testCase.Expected, status)
+			}
+		})
+	}
+
+	if changes != 3 {
+		t.Errorf("Wrong number of changes! Expected: %v Actual: %v", 3, changes)
+	}
+
+	// Nothing is allowed without token
+	status := request(registry.Handler(""), http.MethodPut, "/api/registry/devices/"+testIMEI, `{"name": "Truck 1"}`, "")
+	if status != http.StatusUnauthorized {
+		t.Errorf("Wrong status without token! Expected: %v Actual: %v", http.StatusUnauthorized, status)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/halacs/haltonika/capture"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/fmb920"
+	"github.com/halacs/haltonika/replay"
+	"github.com/spf13/pflag"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runReplay feeds captured packets through the same decoding, deduplication and sinks as live traffic
+func runReplay(args []string) int {
+	flags := pflag.NewFlagSet("replay", pflag.ExitOnError)
+	speed := flags.Float64("speed", 0, "Replay speed. 1 keeps the original timing, 10 is ten times faster, 0 is as fast as possible.")
+	from := flags.String("from", "", "Skip packets captured before this time (RFC3339)")
+	to := flags.String("to", "", "Skip packets captured after this time (RFC3339)")
+	imeis := flags.String("imeis", "", "Replay packets only of these devices. Separated by comma. Empty replays all packets.")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: haltonika replay [flags] <capture file>...")
+		fmt.Fprintln(os.Stderr, "Replays captured packets into the configured sinks. Server and sink configuration is used as well.")
+		// Flags of the subcommand are parsed together with the server and sink ones
+		pflag.CommandLine.PrintDefaults()
+	}
+
+	cfg := parseConfig(args, flags)
+	log := cfg.GetLogger()
+
+	files := pflag.Args()
+	if len(files) == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	replayConfig := replay.Config{
+		Speed: *speed,
+	}
+	var err error
+	if *from != "" {
+		replayConfig.From, err = time.Parse(time.RFC3339, *from)
+		if err != nil {
+			log.Errorf("Invalid start time. %v", err)
+			return 2
+		}
+	}
+	if *to != "" {
+		replayConfig.To, err = time.Parse(time.RFC3339, *to)
+		if err != nil {
+			log.Errorf("Invalid end time. %v", err)
+			return 2
+		}
+	}
+	if *imeis != "" {
+		replayConfig.IMEIs = strings.Split(*imeis, ",")
+	}
+
+	ctxSignals, _ := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx := context.WithValue(ctxSignals, config.ContextConfigKey, cfg)
+
+	// Replayed packets must not be dropped because of full sink queues. Durable queue is not used,
+	// because it is owned by the server and the replay can be repeated anyway.
+	dispatcher := initializeSinks(ctx, log, cfg, false)
+	dispatcher.SetBlocking(true)
+
+	// Server is not started, it only processes the replayed packets
+	var wg sync.WaitGroup
+	server := fmb920.NewServer(ctx, &wg, "", 0, 0, cfg.GetTeltonikaConfig().AllowedDevices(), nil, nil, newSinkCallback(dispatcher, false))
+
+	player, err := replay.NewPlayer(ctx, replayConfig, server.ReplayPacket)
+	if err != nil {
+		log.Errorf("Invalid replay configuration. %v", err)
+		return 2
+	}
+
+	exitCode := 0
+	for _, filename := range files {
+		err = playFile(player, filename)
+		if err != nil {
+			log.Errorf("Failed to replay %s. %v", filename, err)
+			exitCode = 1
+			break
+		}
+	}
+
+	wg.Wait()
+
+	err = dispatcher.Close()
+	if err != nil {
+		log.Errorf("Failed to close sinks. %v", err)
+		exitCode = 1
+	}
+
+	stats := player.Stats()
+	log.Infof("Packets read: %d, replayed: %d, skipped: %d, failed: %d", stats.Read, stats.Replayed, stats.Skipped, stats.Failed)
+	if stats.Failed > 0 {
+		exitCode = 1
+	}
+
+	return exitCode
+}
+
+func playFile(player *replay.Player, filename string) error {
+	file, err := os.Open(filename) // #nosec G304 file is given by the user
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	return player.Play(capture.NewReader(file))
+}
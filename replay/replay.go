@@ -0,0 +1,145 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/halacs/haltonika/capture"
+	"github.com/halacs/haltonika/config"
+	"io"
+	"slices"
+	"time"
+)
+
+// Config tells which packets are replayed and how fast
+type Config struct {
+	Speed float64   // 1 keeps the original timing, 10 replays ten times faster. Zero replays as fast as possible.
+	From  time.Time // Packets captured before this time are skipped. Zero value means no lower limit.
+	To    time.Time // Packets captured after this time are skipped. Zero value means no upper limit.
+	IMEIs []string  // Replay packets only of these devices. Empty replays all packets.
+}
+
+// Handler processes one packet. It returns false if the packet was not an AVL data packet so it was skipped.
+type Handler func(packet capture.Packet) (bool, error)
+
+// Stats counts what happened with the captured packets
+type Stats struct {
+	Read     uint64 // Packets read from the captures
+	Replayed uint64 // AVL packets given to the handler successfully
+	Skipped  uint64 // Filtered out packets and packets which are not AVL data
+	Failed   uint64 // Packets the handler failed to process
+}
+
+// Player replays captures one after the other keeping the timing between them
+type Player struct {
+	ctx     context.Context
+	config  Config
+	handler Handler
+	stats   Stats
+
+	// Time of the first replayed packet and when it was replayed
+	firstCaptured time.Time
+	firstReplayed time.Time
+}
+
+func NewPlayer(ctx context.Context, cfg Config, handler Handler) (*Player, error) {
+	if cfg.Speed < 0 {
+		return nil, fmt.Errorf("speed must not be negative, got %v", cfg.Speed)
+	}
+
+	return &Player{
+		ctx:     ctx,
+		config:  cfg,
+		handler: handler,
+	}, nil
+}
+
+// Play replays all packets of a capture. It returns early if the context is cancelled.
+func (p *Player) Play(reader *capture.Reader) error {
+	log := config.GetLogger(p.ctx)
+
+	for {
+		packet, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		p.stats.Read++
+
+		if p.skip(packet) {
+			p.stats.Skipped++
+			continue
+		}
+
+		err = p.wait(packet.Time)
+		if err != nil {
+			return err
+		}
+
+		replayed, err := p.handler(packet)
+		if err != nil {
+			log.WithField("imei", packet.IMEI).Errorf("Failed to replay packet captured at %v. %v", packet.Time, err)
+			p.stats.Failed++
+			continue
+		}
+		if !replayed {
+			p.stats.Skipped++
+			continue
+		}
+
+		p.stats.Replayed++
+	}
+}
+
+func (p *Player) Stats() Stats {
+	return p.stats
+}
+
+func (p *Player) skip(packet capture.Packet) bool {
+	if packet.Direction != capture.Received {
+		return true
+	}
+	if !p.config.From.IsZero() && packet.Time.Before(p.config.From) {
+		return true
+	}
+	if !p.config.To.IsZero() && packet.Time.After(p.config.To) {
+		return true
+	}
+	if len(p.config.IMEIs) > 0 && !slices.Contains(p.config.IMEIs, packet.IMEI) {
+		return true
+	}
+
+	return false
+}
+
+// wait sleeps until the packet is due according to the speed of the replay
+func (p *Player) wait(captured time.Time) error {
+	if p.firstReplayed.IsZero() {
+		p.firstCaptured = captured
+		p.firstReplayed = time.Now()
+		return nil
+	}
+
+	if p.config.Speed == 0 {
+		return p.ctx.Err()
+	}
+
+	elapsed := time.Duration(float64(captured.Sub(p.firstCaptured)) / p.config.Speed)
+	delay := time.Until(p.firstReplayed.Add(elapsed))
+	if delay <= 0 {
+		return p.ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
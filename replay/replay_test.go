@@ -0,0 +1,100 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/halacs/haltonika/capture"
+	"github.com/halacs/haltonika/config"
+	"github.com/sirupsen/logrus"
+	"testing"
+	"time"
+)
+
+func newTestContext() context.Context {
+	cfg := config.NewConfig(logrus.New(), nil, nil, nil, nil, nil)
+	return context.WithValue(context.Background(), config.ContextConfigKey, cfg)
+}
+
+func newCapture(t *testing.T, packets []capture.Packet) *capture.Reader {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, packet := range packets {
+		err := encoder.Encode(packet)
+		if err != nil {
+			t.Fatalf("Failed to encode packet. %v", err)
+		}
+	}
+
+	return capture.NewReader(&buffer)
+}
+
+func TestPlay(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	packets := []capture.Packet{
+		{Time: start, Direction: capture.Received, IMEI: "350424063817363", Data: []byte{0x01}},
+		{Time: start.Add(time.Second), Direction: capture.Sent, IMEI: "350424063817363", Data: []byte{0x02}},
+		{Time: start.Add(2 * time.Second), Direction: capture.Received, IMEI: "352094089397464", Data: []byte{0x03}},
+		{Time: start.Add(3 * time.Second), Direction: capture.Received, IMEI: "350424063817363", Data: []byte{0x04}},
+		{Time: start.Add(4 * time.Second), Direction: capture.Received, IMEI: "350424063817363", Data: []byte{0x05}},
+		{Time: start.Add(time.Hour), Direction: capture.Received, IMEI: "350424063817363", Data: []byte{0x06}},
+	}
+
+	var replayed []time.Duration
+	began := time.Now()
+	handler := func(packet capture.Packet) (bool, error) {
+		replayed = append(replayed, time.Since(began))
+
+		// Pretend the last but one is not an AVL packet
+		return packet.Data[0] != 0x05, nil
+	}
+
+	player, err := NewPlayer(newTestContext(), Config{
+		Speed: 10,
+		To:    start.Add(time.Minute),
+		IMEIs: []string{"350424063817363"},
+	}, handler)
+	if err != nil {
+		t.Fatalf("Failed to create player. %v", err)
+	}
+
+	err = player.Play(newCapture(t, packets))
+	if err != nil {
+		t.Fatalf("Failed to play capture. %v", err)
+	}
+
+	stats := player.Stats()
+	if stats.Read != 6 || stats.Replayed != 2 || stats.Skipped != 4 || stats.Failed != 0 {
+		t.Errorf("Wrong stats: %+v", stats)
+	}
+
+	// 3 seconds between the first two replayed packets are 300 milliseconds ten times faster
+	if len(replayed) != 3 || replayed[1] < 280*time.Millisecond || replayed[1] > time.Second {
+		t.Errorf("Wrong timing: %v", replayed)
+	}
+}
+
+func TestPlayFast(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	packets := []capture.Packet{
+		{Time: start, Direction: capture.Received, Data: []byte{0x01}},
+		{Time: start.Add(time.Hour), Direction: capture.Received, Data: []byte{0x02}},
+	}
+
+	player, err := NewPlayer(newTestContext(), Config{}, func(packet capture.Packet) (bool, error) {
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to create player. %v", err)
+	}
+
+	began := time.Now()
+	err = player.Play(newCapture(t, packets))
+	if err != nil {
+		t.Fatalf("Failed to play capture. %v", err)
+	}
+
+	if time.Since(began) > time.Second || player.Stats().Replayed != 2 {
+		t.Errorf("Packets must be replayed as fast as possible. Stats: %+v", player.Stats())
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/simulator"
+	"github.com/spf13/pflag"
+	"os"
+	"strings"
+	"time"
+)
+
+// runSimulate emulates a fleet of FMB920 trackers sending AVL packets to a haltonika server
+func runSimulate(args []string) int {
+	flags := pflag.NewFlagSet("simulate", pflag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: haltonika simulate [flags]")
+		fmt.Fprintln(os.Stderr, "Emulates FMB920 trackers sending AVL packets and answering commands.")
+		flags.PrintDefaults()
+	}
+	addLogFlags(flags)
+	address := flags.String("server", "127.0.0.1:9160", "Address of the haltonika server")
+	transport := flags.String("transport", simulator.TransportUdp, "Transport of the devices (udp or tcp)")
+	devices := flags.Int("devices", 1, "Number of virtual devices")
+	imeis := flags.String("imeis", "350424063817363", "IMEIs of the devices, separated by comma. Missing ones are generated after the last one.")
+	interval := flags.Duration("interval", 10*time.Second, "Time between two AVL packets of a device")
+	codecName := flags.String("codec", "8", "Codec of the AVL packets (8, 8e or 16)")
+	count := flags.Int("count", 0, "Number of AVL packets sent by each device. 0 means until interrupted.")
+	gpx := flags.String("gpx", "", "GPX file with the route of the devices. Random walk is used if not set.")
+	lat := flags.Float64("lat", 47.4979, "Latitude of the starting point of random walk")
+	lng := flags.Float64("lng", 19.0402, "Longitude of the starting point of random walk")
+	speed := flags.Float64("speed", 50, "Average speed of random walk in km/h")
+	timeout := flags.Duration("timeout", 5*time.Second, "How long to wait for ACKs")
+	_ = flags.Parse(args)
+
+	ctx, log := newSubcommandContext(flags)
+
+	codecID, err := parseAvlCodec(*codecName)
+	if err != nil {
+		log.Errorf("%v", err)
+		return 2
+	}
+
+	cfg := simulator.Config{
+		Address:   *address,
+		Transport: *transport,
+		Devices:   *devices,
+		IMEIs:     strings.Split(*imeis, ","),
+		Interval:  *interval,
+		CodecID:   codecID,
+		Count:     *count,
+		StartLat:  *lat,
+		StartLng:  *lng,
+		Speed:     *speed,
+		Timeout:   *timeout,
+	}
+
+	if *gpx != "" {
+		cfg.Route, err = simulator.LoadGpxRoute(*gpx)
+		if err != nil {
+			log.Errorf("Failed to load route. %v", err)
+			return 2
+		}
+	}
+
+	sim, err := simulator.NewSimulator(ctx, cfg)
+	if err != nil {
+		log.Errorf("Invalid simulator configuration. %v", err)
+		return 2
+	}
+
+	stats := sim.Run()
+	log.Infof("Devices connected: %d/%d, AVL packets sent: %d, acknowledged: %d, failed: %d, commands answered: %d",
+		stats.Connected, cfg.Devices, stats.Sent, stats.Acked, stats.Failed, stats.Commands)
+
+	if stats.Failed > 0 || stats.Connected < uint64(cfg.Devices) { // #nosec G115
+		return 1
+	}
+
+	return 0
+}
+
+// parseAvlCodec converts the name of an AVL codec to its ID
+func parseAvlCodec(name string) (byte, error) {
+	switch strings.ToLower(name) {
+	case "8":
+		return codec.Codec8, nil
+	case "8e":
+		return codec.Codec8Extended, nil
+	case "16":
+		return codec.Codec16, nil
+	default:
+		return 0, fmt.Errorf("codec must be 8, 8e or 16, got %s", name)
+	}
+}
@@ -0,0 +1,25 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// cannedResponse answers a command like an FMB920 would do
+func cannedResponse(imei string, started time.Time, command string) string {
+	switch strings.ToLower(strings.TrimSpace(command)) {
+	case "getver":
+		return fmt.Sprintf("Ver:03.27.07_00 GPS:AXN_5.10_3333 Hw:FMB920 Mod:13 IMEI:%s Init:%s Uptime:%d MAC:000000000000 SPC:1(0) AXL:0 OBD:0 BL:1.10 BT:4",
+			imei, started.UTC().Format("2006-1-2 15:4"), int(time.Since(started).Seconds()))
+	case "getstatus":
+		return "Data Link: 1 GPRS: 1 Phone: 0 SIM: 0 OP: 21630 Signal: 5 NewSMS: 0 Roaming: 0 SMSFull: 0 LAC: 1 Cell ID: 1 NetType: 1 FwUpd:-"
+	case "getgps":
+		return "GPS:1 Sat:9 Lat:0 Long:0 Alt:0 Speed:0 Dir:0 Date: 0 Time: 0"
+	case "getinfo":
+		return fmt.Sprintf("INI:%s RTC:%s RST:0 ERR:0 SR:0 BR:0 CF:0 FG:0 FL:0 TU:0/0 UT:0 SMS:0 NOGPS:0:0 GPS:3 SAT:9 RS:3 RF:0 SF:0 MD:0",
+			started.UTC().Format("2006/1/2 15:4"), time.Now().UTC().Format("2006/1/2 15:4"))
+	default:
+		return fmt.Sprintf("Unknown command: %s", command)
+	}
+}
@@ -0,0 +1,362 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/config"
+	"github.com/sirupsen/logrus"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// https://wiki.teltonika-gps.com/view/FMB920_Teltonika_Data_Sending_Parameters_ID
+const (
+	ioIgnition        = 239
+	ioMovement        = 240
+	ioGsmSignal       = 21
+	ioExternalVoltage = 66
+	ioBatteryVoltage  = 67
+)
+
+const (
+	maxPacketSize = 64 * 1024
+)
+
+// device is one virtual tracker with its own connection
+type device struct {
+	simulator   *Simulator
+	log         *logrus.Entry
+	imei        string
+	route       Route
+	random      *rand.Rand
+	conn        net.Conn
+	writeLock   sync.Mutex
+	acks        chan []byte
+	avlPacketID byte
+}
+
+func newDevice(s *Simulator, imei string, route Route) *device {
+	return &device{
+		simulator: s,
+		log:       config.GetLogger(s.ctx).WithField("imei", imei),
+		imei:      imei,
+		route:     route,
+		random:    rand.New(rand.NewSource(time.Now().UnixNano())), // #nosec G404 no need for secure random numbers
+		acks:      make(chan []byte, 1),
+	}
+}
+
+func (d *device) run() error {
+	cfg := d.simulator.config
+
+	var err error
+	d.conn, err = net.Dial(cfg.Transport, cfg.Address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s. %v", cfg.Address, err)
+	}
+
+	// Close connection if the simulator is stopped so pending reads return
+	closed := make(chan struct{})
+	defer close(closed)
+	go func() {
+		select {
+		case <-d.simulator.ctx.Done():
+		case <-closed:
+		}
+		err := d.conn.Close()
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			d.log.Errorf("Failed to close connection. %v", err)
+		}
+	}()
+
+	if cfg.Transport == TransportTcp {
+		err = d.handshake()
+		if err != nil {
+			return err
+		}
+	}
+
+	d.simulator.connected.Add(1)
+	d.log.Infof("Device connected to %s over %s", cfg.Address, cfg.Transport)
+
+	go d.receive()
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for sent := 0; cfg.Count == 0 || sent < cfg.Count; sent++ {
+		err = d.sendRecord()
+		if err != nil {
+			d.log.Errorf("Failed to send AVL packet. %v", err)
+			d.simulator.failed.Add(1)
+		}
+
+		// Wait for the next packet but answer commands in the meantime
+		if cfg.Count != 0 && sent+1 == cfg.Count {
+			break
+		}
+		select {
+		case <-ticker.C:
+		case <-d.simulator.ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (d *device) handshake() error {
+	handshake := binary.BigEndian.AppendUint16(nil, uint16(len(d.imei))) // #nosec G115
+	handshake = append(handshake, d.imei...)
+
+	err := d.write(handshake)
+	if err != nil {
+		return fmt.Errorf("failed to send IMEI. %v", err)
+	}
+
+	err = d.conn.SetReadDeadline(time.Now().Add(d.simulator.config.Timeout))
+	if err != nil {
+		return err
+	}
+
+	response := make([]byte, 1)
+	_, err = io.ReadFull(d.conn, response)
+	if err != nil {
+		return fmt.Errorf("failed to read IMEI response. %v", err)
+	}
+	if response[0] != 0x01 {
+		return fmt.Errorf("IMEI was rejected")
+	}
+
+	return d.conn.SetReadDeadline(time.Time{})
+}
+
+func (d *device) nextRecord() (codec.AvlData, error) {
+	position := d.route.Next(d.simulator.config.Interval)
+	now := time.Now()
+
+	moving := uint64(0)
+	if position.Speed >= 1 {
+		moving = 1
+	}
+
+	record := codec.AvlData{
+		UtimeMs:  uint64(now.UnixMilli()), // #nosec G115
+		Utime:    uint64(now.Unix()),      // #nosec G115
+		Lat:      int32(position.Lat * 1e7),
+		Lng:      int32(position.Lng * 1e7),
+		Altitude: int16(position.Altitude),
+		Angle:    uint16(position.Angle) % 360,
+		VisSat:   uint8(7 + d.random.Intn(6)), // #nosec G115
+		Speed:    uint16(position.Speed),
+	}
+
+	elements := []struct {
+		IOID  uint16
+		Size  int
+		Value uint64
+	}{
+		{IOID: ioIgnition, Size: 1, Value: 1},
+		{IOID: ioMovement, Size: 1, Value: moving},
+		{IOID: ioGsmSignal, Size: 1, Value: uint64(3 + d.random.Intn(3))},              // #nosec G115
+		{IOID: ioExternalVoltage, Size: 2, Value: uint64(12000 + d.random.Intn(2000))}, // #nosec G115
+		{IOID: ioBatteryVoltage, Size: 2, Value: uint64(3900 + d.random.Intn(200))},    // #nosec G115
+	}
+	for _, e := range elements {
+		element, err := codec.NewElement(e.IOID, e.Size, e.Value)
+		if err != nil {
+			return codec.AvlData{}, err
+		}
+		record.Elements = append(record.Elements, element)
+	}
+
+	// Codec 8 has 1 byte long event IO IDs
+	if d.simulator.config.CodecID != codec.Codec8 {
+		record.EventID = ioIgnition
+	}
+	if d.simulator.config.CodecID == codec.Codec16 {
+		record.GenerationType = codec.GenerationPeriodical
+	}
+
+	return record, nil
+}
+
+func (d *device) sendRecord() error {
+	cfg := d.simulator.config
+	record, err := d.nextRecord()
+	if err != nil {
+		return err
+	}
+	records := []codec.AvlData{record}
+
+	var packet []byte
+	var decoded codec.Decoded
+	if cfg.Transport == TransportTcp {
+		packet, err = codec.EncodeTcp(cfg.CodecID, records)
+		if err != nil {
+			return err
+		}
+		decoded, err = codec.DecodeTcp(d.imei, packet)
+	} else {
+		d.avlPacketID++
+		packet, err = codec.Encode(d.imei, d.avlPacketID, cfg.CodecID, records)
+		if err != nil {
+			return err
+		}
+		decoded, err = codec.Decode(packet)
+	}
+	if err != nil {
+		return fmt.Errorf("generated packet can not be decoded. %v", err)
+	}
+
+	// Drop late ACK of the previous packet
+	select {
+	case <-d.acks:
+	default:
+	}
+
+	err = d.write(packet)
+	if err != nil {
+		return err
+	}
+	d.simulator.sent.Add(1)
+	d.log.Debugf("AVL packet sent: %+v", records[0])
+
+	select {
+	case ack := <-d.acks:
+		if !bytes.Equal(ack, decoded.Response) {
+			return fmt.Errorf("wrong ACK, want %x, got %x", decoded.Response, ack)
+		}
+		d.simulator.acked.Add(1)
+	case <-time.After(cfg.Timeout):
+		return fmt.Errorf("no ACK in %v", cfg.Timeout)
+	case <-d.simulator.ctx.Done():
+	}
+
+	return nil
+}
+
+// receive reads ACKs and commands until the connection is closed
+func (d *device) receive() {
+	for {
+		var packet []byte
+		var err error
+		if d.simulator.config.Transport == TransportTcp {
+			packet, err = d.receiveTcp()
+		} else {
+			packet, err = d.receiveUdp()
+		}
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.EOF) {
+				d.log.Errorf("Failed to receive. %v", err)
+			}
+			return
+		}
+
+		// ACKs are short, commands start with 4 zero bytes of preamble
+		if len(packet) > 8 && binary.BigEndian.Uint32(packet) == 0 {
+			d.answerCommand(packet)
+			continue
+		}
+
+		select {
+		case d.acks <- packet:
+		default:
+			d.log.Warnf("Unexpected ACK: %x", packet)
+		}
+	}
+}
+
+func (d *device) receiveUdp() ([]byte, error) {
+	buffer := make([]byte, maxPacketSize)
+	n, err := d.conn.Read(buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer[:n], nil
+}
+
+func (d *device) receiveTcp() ([]byte, error) {
+	header := make([]byte, 4)
+	_, err := io.ReadFull(d.conn, header)
+	if err != nil {
+		return nil, err
+	}
+
+	// ACK holds the number of accepted records so it is never zero
+	if binary.BigEndian.Uint32(header) != 0 {
+		return header, nil
+	}
+
+	length := make([]byte, 4)
+	_, err = io.ReadFull(d.conn, length)
+	if err != nil {
+		return nil, err
+	}
+
+	dataLength := binary.BigEndian.Uint32(length)
+	if dataLength > maxPacketSize {
+		return nil, fmt.Errorf("too long command packet: %d bytes", dataLength)
+	}
+
+	packet := make([]byte, 8+int(dataLength)+4)
+	copy(packet[4:], length)
+	_, err = io.ReadFull(d.conn, packet[8:])
+	if err != nil {
+		return nil, err
+	}
+
+	return packet, nil
+}
+
+func (d *device) answerCommand(packet []byte) {
+	command, err := codec.DecodeCommand(packet)
+	if err != nil {
+		d.log.Errorf("Malformed command received. %v", err)
+		return
+	}
+
+	d.log.Infof("Command received: %s", string(command.Payload))
+
+	response := codec.Command{
+		CodecID: command.CodecID,
+		Type:    codec.CommandTypeResponse,
+		IMEI:    d.imei,
+		Payload: []byte(cannedResponse(d.imei, d.simulator.started, string(command.Payload))),
+	}
+
+	// Codec 14 commands addressed to another device are refused
+	if command.CodecID == codec.Codec14 && command.IMEI != d.imei {
+		response.Type = codec.CommandTypeNack
+		response.Payload = nil
+	}
+
+	encoded, err := codec.EncodeCommand(response)
+	if err != nil {
+		d.log.Errorf("Failed to encode command response. %v", err)
+		return
+	}
+
+	err = d.write(encoded)
+	if err != nil {
+		d.log.Errorf("Failed to send command response. %v", err)
+		return
+	}
+
+	d.simulator.commands.Add(1)
+}
+
+func (d *device) write(data []byte) error {
+	d.writeLock.Lock()
+	defer d.writeLock.Unlock()
+
+	_, err := d.conn.Write(data)
+	return err
+}
@@ -0,0 +1,54 @@
+package simulator
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// GenerateIMEIs returns the given IMEIs completed to n ones. Missing IMEIs follow the last given one with valid checksums.
+func GenerateIMEIs(imeis []string, n int) ([]string, error) {
+	if len(imeis) == 0 {
+		return nil, fmt.Errorf("at least one IMEI is needed")
+	}
+
+	result := make([]string, 0, max(n, len(imeis)))
+	result = append(result, imeis...)
+
+	last := imeis[len(imeis)-1]
+	if len(last) != 15 {
+		return nil, fmt.Errorf("IMEI must be 15 digits long, got %s", last)
+	}
+
+	serial, err := strconv.ParseUint(last[:14], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMEI: %s. %v", last, err)
+	}
+
+	for len(result) < n {
+		serial++
+		body := fmt.Sprintf("%014d", serial)
+		if len(body) > 14 {
+			return nil, fmt.Errorf("no more IMEIs after %s", last)
+		}
+		result = append(result, body+strconv.Itoa(luhnCheckDigit(body)))
+	}
+
+	return result, nil
+}
+
+// luhnCheckDigit calculates the last digit of an IMEI from the first 14 ones
+func luhnCheckDigit(body string) int {
+	sum := 0
+	for i := 0; i < len(body); i++ {
+		digit := int(body[i] - '0')
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+
+	return (10 - sum%10) % 10
+}
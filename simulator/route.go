@@ -0,0 +1,181 @@
+package simulator
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+)
+
+const (
+	earthRadius = 6371000.0 // meters
+)
+
+// Position is where a virtual device is and how it moves
+type Position struct {
+	Lat      float64 // degrees
+	Lng      float64 // degrees
+	Altitude float64 // meters
+	Angle    float64 // degrees, 0 is north, increasing clock-wise
+	Speed    float64 // km/h
+}
+
+// Route gives the next position of a device after the given time elapsed
+type Route interface {
+	Next(elapsed time.Duration) Position
+}
+
+// RandomWalk moves around with the given average speed and randomly changing heading
+type RandomWalk struct {
+	position Position
+	speed    float64
+	random   *rand.Rand
+}
+
+func NewRandomWalk(lat, lng, speed float64, seed int64) *RandomWalk {
+	random := rand.New(rand.NewSource(seed)) // #nosec G404 no need for secure random numbers
+	return &RandomWalk{
+		position: Position{
+			Lat:      lat,
+			Lng:      lng,
+			Altitude: 100,
+			Angle:    random.Float64() * 360,
+		},
+		speed:  speed,
+		random: random,
+	}
+}
+
+func (w *RandomWalk) Next(elapsed time.Duration) Position {
+	w.position.Angle = math.Mod(w.position.Angle+w.random.Float64()*60-30+360, 360)
+	w.position.Speed = math.Max(0, w.speed*(0.8+w.random.Float64()*0.4))
+
+	distance := w.position.Speed / 3.6 * elapsed.Seconds()
+	w.position.Lat, w.position.Lng = move(w.position.Lat, w.position.Lng, w.position.Angle, distance)
+
+	return w.position
+}
+
+// GpxRoute goes through the points of a GPX track or route, one point at each step, and starts over at its end
+type GpxRoute struct {
+	points []Position
+	next   int
+}
+
+type gpxPoint struct {
+	Lat float64 `xml:"lat,attr"`
+	Lon float64 `xml:"lon,attr"`
+	Ele float64 `xml:"ele"`
+}
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Routes []struct {
+		Points []gpxPoint `xml:"rtept"`
+	} `xml:"rte"`
+}
+
+// LoadGpxRoute reads the points of all tracks and routes of a GPX file
+func LoadGpxRoute(filename string) ([]Position, error) {
+	content, err := os.ReadFile(filename) // #nosec G304 file is given by the user
+	if err != nil {
+		return nil, err
+	}
+
+	var gpx gpxFile
+	err = xml.Unmarshal(content, &gpx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GPX file. %v", err)
+	}
+
+	var points []gpxPoint
+	for _, track := range gpx.Tracks {
+		for _, segment := range track.Segments {
+			points = append(points, segment.Points...)
+		}
+	}
+	for _, route := range gpx.Routes {
+		points = append(points, route.Points...)
+	}
+
+	if len(points) < 2 {
+		return nil, fmt.Errorf("GPX file must have at least 2 points, got %d", len(points))
+	}
+
+	positions := make([]Position, 0, len(points))
+	for _, point := range points {
+		positions = append(positions, Position{
+			Lat:      point.Lat,
+			Lng:      point.Lon,
+			Altitude: point.Ele,
+		})
+	}
+
+	return positions, nil
+}
+
+// NewGpxRoute creates a route starting at the given point so devices on the same route are spread
+func NewGpxRoute(points []Position, start int) *GpxRoute {
+	return &GpxRoute{
+		points: points,
+		next:   start % len(points),
+	}
+}
+
+func (r *GpxRoute) Next(elapsed time.Duration) Position {
+	previous := r.points[(r.next+len(r.points)-1)%len(r.points)]
+	position := r.points[r.next]
+	r.next = (r.next + 1) % len(r.points)
+
+	position.Angle = bearing(previous.Lat, previous.Lng, position.Lat, position.Lng)
+	if elapsed > 0 {
+		position.Speed = distance(previous.Lat, previous.Lng, position.Lat, position.Lng) / elapsed.Seconds() * 3.6
+	}
+
+	return position
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+// move goes the given meters from a point into the given direction
+func move(lat, lng, angle, meters float64) (float64, float64) {
+	angularDistance := meters / earthRadius
+	lat1 := radians(lat)
+	lng1 := radians(lng)
+	heading := radians(angle)
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(angularDistance) + math.Cos(lat1)*math.Sin(angularDistance)*math.Cos(heading))
+	lng2 := lng1 + math.Atan2(math.Sin(heading)*math.Sin(angularDistance)*math.Cos(lat1), math.Cos(angularDistance)-math.Sin(lat1)*math.Sin(lat2))
+
+	return degrees(lat2), math.Mod(degrees(lng2)+540, 360) - 180
+}
+
+// distance is the great-circle distance of two points in meters
+func distance(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLng := radians(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// bearing is the initial direction from the first point to the second one in degrees
+func bearing(lat1, lng1, lat2, lng2 float64) float64 {
+	dLng := radians(lng2 - lng1)
+	y := math.Sin(dLng) * math.Cos(radians(lat2))
+	x := math.Cos(radians(lat1))*math.Sin(radians(lat2)) - math.Sin(radians(lat1))*math.Cos(radians(lat2))*math.Cos(dLng)
+
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360)
+}
@@ -0,0 +1,127 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/config"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	TransportUdp = "udp"
+	TransportTcp = "tcp"
+)
+
+// Config tells how the virtual devices behave
+type Config struct {
+	Address   string        // Address of the haltonika server, host:port
+	Transport string        // TransportUdp or TransportTcp
+	Devices   int           // Number of virtual devices
+	IMEIs     []string      // IMEIs of the devices. Missing ones are generated after the last one.
+	Interval  time.Duration // Time between two AVL packets of a device
+	CodecID   byte          // Codec of the AVL packets
+	Count     int           // Number of AVL packets sent by each device. Zero means until the simulator is stopped.
+	Route     []Position    // Points the devices go through. Random walk is used if empty.
+	StartLat  float64       // Starting point of random walk
+	StartLng  float64       // Starting point of random walk
+	Speed     float64       // Average speed of random walk in km/h
+	Timeout   time.Duration // How long to wait for ACKs
+}
+
+// Stats counts what happened with the virtual devices
+type Stats struct {
+	Sent      uint64 // AVL packets sent
+	Acked     uint64 // AVL packets acknowledged correctly
+	Failed    uint64 // AVL packets with wrong or missing ACK
+	Commands  uint64 // Commands answered
+	Connected uint64 // Devices connected successfully
+}
+
+// Simulator emulates a fleet of FMB920 trackers
+type Simulator struct {
+	ctx     context.Context
+	config  Config
+	imeis   []string
+	started time.Time
+
+	sent      atomic.Uint64
+	acked     atomic.Uint64
+	failed    atomic.Uint64
+	commands  atomic.Uint64
+	connected atomic.Uint64
+}
+
+func NewSimulator(ctx context.Context, cfg Config) (*Simulator, error) {
+	if cfg.Transport != TransportUdp && cfg.Transport != TransportTcp {
+		return nil, fmt.Errorf("transport must be %s or %s, got %s", TransportUdp, TransportTcp, cfg.Transport)
+	}
+	if cfg.Devices < 1 {
+		return nil, fmt.Errorf("at least one device is needed, got %d", cfg.Devices)
+	}
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %v", cfg.Interval)
+	}
+	if cfg.CodecID != codec.Codec8 && cfg.CodecID != codec.Codec8Extended && cfg.CodecID != codec.Codec16 {
+		return nil, fmt.Errorf("invalid codec ID, want 0x08, 0x8E or 0x10, got 0x%02X", cfg.CodecID)
+	}
+
+	imeis, err := GenerateIMEIs(cfg.IMEIs, cfg.Devices)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Simulator{
+		ctx:    ctx,
+		config: cfg,
+		imeis:  imeis[:cfg.Devices],
+	}, nil
+}
+
+// Run starts all devices and waits until they have sent their packets or the context is cancelled
+func (s *Simulator) Run() Stats {
+	log := config.GetLogger(s.ctx)
+
+	s.started = time.Now()
+
+	var wg sync.WaitGroup
+	for i, imei := range s.imeis {
+		var route Route
+		if len(s.config.Route) > 0 {
+			route = NewGpxRoute(s.config.Route, i*len(s.config.Route)/len(s.imeis))
+		} else {
+			route = NewRandomWalk(s.config.StartLat, s.config.StartLng, s.config.Speed, s.started.UnixNano()+int64(i))
+		}
+
+		d := newDevice(s, imei, route)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := d.run()
+			if err != nil {
+				log.WithField("imei", imei).Errorf("Device stopped. %v", err)
+			}
+		}()
+
+		// Do not connect all devices at the same moment
+		time.Sleep(s.config.Interval / time.Duration(len(s.imeis)))
+	}
+
+	wg.Wait()
+
+	return s.Stats()
+}
+
+func (s *Simulator) Stats() Stats {
+	return Stats{
+		Sent:      s.sent.Load(),
+		Acked:     s.acked.Load(),
+		Failed:    s.failed.Load(),
+		Commands:  s.commands.Load(),
+		Connected: s.connected.Load(),
+	}
+}
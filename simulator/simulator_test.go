@@ -0,0 +1,184 @@
+package simulator
+
+import (
+	"context"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/fmb920"
+	"github.com/halacs/haltonika/uds"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const gpxContent = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="haltonika">
+  <trk><trkseg>
+    <trkpt lat="47.4979" lon="19.0402"><ele>105</ele></trkpt>
+    <trkpt lat="47.4989" lon="19.0402"><ele>106</ele></trkpt>
+    <trkpt lat="47.4989" lon="19.0412"><ele>107</ele></trkpt>
+  </trkseg></trk>
+</gpx>`
+
+func newTestContext() (context.Context, context.CancelFunc) {
+	log := logrus.New()
+	log.SetLevel(logrus.DebugLevel)
+	cfg := config.NewConfig(log, nil, nil, nil, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	return context.WithValue(ctx, config.ContextConfigKey, cfg), cancel
+}
+
+func TestGenerateIMEIs(t *testing.T) {
+	imeis, err := GenerateIMEIs([]string{"350424063817363"}, 3)
+	if err != nil {
+		t.Fatalf("Failed to generate IMEIs. %v", err)
+	}
+
+	if len(imeis) != 3 || imeis[0] != "350424063817363" || !strings.HasPrefix(imeis[1], "35042406381737") || !strings.HasPrefix(imeis[2], "35042406381738") {
+		t.Errorf("Wrong IMEIs: %v", imeis)
+	}
+	for _, imei := range imeis {
+		if !codec.ValidateIMEI(imei) {
+			t.Errorf("Generated IMEI %s is invalid", imei)
+		}
+	}
+}
+
+func TestGpxRoute(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "route.gpx")
+	err := os.WriteFile(filename, []byte(gpxContent), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write GPX file. %v", err)
+	}
+
+	points, err := LoadGpxRoute(filename)
+	if err != nil {
+		t.Fatalf("Failed to load GPX file. %v", err)
+	}
+	if len(points) != 3 {
+		t.Fatalf("Wrong number of points! Expected: 3 Actual: %d", len(points))
+	}
+
+	route := NewGpxRoute(points, 1)
+
+	// Going to north about 111 meters in 10 seconds
+	position := route.Next(10 * time.Second)
+	if position.Lat != 47.4989 || position.Angle > 1 || position.Speed < 39 || position.Speed > 41 {
+		t.Errorf("Wrong position: %+v", position)
+	}
+
+	// Going to east
+	position = route.Next(10 * time.Second)
+	if position.Angle < 89 || position.Angle > 91 {
+		t.Errorf("Wrong position: %+v", position)
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	imeis := []string{"350424063817363", "350424063817371"}
+
+	ctx, cancel := newTestContext()
+	defer cancel()
+
+	var wg sync.WaitGroup
+	server := fmb920.NewServer(ctx, &wg, "127.0.0.1", 9010, 9010, imeis, &uds.MultiServerMock{}, nil, func(ctx context.Context, message fmb920.TeltonikaMessage) error { return nil })
+	err := server.Start()
+	if err != nil {
+		t.Fatalf("Failed to start Teltonika server. %v", err)
+	}
+
+	for _, transport := range []string{TransportUdp, TransportTcp} {
+		t.Run(transport, func(test *testing.T) {
+			sim, err := NewSimulator(ctx, Config{
+				Address:   "127.0.0.1:9010",
+				Transport: transport,
+				Devices:   2,
+				IMEIs:     imeis[:1],
+				Interval:  100 * time.Millisecond,
+				CodecID:   codec.Codec8Extended,
+				Count:     3,
+				StartLat:  47.4979,
+				StartLng:  19.0402,
+				Speed:     50,
+				Timeout:   2 * time.Second,
+			})
+			if err != nil {
+				test.Fatalf("Failed to create simulator. %v", err)
+			}
+
+			stats := sim.Run()
+			if stats.Connected != 2 || stats.Sent != 6 || stats.Acked != 6 || stats.Failed != 0 {
+				test.Errorf("Wrong stats: %+v", stats)
+			}
+		})
+	}
+}
+
+func TestSimulatorCommand(t *testing.T) {
+	const imei = "350424063817363"
+
+	ctx, cancel := newTestContext()
+	defer cancel()
+
+	var wg sync.WaitGroup
+	server := fmb920.NewServer(ctx, &wg, "127.0.0.1", 9011, 9011, []string{imei}, &uds.MultiServerMock{}, nil, func(ctx context.Context, message fmb920.TeltonikaMessage) error { return nil })
+	err := server.Start()
+	if err != nil {
+		t.Fatalf("Failed to start Teltonika server. %v", err)
+	}
+
+	sim, err := NewSimulator(ctx, Config{
+		Address:   "127.0.0.1:9011",
+		Transport: TransportTcp,
+		Devices:   1,
+		IMEIs:     []string{imei},
+		Interval:  100 * time.Millisecond,
+		CodecID:   codec.Codec16,
+		StartLat:  47.4979,
+		StartLng:  19.0402,
+		Speed:     50,
+		Timeout:   2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create simulator. %v", err)
+	}
+
+	done := make(chan Stats)
+	go func() {
+		done <- sim.Run()
+	}()
+
+	requests, _, err := server.GetCommandRequestChannel(imei)
+	if err != nil {
+		t.Fatalf("Failed to get command request channel. %v", err)
+	}
+	responses, _, err := server.GetCommandResponseChannel(imei)
+	if err != nil {
+		t.Fatalf("Failed to get command response channel. %v", err)
+	}
+
+	select {
+	case requests <- "getver":
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Command was not taken by the TCP session")
+	}
+
+	select {
+	case response := <-responses:
+		if !strings.Contains(response, "IMEI:"+imei) {
+			t.Errorf("Wrong response: %s", response)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("No command response")
+	}
+
+	cancel()
+	stats := <-done
+	if stats.Commands != 1 || stats.Failed != 0 || stats.Sent == 0 {
+		t.Errorf("Wrong stats: %+v", stats)
+	}
+}
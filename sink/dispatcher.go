@@ -0,0 +1,599 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/wal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultQueueSize     = 1000
+	DefaultFlushInterval = time.Second
+	DefaultMinBackoff    = time.Second
+	DefaultMaxBackoff    = 5 * time.Minute
+	persistTimeout       = 10 * time.Second
+)
+
+// envelope is a queued message. If done is set, the result of writing and flushing the message is sent into it.
+type envelope struct {
+	message Message
+	done    chan<- error
+}
+
+// output is a sink with its own queue and goroutine, so a slow or failing sink does not affect the others
+type output struct {
+	name          string
+	sink          Sink
+	queue         chan envelope // used without durable queue
+	reader        *wal.Reader   // used with durable queue
+	flushInterval time.Duration
+
+	written uint64
+	failed  uint64
+	dropped uint64
+}
+
+// Dispatcher fans out the decoded packets to all configured sinks
+type Dispatcher struct {
+	ctx      context.Context
+	wg       sync.WaitGroup
+	outputs  []*output
+	blocking bool
+
+	// Durable queue shared by the sinks, each sink reads it by its own reader
+	durable    *wal.Log
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	runCtx     context.Context
+	stop       context.CancelFunc
+
+	// Protects the queues from being written after they are closed
+	lock    sync.RWMutex
+	started bool
+	closed  bool
+}
+
+func NewDispatcher(ctx context.Context) *Dispatcher {
+	runCtx, stop := context.WithCancel(context.Background())
+
+	return &Dispatcher{
+		ctx:    ctx,
+		runCtx: runCtx,
+		stop:   stop,
+	}
+}
+
+// Add registers a sink. Zero queue size and flush interval mean the defaults. Sinks can be added only before Start.
+func (d *Dispatcher) Add(name string, sink Sink, queueSize int, flushInterval time.Duration) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.started {
+		return fmt.Errorf("dispatcher is already started")
+	}
+
+	for _, o := range d.outputs {
+		if o.name == name {
+			return fmt.Errorf("sink with %q name already exists", name)
+		}
+	}
+
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+
+	d.outputs = append(d.outputs, &output{
+		name:          name,
+		sink:          sink,
+		queue:         make(chan envelope, queueSize),
+		flushInterval: flushInterval,
+	})
+
+	return nil
+}
+
+// SetBlocking makes Dispatch wait for free space in the queues instead of dropping the message. Replay needs it.
+func (d *Dispatcher) SetBlocking(blocking bool) {
+	d.blocking = blocking
+}
+
+/*
+SetDurableQueue makes the messages persisted into the write-ahead log before they are passed to the sinks.
+Failed writes are retried with exponential backoff between minBackoff and maxBackoff until they succeed,
+and messages not written yet are passed to the sinks after restart. The log is closed by Close.
+*/
+func (d *Dispatcher) SetDurableQueue(durable *wal.Log, minBackoff time.Duration, maxBackoff time.Duration) {
+	if minBackoff <= 0 {
+		minBackoff = DefaultMinBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = max(DefaultMaxBackoff, minBackoff)
+	}
+
+	d.durable = durable
+	d.minBackoff = minBackoff
+	d.maxBackoff = maxBackoff
+}
+
+// Start starts the goroutines of the sinks
+func (d *Dispatcher) Start() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.started {
+		return nil
+	}
+
+	if d.durable != nil {
+		for _, o := range d.outputs {
+			reader, err := d.durable.Reader(o.name)
+			if err != nil {
+				return fmt.Errorf("failed to create queue reader of %s sink. %v", o.name, err)
+			}
+			o.reader = reader
+		}
+	}
+
+	d.started = true
+
+	for _, o := range d.outputs {
+		d.wg.Add(1)
+		go func(o *output) {
+			defer d.wg.Done()
+			if o.reader != nil {
+				d.runDurable(o)
+			} else {
+				d.run(o)
+			}
+		}(o)
+	}
+
+	return nil
+}
+
+/*
+Dispatch passes the message to all sinks. With durable queue, the message is persisted first
+and error is returned if it failed. Otherwise, the message is put into the queue of each sink.
+If a queue is full, the message is dropped for that sink only and error is returned.
+*/
+func (d *Dispatcher) Dispatch(message Message) error {
+	return d.dispatch(message, nil)
+}
+
+/*
+Persist passes the message to all sinks like Dispatch, but it returns only when the message is stored durably.
+With durable queue, it means the message is persisted into the queue. Otherwise, all sinks have to write and flush it.
+Error is returned if any sink failed or the message was not stored in time.
+*/
+func (d *Dispatcher) Persist(message Message) error {
+	if d.durable != nil {
+		return d.dispatch(message, nil)
+	}
+
+	done := make(chan error, len(d.outputs))
+	err := d.dispatch(message, done)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.NewTimer(persistTimeout)
+	defer timeout.Stop()
+
+	var errs []error
+	for range d.outputs {
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-timeout.C:
+			return fmt.Errorf("message was not stored in %v", persistTimeout)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) dispatch(message Message, done chan<- error) error {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	if d.closed {
+		return fmt.Errorf("sinks are closed")
+	}
+
+	if d.durable != nil {
+		payload, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("failed to serialize message. %v", err)
+		}
+
+		_, err = d.durable.Append(payload)
+		if err != nil {
+			for _, o := range d.outputs {
+				atomic.AddUint64(&o.dropped, 1)
+			}
+			return fmt.Errorf("failed to persist message. %v", err)
+		}
+
+		return nil
+	}
+
+	var dropped []string
+	for _, o := range d.outputs {
+		queued := envelope{
+			message: message,
+			done:    done,
+		}
+
+		if d.blocking {
+			o.queue <- queued
+			continue
+		}
+
+		select {
+		case o.queue <- queued:
+		default:
+			atomic.AddUint64(&o.dropped, 1)
+			dropped = append(dropped, o.name)
+			if done != nil {
+				done <- fmt.Errorf("queue of %s sink is full", o.name)
+			}
+		}
+	}
+
+	if len(dropped) > 0 {
+		return fmt.Errorf("queue of %s sink is full", strings.Join(dropped, ", "))
+	}
+
+	return nil
+}
+
+// Close writes out the queued messages, then flushes and closes all sinks.
+// With durable queue, the remaining messages are kept on disk instead.
+func (d *Dispatcher) Close() error {
+	d.lock.Lock()
+	if d.closed {
+		d.lock.Unlock()
+		return nil
+	}
+	d.closed = true
+	for _, o := range d.outputs {
+		close(o.queue)
+	}
+	started := d.started
+	d.lock.Unlock()
+
+	d.stop()
+	if started {
+		d.wg.Wait()
+	}
+
+	var errs []error
+	for _, o := range d.outputs {
+		err := o.sink.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s sink. %v", o.name, err))
+		}
+	}
+
+	if d.durable != nil {
+		err := d.durable.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to close durable queue. %v", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+
+	return nil
+}
+
+// CommandResponse passes the response of a device to a command to the sinks which forward command responses
+func (d *Dispatcher) CommandResponse(imei string, response string) {
+	log := config.GetLogger(d.ctx)
+
+	for _, o := range d.outputs {
+		writer, ok := o.sink.(CommandResponseWriter)
+		if !ok {
+			continue
+		}
+
+		err := writer.WriteCommandResponse(imei, response)
+		if err != nil {
+			log.Errorf("Failed to write command response of %s device into %s sink. %v", imei, o.name, err)
+		}
+	}
+}
+
+// CommandRequest passes a command sent to a device to the sinks which keep the commands
+func (d *Dispatcher) CommandRequest(imei string, command string) {
+	log := config.GetLogger(d.ctx)
+
+	for _, o := range d.outputs {
+		writer, ok := o.sink.(CommandRequestWriter)
+		if !ok {
+			continue
+		}
+
+		err := writer.WriteCommandRequest(imei, command)
+		if err != nil {
+			log.Errorf("Failed to write command sent to %s device into %s sink. %v", imei, o.name, err)
+		}
+	}
+}
+
+// SetCommandHandler sets the function sending the commands read by the sinks to the devices
+func (d *Dispatcher) SetCommandHandler(handler CommandHandler) {
+	for _, o := range d.outputs {
+		source, ok := o.sink.(CommandSource)
+		if ok {
+			source.SetCommandHandler(handler)
+		}
+	}
+}
+
+// DeviceEvent passes a device coming online or going offline to the sinks which forward device events
+func (d *Dispatcher) DeviceEvent(event DeviceEvent) {
+	log := config.GetLogger(d.ctx)
+
+	for _, o := range d.outputs {
+		writer, ok := o.sink.(DeviceEventWriter)
+		if !ok {
+			continue
+		}
+
+		err := writer.WriteDeviceEvent(event)
+		if err != nil {
+			log.Errorf("Failed to write %s event of %s device into %s sink. %v", event.Type, event.IMEI, o.name, err)
+		}
+	}
+}
+
+// DeviceList passes the registered devices to the sinks which announce them
+func (d *Dispatcher) DeviceList(imeis []string) {
+	log := config.GetLogger(d.ctx)
+
+	for _, o := range d.outputs {
+		writer, ok := o.sink.(DeviceListWriter)
+		if !ok {
+			continue
+		}
+
+		err := writer.WriteDeviceList(imeis)
+		if err != nil {
+			log.Errorf("Failed to write device list into %s sink. %v", o.name, err)
+		}
+	}
+}
+
+// Health returns the health of each sink by its name
+func (d *Dispatcher) Health() map[string]error {
+	health := make(map[string]error, len(d.outputs))
+	for _, o := range d.outputs {
+		health[o.name] = o.sink.Health()
+	}
+
+	return health
+}
+
+/*
+MetricRendererHandler provides queue depth, age of the oldest queued message, written, failed and dropped messages,
+records lost by the sink and health of each sink. Field names are prefixed with the name of the sink. Size of the durable queue is provided as well.
+*/
+func (d *Dispatcher) MetricRendererHandler() (string, map[string]uint64) {
+	metrics := make(map[string]uint64, 6*len(d.outputs)+1)
+	for _, o := range d.outputs {
+		healthy := uint64(0)
+		if o.sink.Health() == nil {
+			healthy = 1
+		}
+
+		if o.reader != nil {
+			metrics[o.name+"_QueueDepth"] = o.reader.Depth()
+			metrics[o.name+"_OldestMessageAgeSeconds"] = uint64(o.reader.OldestAge().Seconds())
+		} else {
+			metrics[o.name+"_QueueDepth"] = uint64(len(o.queue))
+		}
+		metrics[o.name+"_WrittenMessages"] = atomic.LoadUint64(&o.written)
+		metrics[o.name+"_FailedMessages"] = atomic.LoadUint64(&o.failed)
+		metrics[o.name+"_DroppedMessages"] = atomic.LoadUint64(&o.dropped)
+		if reporter, ok := o.sink.(LossReporter); ok {
+			metrics[o.name+"_LostRecords"] = reporter.Lost()
+		}
+		metrics[o.name+"_Healthy"] = healthy
+	}
+
+	if d.durable != nil {
+		metrics["DurableQueueBytes"] = uint64(d.durable.Size()) // #nosec G115
+	}
+
+	return "haltonika_sinks", metrics
+}
+
+/*
+run writes the queued messages into the sink until the queue is closed.
+Messages waiting to be persisted are flushed when the queue gets empty, so they are not delayed by the flush interval.
+*/
+func (d *Dispatcher) run(o *output) {
+	log := config.GetLogger(d.ctx)
+
+	ticker := time.NewTicker(o.flushInterval)
+	defer ticker.Stop()
+
+	var pending []chan<- error // written but not flushed messages waiting to be persisted
+	flush := func() {
+		err := d.flush(o)
+		for _, done := range pending {
+			done <- err
+		}
+		pending = nil
+	}
+
+	for {
+		select {
+		case queued, ok := <-o.queue:
+			if !ok {
+				flush()
+				log.Debugf("Sink %s terminated", o.name)
+				return
+			}
+
+			err := d.write(o, queued.message)
+			if err != nil {
+				atomic.AddUint64(&o.failed, 1)
+				log.Errorf("Failed to write message of %s device into %s sink. %v", queued.message.Decoded.IMEI, o.name, err)
+				if queued.done != nil {
+					queued.done <- fmt.Errorf("failed to write into %s sink. %v", o.name, err)
+				}
+				continue
+			}
+			atomic.AddUint64(&o.written, 1)
+
+			if queued.done != nil {
+				pending = append(pending, queued.done)
+			}
+			if len(pending) > 0 && len(o.queue) == 0 {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+/*
+runDurable writes the messages of the durable queue into the sink until the dispatcher is closed.
+Messages are committed in the queue only after the sink is flushed successfully.
+*/
+func (d *Dispatcher) runDurable(o *output) {
+	log := config.GetLogger(d.ctx)
+
+	var uncommitted uint64 // sequence number of the last written but not committed message
+	commit := func() {
+		if uncommitted == 0 || d.flush(o) != nil {
+			return
+		}
+
+		err := o.reader.Commit(uncommitted)
+		if err != nil {
+			log.Errorf("Failed to commit queue of %s sink. %v", o.name, err)
+			return
+		}
+		uncommitted = 0
+	}
+	defer func() {
+		commit()
+		log.Debugf("Sink %s terminated", o.name)
+	}()
+
+	nextFlush := time.Now().Add(o.flushInterval)
+	for {
+		ctx, cancel := context.WithDeadline(d.runCtx, nextFlush)
+		entry, err := o.reader.Next(ctx)
+		cancel()
+
+		if err != nil {
+			switch {
+			case d.runCtx.Err() != nil || errors.Is(err, wal.ErrClosed):
+				return
+			case errors.Is(err, context.DeadlineExceeded):
+				commit()
+				nextFlush = time.Now().Add(o.flushInterval)
+			default:
+				log.Errorf("Failed to read queue of %s sink. %v", o.name, err)
+				if !d.sleep(d.maxBackoff) {
+					return
+				}
+			}
+			continue
+		}
+
+		var message Message
+		err = json.Unmarshal(entry.Payload, &message)
+		if err != nil {
+			// Retrying would not help
+			atomic.AddUint64(&o.failed, 1)
+			log.Errorf("Dropping invalid message %d from queue of %s sink. %v", entry.Seq, o.name, err)
+			uncommitted = entry.Seq
+			continue
+		}
+
+		backoff := d.minBackoff
+		for {
+			err = d.write(o, message)
+			if err == nil {
+				break
+			}
+
+			atomic.AddUint64(&o.failed, 1)
+			log.Errorf("Failed to write message of %s device into %s sink. Retrying in %v. %v", message.Decoded.IMEI, o.name, backoff, err)
+			if !d.sleep(backoff) {
+				return
+			}
+			backoff = min(2*backoff, d.maxBackoff)
+		}
+		atomic.AddUint64(&o.written, 1)
+		uncommitted = entry.Seq
+
+		if time.Now().After(nextFlush) {
+			commit()
+			nextFlush = time.Now().Add(o.flushInterval)
+		}
+	}
+}
+
+// sleep waits for the given duration and returns false if the dispatcher was closed meanwhile
+func (d *Dispatcher) sleep(duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-d.runCtx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// write calls the sink and turns its panic into error so it can not stop the whole server
+func (d *Dispatcher) write(o *output, message Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("sink panicked: %v", r)
+		}
+	}()
+
+	return o.sink.Write(message)
+}
+
+func (d *Dispatcher) flush(o *output) (err error) {
+	log := config.GetLogger(d.ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("sink panicked: %v", r)
+		}
+		if err != nil {
+			log.Errorf("Failed to flush %s sink. %v", o.name, err)
+		}
+	}()
+
+	return o.sink.Flush()
+}
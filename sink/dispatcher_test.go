@@ -0,0 +1,343 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/wal"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSink records the written messages. It can be slowed down, made failing or panicking.
+type fakeSink struct {
+	lock     sync.Mutex
+	messages []Message
+	flushes  int
+	closed   bool
+	delay    time.Duration
+	fail     bool
+	failures int // number of writes failing before the first successful one
+	panic    bool
+}
+
+func (s *fakeSink) Write(message Message) error {
+	time.Sleep(s.delay)
+
+	if s.panic {
+		panic("fake sink panic")
+	}
+	if s.fail {
+		return fmt.Errorf("fake sink failure")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		return fmt.Errorf("fake sink failure")
+	}
+
+	s.messages = append(s.messages, message)
+
+	return nil
+}
+
+func (s *fakeSink) Flush() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.flushes++
+
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+
+	return nil
+}
+
+func (s *fakeSink) Health() error {
+	if s.fail {
+		return fmt.Errorf("fake sink is failing")
+	}
+
+	return nil
+}
+
+func (s *fakeSink) count() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.messages)
+}
+
+func newTestContext() context.Context {
+	cfg := config.NewConfig(logrus.New(), nil, nil, nil, nil, nil)
+	return context.WithValue(context.Background(), config.ContextConfigKey, cfg)
+}
+
+func newMessage(imei string) Message {
+	return Message{
+		Decoded: codec.Decoded{
+			IMEI:    imei,
+			CodecID: codec.Codec8,
+		},
+		SourceAddress: "127.0.0.1:1234",
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	fast := &fakeSink{}
+	slow := &fakeSink{delay: 100 * time.Millisecond}
+	failing := &fakeSink{fail: true}
+	panicking := &fakeSink{panic: true}
+
+	dispatcher := NewDispatcher(newTestContext())
+	for name, s := range map[string]*fakeSink{"fast": fast, "slow": slow, "failing": failing, "panicking": panicking} {
+		err := dispatcher.Add(name, s, 2, 0)
+		if err != nil {
+			t.Fatalf("Failed to add %s sink. %v", name, err)
+		}
+	}
+
+	err := dispatcher.Add("fast", &fakeSink{}, 0, 0)
+	if err == nil {
+		t.Errorf("Sink names must be unique")
+	}
+
+	err = dispatcher.Start()
+	if err != nil {
+		t.Fatalf("Failed to start dispatcher. %v", err)
+	}
+
+	// Fast sink gets all messages while the slow one is still busy with the first ones
+	for i := 0; i < 10; i++ {
+		_ = dispatcher.Dispatch(newMessage(fmt.Sprintf("35042406381736%d", i)))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if fast.count() != 10 {
+		t.Errorf("Wrong number of messages in fast sink! Expected: 10 Actual: %d", fast.count())
+	}
+
+	_, metrics := dispatcher.MetricRendererHandler()
+	if metrics["slow_DroppedMessages"] == 0 {
+		t.Errorf("Slow sink must drop messages. Metrics: %v", metrics)
+	}
+	if metrics["failing_FailedMessages"] != 10 || metrics["panicking_FailedMessages"] != 10 {
+		t.Errorf("Failed messages must be counted. Metrics: %v", metrics)
+	}
+	if metrics["fast_Healthy"] != 1 || metrics["failing_Healthy"] != 0 {
+		t.Errorf("Wrong health. Metrics: %v", metrics)
+	}
+
+	health := dispatcher.Health()
+	if health["fast"] != nil || health["failing"] == nil {
+		t.Errorf("Wrong health: %v", health)
+	}
+
+	err = dispatcher.Close()
+	if err != nil {
+		t.Fatalf("Failed to close dispatcher. %v", err)
+	}
+
+	for name, s := range map[string]*fakeSink{"fast": fast, "slow": slow, "failing": failing, "panicking": panicking} {
+		if !s.closed || s.flushes == 0 {
+			t.Errorf("Sink %s must be flushed and closed. Flushes: %d Closed: %v", name, s.flushes, s.closed)
+		}
+	}
+
+	if slow.count()+int(metrics["slow_DroppedMessages"]) != 10 {
+		t.Errorf("Queued messages of slow sink must be written before close. Written: %d Dropped: %d", slow.count(), metrics["slow_DroppedMessages"])
+	}
+
+	// Dispatching after close must not panic
+	err = dispatcher.Dispatch(newMessage("350424063817363"))
+	if err == nil {
+		t.Errorf("Dispatching after close must fail")
+	}
+}
+
+func TestDispatcherBlocking(t *testing.T) {
+	slow := &fakeSink{delay: 5 * time.Millisecond}
+
+	dispatcher := NewDispatcher(newTestContext())
+	err := dispatcher.Add("slow", slow, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to add sink. %v", err)
+	}
+	dispatcher.SetBlocking(true)
+	err = dispatcher.Start()
+	if err != nil {
+		t.Fatalf("Failed to start dispatcher. %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		err = dispatcher.Dispatch(newMessage("350424063817363"))
+		if err != nil {
+			t.Errorf("Blocking dispatcher must not fail. %v", err)
+		}
+	}
+
+	err = dispatcher.Close()
+	if err != nil {
+		t.Fatalf("Failed to close dispatcher. %v", err)
+	}
+
+	if slow.count() != 20 {
+		t.Errorf("Blocking dispatcher must not drop messages! Expected: 20 Actual: %d", slow.count())
+	}
+}
+
+func TestDispatcherPersist(t *testing.T) {
+	stable := &fakeSink{}
+	flaky := &fakeSink{failures: 1}
+
+	dispatcher := NewDispatcher(newTestContext())
+	for name, s := range map[string]*fakeSink{"stable": stable, "flaky": flaky} {
+		// Persisted messages must be flushed without waiting for the flush interval
+		err := dispatcher.Add(name, s, 10, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to add %s sink. %v", name, err)
+		}
+	}
+	err := dispatcher.Start()
+	if err != nil {
+		t.Fatalf("Failed to start dispatcher. %v", err)
+	}
+
+	err = dispatcher.Persist(newMessage("350424063817363"))
+	if err == nil {
+		t.Errorf("Persist must fail if any sink failed")
+	}
+
+	err = dispatcher.Persist(newMessage("350424063817363"))
+	if err != nil {
+		t.Errorf("Failed to persist message. %v", err)
+	}
+
+	stable.lock.Lock()
+	flushes := stable.flushes
+	stable.lock.Unlock()
+	if flushes == 0 {
+		t.Errorf("Persisted message was not flushed")
+	}
+
+	err = dispatcher.Close()
+	if err != nil {
+		t.Fatalf("Failed to close dispatcher. %v", err)
+	}
+
+	if stable.count() != 2 || flaky.count() != 1 {
+		t.Errorf("Wrong number of written messages! Expected: 2, 1 Actual: %d, %d", stable.count(), flaky.count())
+	}
+}
+
+func TestDispatcherDurable(t *testing.T) {
+	directory := t.TempDir()
+
+	// Sink fails the first writes, then it must get all messages in order
+	flaky := &fakeSink{failures: 3}
+	down := &fakeSink{fail: true}
+
+	start := func(sinks map[string]*fakeSink) *Dispatcher {
+		queue, err := wal.Open(wal.Config{Directory: directory, NoSync: true})
+		if err != nil {
+			t.Fatalf("Failed to open queue. %v", err)
+		}
+
+		dispatcher := NewDispatcher(newTestContext())
+		for name, s := range sinks {
+			err = dispatcher.Add(name, s, 0, 10*time.Millisecond)
+			if err != nil {
+				t.Fatalf("Failed to add %s sink. %v", name, err)
+			}
+		}
+		dispatcher.SetDurableQueue(queue, time.Millisecond, 5*time.Millisecond)
+
+		err = dispatcher.Start()
+		if err != nil {
+			t.Fatalf("Failed to start dispatcher. %v", err)
+		}
+
+		return dispatcher
+	}
+
+	dispatcher := start(map[string]*fakeSink{"flaky": flaky, "down": down})
+	for i := 0; i < 5; i++ {
+		err := dispatcher.Dispatch(newMessage(fmt.Sprintf("35042406381736%d", i)))
+		if err != nil {
+			t.Fatalf("Failed to dispatch message. %v", err)
+		}
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if flaky.count() != 5 {
+		t.Fatalf("Wrong number of messages in flaky sink! Expected: 5 Actual: %d", flaky.count())
+	}
+	for i, message := range flaky.messages {
+		expected := fmt.Sprintf("35042406381736%d", i)
+		if message.Decoded.IMEI != expected {
+			t.Errorf("Wrong order of messages! Expected: %v Actual: %v", expected, message.Decoded.IMEI)
+		}
+	}
+
+	_, metrics := dispatcher.MetricRendererHandler()
+	if metrics["flaky_QueueDepth"] != 0 || metrics["down_QueueDepth"] != 5 {
+		t.Errorf("Wrong queue depth. Metrics: %v", metrics)
+	}
+	if metrics["down_FailedMessages"] == 0 {
+		t.Errorf("Failed writes must be counted. Metrics: %v", metrics)
+	}
+
+	err := dispatcher.Close()
+	if err != nil {
+		t.Fatalf("Failed to close dispatcher. %v", err)
+	}
+
+	// Messages of the sink which was down are delivered after restart
+	recovered := &fakeSink{}
+	dispatcher = start(map[string]*fakeSink{"flaky": flaky, "down": recovered})
+	time.Sleep(100 * time.Millisecond)
+
+	err = dispatcher.Close()
+	if err != nil {
+		t.Fatalf("Failed to close dispatcher. %v", err)
+	}
+
+	if recovered.count() != 5 {
+		t.Errorf("Wrong number of messages after restart! Expected: 5 Actual: %d", recovered.count())
+	}
+	if flaky.count() != 5 {
+		t.Errorf("Committed messages must not be written again! Expected: 5 Actual: %d", flaky.count())
+	}
+}
+
+func TestDecodeOptions(t *testing.T) {
+	var options struct {
+		Url     string
+		Timeout time.Duration
+		Retries int
+	}
+
+	err := DecodeOptions(map[string]interface{}{"url": "http://localhost:8086", "timeout": "5s", "retries": "3"}, &options)
+	if err != nil {
+		t.Fatalf("Failed to decode options. %v", err)
+	}
+	if options.Url != "http://localhost:8086" || options.Timeout != 5*time.Second || options.Retries != 3 {
+		t.Errorf("Wrong options: %+v", options)
+	}
+
+	err = DecodeOptions(map[string]interface{}{"ulr": "http://localhost:8086"}, &options)
+	if err == nil {
+		t.Errorf("Unknown options must be rejected")
+	}
+}
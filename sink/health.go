@@ -0,0 +1,32 @@
+package sink
+
+import (
+	"fmt"
+	"sync"
+)
+
+// HealthState keeps the result of the last write of a sink for health checks. Sinks embed it to implement Health.
+type HealthState struct {
+	lastError     error
+	lastErrorLock sync.Mutex
+}
+
+// SetLastError stores the result of the last write, nil if it succeeded
+func (h *HealthState) SetLastError(err error) {
+	h.lastErrorLock.Lock()
+	defer h.lastErrorLock.Unlock()
+
+	h.lastError = err
+}
+
+// Health returns the error of the last write
+func (h *HealthState) Health() error {
+	h.lastErrorLock.Lock()
+	defer h.lastErrorLock.Unlock()
+
+	if h.lastError != nil {
+		return fmt.Errorf("last write failed. %v", h.lastError)
+	}
+
+	return nil
+}
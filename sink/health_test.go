@@ -0,0 +1,24 @@
+package sink
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHealthState(t *testing.T) {
+	var state HealthState
+	if err := state.Health(); err != nil {
+		t.Errorf("Wrong health before the first write! Expected: %v Actual: %v", nil, err)
+	}
+
+	state.SetLastError(fmt.Errorf("disk full"))
+	expected := "last write failed. disk full"
+	if err := state.Health(); err == nil || err.Error() != expected {
+		t.Errorf("Wrong health after a failed write! Expected: %v Actual: %v", expected, err)
+	}
+
+	state.SetLastError(nil)
+	if err := state.Health(); err != nil {
+		t.Errorf("Wrong health after a successful write! Expected: %v Actual: %v", nil, err)
+	}
+}
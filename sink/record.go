@@ -0,0 +1,55 @@
+package sink
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/config"
+	"time"
+)
+
+// Latitude returns the latitude of the record in degrees
+func Latitude(avlData codec.AvlData) float64 {
+	return float64(avlData.Lat) / 10000000.0
+}
+
+// Longitude returns the longitude of the record in degrees
+func Longitude(avlData codec.AvlData) float64 {
+	return float64(avlData.Lng) / 10000000.0
+}
+
+// Timestamp returns the time when the device created the record
+func Timestamp(avlData codec.AvlData) time.Time {
+	return time.UnixMilli(int64(avlData.UtimeMs)) // #nosec G115
+}
+
+// IOKey returns the name of an IO element used by the sinks, e.g. IOID66
+func IOKey(id uint16) string {
+	return fmt.Sprintf("IOID%d", id)
+}
+
+// IOValues converts the IO elements of the record. Values up to 8 bytes are integers, longer ones are hex strings.
+func IOValues(ctx context.Context, avlData codec.AvlData) map[string]interface{} {
+	log := config.GetLogger(ctx)
+
+	values := make(map[string]interface{}, len(avlData.Elements))
+	for _, element := range avlData.Elements {
+		IOID := element.IOID
+		if element.Length > 8 {
+			log.Warnf("IOID%d value long! Got %d bytes long IOID value! It will be save as hex string. Value: %s", IOID, element.Length, hex.EncodeToString(element.Value[:element.Length]))
+			values[IOKey(IOID)] = hex.EncodeToString(element.Value)
+		} else {
+			// raw must have 8 bytes to parse it as uint64 so add zero bytes with BigEndian if needed
+			addon := make([]byte, 8-element.Length)
+			raw := append(addon, element.Value...)
+
+			// parse byte array as integer
+			value := binary.BigEndian.Uint64(raw)
+			values[IOKey(IOID)] = int(value) // #nosec G115 TODO if value is not casted to int, influx drops parse error. Why?
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,34 @@
+package sink
+
+import (
+	"github.com/halacs/haltonika/codec"
+	"testing"
+)
+
+func TestIOValues(t *testing.T) {
+	record := codec.AvlData{
+		Elements: []codec.Element{
+			{Length: 1, IOID: 239, Value: []byte{0x01}},
+			{Length: 2, IOID: 66, Value: []byte{0x2e, 0x97}},
+			{Length: 8, IOID: 78, Value: []byte{0, 0, 0, 0, 0, 0, 0x01, 0x00}},
+			{Length: 10, IOID: 256, Value: []byte("VIN1234567")},
+		},
+	}
+
+	expected := map[string]interface{}{
+		"IOID239": 1,
+		"IOID66":  11927,
+		"IOID78":  256,
+		"IOID256": "56494e31323334353637",
+	}
+
+	values := IOValues(newTestContext(), record)
+	if len(values) != len(expected) {
+		t.Errorf("Wrong number of values! Expected: %v Actual: %v", len(expected), len(values))
+	}
+	for key, value := range expected {
+		if values[key] != value {
+			t.Errorf("Wrong value of %s! Expected: %v Actual: %v", key, value, values[key])
+		}
+	}
+}
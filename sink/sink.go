@@ -0,0 +1,111 @@
+package sink
+
+import (
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"github.com/mitchellh/mapstructure"
+	"time"
+)
+
+// Message is a decoded packet of a device passed to the sinks
+type Message struct {
+	Decoded       codec.Decoded
+	SourceAddress string // Remote address of the device
+}
+
+/*
+Sink is a destination of the decoded packets like a database or a message broker.
+Methods are called from one goroutine per sink by the Dispatcher, so sinks do not have to be thread-safe except Health.
+*/
+type Sink interface {
+	// Write stores or sends the message. Sinks may buffer messages until Flush is called.
+	Write(message Message) error
+	// Flush writes out the buffered messages. If it fails, the messages must be kept for the next Flush.
+	Flush() error
+	// Close flushes the buffered messages and releases the resources of the sink
+	Close() error
+	// Health returns nil if the sink works, otherwise the reason why not. It must not block.
+	Health() error
+}
+
+/*
+CommandResponseWriter is implemented by sinks which also forward the responses of the devices to commands.
+Responses are not queued, so WriteCommandResponse must be safe to call concurrently with Write.
+*/
+type CommandResponseWriter interface {
+	WriteCommandResponse(imei string, response string) error
+}
+
+/*
+CommandRequestWriter is implemented by sinks which also keep the commands sent to the devices.
+Commands are not queued, so WriteCommandRequest must be safe to call concurrently with Write.
+*/
+type CommandRequestWriter interface {
+	WriteCommandRequest(imei string, command string) error
+}
+
+// CommandHandler sends a command to a device
+type CommandHandler func(imei string, command string) error
+
+// CommandSource is implemented by sinks which also read commands to be sent to the devices
+type CommandSource interface {
+	SetCommandHandler(handler CommandHandler)
+}
+
+// Types of DeviceEvent
+const (
+	DeviceConnected    = "connected"
+	DeviceDisconnected = "disconnected"
+)
+
+// DeviceEvent reports that a device came online or went offline
+type DeviceEvent struct {
+	IMEI          string
+	Type          string // DeviceConnected or DeviceDisconnected
+	Transport     string // udp or tcp
+	SourceAddress string
+	Time          time.Time
+}
+
+/*
+DeviceEventWriter is implemented by sinks which also forward devices coming online and going offline.
+Events are not queued, so WriteDeviceEvent must be safe to call concurrently with Write.
+*/
+type DeviceEventWriter interface {
+	WriteDeviceEvent(event DeviceEvent) error
+}
+
+/*
+DeviceListWriter is implemented by sinks which announce the registered devices, e.g. to Home Assistant.
+It is called whenever the device registry changes, concurrently with Write.
+*/
+type DeviceListWriter interface {
+	WriteDeviceList(imeis []string) error
+}
+
+// LossReporter is implemented by sinks which drop buffered records refused by their destination
+type LossReporter interface {
+	// Lost returns the number of records dropped since the sink was created
+	Lost() uint64
+}
+
+// DecodeOptions converts the type specific options of a sink from the config file into the target struct.
+// Unknown options are rejected to catch typos.
+func DecodeOptions(options map[string]interface{}, target interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		ErrorUnused:      true,
+		WeaklyTypedInput: true,
+		Result:           target,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create options decoder. %v", err)
+	}
+
+	err = decoder.Decode(options)
+	if err != nil {
+		return fmt.Errorf("invalid options. %v", err)
+	}
+
+	return nil
+}
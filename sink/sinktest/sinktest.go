@@ -0,0 +1,40 @@
+// Package sinktest provides the context and the message the tests of the sinks share
+package sinktest
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/sink"
+	"github.com/sirupsen/logrus"
+	"testing"
+)
+
+// Packet is a Codec 8 UDP packet of 352094089397464 device with 4 records between 2018-06-03T23:37:45Z and 2018-06-03T23:38:10Z
+const Packet = "01e4cafe0128000f333532303934303839333937343634080400000163c803eb02010a2524c01d4a377d00d3012f130032421b0a4503f00150051503ef01510052005900be00c1000ab50008b60006426fd8cd3d1ece605a5400005500007300005a0000c0000007c70000000df1000059d910002d33c65300000000570000000064000000f7bf000000000000000163c803e6e8010a2530781d4a316f00d40131130031421b0a4503f00150051503ef01510052005900be00c1000ab50008b60005426fcbcd3d1ece605a5400005500007300005a0000c0000007c70000000ef1000059d910002d33b95300000000570000000064000000f7bf000000000000000163c803df18010a2536961d4a2e4f00d50134130033421b0a4503f00150051503ef01510052005900be00c1000ab50008b6000542702bcd3d1ece605a5400005500007300005a0000c0000007c70000001ef1000059d910002d33aa5300000000570000000064000000f7bf000000000000000163c8039ce2010a25d8d41d49f42c00dc0123120058421b0a4503f00150051503ef01510052005900be00c1000ab50009b60005427031cd79d8ce605a5400005500007300005a0000c0000007c700000019f1000059d910002d32505300000000570000000064000000f7bf000000000004"
+
+// NewContext returns a context with a config having only a logger
+func NewContext() context.Context {
+	cfg := config.NewConfig(logrus.New(), nil, nil, nil, nil, nil)
+	return context.WithValue(context.Background(), config.ContextConfigKey, cfg)
+}
+
+// NewMessage returns the decoded Packet received from 127.0.0.1:1234
+func NewMessage(t testing.TB) sink.Message {
+	t.Helper()
+
+	packet, err := hex.DecodeString(Packet)
+	if err != nil {
+		t.Fatalf("Incorrect packet. %v", err)
+	}
+	decoded, err := codec.Decode(packet)
+	if err != nil {
+		t.Fatalf("Failed to decode packet. %v", err)
+	}
+
+	return sink.Message{
+		Decoded:       decoded,
+		SourceAddress: "127.0.0.1:1234",
+	}
+}
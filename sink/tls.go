@@ -0,0 +1,38 @@
+package sink
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// NewTLSConfig loads the CA certificates and the client certificate of a sink connecting to its server via TLS. Empty file names are skipped.
+func NewTLSConfig(caCert string, clientCert string, clientKey string, insecureSkipVerify bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify, // #nosec G402 disabled by default
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert) // #nosec G304
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificates. %v", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no CA certificate found in %s", caCert)
+		}
+	}
+
+	if clientCert != "" {
+		certificate, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate. %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
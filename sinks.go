@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/halacs/haltonika/archive"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/fmb920"
+	influxdb2 "github.com/halacs/haltonika/influxdb"
+	"github.com/halacs/haltonika/ioelement"
+	"github.com/halacs/haltonika/jetstream"
+	"github.com/halacs/haltonika/mqtt"
+	"github.com/halacs/haltonika/pipe"
+	"github.com/halacs/haltonika/postgres"
+	"github.com/halacs/haltonika/sink"
+	"github.com/halacs/haltonika/sqlite"
+	"github.com/halacs/haltonika/wal"
+	"github.com/sirupsen/logrus"
+	"os"
+)
+
+// newSink creates a sink of the configured type
+func newSink(ctx context.Context, cfg *config.Config, sinkConfig config.SinkConfig) (sink.Sink, error) {
+	switch sinkConfig.Type {
+	case config.SinkTypeExec:
+		options, err := pipe.ParseOptions(sinkConfig.Options)
+		if err != nil {
+			return nil, err
+		}
+
+		execSink := pipe.NewExec(ctx, options)
+		err = execSink.Start()
+		if err != nil {
+			return nil, err
+		}
+
+		return execSink, nil
+	case config.SinkTypeFile:
+		options, err := archive.ParseOptions(sinkConfig.Options)
+		if err != nil {
+			return nil, err
+		}
+
+		archiveSink := archive.NewArchive(ctx, options)
+		err = archiveSink.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		return archiveSink, nil
+	case config.SinkTypeInfluxDB:
+		influxConfig, err := influxdb2.ApplySinkOptions(*cfg.GetInfluxConfig(), sinkConfig.Options)
+		if err != nil {
+			return nil, err
+		}
+
+		influxdb := influxdb2.NewConnection(ctx, influxConfig)
+		influxdb.SetDeviceRegistry(cfg.GetTeltonikaConfig().Devices)
+		if influxConfig.IOMapping {
+			mapper, err := ioelement.NewMapper(cfg.GetTeltonikaConfig().Profiles.Dictionary, cfg.GetStorageConfig().IOMapping, influxdb2.FieldNames...)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IO mapping. %v", err)
+			}
+			influxdb.SetIOMapper(mapper, cfg.GetTeltonikaConfig().Model)
+		}
+		err = influxdb.Connect()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open influxdb connection. %v", err)
+		}
+
+		return influxdb, nil
+	case config.SinkTypeMQTT:
+		options, err := mqtt.ParseOptions(sinkConfig.Options)
+		if err != nil {
+			return nil, err
+		}
+
+		publisher := mqtt.NewPublisher(ctx, options)
+		if options.HomeAssistant {
+			publisher.SetDiscoveryIMEIs(cfg.GetTeltonikaConfig().AllowedDevices())
+		}
+		err = publisher.Connect()
+		if err != nil {
+			return nil, err
+		}
+
+		return publisher, nil
+	case config.SinkTypeSQLite:
+		options, err := sqlite.ParseOptions(sinkConfig.Options)
+		if err != nil {
+			return nil, err
+		}
+
+		store := sqlite.NewStore(ctx, options)
+		err = store.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		return store, nil
+	case config.SinkTypeNATS:
+		options, err := jetstream.ParseOptions(sinkConfig.Options)
+		if err != nil {
+			return nil, err
+		}
+
+		publisher := jetstream.NewPublisher(ctx, options)
+		err = publisher.Connect()
+		if err != nil {
+			return nil, err
+		}
+
+		return publisher, nil
+	case config.SinkTypePostgres:
+		options, err := postgres.ParseOptions(sinkConfig.Options)
+		if err != nil {
+			return nil, err
+		}
+
+		connection := postgres.NewConnection(ctx, options)
+		err = connection.Connect()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open postgres connection. %v", err)
+		}
+
+		return connection, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type: %q", sinkConfig.Type)
+	}
+}
+
+// initializeSinks creates and starts all configured sinks with the durable queue in front of them if it is enabled
+func initializeSinks(ctx context.Context, log *logrus.Logger, cfg *config.Config, durable bool) *sink.Dispatcher {
+	dispatcher := sink.NewDispatcher(ctx)
+
+	for _, sinkConfig := range cfg.GetStorageConfig().Sinks {
+		s, err := newSink(ctx, cfg, sinkConfig)
+		if err != nil {
+			log.Fatalf("Failed to initialize %s sink. %v", sinkConfig.Name, err)
+			os.Exit(1)
+		}
+
+		err = dispatcher.Add(sinkConfig.Name, s, sinkConfig.QueueSize, sinkConfig.FlushInterval)
+		if err != nil {
+			log.Fatalf("Failed to add %s sink. %v", sinkConfig.Name, err)
+			os.Exit(1)
+		}
+
+		log.Infof("Decoded records are written into %s sink (%s)", sinkConfig.Name, sinkConfig.Type)
+	}
+
+	queueConfig := cfg.GetStorageConfig().Queue
+	if durable && queueConfig.Directory != "" {
+		queue, err := wal.Open(wal.Config{
+			Directory: queueConfig.Directory,
+			MaxSize:   queueConfig.MaxSize,
+			NoSync:    !queueConfig.Sync,
+		})
+		if err != nil {
+			log.Fatalf("Failed to open durable queue. %v", err)
+			os.Exit(1)
+		}
+
+		dispatcher.SetDurableQueue(queue, queueConfig.MinBackoff, queueConfig.MaxBackoff)
+		log.Infof("Decoded records are queued in %s", queueConfig.Directory)
+	}
+
+	err := dispatcher.Start()
+	if err != nil {
+		log.Fatalf("Failed to start sinks. %v", err)
+		os.Exit(1)
+	}
+
+	return dispatcher
+}
+
+// newCommandResponseCallback passes command responses to the sinks forwarding them
+func newCommandResponseCallback(dispatcher *sink.Dispatcher) fmb920.CommandResponseCallback {
+	return func(ctx context.Context, imei string, response string) {
+		dispatcher.CommandResponse(imei, response)
+	}
+}
+
+// newCommandSentCallback passes commands sent to the devices to the sinks keeping them
+func newCommandSentCallback(dispatcher *sink.Dispatcher) fmb920.CommandSentCallback {
+	return func(ctx context.Context, imei string, command string) {
+		dispatcher.CommandRequest(imei, command)
+	}
+}
+
+// newDeviceEventCallback passes devices coming online and going offline to the sinks forwarding them
+func newDeviceEventCallback(dispatcher *sink.Dispatcher) fmb920.DeviceEventCallback {
+	return func(ctx context.Context, event fmb920.DeviceEvent) {
+		dispatcher.DeviceEvent(sink.DeviceEvent{
+			IMEI:          event.IMEI,
+			Type:          event.Type,
+			Transport:     event.Transport,
+			SourceAddress: event.SourceAddress,
+			Time:          event.Time,
+		})
+	}
+}
+
+// newSinkCallback passes decoded packets to all sinks. With persist, it returns only after the packet is stored.
+func newSinkCallback(dispatcher *sink.Dispatcher, persist bool) fmb920.PacketArrivedCallback {
+	return func(ctx context.Context, message fmb920.TeltonikaMessage) error {
+		log := config.GetLogger(ctx)
+
+		log.Debugf("PACKET ARRIVED: %+v", message)
+
+		dispatch := dispatcher.Dispatch
+		if persist {
+			dispatch = dispatcher.Persist
+		}
+
+		err := dispatch(sink.Message{
+			Decoded:       message.Decoded,
+			SourceAddress: message.SourceAddress,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to pass packet to sinks. %v", err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,375 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/sink"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultQueryLimit = 10000
+	MaxQueryLimit     = 100000
+
+	// Time range of a query without from
+	defaultQueryRange = 24 * time.Hour
+)
+
+// Device is the state of a device returned by the query API
+type Device struct {
+	IMEI        string     `json:"imei"`
+	FirstSeen   time.Time  `json:"firstSeen"`
+	LastSeen    time.Time  `json:"lastSeen"`
+	LastSource  string     `json:"lastSource"`
+	Online      bool       `json:"online"`
+	Transport   string     `json:"transport,omitempty"`
+	LastEventAt *time.Time `json:"lastEventAt,omitempty"`
+	Position    *Position  `json:"position,omitempty"` // latest position
+}
+
+// Position is a record returned by the query API
+type Position struct {
+	Time           time.Time              `json:"time"`
+	ServerTime     *time.Time             `json:"serverTime,omitempty"`
+	Source         string                 `json:"source,omitempty"`
+	Latitude       float64                `json:"latitude"`
+	Longitude      float64                `json:"longitude"`
+	Altitude       int                    `json:"altitude"`
+	Angle          int                    `json:"angle"`
+	Satellites     int                    `json:"satellites"`
+	Speed          int                    `json:"speed"`
+	Priority       *int                   `json:"priority,omitempty"`
+	EventID        *int                   `json:"eventID,omitempty"`
+	GenerationType *int                   `json:"generationType,omitempty"` // Codec 16 only
+	IO             map[string]interface{} `json:"io,omitempty"`             // by IOID<n> keys
+}
+
+// Command is a command sent to a device or its response returned by the query API
+type Command struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"` // DirectionRequest or DirectionResponse
+	Text      string    `json:"text"`
+}
+
+// query is the time range and the maximum number of rows of a query
+type query struct {
+	from  time.Time
+	to    time.Time
+	limit int
+}
+
+/*
+handler serves
+  - /api/devices: state of all devices
+  - /api/devices/<imei>: state of a device
+  - /api/devices/<imei>/positions?from=<RFC3339>&to=<RFC3339>&limit=<n>: positions with IO values in ascending order
+  - /api/devices/<imei>/commands?from=<RFC3339>&to=<RFC3339>&limit=<n>: commands and responses in ascending order
+*/
+func (s *Store) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/devices", s.devicesHandler)
+	mux.HandleFunc("/api/devices/", s.deviceHandler)
+
+	return mux
+}
+
+func (s *Store) devicesHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", req.Method))
+		return
+	}
+
+	devices, err := s.queryDevices("")
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeJSON(w, devices)
+}
+
+func (s *Store) deviceHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", req.Method))
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/api/devices/"), "/")
+	imei := parts[0]
+	if imei == "" || len(parts) > 2 {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("unknown path %s", req.URL.Path))
+		return
+	}
+
+	if len(parts) == 1 {
+		devices, err := s.queryDevices(imei)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if len(devices) == 0 {
+			s.writeError(w, http.StatusNotFound, fmt.Errorf("device %s is not found", imei))
+			return
+		}
+
+		s.writeJSON(w, devices[0])
+		return
+	}
+
+	q, err := parseQuery(req, time.Now())
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var result interface{}
+	switch parts[1] {
+	case "positions":
+		result, err = s.queryPositions(imei, q)
+	case "commands":
+		result, err = s.queryCommands(imei, q)
+	default:
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("unknown path %s", req.URL.Path))
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeJSON(w, result)
+}
+
+// parseQuery returns the time range and the limit of a query. Without from, the last 24 hours before to are queried.
+func parseQuery(req *http.Request, now time.Time) (query, error) {
+	values := req.URL.Query()
+	q := query{
+		to:    now,
+		limit: DefaultQueryLimit,
+	}
+
+	var err error
+	if to := values.Get("to"); to != "" {
+		q.to, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			return query{}, fmt.Errorf("invalid to. %v", err)
+		}
+	}
+
+	q.from = q.to.Add(-defaultQueryRange)
+	if from := values.Get("from"); from != "" {
+		q.from, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			return query{}, fmt.Errorf("invalid from. %v", err)
+		}
+	}
+	if q.from.After(q.to) {
+		return query{}, fmt.Errorf("from must not be after to")
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		q.limit, err = strconv.Atoi(limit)
+		if err != nil || q.limit <= 0 || q.limit > MaxQueryLimit {
+			return query{}, fmt.Errorf("limit must be between 1 and %d", MaxQueryLimit)
+		}
+	}
+
+	return q, nil
+}
+
+// queryDevices returns the state of the given device or all devices if imei is empty
+func (s *Store) queryDevices(imei string) ([]Device, error) {
+	rows, err := s.db.QueryContext(s.ctx, `SELECT imei, first_seen, last_seen, last_source, online, transport, last_event_at,
+			position_time, latitude, longitude, altitude, angle, satellites, speed
+		FROM devices WHERE ? = '' OR imei = ? ORDER BY imei`, imei, imei)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query devices. %v", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	devices := make([]Device, 0)
+	for rows.Next() {
+		var device Device
+		var firstSeen, lastSeen int64
+		var transport sql.NullString
+		var lastEventAt, positionTime, altitude, angle, satellites, speed sql.NullInt64
+		var latitude, longitude sql.NullFloat64
+
+		err = rows.Scan(&device.IMEI, &firstSeen, &lastSeen, &device.LastSource, &device.Online, &transport, &lastEventAt,
+			&positionTime, &latitude, &longitude, &altitude, &angle, &satellites, &speed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read device. %v", err)
+		}
+
+		device.FirstSeen = time.UnixMilli(firstSeen).UTC()
+		device.LastSeen = time.UnixMilli(lastSeen).UTC()
+		device.Transport = transport.String
+		if lastEventAt.Valid {
+			eventAt := time.UnixMilli(lastEventAt.Int64).UTC()
+			device.LastEventAt = &eventAt
+		}
+		if positionTime.Valid {
+			device.Position = &Position{
+				Time:       time.UnixMilli(positionTime.Int64).UTC(),
+				Latitude:   latitude.Float64,
+				Longitude:  longitude.Float64,
+				Altitude:   int(altitude.Int64),
+				Angle:      int(angle.Int64),
+				Satellites: int(satellites.Int64),
+				Speed:      int(speed.Int64),
+			}
+		}
+
+		devices = append(devices, device)
+	}
+
+	return devices, rows.Err()
+}
+
+// positionKey identifies a position of a device
+type positionKey struct {
+	time     int64
+	priority int
+	eventID  int
+}
+
+// queryPositions returns the positions of a device with their IO values
+func (s *Store) queryPositions(imei string, q query) ([]Position, error) {
+	rows, err := s.db.QueryContext(s.ctx, `SELECT time, server_time, source, latitude, longitude, altitude, angle, satellites, speed, priority, event_id, generation_type
+		FROM positions WHERE imei = ? AND time >= ? AND time <= ? ORDER BY time, priority, event_id LIMIT ?`,
+		imei, q.from.UnixMilli(), q.to.UnixMilli(), q.limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query positions. %v", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	positions := make([]Position, 0)
+	for rows.Next() {
+		var position Position
+		var timestamp, serverTime int64
+		var priority, eventID int
+		var generationType sql.NullInt64
+
+		err = rows.Scan(&timestamp, &serverTime, &position.Source, &position.Latitude, &position.Longitude, &position.Altitude,
+			&position.Angle, &position.Satellites, &position.Speed, &priority, &eventID, &generationType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read position. %v", err)
+		}
+
+		position.Time = time.UnixMilli(timestamp).UTC()
+		received := time.UnixMilli(serverTime).UTC()
+		position.ServerTime = &received
+		position.Priority = &priority
+		position.EventID = &eventID
+		if generationType.Valid {
+			value := int(generationType.Int64)
+			position.GenerationType = &value
+		}
+		position.IO = make(map[string]interface{})
+
+		positions = append(positions, position)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read positions. %v", err)
+	}
+	if len(positions) == 0 {
+		return positions, nil
+	}
+
+	// IO values of the returned positions only, the limit may have cut the range
+	byKey := make(map[positionKey]*Position, len(positions))
+	for i := range positions {
+		byKey[positionKey{time: positions[i].Time.UnixMilli(), priority: *positions[i].Priority, eventID: *positions[i].EventID}] = &positions[i]
+	}
+
+	values, err := s.db.QueryContext(s.ctx, `SELECT time, priority, event_id, io_id, value FROM io_values WHERE imei = ? AND time >= ? AND time <= ?`,
+		imei, positions[0].Time.UnixMilli(), positions[len(positions)-1].Time.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query IO values. %v", err)
+	}
+	defer func() {
+		_ = values.Close()
+	}()
+
+	for values.Next() {
+		var key positionKey
+		var id uint16
+		var value interface{}
+
+		err = values.Scan(&key.time, &key.priority, &key.eventID, &id, &value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read IO value. %v", err)
+		}
+
+		position, ok := byKey[key]
+		if !ok {
+			continue
+		}
+		if raw, ok := value.([]byte); ok {
+			value = string(raw)
+		}
+		position.IO[sink.IOKey(id)] = value
+	}
+
+	return positions, values.Err()
+}
+
+// queryCommands returns the commands sent to a device and its responses
+func (s *Store) queryCommands(imei string, q query) ([]Command, error) {
+	rows, err := s.db.QueryContext(s.ctx, `SELECT time, direction, text FROM commands WHERE imei = ? AND time >= ? AND time <= ? ORDER BY time, id LIMIT ?`,
+		imei, q.from.UnixMilli(), q.to.UnixMilli(), q.limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query commands. %v", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	commands := make([]Command, 0)
+	for rows.Next() {
+		var command Command
+		var timestamp int64
+
+		err = rows.Scan(&timestamp, &command.Direction, &command.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read command. %v", err)
+		}
+		command.Time = time.UnixMilli(timestamp).UTC()
+
+		commands = append(commands, command)
+	}
+
+	return commands, rows.Err()
+}
+
+func (s *Store) writeJSON(w http.ResponseWriter, value interface{}) {
+	log := config.GetLogger(s.ctx)
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Errorf("Failed to send query result. %v", err)
+	}
+}
+
+func (s *Store) writeError(w http.ResponseWriter, status int, err error) {
+	log := config.GetLogger(s.ctx)
+
+	if status == http.StatusInternalServerError {
+		log.Errorf("Failed to serve sqlite query. %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+/*
+migrations upgrade the schema step by step. The index of a migration plus one is its version.
+Applied migrations must never be changed, new ones have to be appended.
+Times are stored as Unix milliseconds.
+*/
+var migrations = [][]string{
+	// 1: devices, positions, IO values and commands
+	{
+		`CREATE TABLE devices (
+			imei           TEXT PRIMARY KEY,
+			first_seen     INTEGER NOT NULL,
+			last_seen      INTEGER NOT NULL,
+			last_source    TEXT NOT NULL,
+			online         INTEGER NOT NULL DEFAULT 0,
+			transport      TEXT,
+			last_event_at  INTEGER,
+			position_time  INTEGER,
+			latitude       REAL,
+			longitude      REAL,
+			altitude       INTEGER,
+			angle          INTEGER,
+			satellites     INTEGER,
+			speed          INTEGER
+		)`,
+		`CREATE TABLE positions (
+			imei            TEXT NOT NULL,
+			time            INTEGER NOT NULL,
+			server_time     INTEGER NOT NULL,
+			source          TEXT NOT NULL,
+			codec_id        INTEGER NOT NULL,
+			priority        INTEGER NOT NULL,
+			latitude        REAL NOT NULL,
+			longitude       REAL NOT NULL,
+			altitude        INTEGER NOT NULL,
+			angle           INTEGER NOT NULL,
+			satellites      INTEGER NOT NULL,
+			speed           INTEGER NOT NULL,
+			event_id        INTEGER NOT NULL,
+			generation_type INTEGER,
+			PRIMARY KEY (imei, time)
+		) WITHOUT ROWID`,
+		`CREATE TABLE io_values (
+			imei  TEXT NOT NULL,
+			time  INTEGER NOT NULL,
+			io_id INTEGER NOT NULL,
+			value NOT NULL, -- integer, or hex string for values longer than 8 bytes
+			PRIMARY KEY (imei, time, io_id)
+		) WITHOUT ROWID`,
+		`CREATE TABLE commands (
+			id        INTEGER PRIMARY KEY,
+			imei      TEXT NOT NULL,
+			time      INTEGER NOT NULL,
+			direction TEXT NOT NULL, -- request or response
+			text      TEXT NOT NULL
+		)`,
+		`CREATE INDEX commands_imei_time_idx ON commands (imei, time)`,
+	},
+	// 2: records of the same millisecond are told apart by their priority and event ID
+	{
+		`CREATE TABLE positions_new (
+			imei            TEXT NOT NULL,
+			time            INTEGER NOT NULL,
+			server_time     INTEGER NOT NULL,
+			source          TEXT NOT NULL,
+			codec_id        INTEGER NOT NULL,
+			priority        INTEGER NOT NULL,
+			latitude        REAL NOT NULL,
+			longitude       REAL NOT NULL,
+			altitude        INTEGER NOT NULL,
+			angle           INTEGER NOT NULL,
+			satellites      INTEGER NOT NULL,
+			speed           INTEGER NOT NULL,
+			event_id        INTEGER NOT NULL,
+			generation_type INTEGER,
+			PRIMARY KEY (imei, time, priority, event_id)
+		) WITHOUT ROWID`,
+		`INSERT INTO positions_new SELECT imei, time, server_time, source, codec_id, priority, latitude, longitude, altitude, angle,
+			satellites, speed, event_id, generation_type FROM positions`,
+		`CREATE TABLE io_values_new (
+			imei     TEXT NOT NULL,
+			time     INTEGER NOT NULL,
+			priority INTEGER NOT NULL,
+			event_id INTEGER NOT NULL,
+			io_id    INTEGER NOT NULL,
+			value    NOT NULL, -- integer, or hex string for values longer than 8 bytes
+			PRIMARY KEY (imei, time, priority, event_id, io_id)
+		) WITHOUT ROWID`,
+		`INSERT INTO io_values_new SELECT v.imei, v.time, p.priority, p.event_id, v.io_id, v.value
+			FROM io_values v JOIN positions p ON p.imei = v.imei AND p.time = v.time`,
+		`DROP TABLE positions`,
+		`DROP TABLE io_values`,
+		`ALTER TABLE positions_new RENAME TO positions`,
+		`ALTER TABLE io_values_new RENAME TO io_values`,
+	},
+}
+
+// migrate applies the migrations which are not applied yet in one transaction
+func migrate(ctx context.Context, db *sql.DB) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction. %v", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations table. %v", err)
+	}
+
+	var version int
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schema version. %v", err)
+	}
+	if version > len(migrations) {
+		return 0, fmt.Errorf("schema version %d is newer than the latest known version %d", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		for _, statement := range migrations[i] {
+			_, err = tx.ExecContext(ctx, statement)
+			if err != nil {
+				return 0, fmt.Errorf("failed to apply migration %d. %v", i+1, err)
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, strftime('%s', 'now') * 1000)`, i+1)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record migration %d. %v", i+1, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit migrations. %v", err)
+	}
+
+	return len(migrations) - version, nil
+}
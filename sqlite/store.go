@@ -0,0 +1,366 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/halacs/haltonika/codec"
+	"github.com/halacs/haltonika/config"
+	"github.com/halacs/haltonika/sink"
+	_ "modernc.org/sqlite" // registers the pure Go sqlite driver
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	pruneInterval   = time.Hour
+	shutdownTimeout = 5 * time.Second
+
+	// Directions of the commands
+	DirectionRequest  = "request"
+	DirectionResponse = "response"
+)
+
+// Options of a SQLite sink
+type Options struct {
+	Path          string // database file, created if it does not exist
+	RetentionDays int    // positions and commands older than this many days are removed. Zero keeps all.
+	Listen        string // address of the HTTP query API, e.g. 127.0.0.1:8090. Empty disables the API.
+}
+
+// ParseOptions decodes the options of a SQLite sink
+func ParseOptions(options map[string]interface{}) (Options, error) {
+	var parsed Options
+
+	err := sink.DecodeOptions(options, &parsed)
+	if err != nil {
+		return Options{}, err
+	}
+
+	if parsed.Path == "" {
+		return Options{}, fmt.Errorf("path must not be empty")
+	}
+	if parsed.RetentionDays < 0 {
+		return Options{}, fmt.Errorf("retentiondays must not be negative")
+	}
+
+	return parsed, nil
+}
+
+/*
+Store keeps positions, IO values, commands and the state of the devices in an embedded SQLite database
+and serves them over HTTP. It implements sink.Sink, so Haltonika can be used without any database server.
+*/
+type Store struct {
+	ctx     context.Context
+	options Options
+
+	db         *sql.DB
+	httpServer *http.Server
+	stop       context.CancelFunc
+	wg         sync.WaitGroup
+
+	sink.HealthState
+}
+
+func NewStore(ctx context.Context, options Options) *Store {
+	return &Store{
+		ctx:     ctx,
+		options: options,
+	}
+}
+
+// Open opens the database, migrates its schema and starts the pruning and the HTTP API
+func (s *Store) Open() error {
+	log := config.GetLogger(s.ctx)
+
+	// WAL lets the HTTP API read while records are written
+	db, err := sql.Open("sqlite", "file:"+s.options.Path+"?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite database. %v", err)
+	}
+
+	applied, err := migrate(s.ctx, db)
+	if err != nil {
+		_ = db.Close()
+		return fmt.Errorf("failed to migrate sqlite schema. %v", err)
+	}
+	if applied > 0 {
+		log.Infof("%d sqlite schema migrations are applied", applied)
+	}
+
+	s.db = db
+
+	var listener net.Listener
+	if s.options.Listen != "" {
+		listener, err = net.Listen("tcp", s.options.Listen)
+		if err != nil {
+			_ = db.Close()
+			return fmt.Errorf("failed to listen on %s. %v", s.options.Listen, err)
+		}
+	}
+
+	ctx, stop := context.WithCancel(s.ctx)
+	s.stop = stop
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.pruneLoop(ctx)
+	}()
+
+	if listener != nil {
+		s.httpServer = &http.Server{
+			Handler:           s.handler(),
+			ReadHeaderTimeout: 5 * time.Second, // Potential Slowloris Attack if not set
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			log.Infof("Start sqlite query API on %s", listener.Addr())
+			err := s.httpServer.Serve(listener)
+			if err != nil && err != http.ErrServerClosed {
+				log.Errorf("Error in sqlite query API. %v", err)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// Close stops the HTTP API and closes the database
+func (s *Store) Close() error {
+	if s.db == nil {
+		return nil
+	}
+
+	if s.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		_ = s.httpServer.Shutdown(ctx)
+	}
+	s.stop()
+	s.wg.Wait()
+
+	err := s.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close sqlite database. %v", err)
+	}
+
+	return nil
+}
+
+// Write inserts the records of the message in one transaction. Records already stored are skipped.
+func (s *Store) Write(message sink.Message) error {
+	err := s.insert(message)
+	s.SetLastError(err)
+
+	return err
+}
+
+// Flush does nothing because every message is committed by Write
+func (s *Store) Flush() error {
+	return nil
+}
+
+func (s *Store) insert(message sink.Message) error {
+	if s.db == nil {
+		return fmt.Errorf("sqlite database is not open")
+	}
+
+	imei := message.Decoded.IMEI
+	now := time.Now().UnixMilli()
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction. %v", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(s.ctx, `INSERT INTO devices (imei, first_seen, last_seen, last_source) VALUES (?, ?, ?, ?)
+		ON CONFLICT (imei) DO UPDATE SET last_seen = excluded.last_seen, last_source = excluded.last_source`,
+		imei, now, now, message.SourceAddress)
+	if err != nil {
+		return fmt.Errorf("failed to upsert device. %v", err)
+	}
+
+	var latest *codec.AvlData
+	for i, record := range message.Decoded.Data {
+		err = s.insertPosition(tx, message, record, now)
+		if err != nil {
+			return err
+		}
+
+		if latest == nil || record.UtimeMs > latest.UtimeMs {
+			latest = &message.Decoded.Data[i]
+		}
+	}
+
+	// Records buffered by the device may arrive later than newer ones
+	if latest != nil {
+		_, err = tx.ExecContext(s.ctx, `UPDATE devices SET position_time = ?, latitude = ?, longitude = ?, altitude = ?, angle = ?, satellites = ?, speed = ?
+			WHERE imei = ? AND (position_time IS NULL OR position_time < ?)`,
+			sink.Timestamp(*latest).UnixMilli(), sink.Latitude(*latest), sink.Longitude(*latest), latest.Altitude, latest.Angle, latest.VisSat, latest.Speed,
+			imei, sink.Timestamp(*latest).UnixMilli())
+		if err != nil {
+			return fmt.Errorf("failed to update position of device. %v", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit records. %v", err)
+	}
+
+	return nil
+}
+
+// insertPosition stores a record with its IO values unless it is already stored, e.g. the device sent it again.
+// Records of the same millisecond are kept if their priority or event ID differ.
+func (s *Store) insertPosition(tx *sql.Tx, message sink.Message, record codec.AvlData, now int64) error {
+	imei := message.Decoded.IMEI
+	timestamp := sink.Timestamp(record).UnixMilli()
+
+	// Only Codec 16 records tell why they were generated
+	generationType := sql.NullInt16{}
+	if record.GenerationType != codec.GenerationUnsupported {
+		generationType = sql.NullInt16{Int16: int16(record.GenerationType), Valid: true}
+	}
+
+	result, err := tx.ExecContext(s.ctx, `INSERT OR IGNORE INTO positions
+		(imei, time, server_time, source, codec_id, priority, latitude, longitude, altitude, angle, satellites, speed, event_id, generation_type)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		imei, timestamp, now, message.SourceAddress, int16(message.Decoded.CodecID), int16(record.Priority),
+		sink.Latitude(record), sink.Longitude(record), record.Altitude, record.Angle, record.VisSat, record.Speed, record.EventID,
+		generationType)
+	if err != nil {
+		return fmt.Errorf("failed to insert position. %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get number of inserted positions. %v", err)
+	}
+	if affected == 0 {
+		return nil
+	}
+
+	values := sink.IOValues(s.ctx, record)
+	for _, element := range record.Elements {
+		_, err = tx.ExecContext(s.ctx, `INSERT OR IGNORE INTO io_values (imei, time, priority, event_id, io_id, value) VALUES (?, ?, ?, ?, ?, ?)`,
+			imei, timestamp, int16(record.Priority), record.EventID, element.IOID, values[sink.IOKey(element.IOID)])
+		if err != nil {
+			return fmt.Errorf("failed to insert IO value. %v", err)
+		}
+	}
+
+	return nil
+}
+
+// WriteCommandRequest keeps a command sent to a device
+func (s *Store) WriteCommandRequest(imei string, command string) error {
+	return s.insertCommand(imei, DirectionRequest, command)
+}
+
+// WriteCommandResponse keeps the response of a device to a command
+func (s *Store) WriteCommandResponse(imei string, response string) error {
+	return s.insertCommand(imei, DirectionResponse, response)
+}
+
+func (s *Store) insertCommand(imei string, direction string, text string) error {
+	_, err := s.db.ExecContext(s.ctx, `INSERT INTO commands (imei, time, direction, text) VALUES (?, ?, ?, ?)`,
+		imei, time.Now().UnixMilli(), direction, text)
+	if err != nil {
+		return fmt.Errorf("failed to insert command %s. %v", direction, err)
+	}
+
+	return nil
+}
+
+// WriteDeviceEvent keeps whether the device is online
+func (s *Store) WriteDeviceEvent(event sink.DeviceEvent) error {
+	online := 0
+	if event.Type == sink.DeviceConnected {
+		online = 1
+	}
+
+	_, err := s.db.ExecContext(s.ctx, `INSERT INTO devices (imei, first_seen, last_seen, last_source, online, transport, last_event_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (imei) DO UPDATE SET online = excluded.online, transport = excluded.transport, last_event_at = excluded.last_event_at`,
+		event.IMEI, event.Time.UnixMilli(), event.Time.UnixMilli(), event.SourceAddress, online, event.Transport, event.Time.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to update device state. %v", err)
+	}
+
+	return nil
+}
+
+// pruneLoop removes the old positions and commands periodically
+func (s *Store) pruneLoop(ctx context.Context) {
+	log := config.GetLogger(s.ctx)
+
+	if s.options.RetentionDays == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := s.prune(time.Now().AddDate(0, 0, -s.options.RetentionDays))
+		if err != nil {
+			log.Errorf("Failed to remove old records from sqlite. %v", err)
+		} else if removed > 0 {
+			log.Infof("%d positions older than %d days are removed from sqlite", removed, s.options.RetentionDays)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune removes the positions, IO values and commands older than the given time. Returns the number of removed positions.
+func (s *Store) prune(before time.Time) (int64, error) {
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction. %v", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.ExecContext(s.ctx, `DELETE FROM positions WHERE time < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove positions. %v", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get number of removed positions. %v", err)
+	}
+
+	_, err = tx.ExecContext(s.ctx, `DELETE FROM io_values WHERE time < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove IO values. %v", err)
+	}
+
+	_, err = tx.ExecContext(s.ctx, `DELETE FROM commands WHERE time < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove commands. %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit removal. %v", err)
+	}
+
+	return removed, nil
+}
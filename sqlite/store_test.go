@@ -0,0 +1,248 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"github.com/halacs/haltonika/sink"
+	"github.com/halacs/haltonika/sink/sinktest"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// get requests the path from the query API and decodes its JSON response
+func get(t *testing.T, handler http.Handler, path string, result interface{}) int {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+	if result != nil && recorder.Code == http.StatusOK {
+		err := json.Unmarshal(recorder.Body.Bytes(), result)
+		if err != nil {
+			t.Fatalf("Invalid response of %s. %v", path, err)
+		}
+	}
+
+	return recorder.Code
+}
+
+func TestParseOptions(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Options       map[string]interface{}
+		ExpectedError bool
+	}{
+		{
+			Name:    "Defaults",
+			Options: map[string]interface{}{"path": "/var/lib/haltonika/haltonika.db"},
+		},
+		{
+			Name:    "All options",
+			Options: map[string]interface{}{"path": "haltonika.db", "retentiondays": 90, "listen": "127.0.0.1:8090"},
+		},
+		{
+			Name:          "Missing path",
+			Options:       map[string]interface{}{},
+			ExpectedError: true,
+		},
+		{
+			Name:          "Negative retention",
+			Options:       map[string]interface{}{"path": "haltonika.db", "retentiondays": -1},
+			ExpectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			_, err := ParseOptions(testCase.Options)
+			if (err != nil) != testCase.ExpectedError {
+				test.Errorf("Wrong result! Expected error: %v Actual: %v", testCase.ExpectedError, err)
+			}
+		})
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		Name          string
+		Query         string
+		ExpectedFrom  time.Time
+		ExpectedTo    time.Time
+		ExpectedLimit int
+		ExpectedError bool
+	}{
+		{
+			Name:          "Last day by default",
+			Query:         "",
+			ExpectedFrom:  now.Add(-24 * time.Hour),
+			ExpectedTo:    now,
+			ExpectedLimit: DefaultQueryLimit,
+		},
+		{
+			Name:          "Range",
+			Query:         "?from=2024-01-01T00:00:00Z&to=2024-01-01T12:00:00%2B02:00&limit=10",
+			ExpectedFrom:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			ExpectedTo:    time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			ExpectedLimit: 10,
+		},
+		{
+			Name:          "Invalid time",
+			Query:         "?from=yesterday",
+			ExpectedError: true,
+		},
+		{
+			Name:          "From after to",
+			Query:         "?from=2024-01-03T00:00:00Z",
+			ExpectedError: true,
+		},
+		{
+			Name:          "Too large limit",
+			Query:         "?limit=1000000",
+			ExpectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(test *testing.T) {
+			q, err := parseQuery(httptest.NewRequest(http.MethodGet, "/api/devices/1/positions"+testCase.Query, nil), now)
+			if (err != nil) != testCase.ExpectedError {
+				test.Fatalf("Wrong result! Expected error: %v Actual: %v", testCase.ExpectedError, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if !q.from.Equal(testCase.ExpectedFrom) || !q.to.Equal(testCase.ExpectedTo) || q.limit != testCase.ExpectedLimit {
+				test.Errorf("Wrong query! Expected: %v %v %v Actual: %v %v %v", testCase.ExpectedFrom, testCase.ExpectedTo, testCase.ExpectedLimit, q.from, q.to, q.limit)
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(sinktest.NewContext(), Options{Path: filepath.Join(t.TempDir(), "haltonika.db")})
+	err := store.Open()
+	if err != nil {
+		t.Fatalf("Failed to open store. %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+
+	// Second write of the same records is skipped
+	message := sinktest.NewMessage(t)
+	for i := 0; i < 2; i++ {
+		err = store.Write(message)
+		if err != nil {
+			t.Fatalf("Failed to write message. %v", err)
+		}
+	}
+
+	err = store.WriteDeviceEvent(sink.DeviceEvent{IMEI: "352094089397464", Type: sink.DeviceConnected, Transport: "udp", SourceAddress: "127.0.0.1:1234", Time: time.Now()})
+	if err != nil {
+		t.Fatalf("Failed to write device event. %v", err)
+	}
+	err = store.WriteCommandRequest("352094089397464", "getver")
+	if err != nil {
+		t.Fatalf("Failed to write command. %v", err)
+	}
+	err = store.WriteCommandResponse("352094089397464", "Ver:03.25.14")
+	if err != nil {
+		t.Fatalf("Failed to write command response. %v", err)
+	}
+
+	handler := store.handler()
+
+	var devices []Device
+	status := get(t, handler, "/api/devices", &devices)
+	if status != http.StatusOK || len(devices) != 1 {
+		t.Fatalf("Wrong devices! Status: %v Devices: %+v", status, devices)
+	}
+	device := devices[0]
+	if !device.Online || device.Transport != "udp" || device.Position == nil || !device.Position.Time.Equal(time.UnixMilli(1528069090050)) {
+		t.Errorf("Wrong device state: %+v %+v", device, device.Position)
+	}
+
+	var positions []Position
+	status = get(t, handler, "/api/devices/352094089397464/positions?from=2018-06-03T23:00:00Z&to=2018-06-04T00:00:00Z", &positions)
+	if status != http.StatusOK || len(positions) != 4 {
+		t.Fatalf("Wrong positions! Status: %v Positions: %+v", status, positions)
+	}
+	if positions[0].Time.After(positions[3].Time) || positions[3].IO["IOID66"] != float64(28632) || len(positions[3].IO) != 27 {
+		t.Errorf("Wrong position: %+v", positions[3])
+	}
+
+	status = get(t, handler, "/api/devices/352094089397464/positions?from=2018-06-03T23:00:00Z&to=2018-06-04T00:00:00Z&limit=1", &positions)
+	if status != http.StatusOK || len(positions) != 1 || len(positions[0].IO) != 27 {
+		t.Errorf("Wrong limited positions! Status: %v Positions: %+v", status, positions)
+	}
+
+	var commands []Command
+	status = get(t, handler, "/api/devices/352094089397464/commands", &commands)
+	if status != http.StatusOK || len(commands) != 2 || commands[0].Direction != DirectionRequest || commands[1].Text != "Ver:03.25.14" {
+		t.Errorf("Wrong commands! Status: %v Commands: %+v", status, commands)
+	}
+
+	for path, expected := range map[string]int{
+		"/api/devices/123":                            http.StatusNotFound,
+		"/api/devices/352094089397464/trips":          http.StatusNotFound,
+		"/api/devices/352094089397464/positions?to=x": http.StatusBadRequest,
+	} {
+		status = get(t, handler, path, nil)
+		if status != expected {
+			t.Errorf("Wrong status of %s! Expected: %v Actual: %v", path, expected, status)
+		}
+	}
+
+	// Everything is older than the retention
+	removed, err := store.prune(time.Now())
+	if err != nil || removed != 4 {
+		t.Errorf("Wrong prune! Expected: 4 removed positions Actual: %v %v", removed, err)
+	}
+	status = get(t, handler, "/api/devices/352094089397464/positions?from=2018-06-03T23:00:00Z&to=2018-06-04T00:00:00Z", &positions)
+	if status != http.StatusOK || len(positions) != 0 {
+		t.Errorf("Positions must be removed: %+v", positions)
+	}
+}
+
+func TestStoreSameTime(t *testing.T) {
+	store := NewStore(sinktest.NewContext(), Options{Path: filepath.Join(t.TempDir(), "haltonika.db")})
+	err := store.Open()
+	if err != nil {
+		t.Fatalf("Failed to open store. %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+
+	// Records generated by an other event in the same millisecond
+	message := sinktest.NewMessage(t)
+	other := sinktest.NewMessage(t)
+	for i := range other.Decoded.Data {
+		other.Decoded.Data[i].EventID = 0xfffe
+		other.Decoded.Data[i].Elements = other.Decoded.Data[i].Elements[:1]
+	}
+	for _, m := range []sink.Message{message, other, message} {
+		err = store.Write(m)
+		if err != nil {
+			t.Fatalf("Failed to write message. %v", err)
+		}
+	}
+
+	var positions []Position
+	status := get(t, store.handler(), "/api/devices/352094089397464/positions?from=2018-06-03T23:00:00Z&to=2018-06-04T00:00:00Z", &positions)
+	if status != http.StatusOK || len(positions) != 8 {
+		t.Fatalf("Wrong positions! Status: %v Positions: %+v", status, positions)
+	}
+	for _, position := range positions {
+		expected := 27
+		if *position.EventID == 0xfffe {
+			expected = 1
+		}
+		if len(position.IO) != expected {
+			t.Errorf("Wrong number of IO values! Expected: %v Actual: %v", expected, len(position.IO))
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"github.com/halacs/haltonika/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"os"
+	"os/signal"
+)
+
+// subcommands are tools next to the server. They are selected by the first command line argument.
+var subcommands = map[string]func(args []string) int{
+	"simulate": runSimulate,
+	"replay":   runReplay,
+	"decode":   runDecode,
+}
+
+// newSubcommandContext creates the logger and the context of a subcommand. It is cancelled by interrupt.
+func newSubcommandContext(flags *pflag.FlagSet) (context.Context, *logrus.Logger) {
+	log := config.NewLogger()
+
+	if verbose, _ := flags.GetBool(config.Verbose); verbose {
+		log.SetLevel(logrus.TraceLevel)
+	} else if debug, _ := flags.GetBool(config.Debug); debug {
+		log.SetLevel(logrus.DebugLevel)
+	}
+
+	cfg := config.NewConfig(log, nil, nil, nil, nil, nil)
+	ctxSignals, _ := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	return context.WithValue(ctxSignals, config.ContextConfigKey, cfg), log
+}
+
+// addLogFlags adds the same log level flags to a subcommand as the server has
+func addLogFlags(flags *pflag.FlagSet) {
+	flags.Bool(config.Debug, config.DefaultDebug, "Set log level to debug")
+	flags.Bool(config.Verbose, config.DefaultVerbose, "Set log level to verbose")
+}